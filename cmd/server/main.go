@@ -5,9 +5,15 @@ import (
 	"crypto/tls"
 	"database/sql"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -28,11 +34,14 @@ func main() {
 	cfg := config.Load()
 
 	// 2. Database
-	db, err := sql.Open("sqlite3", cfg.DatabasePath)
+	db, err := sql.Open("sqlite3", sqliteDSN(cfg.DatabasePath, cfg.DBBusyTimeout))
 	if err != nil {
 		log.Fatalf("failed to open db: %v", err)
 	}
 	defer db.Close()
+	// One writer at a time regardless; a small pool serves concurrent WAL readers
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxOpenConns)
 
 	// 3. Run migrations (golang-migrate)
 	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
@@ -74,65 +83,93 @@ func main() {
 	// 6. Security & Server Setup
 	e := EchoServer(queries, cfg, worker, db)
 	// Global Middleware for Security Headers (HSTS, CSP, etc.)
-	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			// CSP: Strict security policy
-			c.Response().Header().Set("Content-Security-Policy", "default-src 'self'; img-src 'self' blob: data:; style-src 'self' 'unsafe-inline'; script-src 'self'; connect-src 'self' ws: wss:;")
-			// Security Headers
-			c.Response().Header().Set("X-Content-Type-Options", "nosniff")
-			c.Response().Header().Set("X-Frame-Options", "DENY")
-			if cfg.TLSDomain != "" {
-				c.Response().Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
-			}
-			return next(c)
-		}
-	})
+	e.Use(api.SecurityHeaders(cfg))
 
 	// Start Server
-	StartServer(e, cfg)
+	StartServer(e, cfg, worker)
+}
+
+// sqliteDSN adds WAL mode and the busy timeout as DSN parameters so every pooled
+// connection gets them (a PRAGMA run through db.Exec only reaches one connection).
+// Parameters already present in DATABASE_PATH take precedence.
+func sqliteDSN(path string, busyTimeout time.Duration) string {
+	base, query, _ := strings.Cut(path, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		log.Printf("Warning: ignoring malformed DATABASE_PATH parameters: %v", err)
+		params = url.Values{}
+	}
+	defaults := map[string]string{
+		"_journal_mode": "WAL",
+		"_synchronous":  "NORMAL",
+		"_busy_timeout": strconv.FormatInt(busyTimeout.Milliseconds(), 10),
+		// Take the write lock at BEGIN so busy_timeout applies instead of failing on upgrade
+		"_txlock": "immediate",
+	}
+	for k, v := range defaults {
+		if !params.Has(k) {
+			params.Set(k, v)
+		}
+	}
+	return base + "?" + params.Encode()
 }
 
 func EchoServer(q *database.Queries, cfg *config.Config, w *recorder.Worker, db *sql.DB) *echo.Echo {
 	e := echo.New()
+	ipExtractor, err := api.IPExtractor(cfg)
+	if err != nil {
+		log.Fatalf("Config validation failed: %v", err)
+	}
+	e.IPExtractor = ipExtractor
+	if len(cfg.TrustedProxies) == 0 {
+		log.Println("Warning: TRUSTED_PROXIES is not set, so X-Forwarded-For is ignored; behind a reverse proxy every client shares the proxy's address (and its rate limits)")
+	}
 
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
+	e.Use(api.ConcurrencyLimit(cfg.MaxConcurrentRequests))
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		AllowOrigins:  []string{"*"},
+		AllowHeaders:  []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		ExposeHeaders: api.PaginationHeaders,
 	}))
 
 	h := api.New(q, cfg, w, db)
 	h.RegisterRoutes(e)
 
+	// Expose recordings (downloads can outlive WRITE_TIMEOUT)
+	if cfg.ServeRecordingsStatic {
+		e.Group("/recordings", h.NoWriteDeadlineMiddleware).Static("/", "/app/recordings")
+	}
+
 	// Serve Frontend (SPA)
-	e.Static("/assets", "web/dist/assets")
-	e.Static("/recordings", "/app/recordings") // Expose recordings
-	e.File("/favicon.ico", "web/dist/favicon.ico")
-	e.GET("/*", func(c echo.Context) error {
-		return c.File("web/dist/index.html")
-	})
+	if cfg.ServeFrontend {
+		e.Static("/assets", "web/dist/assets")
+		e.File("/favicon.ico", "web/dist/favicon.ico")
+		// The SPA shell carries the backend readiness for the UI
+		e.GET("/*", h.ServeIndex("web/dist/index.html"))
+	}
 
 	return e
 }
 
-func StartServer(e *echo.Echo, cfg *config.Config) {
+func StartServer(e *echo.Echo, cfg *config.Config, worker *recorder.Worker) {
 	// Validate Config (Permissions check)
 	if err := cfg.Validate(); err != nil {
 		log.Fatalf("Config validation failed: %v", err)
 	}
 
-	// Context for graceful shutdown
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	// Context for graceful shutdown (SIGTERM is what container runtimes send)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	// Default Timeouts for Slowloris Mitigation
-	const (
-		readTimeout       = 10 * time.Second
-		writeTimeout      = 30 * time.Second // Longer for uploads/downloads if any
-		readHeaderTimeout = 5 * time.Second
-		idleTimeout       = 120 * time.Second
-	)
+	conns := &connTracker{states: make(map[net.Conn]http.ConnState)}
+
+	// Timeouts for Slowloris Mitigation (configurable via READ_TIMEOUT/WRITE_TIMEOUT/IDLE_TIMEOUT)
+	const readHeaderTimeout = 5 * time.Second
+	readTimeout := cfg.ReadTimeout
+	writeTimeout := cfg.WriteTimeout
+	idleTimeout := cfg.IdleTimeout
 
 	// HTTP Server
 	httpServer := &http.Server{
@@ -142,6 +179,7 @@ func StartServer(e *echo.Echo, cfg *config.Config) {
 		WriteTimeout:      writeTimeout,
 		ReadHeaderTimeout: readHeaderTimeout,
 		IdleTimeout:       idleTimeout,
+		ConnState:         conns.track,
 	}
 
 	// HTTPS Server (Optional)
@@ -167,14 +205,21 @@ func StartServer(e *echo.Echo, cfg *config.Config) {
 		tlsConfig := autoTLSManager.TLSConfig()
 		tlsConfig.MinVersion = tls.VersionTLS12
 
+		// Explicitly serve HTTP/2 over TLS (with HTTP/1.1 fallback)
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+
 		httpsServer = &http.Server{
 			Addr:              ":" + cfg.HTTPSPort,
 			Handler:           e,
 			TLSConfig:         tlsConfig,
+			Protocols:         protocols,
 			ReadTimeout:       readTimeout,
 			WriteTimeout:      writeTimeout,
 			ReadHeaderTimeout: readHeaderTimeout,
 			IdleTimeout:       idleTimeout,
+			ConnState:         conns.track,
 		}
 
 		// Start HTTPS
@@ -205,15 +250,58 @@ func StartServer(e *echo.Echo, cfg *config.Config) {
 	<-ctx.Done()
 	log.Println("Shutting down gracefully...")
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Requests drain while recordings finalize, both within SHUTDOWN_TIMEOUT
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
-	if err := httpServer.Shutdown(shutdownCtx); err != nil {
-		e.Logger.Fatal(err)
-	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := worker.FinishRecordings(shutdownCtx); err != nil {
+			log.Printf("Shutdown: %v", err)
+		}
+	}()
+
+	servers := []*http.Server{httpServer}
 	if httpsServer != nil {
-		if err := httpsServer.Shutdown(shutdownCtx); err != nil {
-			e.Logger.Fatal(err)
+		servers = append(servers, httpsServer)
+	}
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Shutdown: %s: %v (%d request(s) still in flight)", srv.Addr, err, conns.active())
+		}
+	}
+	wg.Wait()
+}
+
+// connTracker follows the server's connections so a shutdown that times out can
+// report how many requests (e.g. long downloads) it was still waiting for
+type connTracker struct {
+	mu     sync.Mutex
+	states map[net.Conn]http.ConnState
+}
+
+func (t *connTracker) track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch state {
+	case http.StateHijacked, http.StateClosed:
+		delete(t.states, c)
+	default:
+		t.states[c] = state
+	}
+}
+
+// active returns the number of connections with a request in progress
+func (t *connTracker) active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, state := range t.states {
+		if state == http.StateActive {
+			n++
 		}
 	}
+	return n
 }
@@ -0,0 +1 @@
+ALTER TABLE recordings ADD COLUMN is_protected BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS oidc_sessions (
+    username TEXT PRIMARY KEY,
+    refresh_token TEXT NOT NULL, -- AES-GCM encrypted
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN max_recordings INTEGER NOT NULL DEFAULT 0;
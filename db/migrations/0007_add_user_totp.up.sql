@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+    username TEXT PRIMARY KEY,
+    secret TEXT NOT NULL, -- AES-GCM encrypted
+    enabled BOOLEAN NOT NULL DEFAULT 0,
+    recovery_codes TEXT NOT NULL DEFAULT '', -- comma-separated SHA-256 hashes
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
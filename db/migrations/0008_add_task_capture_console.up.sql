@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN capture_console BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE recordings ADD COLUMN dropped_frames INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE recordings ADD COLUMN is_degraded BOOLEAN NOT NULL DEFAULT 0;
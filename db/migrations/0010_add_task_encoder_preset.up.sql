@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN encoder_preset TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN encoder_tune TEXT NOT NULL DEFAULT '';
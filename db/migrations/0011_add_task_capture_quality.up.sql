@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN capture_quality INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS task_credentials (
+    task_id INTEGER PRIMARY KEY,
+    username TEXT NOT NULL, -- AES-GCM encrypted
+    password TEXT NOT NULL, -- AES-GCM encrypted
+    username_selector TEXT NOT NULL,
+    password_selector TEXT NOT NULL,
+    submit_selector TEXT NOT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN notify_size_bytes INTEGER NOT NULL DEFAULT 0;
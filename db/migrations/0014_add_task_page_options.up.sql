@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN referer TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN java_script_enabled BOOLEAN NOT NULL DEFAULT 1;
+ALTER TABLE tasks ADD COLUMN offline BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE recordings ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE recordings ADD COLUMN duplicate_of INTEGER;
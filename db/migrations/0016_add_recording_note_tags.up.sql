@@ -0,0 +1,2 @@
+ALTER TABLE recordings ADD COLUMN note TEXT NOT NULL DEFAULT '';
+ALTER TABLE recordings ADD COLUMN tags TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN viewports TEXT NOT NULL DEFAULT '';
+ALTER TABLE recordings ADD COLUMN viewport TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN record_on_change BOOLEAN NOT NULL DEFAULT 0;
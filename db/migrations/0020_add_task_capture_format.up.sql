@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN capture_format TEXT NOT NULL DEFAULT 'jpeg';
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN blocked_resources TEXT NOT NULL DEFAULT '';
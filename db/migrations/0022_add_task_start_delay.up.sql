@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN start_delay_ms INTEGER NOT NULL DEFAULT 0;
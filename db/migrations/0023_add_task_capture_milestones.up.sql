@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN capture_milestones BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN output_dir TEXT NOT NULL DEFAULT '';
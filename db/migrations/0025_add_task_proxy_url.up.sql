@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN proxy_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN regions TEXT NOT NULL DEFAULT '';
+ALTER TABLE recordings ADD COLUMN region TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN keepalive TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN watermark TEXT NOT NULL DEFAULT '';
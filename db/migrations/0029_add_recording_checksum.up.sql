@@ -0,0 +1,2 @@
+ALTER TABLE recordings ADD COLUMN checksum TEXT NOT NULL DEFAULT '';
+ALTER TABLE recordings ADD COLUMN checksum_signature TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE user_totp ADD COLUMN last_counter INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE recordings ADD COLUMN run_id TEXT NOT NULL DEFAULT '';
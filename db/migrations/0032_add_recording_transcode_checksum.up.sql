@@ -0,0 +1 @@
+ALTER TABLE recordings ADD COLUMN transcode_checksum TEXT NOT NULL DEFAULT '';
@@ -5,12 +5,19 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/nullpo7z/dashboard-recorder/internal/auth"
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
 	"golang.org/x/oauth2"
 )
 
@@ -19,14 +26,20 @@ const (
 	cookieState    = "oidc_state"
 	cookieNonce    = "oidc_nonce"
 	cookieVerifier = "oidc_verifier"
+	cookieNext     = "oidc_next"
 )
 
 // OIDCContext holds the provider and config for reuse
 type OIDCContext struct {
 	Provider *oidc.Provider
 	Config   *oauth2.Config
+	// EndSessionURL is the provider's end_session_endpoint (empty if not advertised)
+	EndSessionURL string
 }
 
+// oidcDiscoveryTimeout bounds a single discovery attempt so a hanging IdP can't block startup
+const oidcDiscoveryTimeout = 10 * time.Second
+
 // InitOIDC initializes the OIDC provider (discovery)
 func (h *Handler) InitOIDC() error {
 	if h.Config.OIDCProvider == "" {
@@ -34,13 +47,25 @@ func (h *Handler) InitOIDC() error {
 		return nil
 	}
 
-	provider, err := oidc.NewProvider(context.Background(), h.Config.OIDCProvider)
+	ctx, cancel := context.WithTimeout(context.Background(), oidcDiscoveryTimeout)
+	defer cancel()
+
+	provider, err := oidc.NewProvider(ctx, h.Config.OIDCProvider)
 	if err != nil {
 		return fmt.Errorf("failed to get OIDC provider: %w", err)
 	}
 
-	h.OIDC = &OIDCContext{
-		Provider: provider,
+	// Optional RP-initiated logout support (not part of go-oidc's typed endpoint)
+	var discovery struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := provider.Claims(&discovery); err != nil {
+		fmt.Printf("OIDC: Failed to read discovery claims: %v\n", err)
+	}
+
+	h.oidcCtx.Store(&OIDCContext{
+		EndSessionURL: discovery.EndSessionEndpoint,
+		Provider:      provider,
 		Config: &oauth2.Config{
 			ClientID:     h.Config.OIDCClientID,
 			ClientSecret: h.Config.OIDCClientSecret,
@@ -48,14 +73,51 @@ func (h *Handler) InitOIDC() error {
 			Endpoint:     provider.Endpoint(),
 			Scopes:       h.Config.OIDCScopes,
 		},
-	}
+	})
 	fmt.Printf("OIDC: Initialized with provider %s\n", h.Config.OIDCProvider)
 	return nil
 }
 
+// OIDC returns the initialized provider, or nil while OIDC is disabled or discovery
+// hasn't succeeded yet
+func (h *Handler) OIDC() *OIDCContext {
+	return h.oidcCtx.Load()
+}
+
+// retryOIDCDiscovery keeps re-attempting discovery with exponential backoff until it
+// succeeds or ctx ends, so an IdP that starts after us is picked up without a restart
+func (h *Handler) retryOIDCDiscovery(ctx context.Context) {
+	backoff := h.Config.OIDCDiscoveryBackoff
+	for attempt := 2; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		fmt.Printf("OIDC: Discovery attempt %d for %s\n", attempt, h.Config.OIDCProvider)
+		err := h.InitOIDC()
+		if err == nil {
+			return
+		}
+		backoff = nextOIDCBackoff(backoff, h.Config.OIDCDiscoveryMaxBackoff)
+		fmt.Printf("OIDC: Discovery attempt %d failed: %v (retrying in %s)\n", attempt, err, backoff)
+	}
+}
+
+// nextOIDCBackoff doubles the delay up to limit
+func nextOIDCBackoff(current, limit time.Duration) time.Duration {
+	next := current * 2
+	if next > limit || next <= 0 {
+		return limit
+	}
+	return next
+}
+
 // AuthLogin initiates the OIDC flow
 func (h *Handler) AuthLogin(c echo.Context) error {
-	if h.OIDC == nil {
+	oidcCtx := h.OIDC()
+	if oidcCtx == nil {
 		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "OIDC not configured"})
 	}
 
@@ -81,14 +143,17 @@ func (h *Handler) AuthLogin(c echo.Context) error {
 	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
 
 	// 4. Set Secure Cookies (One-time use)
-	// Use __Host- prefix for extra security if over HTTPS (recommended)
-	// We'll trust the user has TLS termination or local usage.
+	// Secure/SameSite/__Host- prefix follow COOKIE_* settings (see setCookie)
 	h.setCookie(c, cookieState, state, 300)
 	h.setCookie(c, cookieNonce, nonce, 300)
 	h.setCookie(c, cookieVerifier, verifier, 300)
+	// Page to return to after login (?next=), same-site paths only
+	if next := safeNextPath(c.QueryParam("next")); next != "" {
+		h.setCookie(c, cookieNext, url.QueryEscape(next), 300)
+	}
 
 	// 5. Redirect
-	authURL := h.OIDC.Config.AuthCodeURL(
+	authURL := oidcCtx.Config.AuthCodeURL(
 		state,
 		oidc.Nonce(nonce),
 		oauth2.SetAuthURLParam("code_challenge", challenge),
@@ -100,8 +165,9 @@ func (h *Handler) AuthLogin(c echo.Context) error {
 
 // AuthCallback handles the IDP response
 func (h *Handler) AuthCallback(c echo.Context) error {
-	if h.OIDC == nil {
-		return c.Redirect(http.StatusFound, "/login?error=oidc_disabled")
+	oidcCtx := h.OIDC()
+	if oidcCtx == nil {
+		return h.redirectToLogin(c, url.Values{"error": {"oidc_disabled"}})
 	}
 
 	// Cleanup cookies regardless of outcome
@@ -109,53 +175,66 @@ func (h *Handler) AuthCallback(c echo.Context) error {
 
 	// 1. Validate State
 	queryState := c.QueryParam("state")
-	cookieStateVal, err := c.Cookie(cookieState)
+	cookieStateVal, err := c.Cookie(h.cookieName(cookieState))
 	if err != nil || queryState != cookieStateVal.Value {
 		fmt.Printf("OIDC Error: State mismatch. Query: %s, Cookie: %v\n", queryState, err)
-		return c.Redirect(http.StatusFound, "/login?error=invalid_state")
+		return h.redirectToLogin(c, url.Values{"error": {"invalid_state"}})
 	}
 
 	// 2. Exchange Code for Token (PKCE)
 	code := c.QueryParam("code")
 	if code == "" {
-		return c.Redirect(http.StatusFound, "/login?error=missing_code")
+		return h.redirectToLogin(c, url.Values{"error": {"missing_code"}})
 	}
 
-	cookieVerifierVal, err := c.Cookie(cookieVerifier)
+	cookieVerifierVal, err := c.Cookie(h.cookieName(cookieVerifier))
 	if err != nil {
-		return c.Redirect(http.StatusFound, "/login?error=missing_verifier")
+		return h.redirectToLogin(c, url.Values{"error": {"missing_verifier"}})
 	}
 
-	token, err := h.OIDC.Config.Exchange(
-		c.Request().Context(),
-		code,
-		oauth2.VerifierOption(cookieVerifierVal.Value),
-	)
+	var token *oauth2.Token
+	err = h.oidcRoundTrip(c.Request().Context(), func(ctx context.Context) error {
+		var err error
+		token, err = oidcCtx.Config.Exchange(ctx, code, oauth2.VerifierOption(cookieVerifierVal.Value))
+		return err
+	})
 	if err != nil {
 		fmt.Printf("OIDC Error: Token exchange failed: %v\n", err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return h.redirectToLogin(c, url.Values{"error": {"idp_timeout"}})
+		}
 		// Mask error
-		return c.Redirect(http.StatusFound, "/login?error=token_exchange_failed")
+		return h.redirectToLogin(c, url.Values{"error": {"token_exchange_failed"}})
 	}
 
 	// 3. Extract ID Token
 	rawIDToken, ok := token.Extra("id_token").(string)
 	if !ok {
-		return c.Redirect(http.StatusFound, "/login?error=no_id_token")
+		return h.redirectToLogin(c, url.Values{"error": {"no_id_token"}})
 	}
 
 	// 4. Verify ID Token
-	verifier := h.OIDC.Provider.Verifier(&oidc.Config{ClientID: h.Config.OIDCClientID})
-	idToken, err := verifier.Verify(c.Request().Context(), rawIDToken)
+	// (may fetch the IdP's signing keys, so it gets the same bound as the exchange)
+	verifier := oidcCtx.Provider.Verifier(&oidc.Config{ClientID: h.Config.OIDCClientID})
+	var idToken *oidc.IDToken
+	err = h.oidcRoundTrip(c.Request().Context(), func(ctx context.Context) error {
+		var err error
+		idToken, err = verifier.Verify(ctx, rawIDToken)
+		return err
+	})
 	if err != nil {
 		fmt.Printf("OIDC Error: Token verification failed: %v\n", err)
-		return c.Redirect(http.StatusFound, "/login?error=token_verification_failed")
+		if errors.Is(err, context.DeadlineExceeded) {
+			return h.redirectToLogin(c, url.Values{"error": {"idp_timeout"}})
+		}
+		return h.redirectToLogin(c, url.Values{"error": {"token_verification_failed"}})
 	}
 
 	// 5. Verify Nonce
-	cookieNonceVal, err := c.Cookie(cookieNonce)
+	cookieNonceVal, err := c.Cookie(h.cookieName(cookieNonce))
 	if err != nil || idToken.Nonce != cookieNonceVal.Value {
 		fmt.Println("OIDC Error: Nonce mismatch")
-		return c.Redirect(http.StatusFound, "/login?error=invalid_nonce")
+		return h.redirectToLogin(c, url.Values{"error": {"invalid_nonce"}})
 	}
 
 	// 6. Access Control (Email Check)
@@ -164,13 +243,23 @@ func (h *Handler) AuthCallback(c echo.Context) error {
 		EmailVerified bool   `json:"email_verified"`
 	}
 	if err := idToken.Claims(&claims); err != nil {
-		return c.Redirect(http.StatusFound, "/login?error=claims_error")
+		return h.redirectToLogin(c, url.Values{"error": {"claims_error"}})
 	}
 
 	if !h.isEmailAllowed(claims.Email) {
 		fmt.Printf("OIDC Error: Email %s not in allowed list\n", claims.Email)
 		// Specific error for unauthorized user
-		return c.Redirect(http.StatusFound, "/login?error=access_denied")
+		return h.redirectToLogin(c, url.Values{"error": {"access_denied"}})
+	}
+
+	// Keep the ID token so logout can pass it as id_token_hint
+	h.storeIDTokenHint(claims.Email, rawIDToken)
+
+	// Keep the refresh token (only issued when offline_access is granted) for silent re-auth
+	if token.RefreshToken != "" {
+		if err := h.storeRefreshToken(c.Request().Context(), claims.Email, token.RefreshToken); err != nil {
+			fmt.Printf("OIDC Warning: Failed to store refresh token: %v\n", err)
+		}
 	}
 
 	// 7. Establish Session
@@ -178,7 +267,7 @@ func (h *Handler) AuthCallback(c echo.Context) error {
 	appToken, err := h.generateAppToken(claims.Email)
 	if err != nil {
 		fmt.Printf("OIDC Error: Failed to generate app token: %v\n", err)
-		return c.Redirect(http.StatusFound, "/login?error=session_error")
+		return h.redirectToLogin(c, url.Values{"error": {"session_error"}})
 	}
 
 	// Return HTML attempting to store token and redirect
@@ -194,11 +283,192 @@ func (h *Handler) AuthCallback(c echo.Context) error {
 	// The user request didn't specify session mechanism change, but "Login Handler" returns JSON.
 	// Here we are in a browser redirect flow.
 	// We will redirect to /login?token=... and let frontend handle it.
-	return c.Redirect(http.StatusFound, fmt.Sprintf("/login?token=%s", appToken))
+	params := url.Values{"token": {appToken}}
+	if nextVal, err := c.Cookie(h.cookieName(cookieNext)); err == nil {
+		if next, err := url.QueryUnescape(nextVal.Value); err == nil && safeNextPath(next) != "" {
+			params.Set("next", next)
+		}
+	}
+	return h.redirectToLogin(c, params)
+}
+
+// AuthLogout ends the IdP session (RP-initiated logout) in addition to the app session.
+// Browsers posting a form are redirected; API clients asking for JSON receive the URL instead.
+func (h *Handler) AuthLogout(c echo.Context) error {
+	username, _ := usernameFromContext(c)
+	hint := h.takeIDTokenHint(username)
+	if h.Queries != nil && username != "" {
+		_ = h.Queries.DeleteOIDCSession(c.Request().Context(), username)
+	}
+
+	oidcCtx := h.OIDC()
+	if oidcCtx == nil || oidcCtx.EndSessionURL == "" {
+		// Nothing to do server-side; the client simply discards its token
+		return c.JSON(http.StatusOK, map[string]string{"status": "logged_out"})
+	}
+
+	logoutURL, err := url.Parse(oidcCtx.EndSessionURL)
+	if err != nil {
+		return h.mapOIDCError(c, err, "invalid end_session_endpoint")
+	}
+	q := logoutURL.Query()
+	if hint != "" {
+		q.Set("id_token_hint", hint)
+	}
+	q.Set("client_id", h.Config.OIDCClientID)
+	if h.Config.OIDCPostLogoutRedirectURL != "" {
+		q.Set("post_logout_redirect_uri", h.Config.OIDCPostLogoutRedirectURL)
+	}
+	logoutURL.RawQuery = q.Encode()
+
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMEApplicationJSON) {
+		return c.JSON(http.StatusOK, map[string]string{"status": "logged_out", "logout_url": logoutURL.String()})
+	}
+	return c.Redirect(http.StatusSeeOther, logoutURL.String())
+}
+
+// AuthRefresh re-issues the app JWT using the stored OIDC refresh token, so OIDC users
+// stay logged in without another IdP redirect. The IdP remains the source of truth:
+// a revoked refresh token or a de-listed email ends the session.
+func (h *Handler) AuthRefresh(c echo.Context) error {
+	oidcCtx := h.OIDC()
+	if oidcCtx == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "OIDC not configured"})
+	}
+
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+
+	ctx := c.Request().Context()
+	session, err := h.Queries.GetOIDCSession(ctx, username)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "no refresh token available"})
+	}
+	refreshToken, err := auth.Decrypt(h.encryptionKey("oidc-refresh"), session.RefreshToken)
+	if err != nil {
+		fmt.Printf("OIDC Error: Failed to decrypt refresh token for %s: %v\n", username, err)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "no refresh token available"})
+	}
+
+	var token *oauth2.Token
+	err = h.oidcRoundTrip(ctx, func(ctx context.Context) error {
+		var err error
+		token, err = oidcCtx.Config.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+		return err
+	})
+	if err != nil {
+		fmt.Printf("OIDC Error: Token refresh failed for %s: %v\n", username, err)
+		// An unreachable IdP says nothing about the refresh token; keep it for the next try
+		if isTransientOIDCError(err) {
+			return c.JSON(http.StatusGatewayTimeout, map[string]string{"error": "identity provider did not respond, try again"})
+		}
+		_ = h.Queries.DeleteOIDCSession(ctx, username)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "refresh failed, please log in again"})
+	}
+
+	if !h.isEmailAllowed(username) {
+		_ = h.Queries.DeleteOIDCSession(ctx, username)
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "access denied"})
+	}
+
+	// Providers may rotate refresh tokens
+	if token.RefreshToken != "" && token.RefreshToken != refreshToken {
+		if err := h.storeRefreshToken(ctx, username, token.RefreshToken); err != nil {
+			fmt.Printf("OIDC Warning: Failed to store rotated refresh token: %v\n", err)
+		}
+	}
+
+	appToken, err := h.generateAppToken(username)
+	if err != nil {
+		return h.mapOIDCError(c, err, "failed to generate app token")
+	}
+	return c.JSON(http.StatusOK, map[string]string{"token": appToken})
 }
 
 // Helpers
 
+// oidcRoundTrip runs one IdP request bounded by OIDC_EXCHANGE_TIMEOUT, retrying
+// transport failures up to OIDC_EXCHANGE_RETRIES times. Errors the IdP answered
+// with are final: an authorization code can only be redeemed once.
+func (h *Handler) oidcRoundTrip(parent context.Context, call func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; attempt <= h.Config.OIDCExchangeRetries; attempt++ {
+		ctx, cancel := context.WithTimeout(parent, h.Config.OIDCExchangeTimeout)
+		err = call(ctx)
+		cancel()
+		if err == nil || parent.Err() != nil || !isTransientOIDCError(err) {
+			return err
+		}
+		fmt.Printf("OIDC Warning: IdP request failed (attempt %d): %v\n", attempt+1, err)
+	}
+	return err
+}
+
+// isTransientOIDCError reports whether an IdP request failed in transit (timeout,
+// connection error) rather than with a response from the IdP
+func isTransientOIDCError(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr)
+}
+
+// encryptionKey derives a purpose-specific key for secrets stored at rest
+func (h *Handler) encryptionKey(purpose string) []byte {
+	secret := h.Config.EncryptionKey
+	if secret == "" {
+		secret = h.Config.JWTSecret
+	}
+	return auth.DeriveKey(secret, purpose)
+}
+
+func (h *Handler) storeRefreshToken(ctx context.Context, username, refreshToken string) error {
+	sealed, err := auth.Encrypt(h.encryptionKey("oidc-refresh"), refreshToken)
+	if err != nil {
+		return err
+	}
+	return h.Queries.UpsertOIDCSession(ctx, database.UpsertOIDCSessionParams{
+		Username:     username,
+		RefreshToken: sealed,
+	})
+}
+
+// usernameFromContext extracts the "user" claim set by the JWT middleware
+func usernameFromContext(c echo.Context) (string, bool) {
+	userToken, ok := c.Get("user").(*jwt.Token)
+	if !ok || userToken == nil {
+		return "", false
+	}
+	claims, ok := userToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", false
+	}
+	username, ok := claims["user"].(string)
+	return username, ok
+}
+
+func (h *Handler) storeIDTokenHint(username, rawIDToken string) {
+	h.idTokenMu.Lock()
+	defer h.idTokenMu.Unlock()
+	if h.idTokenHints == nil {
+		h.idTokenHints = make(map[string]string)
+	}
+	h.idTokenHints[username] = rawIDToken
+}
+
+// takeIDTokenHint returns and forgets the stored ID token for a user
+func (h *Handler) takeIDTokenHint(username string) string {
+	h.idTokenMu.Lock()
+	defer h.idTokenMu.Unlock()
+	hint := h.idTokenHints[username]
+	delete(h.idTokenHints, username)
+	return hint
+}
+
 func generateRandomString(n int) (string, error) {
 	b := make([]byte, n)
 	if _, err := rand.Read(b); err != nil {
@@ -207,24 +477,78 @@ func generateRandomString(n int) (string, error) {
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
+// cookieName applies the __Host- prefix when COOKIE_HOST_PREFIX is enabled
+func (h *Handler) cookieName(name string) string {
+	if h.Config.CookieHostPrefix {
+		return "__Host-" + name
+	}
+	return name
+}
+
+// cookiePath scopes OIDC cookies to the auth endpoints under BASE_PATH.
+// __Host- cookies must use Path=/ (config validation ensures BASE_PATH is empty then).
+func (h *Handler) cookiePath() string {
+	if h.Config.CookieHostPrefix {
+		return "/"
+	}
+	return h.Config.BasePath + "/auth"
+}
+
 func (h *Handler) setCookie(c echo.Context, name, value string, maxAge int) {
 	cookie := new(http.Cookie)
-	cookie.Name = name
+	cookie.Name = h.cookieName(name)
 	cookie.Value = value
-	cookie.Path = "/api/auth" // Scope to auth endpoints
+	cookie.Path = h.cookiePath()
 	cookie.HttpOnly = true
-	cookie.Secure = true // Always set Secure (assumes TLS or localhost)
+	cookie.Secure = h.Config.CookieSecure // Disable only for plain-HTTP local development
 	cookie.SameSite = http.SameSiteLaxMode
+	if h.Config.CookieSameSite == "none" {
+		cookie.SameSite = http.SameSiteNoneMode
+	}
 	cookie.MaxAge = maxAge
 	c.SetCookie(cookie)
 }
 
 func (h *Handler) deleteAuthCookies(c echo.Context) {
-	for _, name := range []string{cookieState, cookieNonce, cookieVerifier} {
+	for _, name := range []string{cookieState, cookieNonce, cookieVerifier, cookieNext} {
 		h.setCookie(c, name, "", -1)
 	}
 }
 
+// redirectToLogin sends the browser to the post-login target (OIDC_POST_LOGIN_REDIRECT,
+// default BASE_PATH/login) with the token or error in the query string
+func (h *Handler) redirectToLogin(c echo.Context, params url.Values) error {
+	target := h.Config.OIDCPostLoginRedirect
+	if target == "" {
+		target = h.Config.BasePath + "/login"
+	}
+	u, err := url.Parse(target) // validated at startup
+	if err != nil {
+		u = &url.URL{Path: h.Config.BasePath + "/login"}
+	}
+	q := u.Query()
+	for key, values := range params {
+		for _, v := range values {
+			q.Add(key, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return c.Redirect(http.StatusFound, u.String())
+}
+
+// safeNextPath accepts a ?next= return target only if it is a path on this site;
+// anything that could leave it ("//host", "https://...", "/\host") yields ""
+func safeNextPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.ContainsAny(next, "\\\r\n") {
+		return ""
+	}
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return ""
+	}
+	return next
+}
+
 func (h *Handler) isEmailAllowed(email string) bool {
 	if len(h.Config.OIDCAllowedEmails) == 0 {
 		return false // Deny by default if list is empty
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nullpo7z/dashboard-recorder/internal/config"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestNextOIDCBackoff(t *testing.T) {
+	limit := 5 * time.Minute
+
+	assert.Equal(t, 4*time.Second, nextOIDCBackoff(2*time.Second, limit))
+	assert.Equal(t, limit, nextOIDCBackoff(3*time.Minute, limit))
+	assert.Equal(t, limit, nextOIDCBackoff(limit, limit))
+}
+
+func TestOIDC_DisabledUntilDiscovered(t *testing.T) {
+	h := &Handler{}
+	assert.Nil(t, h.OIDC())
+
+	h.oidcCtx.Store(&OIDCContext{EndSessionURL: "https://idp.example.com/logout"})
+	assert.Equal(t, "https://idp.example.com/logout", h.OIDC().EndSessionURL)
+}
+
+func TestSetCookie_Config(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		wantName string
+		wantPath string
+		secure   bool
+		sameSite http.SameSite
+	}{
+		{"Defaults", config.Config{CookieSecure: true, CookieSameSite: "lax"}, "oidc_state", "/auth", true, http.SameSiteLaxMode},
+		{"Base Path", config.Config{BasePath: "/recorder", CookieSecure: true, CookieSameSite: "lax"}, "oidc_state", "/recorder/auth", true, http.SameSiteLaxMode},
+		{"Local Dev", config.Config{CookieSecure: false, CookieSameSite: "lax"}, "oidc_state", "/auth", false, http.SameSiteLaxMode},
+		{"Host Prefix", config.Config{CookieSecure: true, CookieSameSite: "none", CookieHostPrefix: true}, "__Host-oidc_state", "/", true, http.SameSiteNoneMode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/auth/login", nil), rec)
+			h := &Handler{Config: &tt.cfg}
+
+			h.setCookie(c, cookieState, "value", 300)
+
+			cookies := rec.Result().Cookies()
+			if assert.Equal(t, 1, len(cookies)) {
+				assert.Equal(t, tt.wantName, cookies[0].Name)
+				assert.Equal(t, tt.wantPath, cookies[0].Path)
+				assert.Equal(t, tt.secure, cookies[0].Secure)
+				assert.Equal(t, tt.sameSite, cookies[0].SameSite)
+			}
+		})
+	}
+}
+
+func TestOIDCRoundTrip(t *testing.T) {
+	h := &Handler{Config: &config.Config{OIDCExchangeTimeout: 20 * time.Millisecond, OIDCExchangeRetries: 1}}
+
+	// A hung IdP is cut off by the timeout and retried once
+	calls := 0
+	err := h.oidcRoundTrip(context.Background(), func(ctx context.Context) error {
+		calls++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, calls)
+
+	// An answer from the IdP is final
+	calls = 0
+	err = h.oidcRoundTrip(context.Background(), func(ctx context.Context) error {
+		calls++
+		return &oauth2.RetrieveError{ErrorCode: "invalid_grant"}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+
+	// Transport errors are retried until one succeeds
+	calls = 0
+	err = h.oidcRoundTrip(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestSafeNextPath(t *testing.T) {
+	assert.Equal(t, "/recordings?tag=x", safeNextPath("/recordings?tag=x"))
+	assert.Equal(t, "/", safeNextPath("/"))
+	for _, next := range []string{"", "recordings", "//evil.example", "/\\evil.example", "https://evil.example/", "/a\r\nb"} {
+		assert.Empty(t, safeNextPath(next), next)
+	}
+}
+
+func TestRedirectToLogin(t *testing.T) {
+	e := echo.New()
+	redirect := func(cfg *config.Config, params url.Values) string {
+		h := &Handler{Config: cfg}
+		rec := httptest.NewRecorder()
+		assert.NoError(t, h.redirectToLogin(e.NewContext(httptest.NewRequest(http.MethodGet, "/auth/callback", nil), rec), params))
+		assert.Equal(t, http.StatusFound, rec.Code)
+		return rec.Header().Get("Location")
+	}
+
+	assert.Equal(t, "/login?error=invalid_state", redirect(&config.Config{}, url.Values{"error": {"invalid_state"}}))
+	assert.Equal(t, "/recorder/login?next=%2Fa&token=t", redirect(&config.Config{BasePath: "/recorder"}, url.Values{"token": {"t"}, "next": {"/a"}}))
+	assert.Equal(t, "https://app.example/signin?from=oidc&token=t", redirect(&config.Config{OIDCPostLoginRedirect: "https://app.example/signin?from=oidc"}, url.Values{"token": {"t"}}))
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ConcurrencyLimit caps the number of requests served at once. Requests over the cap
+// are rejected with 503 instead of queueing, so a flood of polls can't pile up
+// goroutines behind slow handlers. limit <= 0 disables the cap.
+//
+// WebSocket upgrades are not counted: sessions are long-lived, need a ticket, and
+// would otherwise hold slots for their whole lifetime.
+func ConcurrencyLimit(limit int) echo.MiddlewareFunc {
+	if limit <= 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+	slots := make(chan struct{}, limit)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if strings.EqualFold(c.Request().Header.Get(echo.HeaderUpgrade), "websocket") {
+				return next(c)
+			}
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+				return next(c)
+			default:
+				c.Response().Header().Set("Retry-After", "1")
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "server is busy, try again"})
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	e := echo.New()
+	limit := ConcurrencyLimit(1)
+
+	serve := func(req *http.Request, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		assert.NoError(t, limit(handler)(e.NewContext(req, rec)))
+		return rec
+	}
+
+	var inner *httptest.ResponseRecorder
+	outer := serve(httptest.NewRequest(http.MethodGet, "/", nil), func(c echo.Context) error {
+		// The single slot is held here, so a concurrent request is turned away
+		inner = serve(httptest.NewRequest(http.MethodGet, "/", nil), func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		ws := httptest.NewRequest(http.MethodGet, "/", nil)
+		ws.Header.Set(echo.HeaderUpgrade, "websocket")
+		assert.Equal(t, http.StatusOK, serve(ws, func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}).Code, "websocket upgrades are not counted")
+
+		return c.NoContent(http.StatusOK)
+	})
+
+	assert.Equal(t, http.StatusOK, outer.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, inner.Code)
+	assert.Equal(t, "1", inner.Header().Get("Retry-After"))
+
+	// The slot is released once the request finishes
+	assert.Equal(t, http.StatusOK, serve(httptest.NewRequest(http.MethodGet, "/", nil), func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}).Code)
+}
+
+func TestConcurrencyLimit_Disabled(t *testing.T) {
+	e := echo.New()
+	called := false
+	handler := ConcurrencyLimit(0)(func(c echo.Context) error {
+		called = true
+		return nil
+	})
+	assert.NoError(t, handler(e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())))
+	assert.True(t, called)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nullpo7z/dashboard-recorder/internal/auth"
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+	"github.com/nullpo7z/dashboard-recorder/internal/recorder"
+)
+
+// maxCredentialLength bounds stored usernames and passwords
+const maxCredentialLength = 1024
+
+type TaskCredentialsRequest struct {
+	Username         string `json:"username"`
+	Password         string `json:"password"` // empty keeps the stored password
+	UsernameSelector string `json:"username_selector"`
+	PasswordSelector string `json:"password_selector"`
+	SubmitSelector   string `json:"submit_selector"`
+}
+
+// TaskCredentialsDTO never carries the password back to the client
+type TaskCredentialsDTO struct {
+	Username         string `json:"username"`
+	UsernameSelector string `json:"username_selector"`
+	PasswordSelector string `json:"password_selector"`
+	SubmitSelector   string `json:"submit_selector"`
+	HasPassword      bool   `json:"has_password"`
+}
+
+// GetTaskCredentials returns the task's login form settings (without the password)
+func (h *Handler) GetTaskCredentials(c echo.Context) error {
+	idParam := c.Param("id")
+	var taskID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+	}
+
+	login, err := h.loadFormLogin(c.Request().Context(), taskID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if login == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "no credentials stored"})
+	}
+
+	return c.JSON(http.StatusOK, TaskCredentialsDTO{
+		Username:         login.Username,
+		UsernameSelector: login.UsernameSelector,
+		PasswordSelector: login.PasswordSelector,
+		SubmitSelector:   login.SubmitSelector,
+		HasPassword:      login.Password != "",
+	})
+}
+
+// SetTaskCredentials stores (encrypted) credentials that recordings use to log in
+// through the dashboard's login form after navigation
+func (h *Handler) SetTaskCredentials(c echo.Context) error {
+	idParam := c.Param("id")
+	var taskID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+	}
+	var req TaskCredentialsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	if req.Username == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "username is required"})
+	}
+	if len(req.Username) > maxCredentialLength || len(req.Password) > maxCredentialLength {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("username and password must be at most %d bytes", maxCredentialLength)})
+	}
+	if err := recorder.ValidateLoginSelectors(req.UsernameSelector, req.PasswordSelector, req.SubmitSelector); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if _, err := h.Queries.GetTask(ctx, taskID); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "task not found"})
+	}
+
+	password := req.Password
+	if password == "" {
+		existing, err := h.loadFormLogin(ctx, taskID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		if existing == nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "password is required"})
+		}
+		password = existing.Password
+	}
+
+	key := h.encryptionKey("task-credentials")
+	sealedUser, err := auth.Encrypt(key, req.Username)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to store credentials"})
+	}
+	sealedPass, err := auth.Encrypt(key, password)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to store credentials"})
+	}
+
+	if err := h.Queries.UpsertTaskCredentials(ctx, database.UpsertTaskCredentialsParams{
+		TaskID:           taskID,
+		Username:         sealedUser,
+		Password:         sealedPass,
+		UsernameSelector: req.UsernameSelector,
+		PasswordSelector: req.PasswordSelector,
+		SubmitSelector:   req.SubmitSelector,
+	}); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+
+	fmt.Printf("Credentials: stored login for task %d (user %s)\n", taskID, recorder.MaskCredential(req.Username))
+	return c.JSON(http.StatusOK, map[string]string{"status": "saved"})
+}
+
+// DeleteTaskCredentials removes stored credentials; recordings stop logging in
+func (h *Handler) DeleteTaskCredentials(c echo.Context) error {
+	idParam := c.Param("id")
+	var taskID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+	}
+	if err := h.Queries.DeleteTaskCredentials(c.Request().Context(), taskID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// loadFormLogin decrypts the task's stored credentials; nil when none are stored
+func (h *Handler) loadFormLogin(ctx context.Context, taskID int64) (*recorder.FormLogin, error) {
+	record, err := h.Queries.GetTaskCredentials(ctx, taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credentials: %w", err)
+	}
+
+	key := h.encryptionKey("task-credentials")
+	username, err := auth.Decrypt(key, record.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt stored credentials (was ENCRYPTION_KEY changed?)")
+	}
+	password, err := auth.Decrypt(key, record.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt stored credentials (was ENCRYPTION_KEY changed?)")
+	}
+
+	return &recorder.FormLogin{
+		Username:         username,
+		Password:         password,
+		UsernameSelector: record.UsernameSelector,
+		PasswordSelector: record.PasswordSelector,
+		SubmitSelector:   record.SubmitSelector,
+	}, nil
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+	"github.com/nullpo7z/dashboard-recorder/internal/recorder"
+)
+
+// maxBulkDownloadRecordings bounds the recordings of one bulk download
+const maxBulkDownloadRecordings = 200
+
+// DownloadRecordings streams the files of the requested finished recordings as one
+// ZIP archive, named "<task>/<file>". The total size is checked against
+// MAX_BULK_DOWNLOAD_BYTES before anything is sent; the archive is written straight
+// to the response, never buffered.
+func (h *Handler) DownloadRecordings(c echo.Context) error {
+	type DownloadRequest struct {
+		RecordingIDs []int64 `json:"recording_ids"`
+	}
+	var req DownloadRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if len(req.RecordingIDs) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "recording_ids is required"})
+	}
+	if len(req.RecordingIDs) > maxBulkDownloadRecordings {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("at most %d recordings per download", maxBulkDownloadRecordings)})
+	}
+
+	recs, err := h.Queries.ListRecordings(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	byID := make(map[int64]database.ListRecordingsRow, len(recs))
+	for _, r := range recs {
+		byID[r.ID] = r
+	}
+
+	selected := make([]database.ListRecordingsRow, 0, len(req.RecordingIDs))
+	seen := make(map[int64]bool, len(req.RecordingIDs))
+	var total int64
+	for _, id := range req.RecordingIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		r, ok := byID[id]
+		if !ok {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": fmt.Sprintf("recording %d not found", id)})
+		}
+		if recorder.StatusRecording.Is(r.Status) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": fmt.Sprintf("recording %d is still in progress", id)})
+		}
+		info, err := os.Stat(r.FilePath)
+		if r.FilePath == "" || err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": fmt.Sprintf("file of recording %d not found on disk", id)})
+		}
+		total += info.Size()
+		selected = append(selected, r)
+	}
+	if limit := int64(h.Config.MaxBulkDownloadBytes); limit > 0 && total > limit {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("selected recordings total %d bytes, more than the limit of %d", total, limit)})
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/zip")
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "recordings-"+time.Now().UTC().Format("20060102-150405")+".zip"))
+	res.WriteHeader(http.StatusOK)
+
+	// Once streaming has begun, errors can only end the archive early
+	zw := zip.NewWriter(res)
+	for i, name := range bulkDownloadNames(selected) {
+		if err := addZipFile(zw, name, selected[i].FilePath); err != nil {
+			fmt.Printf("Download: bulk download aborted at recording %d: %v\n", selected[i].ID, err)
+			return nil
+		}
+	}
+	if err := zw.Close(); err != nil {
+		fmt.Printf("Download: failed to finish bulk download: %v\n", err)
+		return nil
+	}
+
+	username, _ := usernameFromContext(c)
+	fmt.Printf("Download: %s downloaded %d recording(s), %d bytes\n", username, len(selected), total)
+	return nil
+}
+
+// bulkDownloadNames picks the archive path of each recording: its file name in a
+// folder named after the task. Clashing names get the recording ID appended.
+func bulkDownloadNames(recs []database.ListRecordingsRow) []string {
+	names := make([]string, len(recs))
+	used := make(map[string]bool, len(recs))
+	for i, r := range recs {
+		folder := sanitizeFilenamePart(r.TaskName)
+		if folder == "" || folder == "_" {
+			folder = fmt.Sprintf("task_%d", r.TaskID)
+		}
+		file := filepath.Base(r.FilePath)
+		name := folder + "/" + file
+		if used[name] {
+			ext := filepath.Ext(file)
+			name = fmt.Sprintf("%s/%s_%d%s", folder, strings.TrimSuffix(file, ext), r.ID, ext)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// addZipFile copies a file into the archive uncompressed (videos don't compress)
+func addZipFile(zw *zip.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Store
+	dst, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, f)
+	return err
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkDownloadNames(t *testing.T) {
+	names := bulkDownloadNames([]database.ListRecordingsRow{
+		{ID: 1, TaskID: 1, TaskName: "Ops Board", FilePath: "/app/recordings/a.mkv"},
+		{ID: 2, TaskID: 2, TaskName: "Sales", FilePath: "/app/recordings/a.mkv"},
+		{ID: 3, TaskID: 1, TaskName: "Ops Board", FilePath: "/app/recordings/other/a.mkv"},
+		{ID: 4, TaskID: 9, TaskName: "", FilePath: "/app/recordings/b.mkv"},
+	})
+	assert.Equal(t, []string{"Ops_Board/a.mkv", "Sales/a.mkv", "Ops_Board/a_3.mkv", "task_9/b.mkv"}, names)
+}
+
+func TestAddZipFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.mkv")
+	assert.NoError(t, os.WriteFile(path, []byte("video"), 0644))
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	assert.NoError(t, addZipFile(zw, "Ops/rec.mkv", path))
+	assert.Error(t, addZipFile(zw, "Ops/gone.mkv", path+".gone"))
+	assert.NoError(t, zw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if assert.NoError(t, err) && assert.Len(t, zr.File, 1) {
+		assert.Equal(t, "Ops/rec.mkv", zr.File[0].Name)
+		assert.Equal(t, zip.Store, zr.File[0].Method)
+		f, _ := zr.File[0].Open()
+		data, _ := io.ReadAll(f)
+		assert.Equal(t, "video", string(data))
+	}
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+)
+
+var (
+	// filenameSafePattern is the allowed character set for a filename after expansion
+	filenameSafePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+	// filenameVarPattern matches {variable} tokens in a filename template
+	filenameVarPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+	// filenameUnsafeChars is used to sanitize variable values (e.g. task names with spaces)
+	filenameUnsafeChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+)
+
+// filenameTemplateVars is the fixed set of variables a template may reference.
+// Deliberately no access to environment variables or arbitrary task fields.
+var filenameTemplateVars = map[string]func(task database.Task, now time.Time) string{
+	"task_name": func(task database.Task, _ time.Time) string { return sanitizeFilenamePart(task.Name) },
+	"id":        func(task database.Task, _ time.Time) string { return fmt.Sprintf("%d", task.ID) },
+	"date":      func(_ database.Task, now time.Time) string { return now.Format("20060102") },
+	"time":      func(_ database.Task, now time.Time) string { return now.Format("150405") },
+}
+
+// validateFilenameTemplate checks a template for path traversal and unknown variables.
+// Plain strings (no variables) are validated exactly as before.
+func validateFilenameTemplate(tmpl string) error {
+	if tmpl == "" {
+		return nil
+	}
+
+	for _, m := range filenameVarPattern.FindAllStringSubmatch(tmpl, -1) {
+		if _, ok := filenameTemplateVars[m[1]]; !ok {
+			return fmt.Errorf("filename_template contains unknown variable {%s}. Allowed: {task_name}, {date}, {time}, {id}", m[1])
+		}
+	}
+
+	// Validate the literal parts with the variables removed
+	literal := filenameVarPattern.ReplaceAllString(tmpl, "")
+	if literal != "" && !filenameSafePattern.MatchString(literal) {
+		return fmt.Errorf("filename_template contains invalid characters. Allowed: a-z, A-Z, 0-9, _, ., - and {variables}")
+	}
+	// Explicitly reject traversal and separators
+	if strings.Contains(tmpl, "..") || strings.Contains(tmpl, "/") || strings.Contains(tmpl, "\\") {
+		return fmt.Errorf("filename_template cannot contain path traversal or separators")
+	}
+	return nil
+}
+
+// buildRecordingFilename expands the task's filename template into a file name (without directory).
+// Plain templates keep the legacy "<template>_<timestamp>.mkv" form; templates without {time}
+// still get a timestamp suffix so consecutive recordings never overwrite each other.
+func buildRecordingFilename(task database.Task, now time.Time) string {
+	timestamp := now.Format("20060102150405")
+	if task.FilenameTemplate == "" {
+		// Fallback to legacy ID_TIMESTAMP format if no template
+		return fmt.Sprintf("%d_%d.mkv", task.ID, now.Unix())
+	}
+
+	expanded := filenameVarPattern.ReplaceAllStringFunc(task.FilenameTemplate, func(token string) string {
+		name := strings.Trim(token, "{}")
+		if fn, ok := filenameTemplateVars[name]; ok {
+			return fn(task, now)
+		}
+		return ""
+	})
+
+	// Defense-in-depth: re-sanitize the expanded result
+	expanded = filepath.Base(sanitizeFilenamePart(expanded))
+	if expanded == "" || expanded == "." || strings.Contains(expanded, "..") {
+		return fmt.Sprintf("%d_%d.mkv", task.ID, now.Unix())
+	}
+
+	if !strings.Contains(task.FilenameTemplate, "{time}") {
+		expanded = fmt.Sprintf("%s_%s", expanded, timestamp)
+	}
+	return expanded + ".mkv"
+}
+
+const (
+	// recordingsDir is the root of all recording files (served under /recordings)
+	recordingsDir = "/app/recordings"
+	// maxOutputDirLength and maxOutputDirDepth bound a task's output_dir
+	maxOutputDirLength = 128
+	maxOutputDirDepth  = 4
+)
+
+// normalizeOutputDir validates a task's output_dir: a relative path of up to
+// maxOutputDirDepth safe segments below the recordings directory. Returns it
+// cleaned; "" keeps the default layout.
+func normalizeOutputDir(dir string) (string, error) {
+	dir = strings.TrimSuffix(strings.TrimSpace(dir), "/")
+	if dir == "" {
+		return "", nil
+	}
+	if len(dir) > maxOutputDirLength {
+		return "", fmt.Errorf("output_dir exceeds %d characters", maxOutputDirLength)
+	}
+	if strings.HasPrefix(dir, "/") || strings.Contains(dir, "\\") {
+		return "", fmt.Errorf("output_dir must be a relative path below the recordings directory")
+	}
+	segments := strings.Split(dir, "/")
+	if len(segments) > maxOutputDirDepth {
+		return "", fmt.Errorf("output_dir cannot be nested more than %d levels", maxOutputDirDepth)
+	}
+	for _, seg := range segments {
+		if seg == "" || strings.HasPrefix(seg, ".") || !filenameSafePattern.MatchString(seg) {
+			return "", fmt.Errorf("output_dir contains an invalid segment %q. Allowed: a-z, A-Z, 0-9, _, ., - (not starting with a dot)", seg)
+		}
+	}
+	return dir, nil
+}
+
+// recordingPath places a recording file of the task: in its output_dir, else in
+// task_<id> with RECORDINGS_PER_TASK_DIR, else directly in the recordings directory
+func recordingPath(task database.Task, perTaskDir bool, filename string) string {
+	switch {
+	case task.OutputDir != "":
+		return filepath.Join(recordingsDir, task.OutputDir, filename)
+	case perTaskDir:
+		return filepath.Join(recordingsDir, fmt.Sprintf("task_%d", task.ID), filename)
+	}
+	return filepath.Join(recordingsDir, filename)
+}
+
+// recordingDownloadURL maps a recording file to its URL under /recordings
+// ("" for files outside the recordings directory)
+func recordingDownloadURL(path string) string {
+	rel, err := filepath.Rel(recordingsDir, filepath.Clean(path))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return ""
+	}
+	segments := strings.Split(rel, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return "/recordings/" + strings.Join(segments, "/")
+}
+
+// viewportFilename tags a recording file with its viewport profile ("a.mkv" -> "a_mobile.mkv");
+// the default (unnamed) viewport keeps the plain name
+func viewportFilename(filename, viewport string) string {
+	if viewport == "" {
+		return filename
+	}
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "_" + viewport + ext
+}
+
+// sanitizeFilenamePart replaces any run of disallowed characters with an underscore
+func sanitizeFilenamePart(s string) string {
+	s = filenameUnsafeChars.ReplaceAllString(strings.TrimSpace(s), "_")
+	return strings.ReplaceAll(s, "..", "_")
+}
+
+// validateRecordingRename checks a user-supplied name for an existing recording and
+// returns the final file name. The extension of the current file is always kept so a
+// rename can't turn a recording into something the player/cleanup don't recognise.
+func validateRecordingRename(name, currentPath string) (string, error) {
+	ext := filepath.Ext(currentPath)
+	if ext == "" {
+		ext = ".mkv"
+	}
+	name = strings.TrimSuffix(strings.TrimSpace(name), ext)
+
+	if name == "" {
+		return "", fmt.Errorf("filename is required")
+	}
+	if strings.Contains(name, "..") || strings.Contains(name, "/") || strings.Contains(name, "\\") {
+		return "", fmt.Errorf("filename cannot contain path traversal or separators")
+	}
+	if !filenameSafePattern.MatchString(name) {
+		return "", fmt.Errorf("filename contains invalid characters. Allowed: a-z, A-Z, 0-9, _, ., -")
+	}
+	return name + ext, nil
+}
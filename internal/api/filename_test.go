@@ -0,0 +1,129 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFilenameTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		wantErr bool
+	}{
+		{"Empty", "", false},
+		{"Plain", "daily_report", false},
+		{"Variables", "{task_name}_{date}-{time}_{id}", false},
+		{"Unknown Variable", "{home}", true},
+		{"Env Style", "${HOME}", true},
+		{"Traversal", "../etc", true},
+		{"Separator", "a/{date}", true},
+		{"Unbalanced Brace", "{date", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFilenameTemplate(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFilenameTemplate(%q) error = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildRecordingFilename(t *testing.T) {
+	now := time.Date(2024, 3, 9, 14, 5, 7, 0, time.UTC)
+	task := database.Task{ID: 42, Name: "Ops Board / Prod"}
+
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{"Legacy", "", "42_1709993107.mkv"},
+		{"Plain", "report", "report_20240309140507.mkv"},
+		{"With Time", "{task_name}_{date}_{time}", "Ops_Board_Prod_20240309_140507.mkv"},
+		{"Without Time", "{id}-{date}", "42-20240309_20240309140507.mkv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task.FilenameTemplate = tt.tmpl
+			assert.Equal(t, tt.want, buildRecordingFilename(task, now))
+		})
+	}
+}
+
+func TestValidateRecordingRename(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		current string
+		want    string
+		wantErr bool
+	}{
+		{"Plain", "incident_42", "/app/recordings/1_1700000000.mkv", "incident_42.mkv", false},
+		{"Extension Kept", "incident.mkv", "/app/recordings/1_1700000000.mkv", "incident.mkv", false},
+		{"Other Extension", "clip", "/app/recordings/task_1/1.mp4", "clip.mp4", false},
+		{"Empty", "  ", "/app/recordings/1.mkv", "", true},
+		{"Traversal", "../etc/passwd", "/app/recordings/1.mkv", "", true},
+		{"Separator", "a/b", "/app/recordings/1.mkv", "", true},
+		{"Backslash", "a\\b", "/app/recordings/1.mkv", "", true},
+		{"Invalid Chars", "my file", "/app/recordings/1.mkv", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateRecordingRename(tt.input, tt.current)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestViewportFilename(t *testing.T) {
+	assert.Equal(t, "1_1700000000.mkv", viewportFilename("1_1700000000.mkv", ""))
+	assert.Equal(t, "1_1700000000_mobile.mkv", viewportFilename("1_1700000000.mkv", "mobile"))
+}
+
+func TestNormalizeOutputDir(t *testing.T) {
+	for in, want := range map[string]string{
+		"":             "",
+		" ops/daily/ ": "ops/daily",
+		"team-a":       "team-a",
+		"a/b/c/d":      "a/b/c/d",
+		"reports_v1.2": "reports_v1.2",
+	} {
+		got, err := normalizeOutputDir(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"../etc", "ops/../../x", "/abs", "a//b", ".hidden", "a\\b", "with space", "a/b/c/d/e", strings.Repeat("a", 129)} {
+		_, err := normalizeOutputDir(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestRecordingPath(t *testing.T) {
+	task := database.Task{ID: 7}
+	assert.Equal(t, "/app/recordings/r.mkv", recordingPath(task, false, "r.mkv"))
+	assert.Equal(t, "/app/recordings/task_7/r.mkv", recordingPath(task, true, "r.mkv"))
+	task.OutputDir = "ops/daily"
+	assert.Equal(t, "/app/recordings/ops/daily/r.mkv", recordingPath(task, true, "r.mkv"))
+}
+
+func TestRecordingDownloadURL(t *testing.T) {
+	assert.Equal(t, "/recordings/r.mkv", recordingDownloadURL("/app/recordings/r.mkv"))
+	assert.Equal(t, "/recordings/ops/daily/a%20b.mkv", recordingDownloadURL("/app/recordings/ops/daily/a b.mkv"))
+	assert.Equal(t, "", recordingDownloadURL("/etc/passwd"))
+	assert.Equal(t, "", recordingDownloadURL(""))
+}
@@ -3,14 +3,18 @@ package api
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -40,12 +44,24 @@ type Handler struct {
 	limiterMu   sync.Mutex
 	lastCleanup time.Time
 	clients     map[string]*rate.Limiter
+	// Allowed/rejected requests per rate-limited route, reported by GetMetrics
+	rateLimits rateLimitCounters
+
+	// Caps concurrent previews (each opens a browser context)
+	previewLimit echo.MiddlewareFunc
 
 	// Ticket Store
 	TicketStore auth.TicketStore
 
-	// OIDC
-	OIDC *OIDCContext
+	// Signs and verifies the app's tokens (JWT_ALGORITHM); see tokenKeys
+	jwtKeys *auth.JWTKeys
+
+	// OIDC (set by discovery, possibly after startup; read via OIDC())
+	oidcCtx atomic.Pointer[OIDCContext]
+
+	// ID tokens kept for RP-initiated logout (username -> raw id_token)
+	idTokenMu    sync.Mutex
+	idTokenHints map[string]string
 }
 
 func New(q *database.Queries, cfg *config.Config, rec *recorder.Worker, db *sql.DB) *Handler {
@@ -56,14 +72,23 @@ func New(q *database.Queries, cfg *config.Config, rec *recorder.Worker, db *sql.
 		DB:          db,
 		clients:     make(map[string]*rate.Limiter),
 		TicketStore: auth.NewInMemoryTicketStore(),
+
+		previewLimit: ConcurrencyLimit(cfg.MaxConcurrentPreviews),
 	}
 
+	keys, err := auth.LoadJWTKeys(cfg.JWTAlgorithm, cfg.JWTSecret, cfg.JWTPrivateKeyFile, cfg.JWTPublicKeyFile)
+	if err != nil {
+		panic(fmt.Sprintf("CRITICAL ERROR: failed to load JWT signing keys: %v. Refusing to start.", err))
+	}
+	h.jwtKeys = keys
+
 	// Initialize admin user if needed
 	go h.initAdminUser()
 
-	// Initialize OIDC
+	// Initialize OIDC (an unreachable IdP is retried in the background)
 	if err := h.InitOIDC(); err != nil {
-		fmt.Printf("WARNING: OIDC Initialization failed: %v\n", err)
+		fmt.Printf("WARNING: OIDC Initialization failed: %v. Retrying in %s.\n", err, cfg.OIDCDiscoveryBackoff)
+		go h.retryOIDCDiscovery(context.Background())
 	}
 
 	// Start ticket cleanup routine
@@ -82,8 +107,16 @@ func (h *Handler) initAdminUser() {
 	}
 
 	if count == 0 {
-		// Create default admin
-		password := "admin" // Default password
+		// Create bootstrap admin (ADMIN_USERNAME / ADMIN_PASSWORD[_FILE])
+		username := h.adminUsername()
+		password := h.Config.AdminPassword
+		if password == "" {
+			if h.Config.AdminStrict {
+				fmt.Println("WARNING: ADMIN_STRICT is set but ADMIN_PASSWORD is empty. No admin user was created.")
+				return
+			}
+			password = "admin" // Default password
+		}
 		hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 		if err != nil {
 			fmt.Printf("CRITICAL: Failed to hash default password: %v\n", err)
@@ -91,17 +124,21 @@ func (h *Handler) initAdminUser() {
 		}
 
 		_, err = h.Queries.CreateUser(ctx, database.CreateUserParams{
-			Username:     "admin",
+			Username:     username,
 			PasswordHash: string(hashed),
 		})
 		if err != nil {
 			fmt.Printf("CRITICAL: Failed to create default admin: %v\n", err)
 			return
 		}
-		fmt.Println("WARNING: Created default 'admin' user with password 'admin'. Please change this immediately.")
+		if h.Config.AdminPassword == "" {
+			fmt.Printf("WARNING: Created default '%s' user with password 'admin'. Please change this immediately.\n", username)
+		} else {
+			fmt.Printf("Created admin user '%s' from configuration.\n", username)
+		}
 	} else {
 		// Ensure admin exists, but DO NOT overwrite password
-		_, err := h.Queries.GetUserByUsername(ctx, "admin")
+		_, err := h.Queries.GetUserByUsername(ctx, h.adminUsername())
 		if err == sql.ErrNoRows {
 			// If other users exist but not admin, maybe create it?
 			// Logic says check count. If > 0 and no admin, strange but okay.
@@ -110,6 +147,14 @@ func (h *Handler) initAdminUser() {
 	}
 }
 
+// adminUsername returns the configured bootstrap admin name
+func (h *Handler) adminUsername() string {
+	if h.Config == nil || h.Config.AdminUsername == "" {
+		return "admin"
+	}
+	return h.Config.AdminUsername
+}
+
 // RateLimitMiddleware enforces simple IP-based rate limiting
 func (h *Handler) RateLimitMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -132,7 +177,9 @@ func (h *Handler) RateLimitMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			h.clients[ip] = limiter
 		}
 
-		if !limiter.Allow() {
+		allowed := limiter.Allow()
+		h.rateLimits.record(c.Path(), allowed)
+		if !allowed {
 			return c.JSON(http.StatusTooManyRequests, map[string]string{"error": "Too many requests"})
 		}
 
@@ -141,9 +188,38 @@ func (h *Handler) RateLimitMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// NoWriteDeadlineMiddleware lifts the server-wide deadlines for long-lived responses
+// (WebSocket sessions, recording downloads). The deadlines stay on a hijacked
+// connection, so they must be cleared before the upgrade.
+func (h *Handler) NoWriteDeadlineMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		rc := http.NewResponseController(c.Response())
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			fmt.Printf("Warning: failed to clear write deadline: %v\n", err)
+		}
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			fmt.Printf("Warning: failed to clear read deadline: %v\n", err)
+		}
+		return next(c)
+	}
+}
+
+// RequireAdmin restricts a route to the bootstrap admin account (ADMIN_USERNAME)
+func (h *Handler) RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		username, ok := usernameFromContext(c)
+		if !ok || username != h.adminUsername() {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Admin privileges required"})
+		}
+		return next(c)
+	}
+}
+
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// TOTPCode is required once two-factor authentication is enabled (a recovery code is also accepted)
+	TOTPCode string `json:"totp_code"`
 }
 
 func (h *Handler) Login(c echo.Context) error {
@@ -171,13 +247,29 @@ func (h *Handler) Login(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
 	}
 
-	// Create JWT
-	t, err := h.createJWT(req.Username)
+	// Second factor (JWT is only issued after it passes)
+	if _, err := h.verifySecondFactor(c.Request().Context(), user.Username, req.TOTPCode); err != nil {
+		if errors.Is(err, errInvalidSecondFactor) {
+			if strings.TrimSpace(req.TOTPCode) == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "totp code required", "totp_required": true})
+			}
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid totp code"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+
+	// must_change_password is set after an admin reset with a temporary password;
+	// until it is changed the token is good for POST /api/password only
+	createToken := h.createJWT
+	if user.MustChangePassword {
+		createToken = h.createPasswordChangeJWT
+	}
+	t, err := createToken(req.Username)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{"token": t})
+	return c.JSON(http.StatusOK, map[string]interface{}{"token": t, "must_change_password": user.MustChangePassword})
 }
 
 func (h *Handler) createJWT(username string) (string, error) {
@@ -185,18 +277,52 @@ func (h *Handler) createJWT(username string) (string, error) {
 	// Reduced usage for security
 	exp := now.Add(time.Hour * 24)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	t, err := h.tokenKeys().Sign(jwt.MapClaims{
 		"user": username,
 		"exp":  jwt.NewNumericDate(exp),
 	})
-
-	t, err := token.SignedString([]byte(h.Config.JWTSecret))
 	if err != nil {
 		return "", err
 	}
 	return t, nil
 }
 
+// passwordChangeScope is the scope claim of a token issued while the user must
+// change their password
+const passwordChangeScope = "password_change"
+
+// createPasswordChangeJWT issues a short-lived token that RequirePasswordChanged
+// only lets through to the password change
+func (h *Handler) createPasswordChangeJWT(username string) (string, error) {
+	return h.tokenKeys().Sign(jwt.MapClaims{
+		"user":  username,
+		"scope": passwordChangeScope,
+		"exp":   jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
+	})
+}
+
+// RequirePasswordChanged rejects a password-change token (see Login) on every
+// route but the password change itself
+func (h *Handler) RequirePasswordChanged(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if userToken, ok := c.Get("user").(*jwt.Token); ok && userToken != nil {
+			if claims, ok := userToken.Claims.(jwt.MapClaims); ok && claims["scope"] == passwordChangeScope && c.Path() != "/api/password" {
+				return c.JSON(http.StatusForbidden, map[string]interface{}{"error": "password change required", "must_change_password": true})
+			}
+		}
+		return next(c)
+	}
+}
+
+// tokenKeys returns the configured signing keys, or HS256 with JWT_SECRET for
+// handlers built without New
+func (h *Handler) tokenKeys() *auth.JWTKeys {
+	if h.jwtKeys != nil {
+		return h.jwtKeys
+	}
+	return auth.HMACKeys(h.Config.JWTSecret)
+}
+
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password"`
 	NewPassword string `json:"new_password"`
@@ -262,6 +388,82 @@ func (h *Handler) ChangePassword(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"status": "password updated"})
 }
 
+type ResetPasswordRequest struct {
+	// Password is the new password; empty generates a temporary one
+	Password string `json:"password"`
+	// MustChangePassword asks the user to change the password after login (default true)
+	MustChangePassword *bool `json:"must_change_password"`
+}
+
+// temporaryPasswordBytes is the entropy of generated passwords (24 base64url characters)
+const temporaryPasswordBytes = 18
+
+// ResetUserPassword lets an admin set a new password for a locked-out user.
+// Without a password a temporary one is generated and returned once; it always
+// has to be changed after login.
+func (h *Handler) ResetUserPassword(c echo.Context) error {
+	var id int64
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+
+	var req ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	req.Password = strings.TrimSpace(req.Password)
+	mustChange := true
+	if req.MustChangePassword != nil {
+		mustChange = *req.MustChangePassword
+	}
+
+	var temporary string
+	if req.Password == "" {
+		p, err := generateRandomString(temporaryPasswordBytes)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to generate password"})
+		}
+		temporary, req.Password, mustChange = p, p, true
+	} else if len(req.Password) < 12 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "New password must be at least 12 characters long"})
+	}
+
+	user, err := h.Queries.GetUser(c.Request().Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "user not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to hash password"})
+	}
+
+	if err := h.Queries.ResetUserPassword(c.Request().Context(), database.ResetUserPasswordParams{
+		PasswordHash:       string(hashed),
+		MustChangePassword: mustChange,
+		ID:                 user.ID,
+	}); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update password"})
+	}
+
+	admin, _ := usernameFromContext(c)
+	fmt.Printf("Audit: %s reset the password of user %s (id %d, must change: %t)\n", admin, user.Username, user.ID, mustChange)
+
+	resp := map[string]interface{}{
+		"status":               "password reset",
+		"username":             user.Username,
+		"must_change_password": mustChange,
+	}
+	if temporary != "" {
+		resp["temporary_password"] = temporary
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
 // Authenticated route to generate a one-time ticket
 // Authenticated route to generate a one-time ticket
 func (h *Handler) GenerateTicket(c echo.Context) error {
@@ -298,7 +500,7 @@ func (h *Handler) GenerateTicket(c echo.Context) error {
 	}
 
 	// Generate Ticket via Store (Atomic, Secure)
-	ticket, err := h.TicketStore.Generate(username, req.TaskID, 30*time.Second)
+	ticket, err := h.TicketStore.Generate(username, req.TaskID, h.Config.TicketTTL, h.Config.TicketEntropyBytes)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to generate ticket"})
 	}
@@ -307,29 +509,236 @@ func (h *Handler) GenerateTicket(c echo.Context) error {
 }
 
 type TaskDTO struct {
-	ID                int64     `json:"id"`
-	Name              string    `json:"name"`
-	TargetURL         string    `json:"target_url"`
-	IsEnabled         bool      `json:"is_enabled"`
-	CreatedAt         time.Time `json:"created_at"`
-	CustomCSS         string    `json:"custom_css"`
-	Fps               int64     `json:"fps"`
-	Crf               int64     `json:"crf"`
-	FilenameTemplate  string    `json:"filename_template"`
-	TimeOverlay       bool      `json:"time_overlay"`
-	TimeOverlayConfig string    `json:"time_overlay_config"`
+	ID                int64               `json:"id"`
+	Name              string              `json:"name"`
+	TargetURL         string              `json:"target_url"`
+	IsEnabled         bool                `json:"is_enabled"`
+	CreatedAt         time.Time           `json:"created_at"`
+	CustomCSS         string              `json:"custom_css"`
+	Fps               int64               `json:"fps"`
+	Crf               int64               `json:"crf"`
+	FilenameTemplate  string              `json:"filename_template"`
+	TimeOverlay       bool                `json:"time_overlay"`
+	TimeOverlayConfig string              `json:"time_overlay_config"`
+	SortOrder         int64               `json:"sort_order"`
+	MaxRecordings     int64               `json:"max_recordings"`
+	CaptureConsole    bool                `json:"capture_console"`
+	Preset            string              `json:"preset"`
+	Tune              string              `json:"tune"`
+	CaptureQuality    int64               `json:"capture_quality"`
+	NotifySizeBytes   int64               `json:"notify_size_bytes"`
+	Referer           string              `json:"referer"`
+	JavaScriptEnabled bool                `json:"java_script_enabled"`
+	Offline           bool                `json:"offline"`
+	Viewports         []recorder.Viewport `json:"viewports"`
+	RecordOnChange    bool                `json:"record_on_change"`
+	CaptureFormat     string              `json:"capture_format"`
+	BlockedResources  []string            `json:"blocked_resources"`
+	StartDelayMs      int64               `json:"start_delay_ms"`
+	CaptureMilestones bool                `json:"capture_milestones"`
+	OutputDir         string              `json:"output_dir"`
+	ProxyURL          string              `json:"proxy_url"` // password redacted
+	Regions           []recorder.Region   `json:"regions"`
+	KeepAlive         *recorder.KeepAlive `json:"keepalive"`
+	Watermark         *recorder.Watermark `json:"watermark"`
+}
+
+// taskViewports decodes a task's stored viewport profiles for the API ([] when none)
+func taskViewports(stored string) []recorder.Viewport {
+	viewports, err := recorder.DecodeViewports(stored)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if viewports == nil {
+		return []recorder.Viewport{}
+	}
+	return viewports
+}
+
+// taskRegions decodes a task's stored capture regions for the API ([] when none)
+func taskRegions(stored string) []recorder.Region {
+	regions, err := recorder.DecodeRegions(stored)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if regions == nil {
+		return []recorder.Region{}
+	}
+	return regions
+}
+
+// taskKeepAlive decodes a task's stored keep-alive for the API (nil when none)
+func taskKeepAlive(stored string) *recorder.KeepAlive {
+	keepAlive, err := recorder.DecodeKeepAlive(stored)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	return keepAlive
+}
+
+// validateTaskKeepAlive checks a keep-alive against every viewport the task records at
+func validateTaskKeepAlive(keepAlive *recorder.KeepAlive, viewports []recorder.Viewport) error {
+	if keepAlive == nil {
+		return nil
+	}
+	for _, viewport := range recorder.RecordingViewports(viewports) {
+		if err := keepAlive.Validate(viewport); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskWatermark decodes a task's stored watermark for the API (nil when none)
+func taskWatermark(stored string) *recorder.Watermark {
+	watermark, err := recorder.DecodeWatermark(stored)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	return watermark
+}
+
+// validateTaskWatermark checks a watermark's source against WATERMARK_ALLOWED_HOSTS
+// and its width against every viewport the task records at
+func (h *Handler) validateTaskWatermark(watermark *recorder.Watermark, viewports []recorder.Viewport) error {
+	if watermark == nil {
+		return nil
+	}
+	for _, viewport := range recorder.RecordingViewports(viewports) {
+		if err := watermark.Validate(h.Config.WatermarkAllowedHosts, viewport); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTaskRegions checks capture regions against the default viewport they are
+// cropped from; a task records either regions or viewport profiles
+func validateTaskRegions(regions []recorder.Region, viewports []recorder.Viewport) error {
+	if len(regions) > 0 && len(viewports) > 0 {
+		return fmt.Errorf("a task can't have both regions and viewports")
+	}
+	return recorder.ValidateRegions(regions, recorder.DefaultViewport())
+}
+
+// buildPageOptions applies request overrides to the default page options
+// (JavaScript stays enabled unless explicitly turned off)
+func buildPageOptions(referer string, javaScriptEnabled *bool, offline bool, blockedResources []string) (recorder.PageOptions, error) {
+	opts := recorder.DefaultPageOptions()
+	opts.Referer = referer
+	if javaScriptEnabled != nil {
+		opts.JavaScriptEnabled = *javaScriptEnabled
+	}
+	opts.Offline = offline
+	opts.BlockedResources = recorder.ParseBlockedResources(strings.Join(blockedResources, ","))
+	return opts, opts.Validate()
+}
+
+// taskBlockedResources returns a task's stored block list for the API ([] when none)
+func taskBlockedResources(stored string) []string {
+	if list := recorder.ParseBlockedResources(stored); list != nil {
+		return list
+	}
+	return []string{}
+}
+
+// validateCaptureQuality accepts 0 (derive from CRF) or a JPEG quality of 1-100;
+// the recorder still clamps it to MinJpegQuality..MaxJpegQuality
+func validateCaptureQuality(quality int64) error {
+	if quality < 0 || quality > 100 {
+		return fmt.Errorf("capture_quality must be between 0 and 100 (0 derives it from crf)")
+	}
+	return nil
+}
+
+// maxStartDelayMs bounds start_delay_ms; a longer wait points at a broken page, not a splash screen
+const maxStartDelayMs = 60000
+
+// validateStartDelay accepts 0 (capture right away) up to maxStartDelayMs
+func validateStartDelay(ms int64) error {
+	if ms < 0 || ms > maxStartDelayMs {
+		return fmt.Errorf("start_delay_ms must be between 0 and %d", maxStartDelayMs)
+	}
+	return nil
+}
+
+// validateCustomCSS enforces MAX_CUSTOM_CSS_LENGTH (0 in a bare config means unlimited)
+func (h *Handler) validateCustomCSS(css string) error {
+	if limit := h.Config.MaxCustomCSSLength; limit > 0 && len(css) > limit {
+		return fmt.Errorf("custom_css exceeds maximum length of %d bytes", limit)
+	}
+	return nil
+}
+
+// validatePixelBudget enforces MAX_PIXEL_RATE on top of the individual fps limits,
+// so operators can bound per-task capture cost with one number (0 = unlimited).
+// Viewport profiles record in parallel, so their pixel rates add up.
+func (h *Handler) validatePixelBudget(fps int64, viewports []recorder.Viewport) error {
+	limit := int64(h.Config.MaxPixelRate)
+	if limit <= 0 {
+		return nil
+	}
+	if len(viewports) == 0 {
+		rate := recorder.PixelRate(recorder.RecordingWidth, recorder.RecordingHeight, fps)
+		if rate > limit {
+			return fmt.Errorf("%dx%d at %d fps (%d pixels/s) exceeds the server budget of %d pixels/s", recorder.RecordingWidth, recorder.RecordingHeight, fps, rate, limit)
+		}
+		return nil
+	}
+	var rate int64
+	for _, v := range viewports {
+		rate += recorder.PixelRate(v.Width, v.Height, fps)
+	}
+	if rate > limit {
+		return fmt.Errorf("%d viewports at %d fps (%d pixels/s) exceed the server budget of %d pixels/s", len(viewports), fps, rate, limit)
+	}
+	return nil
+}
+
+// timeOverlayPositions are the corners the time overlay can be anchored to
+var timeOverlayPositions = map[string]bool{"top-left": true, "top-right": true, "bottom-left": true, "bottom-right": true}
+
+// normalizeTimeOverlayConfig validates the overlay position, defaulting to bottom-right
+func normalizeTimeOverlayConfig(position string) (string, error) {
+	if position == "" {
+		return "bottom-right", nil
+	}
+	if !timeOverlayPositions[position] {
+		return "", fmt.Errorf("invalid time_overlay_config")
+	}
+	return position, nil
 }
 
 func (h *Handler) CreateTask(c echo.Context) error {
 	type CreateTaskRequest struct {
-		Name              string `json:"name"`
-		TargetURL         string `json:"target_url"`
-		FilenameTemplate  string `json:"filename_template"`
-		CustomCSS         string `json:"custom_css"`
-		Fps               *int64 `json:"fps"`
-		Crf               *int64 `json:"crf"`
-		TimeOverlay       bool   `json:"time_overlay"`
-		TimeOverlayConfig string `json:"time_overlay_config"`
+		Name              string              `json:"name"`
+		TargetURL         string              `json:"target_url"`
+		FilenameTemplate  string              `json:"filename_template"`
+		CustomCSS         string              `json:"custom_css"`
+		Fps               *int64              `json:"fps"`
+		Crf               *int64              `json:"crf"`
+		TimeOverlay       bool                `json:"time_overlay"`
+		TimeOverlayConfig string              `json:"time_overlay_config"`
+		MaxRecordings     int64               `json:"max_recordings"` // starts kept (a start's viewports and regions count once), 0 = unlimited
+		CaptureConsole    bool                `json:"capture_console"`
+		Preset            string              `json:"preset"`
+		Tune              string              `json:"tune"`
+		CaptureQuality    int64               `json:"capture_quality"`
+		NotifySizeBytes   int64               `json:"notify_size_bytes"`
+		Referer           string              `json:"referer"`
+		JavaScriptEnabled *bool               `json:"java_script_enabled"` // omitted = enabled
+		Offline           bool                `json:"offline"`
+		Viewports         []recorder.Viewport `json:"viewports"` // empty = one recording at the default viewport
+		RecordOnChange    bool                `json:"record_on_change"`
+		CaptureFormat     string              `json:"capture_format"` // jpeg (default) or png
+		BlockedResources  []string            `json:"blocked_resources"`
+		StartDelayMs      int64               `json:"start_delay_ms"` // wait before the first frame
+		CaptureMilestones bool                `json:"capture_milestones"`
+		OutputDir         string              `json:"output_dir"` // subdirectory of the recordings directory
+		ProxyURL          string              `json:"proxy_url"`  // scheme://[user:pass@]host:port, "" = direct
+		Regions           []recorder.Region   `json:"regions"`    // empty = one recording of the whole page
+		KeepAlive         *recorder.KeepAlive `json:"keepalive"`  // null = no interaction while recording
+		Watermark         *recorder.Watermark `json:"watermark"`  // null = no watermark
 	}
 
 	var req CreateTaskRequest
@@ -338,22 +747,36 @@ func (h *Handler) CreateTask(c echo.Context) error {
 	}
 
 	// Input Validation
-	// 1. Target URL
-	if _, err := url.ParseRequestURI(req.TargetURL); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid target_url"})
+	// 1. Target URL (stored normalized)
+	targetURL, err := recorder.NormalizeTargetURL(req.TargetURL)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
+	req.TargetURL = targetURL
 
-	// 2. Filename Template (Path Traversal Prevention)
-	if req.FilenameTemplate != "" {
-		// Allow alphanumeric, underscore, dot, dash.
-		matched, _ := regexp.MatchString(`^[a-zA-Z0-9_.-]+$`, req.FilenameTemplate)
-		if !matched {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "filename_template contains invalid characters. Allowed: a-z, A-Z, 0-9, _, ., -"})
-		}
-		// Explicitly reject traversal and separators
-		if strings.Contains(req.FilenameTemplate, "..") || strings.Contains(req.FilenameTemplate, "/") || strings.Contains(req.FilenameTemplate, "\\") {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "filename_template cannot contain path traversal or separators"})
-		}
+	// 2. Filename Template (Path Traversal Prevention, known variables only)
+	if err := validateFilenameTemplate(req.FilenameTemplate); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Custom CSS size bound
+	if err := h.validateCustomCSS(req.CustomCSS); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Viewport profiles (validated before fps: they count towards the pixel budget)
+	if err := recorder.ValidateViewports(req.Viewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	// Capture regions (cropped from one capture, so they don't add to the pixel budget)
+	if err := validateTaskRegions(req.Regions, req.Viewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := validateTaskKeepAlive(req.KeepAlive, req.Viewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := h.validateTaskWatermark(req.Watermark, req.Viewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
 	// 3. FPS Validation
@@ -370,6 +793,9 @@ func (h *Handler) CreateTask(c echo.Context) error {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("fps cannot exceed server limit of %d", h.Config.MaxFpsLimit)})
 		}
 	}
+	if err := h.validatePixelBudget(fps, req.Viewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
 	// 4. CRF Validation
 	var crf int64 = 23 // Default
@@ -384,13 +810,76 @@ func (h *Handler) CreateTask(c echo.Context) error {
 	}
 
 	// 5. Time Overlay Validation
-	if req.TimeOverlayConfig != "" {
-		valid := map[string]bool{"top-left": true, "top-right": true, "bottom-left": true, "bottom-right": true}
-		if !valid[req.TimeOverlayConfig] {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid time_overlay_config"})
-		}
-	} else {
-		req.TimeOverlayConfig = "bottom-right" // Default
+	overlayConfig, err := normalizeTimeOverlayConfig(req.TimeOverlayConfig)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// 6. Rotation limit (0 = unlimited)
+	if req.MaxRecordings < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "max_recordings must be >= 0"})
+	}
+
+	// 7. Encoder preset/tune (empty uses the server default)
+	if err := recorder.ValidateEncoderSettings(req.Preset, req.Tune); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// 8. Capture JPEG quality override (0 = derive from CRF) and frame format
+	if err := validateCaptureQuality(req.CaptureQuality); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := recorder.ValidateCaptureFormat(req.CaptureFormat); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if req.CaptureFormat == "" {
+		req.CaptureFormat = recorder.CaptureFormatJPEG
+	}
+
+	// 9. File size alert threshold (0 = off) and capture start delay
+	if req.NotifySizeBytes < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "notify_size_bytes must be >= 0"})
+	}
+	if err := validateStartDelay(req.StartDelayMs); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Output subdirectory (path traversal prevention, "" = default layout)
+	outputDir, err := normalizeOutputDir(req.OutputDir)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Egress proxy (TASK_PROXY_ALLOWED_HOSTS), encrypted at rest
+	proxy, err := h.normalizeTaskProxy(c.Request().Context(), 0, req.ProxyURL)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	sealedProxy, err := h.sealTaskProxy(proxy)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	// 10. Navigation referer and context options
+	pageOpts, err := buildPageOptions(req.Referer, req.JavaScriptEnabled, req.Offline, req.BlockedResources)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	viewports, err := recorder.EncodeViewports(req.Viewports)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	regions, err := recorder.EncodeRegions(req.Regions)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	keepAlive, err := recorder.EncodeKeepAlive(req.KeepAlive)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	watermark, err := recorder.EncodeWatermark(req.Watermark)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
 	params := database.CreateTaskParams{
@@ -401,13 +890,57 @@ func (h *Handler) CreateTask(c echo.Context) error {
 		Fps:               fps,
 		Crf:               crf,
 		TimeOverlay:       req.TimeOverlay,
-		TimeOverlayConfig: req.TimeOverlayConfig,
+		TimeOverlayConfig: overlayConfig,
+		MaxRecordings:     req.MaxRecordings,
+		CaptureConsole:    req.CaptureConsole,
+		EncoderPreset:     req.Preset,
+		EncoderTune:       req.Tune,
+		CaptureQuality:    req.CaptureQuality,
+		NotifySizeBytes:   req.NotifySizeBytes,
+		Referer:           pageOpts.Referer,
+		JavaScriptEnabled: pageOpts.JavaScriptEnabled,
+		Offline:           pageOpts.Offline,
+		Viewports:         viewports,
+		RecordOnChange:    req.RecordOnChange,
+		CaptureFormat:     req.CaptureFormat,
+		BlockedResources:  strings.Join(pageOpts.BlockedResources, ","),
+		StartDelayMs:      req.StartDelayMs,
+		CaptureMilestones: req.CaptureMilestones,
+		OutputDir:         outputDir,
+		ProxyUrl:          sealedProxy,
+		Regions:           regions,
+		Keepalive:         keepAlive,
+		Watermark:         watermark,
+	}
+
+	// Count and insert in one transaction (a write transaction, as the DSN sets
+	// _txlock=immediate), so concurrent creates can't both pass the limit
+	ctx := c.Request().Context()
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	defer tx.Rollback()
+	qtx := h.Queries.WithTx(tx)
+
+	// Capacity: MAX_TASKS bounds non-deleted tasks (0 = unlimited)
+	if h.Config.MaxTasks > 0 {
+		count, err := qtx.CountTasks(ctx)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		if count >= int64(h.Config.MaxTasks) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": fmt.Sprintf("task limit reached (MAX_TASKS=%d)", h.Config.MaxTasks)})
+		}
 	}
 
-	task, err := h.Queries.CreateTask(c.Request().Context(), params)
+	task, err := qtx.CreateTask(ctx, params)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
 
 	return c.JSON(http.StatusCreated, TaskDTO{
 		ID:                task.ID,
@@ -421,96 +954,459 @@ func (h *Handler) CreateTask(c echo.Context) error {
 		FilenameTemplate:  task.FilenameTemplate,
 		TimeOverlay:       task.TimeOverlay,
 		TimeOverlayConfig: task.TimeOverlayConfig,
+		SortOrder:         task.SortOrder,
+		MaxRecordings:     task.MaxRecordings,
+		CaptureConsole:    task.CaptureConsole,
+		Preset:            task.EncoderPreset,
+		Tune:              task.EncoderTune,
+		CaptureQuality:    task.CaptureQuality,
+		NotifySizeBytes:   task.NotifySizeBytes,
+		Referer:           task.Referer,
+		JavaScriptEnabled: task.JavaScriptEnabled,
+		Offline:           task.Offline,
+		Viewports:         taskViewports(task.Viewports),
+		RecordOnChange:    task.RecordOnChange,
+		CaptureFormat:     task.CaptureFormat,
+		BlockedResources:  taskBlockedResources(task.BlockedResources),
+		StartDelayMs:      task.StartDelayMs,
+		CaptureMilestones: task.CaptureMilestones,
+		OutputDir:         task.OutputDir,
+		ProxyURL:          recorder.RedactProxyURL(proxy),
+		Regions:           taskRegions(task.Regions),
+		KeepAlive:         taskKeepAlive(task.Keepalive),
+		Watermark:         taskWatermark(task.Watermark),
 	})
 }
 
 func (h *Handler) ListTasks(c echo.Context) error {
+	// Optional pagination (?page=, ?page_size=)
+	page, paginate, err := parsePagination(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
 	tasks, err := h.Queries.ListTasks(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	if paginate {
+		setPaginationHeaders(c, page, len(tasks))
+		start, end := page.bounds(len(tasks))
+		tasks = tasks[start:end]
+	}
 
 	dtos := make([]TaskDTO, len(tasks))
 	for i, t := range tasks {
 		dtos[i] = TaskDTO{
-			ID:               t.ID,
-			Name:             t.Name,
-			TargetURL:        t.TargetUrl,
-			IsEnabled:        t.IsEnabled,
-			CreatedAt:        t.CreatedAt,
-			Fps:              t.Fps,
-			Crf:              t.Crf,
-			CustomCSS:        t.CustomCss,
-			FilenameTemplate: t.FilenameTemplate,
+			ID:                t.ID,
+			Name:              t.Name,
+			TargetURL:         t.TargetUrl,
+			IsEnabled:         t.IsEnabled,
+			CreatedAt:         t.CreatedAt,
+			Fps:               t.Fps,
+			Crf:               t.Crf,
+			CustomCSS:         t.CustomCss,
+			FilenameTemplate:  t.FilenameTemplate,
+			TimeOverlay:       t.TimeOverlay,
+			TimeOverlayConfig: t.TimeOverlayConfig,
+			SortOrder:         t.SortOrder,
+			MaxRecordings:     t.MaxRecordings,
+			CaptureConsole:    t.CaptureConsole,
+			Preset:            t.EncoderPreset,
+			Tune:              t.EncoderTune,
+			CaptureQuality:    t.CaptureQuality,
+			NotifySizeBytes:   t.NotifySizeBytes,
+			Referer:           t.Referer,
+			JavaScriptEnabled: t.JavaScriptEnabled,
+			Offline:           t.Offline,
+			Viewports:         taskViewports(t.Viewports),
+			RecordOnChange:    t.RecordOnChange,
+			CaptureFormat:     t.CaptureFormat,
+			BlockedResources:  taskBlockedResources(t.BlockedResources),
+			StartDelayMs:      t.StartDelayMs,
+			CaptureMilestones: t.CaptureMilestones,
+			OutputDir:         t.OutputDir,
+			ProxyURL:          h.taskProxyDTO(t.ProxyUrl),
+			Regions:           taskRegions(t.Regions),
+			KeepAlive:         taskKeepAlive(t.Keepalive),
+			Watermark:         taskWatermark(t.Watermark),
 		}
 	}
 	return c.JSON(http.StatusOK, dtos)
 }
 
-// StartTask enables the task and starts the worker
-func (h *Handler) StartTask(c echo.Context) error {
-	idParam := c.Param("id")
-	var taskID int64
-	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+// ReorderTasks persists a manual sort position for each task in the given order.
+// Live tasks missing from the list keep their relative order after the listed ones,
+// so a stale or partial list can't leave two tasks at the same position.
+func (h *Handler) ReorderTasks(c echo.Context) error {
+	type ReorderRequest struct {
+		TaskIDs []int64 `json:"task_ids"`
 	}
-
-	// 1. Enable Task in DB
-	if err := h.Queries.EnableTask(c.Request().Context(), taskID); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to enable task: %v", err)})
+	var req ReorderRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if len(req.TaskIDs) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "task_ids is required"})
 	}
 
-	// 2. Fetch task details
-	task, err := h.Queries.GetTask(c.Request().Context(), taskID)
-	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "task not found"})
+	seen := make(map[int64]bool, len(req.TaskIDs))
+	for _, id := range req.TaskIDs {
+		if seen[id] {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("duplicate task id %d", id)})
+		}
+		seen[id] = true
 	}
 
-	// 3. Generate Filename
-	timestamp := time.Now().Format("20060102150405")
-	var filename string
-	if task.FilenameTemplate != "" {
-		// Defense-in-depth: Fallback sanitization
-		safeTemplate := filepath.Base(task.FilenameTemplate)
-		filename = fmt.Sprintf("%s_%s.mkv", safeTemplate, timestamp)
-	} else {
-		// Fallback to legacy ID_TIMESTAMP format if no template
-		filename = fmt.Sprintf("%d_%d.mkv", taskID, time.Now().Unix())
+	// Apply all positions atomically so the list is never half-reordered
+	ctx := c.Request().Context()
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	fullPath := fmt.Sprintf("/app/recordings/%s", filename)
+	defer tx.Rollback()
 
-	// 4. Create Recording Entry
-	rec, err := h.Queries.CreateRecording(c.Request().Context(), database.CreateRecordingParams{
-		TaskID:   taskID,
-		Status:   "RECORDING",
-		FilePath: fullPath,
-	})
+	qtx := h.Queries.WithTx(tx)
+	tasks, err := qtx.ListTasks(ctx)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to create recording log: %v", err)})
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	order := append([]int64(nil), req.TaskIDs...)
+	for _, t := range tasks {
+		if !seen[t.ID] {
+			order = append(order, t.ID)
+		}
 	}
 
-	// 5. Start Worker
-	if err := h.Recorder.StartRecording(c.Request().Context(), taskID, task.TargetUrl, rec.ID, fullPath, task.CustomCss, task.Fps, task.Crf, task.TimeOverlay, task.TimeOverlayConfig); err != nil {
-		// Update status to failed
-		_ = h.Queries.UpdateRecordingStatus(c.Request().Context(), database.UpdateRecordingStatusParams{
-			Status: "FAILED",
-			ID:     rec.ID,
+	for i, id := range order {
+		n, err := qtx.UpdateTaskSortOrder(ctx, database.UpdateTaskSortOrderParams{
+			SortOrder: int64(i),
+			ID:        id,
 		})
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to start worker: %v", err)})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		// Unknown or deleted: roll back rather than apply the rest of the order
+		if n == 0 {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": fmt.Sprintf("task %d not found", id)})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{"status": "started", "recording_id": fmt.Sprintf("%d", rec.ID)})
+	return c.JSON(http.StatusOK, map[string]string{"status": "reordered"})
 }
 
-// StopTask disables the task and stops the worker
-func (h *Handler) StopTask(c echo.Context) error {
+// restartStopTimeout bounds finalizing the old files of a restart (ffmpeg gets 5s per file)
+const restartStopTimeout = 30 * time.Second
+
+// StartTask enables the task and starts the worker
+func (h *Handler) StartTask(c echo.Context) error {
 	idParam := c.Param("id")
 	var taskID int64
 	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
 	}
 
-	// 1. Disable Task in DB
+	// 0. Claim the task so a double-click cannot create a second RECORDING row
+	release, err := h.Recorder.ReserveStart(taskID)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+	defer release()
+
+	return h.startTask(c, taskID, map[string]interface{}{})
+}
+
+// RestartTask cuts the task's recording: the active files are finalized and new
+// recordings start right away. The task stays claimed throughout, so no other
+// start can slip in between; the gap is the finalization plus the page load.
+func (h *Handler) RestartTask(c echo.Context) error {
+	idParam := c.Param("id")
+	var taskID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+	}
+
+	release, err := h.Recorder.ReserveRestart(taskID)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+	defer release()
+
+	// The recordings being cut, for the response
+	active, err := h.Queries.ListActiveRecordings(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	stoppedIDs := []string{}
+	for _, r := range active {
+		if r.TaskID == taskID {
+			stoppedIDs = append(stoppedIDs, fmt.Sprintf("%d", r.ID))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), restartStopTimeout)
+	defer cancel()
+	if err := h.Recorder.StopRecordingAndWait(ctx, taskID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to stop the current recording: %v", err)})
+	}
+
+	return h.startTask(c, taskID, map[string]interface{}{"stopped_recording_ids": stoppedIDs})
+}
+
+// startTask starts the recordings of a claimed task and responds with their IDs
+// (added to resp)
+func (h *Handler) startTask(c echo.Context, taskID int64, resp map[string]interface{}) error {
+	// 1. Enable Task in DB
+	if err := h.Queries.EnableTask(c.Request().Context(), taskID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to enable task: %v", err)})
+	}
+
+	// 2. Fetch task details
+	task, err := h.Queries.GetTask(c.Request().Context(), taskID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "task not found"})
+	}
+
+	// 2b. Capture settings, stored credentials and proxy included
+	opts, err := h.recordingOptions(c.Request().Context(), task)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	// 2c. Viewport profiles: one parallel recording (own file and row) per profile
+	profiles, err := recorder.DecodeViewports(task.Viewports)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	// 2d. Capture regions: one recording (own file and row) per region, all cropped
+	// from the same session's frames
+	regions, err := recorder.DecodeRegions(task.Regions)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	// 3. Generate Filename (expands {task_name}, {date}, {time}, {id})
+	baseFilename := buildRecordingFilename(task, time.Now())
+
+	// All recordings of this start form one run, which max_recordings counts as one
+	runID, err := generateRandomString(12)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	var recordingIDs []string
+	var bytesPerHour int64
+	for _, viewport := range recorder.RecordingViewports(profiles) {
+		// The directory is created by StartRecording's writability check
+		fullPath := recordingPath(task, h.Config.RecordingsPerTaskDir, viewportFilename(baseFilename, viewport.Name))
+
+		// 4. Create Recording Entries
+		outputs, err := h.createRecordingOutputs(c.Request().Context(), taskID, runID, fullPath, viewport.Name, regions)
+		if err != nil {
+			if len(recordingIDs) > 0 {
+				_ = h.Recorder.StopRecording(taskID)
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to create recording log: %v", err)})
+		}
+
+		// 5. Start Worker
+		if err := h.Recorder.StartRecording(c.Request().Context(), opts, outputs, viewport); err != nil {
+			// Update status to failed
+			for _, o := range outputs {
+				_ = recorder.SetRecordingStatus(c.Request().Context(), h.Queries, o.RecordingID, recorder.StatusRecording, recorder.StatusFailed)
+			}
+			// Don't leave the task half-started: stop the viewports already recording
+			if len(recordingIDs) > 0 {
+				_ = h.Recorder.StopRecording(taskID)
+			}
+			if errors.Is(err, recorder.ErrTargetRejected) {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+			if errors.Is(err, recorder.ErrAlreadyRecording) {
+				return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+			}
+			if errors.Is(err, recorder.ErrStorageFull) {
+				return c.JSON(http.StatusInsufficientStorage, map[string]string{"error": err.Error(), "reason": "disk_full"})
+			}
+			if errors.Is(err, recorder.ErrStorageReadOnly) {
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error(), "reason": "read_only_filesystem"})
+			}
+			if errors.Is(err, recorder.ErrStoragePermission) {
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error(), "reason": "permission_denied"})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to start worker: %v", err)})
+		}
+
+		for _, o := range outputs {
+			recordingIDs = append(recordingIDs, fmt.Sprintf("%d", o.RecordingID))
+			width, height := viewport.Width, viewport.Height
+			if o.Region != nil && o.Region.Selector == "" {
+				width, height = o.Region.Width, o.Region.Height
+			}
+			bytesPerHour += recorder.EstimateBytesPerHour(h.estimateInput(width, height, task.Fps, task.Crf))
+		}
+	}
+
+	resp["status"] = "started"
+	resp["recording_id"] = recordingIDs[0]
+	resp["recording_ids"] = recordingIDs
+	resp["estimated_bytes_per_hour"] = bytesPerHour
+	return c.JSON(http.StatusOK, resp)
+}
+
+// recordingOptions builds the capture settings of a task from its row, decrypting
+// the stored form login and proxy
+func (h *Handler) recordingOptions(ctx context.Context, task database.Task) (recorder.RecordingOptions, error) {
+	login, err := h.loadFormLogin(ctx, task.ID)
+	if err != nil {
+		return recorder.RecordingOptions{}, err
+	}
+	keepAlive, err := recorder.DecodeKeepAlive(task.Keepalive)
+	if err != nil {
+		return recorder.RecordingOptions{}, err
+	}
+	watermark, err := recorder.DecodeWatermark(task.Watermark)
+	if err != nil {
+		return recorder.RecordingOptions{}, err
+	}
+	proxy, err := h.taskProxy(task.ProxyUrl)
+	if err != nil {
+		return recorder.RecordingOptions{}, err
+	}
+	return recorder.RecordingOptions{
+		TaskID:            task.ID,
+		URL:               task.TargetUrl,
+		CustomCSS:         task.CustomCss,
+		FPS:               task.Fps,
+		CRF:               task.Crf,
+		TimeOverlay:       task.TimeOverlay,
+		TimeOverlayConfig: task.TimeOverlayConfig,
+		CaptureConsole:    task.CaptureConsole,
+		Preset:            task.EncoderPreset,
+		Tune:              task.EncoderTune,
+		CaptureQuality:    task.CaptureQuality,
+		NotifySizeBytes:   task.NotifySizeBytes,
+		RecordOnChange:    task.RecordOnChange,
+		CaptureFormat:     task.CaptureFormat,
+		StartDelayMs:      task.StartDelayMs,
+		CaptureMilestones: task.CaptureMilestones,
+		Page: recorder.PageOptions{
+			Referer:           task.Referer,
+			JavaScriptEnabled: task.JavaScriptEnabled,
+			Offline:           task.Offline,
+			BlockedResources:  recorder.ParseBlockedResources(task.BlockedResources),
+			Proxy:             proxy,
+		},
+		Login:     login,
+		KeepAlive: keepAlive,
+		Watermark: watermark,
+	}, nil
+}
+
+// createRecordingOutputs inserts the recording rows of one session: one for the whole
+// page, or one per capture region (its name appended to the file name). Rows already
+// inserted are marked failed when a later insert fails. runID groups the rows of
+// one start for max_recordings rotation.
+func (h *Handler) createRecordingOutputs(ctx context.Context, taskID int64, runID, fullPath, viewport string, regions []recorder.Region) ([]recorder.RecordingOutput, error) {
+	if len(regions) == 0 {
+		regions = []recorder.Region{{}}
+	}
+	outputs := make([]recorder.RecordingOutput, 0, len(regions))
+	for i := range regions {
+		var region *recorder.Region
+		if regions[i].Name != "" {
+			region = &regions[i]
+		}
+		path := fullPath
+		if region != nil {
+			path = viewportFilename(fullPath, region.Name)
+		}
+		rec, err := h.Queries.CreateRecording(ctx, database.CreateRecordingParams{
+			TaskID:   taskID,
+			Status:   string(recorder.StatusRecording),
+			FilePath: path,
+			Viewport: viewport,
+			Region:   regions[i].Name,
+			RunID:    runID,
+		})
+		if err != nil {
+			for _, o := range outputs {
+				_ = recorder.SetRecordingStatus(ctx, h.Queries, o.RecordingID, recorder.StatusRecording, recorder.StatusFailed)
+			}
+			return nil, err
+		}
+		outputs = append(outputs, recorder.RecordingOutput{RecordingID: rec.ID, Path: path, Region: region})
+	}
+	return outputs, nil
+}
+
+func (h *Handler) estimateInput(width, height int, fps, crf int64) recorder.EstimateInput {
+	return recorder.EstimateInput{
+		Width:        width,
+		Height:       height,
+		Fps:          fps,
+		Crf:          crf,
+		BitsPerPixel: h.Config.EstimateBitsPerPixel,
+	}
+}
+
+// EstimateRecording is a dry run that returns a rough disk usage estimate for the given settings
+func (h *Handler) EstimateRecording(c echo.Context) error {
+	type EstimateRequest struct {
+		Fps             int64 `json:"fps"`
+		Crf             int64 `json:"crf"`
+		Width           int   `json:"width"`
+		Height          int   `json:"height"`
+		DurationSeconds int64 `json:"duration_seconds"`
+	}
+	req := EstimateRequest{Fps: 5, Crf: 23, Width: recorder.RecordingWidth, Height: recorder.RecordingHeight}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	}
+	if req.Fps < 1 || req.Fps > 15 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "fps must be between 1 and 15"})
+	}
+	if req.Crf < 0 || req.Crf > 51 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "crf must be between 0 and 51"})
+	}
+	if req.Width <= 0 || req.Height <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "width and height must be positive"})
+	}
+	if req.DurationSeconds < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "duration_seconds must be >= 0"})
+	}
+	if req.DurationSeconds == 0 {
+		req.DurationSeconds = 3600
+	}
+
+	input := h.estimateInput(req.Width, req.Height, req.Fps, req.Crf)
+	perHour := recorder.EstimateBytesPerHour(input)
+	estimated := recorder.EstimateBytes(input, time.Duration(req.DurationSeconds)*time.Second)
+
+	resp := map[string]interface{}{
+		"bytes_per_hour":   perHour,
+		"duration_seconds": req.DurationSeconds,
+		"estimated_bytes":  estimated,
+	}
+	if diskStats, err := disk.Usage("/app/recordings"); err == nil {
+		resp["disk_free_bytes"] = diskStats.Free
+		resp["exceeds_free_space"] = uint64(estimated) > diskStats.Free
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// StopTask disables the task and stops the worker
+func (h *Handler) StopTask(c echo.Context) error {
+	idParam := c.Param("id")
+	var taskID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+	}
+
+	// 1. Disable Task in DB
 	if err := h.Queries.DisableTask(c.Request().Context(), taskID); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to disable task: %v", err)})
 	}
@@ -525,6 +1421,121 @@ func (h *Handler) StopTask(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"status": "stopped"})
 }
 
+// StopAllRecordings is the incident kill-switch: it disables every enabled task
+// and stops every active recording session
+func (h *Handler) StopAllRecordings(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	// 1. Disable first so nothing gets restarted while we are stopping
+	enabled, err := h.Queries.ListEnabledTasks(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to list tasks: %v", err)})
+	}
+	for _, t := range enabled {
+		if err := h.Queries.DisableTask(ctx, t.ID); err != nil {
+			fmt.Printf("StopAll: failed to disable task %d: %v\n", t.ID, err)
+		}
+	}
+
+	// 2. Stop every active session (including tasks that were already disabled)
+	stopped := []int64{}
+	for _, taskID := range h.Recorder.ActiveTaskIDs() {
+		if err := h.Recorder.StopRecording(taskID); err != nil {
+			fmt.Printf("StopAll: worker stop warning: %v\n", err)
+			continue
+		}
+		stopped = append(stopped, taskID)
+	}
+
+	username, _ := usernameFromContext(c)
+	fmt.Printf("StopAll: %s stopped %d recording(s): %v\n", username, len(stopped), stopped)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"stopped": stopped})
+}
+
+// scanOrphans reports files in the recordings directory without a row, and rows without a file
+func (h *Handler) scanOrphans(ctx context.Context) (orphanReport, error) {
+	recs, err := h.Queries.ListAllRecordings(ctx)
+	if err != nil {
+		return orphanReport{}, fmt.Errorf("failed to list recordings: %w", err)
+	}
+	report, err := findOrphans("/app/recordings", recs, time.Now())
+	if err != nil {
+		return orphanReport{}, fmt.Errorf("failed to scan recordings directory: %w", err)
+	}
+	return report, nil
+}
+
+// ListOrphans lists orphaned recording files and rows (admin), with the number of
+// finished recordings whose file is still on disk and their total size, e.g. to
+// check the archive after a storage migration
+func (h *Handler) ListOrphans(c echo.Context) error {
+	report, err := h.scanOrphans(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// CleanupOrphans deletes orphaned files and/or rows (admin). The scan is redone
+// server-side, so only what is orphaned right now is touched. Protected rows are kept.
+func (h *Handler) CleanupOrphans(c echo.Context) error {
+	type CleanupRequest struct {
+		Files bool `json:"files"`
+		Rows  bool `json:"rows"`
+	}
+	var req CleanupRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	if !req.Files && !req.Rows {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "nothing to clean up: set files and/or rows"})
+	}
+
+	ctx := c.Request().Context()
+	report, err := h.scanOrphans(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	removedFiles := []string{}
+	removedRows := []int64{}
+	failures := []string{}
+
+	if req.Files {
+		for _, f := range report.Files {
+			if err := os.Remove(f.Path); err != nil && !os.IsNotExist(err) {
+				failures = append(failures, fmt.Sprintf("%s: %v", f.Path, err))
+				continue
+			}
+			removedFiles = append(removedFiles, f.Path)
+		}
+	}
+	if req.Rows {
+		for _, r := range report.Rows {
+			if r.IsProtected {
+				continue
+			}
+			if err := h.Queries.DeleteRecording(ctx, r.ID); err != nil {
+				failures = append(failures, fmt.Sprintf("recording %d: %v", r.ID, err))
+				continue
+			}
+			removedRows = append(removedRows, r.ID)
+		}
+	}
+
+	username, _ := usernameFromContext(c)
+	fmt.Printf("Orphans: %s removed %d file(s) and %d row(s), %d failure(s)\n", username, len(removedFiles), len(removedRows), len(failures))
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"removed_files": removedFiles,
+		"removed_rows":  removedRows,
+		"errors":        failures,
+	})
+}
+
+// UpdateTask replaces a task's settings. Fields left out of the body keep their
+// stored values, so a client that only knows some settings can't reset the rest.
 func (h *Handler) UpdateTask(c echo.Context) error {
 	idParam := c.Param("id")
 	var taskID int64
@@ -533,35 +1544,121 @@ func (h *Handler) UpdateTask(c echo.Context) error {
 	}
 
 	type UpdateTaskRequest struct {
-		Name             string `json:"name"`
-		TargetURL        string `json:"target_url"`
-		FilenameTemplate string `json:"filename_template"`
-		CustomCSS        string `json:"custom_css"`
-		Fps              *int64 `json:"fps"`
-		Crf              *int64 `json:"crf"`
+		Name              string              `json:"name"`
+		TargetURL         string              `json:"target_url"`
+		FilenameTemplate  string              `json:"filename_template"`
+		CustomCSS         string              `json:"custom_css"`
+		Fps               *int64              `json:"fps"`
+		Crf               *int64              `json:"crf"`
+		TimeOverlay       bool                `json:"time_overlay"`
+		TimeOverlayConfig string              `json:"time_overlay_config"`
+		MaxRecordings     int64               `json:"max_recordings"` // starts kept (a start's viewports and regions count once), 0 = unlimited
+		CaptureConsole    bool                `json:"capture_console"`
+		Preset            string              `json:"preset"`
+		Tune              string              `json:"tune"`
+		CaptureQuality    int64               `json:"capture_quality"`
+		NotifySizeBytes   int64               `json:"notify_size_bytes"`
+		Referer           string              `json:"referer"`
+		JavaScriptEnabled *bool               `json:"java_script_enabled"`
+		Offline           bool                `json:"offline"`
+		Viewports         []recorder.Viewport `json:"viewports"` // empty = one recording at the default viewport
+		RecordOnChange    bool                `json:"record_on_change"`
+		CaptureFormat     string              `json:"capture_format"` // jpeg (default) or png
+		BlockedResources  []string            `json:"blocked_resources"`
+		StartDelayMs      int64               `json:"start_delay_ms"` // wait before the first frame
+		CaptureMilestones bool                `json:"capture_milestones"`
+		OutputDir         string              `json:"output_dir"` // subdirectory of the recordings directory
+		ProxyURL          *string             `json:"proxy_url"`  // scheme://[user:pass@]host:port, "" = direct, omitted/null = unchanged
+		Regions           []recorder.Region   `json:"regions"`    // empty = one recording of the whole page
+		KeepAlive         *recorder.KeepAlive `json:"keepalive"`  // null = no interaction while recording
+		Watermark         *recorder.Watermark `json:"watermark"`  // null = no watermark
+	}
+
+	// The body is decoded over the stored task: an omitted field keeps its value,
+	// an explicit empty list or null clears it
+	task, err := h.Queries.GetTask(c.Request().Context(), taskID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "task not found"})
+	}
+	storedViewports, err := recorder.DecodeViewports(task.Viewports)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	storedRegions, err := recorder.DecodeRegions(task.Regions)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	storedKeepAlive, err := recorder.DecodeKeepAlive(task.Keepalive)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	storedWatermark, err := recorder.DecodeWatermark(task.Watermark)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	req := UpdateTaskRequest{
+		Name:              task.Name,
+		TargetURL:         task.TargetUrl,
+		FilenameTemplate:  task.FilenameTemplate,
+		CustomCSS:         task.CustomCss,
+		Fps:               &task.Fps,
+		Crf:               &task.Crf,
+		TimeOverlay:       task.TimeOverlay,
+		TimeOverlayConfig: task.TimeOverlayConfig,
+		MaxRecordings:     task.MaxRecordings,
+		CaptureConsole:    task.CaptureConsole,
+		Preset:            task.EncoderPreset,
+		Tune:              task.EncoderTune,
+		CaptureQuality:    task.CaptureQuality,
+		NotifySizeBytes:   task.NotifySizeBytes,
+		Referer:           task.Referer,
+		JavaScriptEnabled: &task.JavaScriptEnabled,
+		Offline:           task.Offline,
+		Viewports:         storedViewports,
+		RecordOnChange:    task.RecordOnChange,
+		CaptureFormat:     task.CaptureFormat,
+		BlockedResources:  recorder.ParseBlockedResources(task.BlockedResources),
+		StartDelayMs:      task.StartDelayMs,
+		CaptureMilestones: task.CaptureMilestones,
+		OutputDir:         task.OutputDir,
+		Regions:           storedRegions,
+		KeepAlive:         storedKeepAlive,
+		Watermark:         storedWatermark,
 	}
-
-	var req UpdateTaskRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
 	// Input Validation (Reuse logic from CreateTask, should ideally be shared)
-	if _, err := url.ParseRequestURI(req.TargetURL); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid target_url"})
+	targetURL, err := recorder.NormalizeTargetURL(req.TargetURL)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
+	req.TargetURL = targetURL
 
-	// 2. Filename Template (Path Traversal Prevention)
-	if req.FilenameTemplate != "" {
-		// Allow alphanumeric, underscore, dot, dash.
-		matched, _ := regexp.MatchString(`^[a-zA-Z0-9_.-]+$`, req.FilenameTemplate)
-		if !matched {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "filename_template contains invalid characters. Allowed: a-z, A-Z, 0-9, _, ., -"})
-		}
-		// Explicitly reject traversal and separators
-		if strings.Contains(req.FilenameTemplate, "..") || strings.Contains(req.FilenameTemplate, "/") || strings.Contains(req.FilenameTemplate, "\\") {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "filename_template cannot contain path traversal or separators"})
-		}
+	// 2. Filename Template (Path Traversal Prevention, known variables only)
+	if err := validateFilenameTemplate(req.FilenameTemplate); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Custom CSS size bound
+	if err := h.validateCustomCSS(req.CustomCSS); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Viewport profiles (validated before fps: they count towards the pixel budget)
+	if err := recorder.ValidateViewports(req.Viewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	// Capture regions (cropped from one capture, so they don't add to the pixel budget)
+	if err := validateTaskRegions(req.Regions, req.Viewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := validateTaskKeepAlive(req.KeepAlive, req.Viewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := h.validateTaskWatermark(req.Watermark, req.Viewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
 	// 3. FPS Validation
@@ -575,6 +1672,9 @@ func (h *Handler) UpdateTask(c echo.Context) error {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("fps cannot exceed server limit of %d", h.Config.MaxFpsLimit)})
 		}
 	}
+	if err := h.validatePixelBudget(fps, req.Viewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
 	// 4. CRF Validation
 	var crf int64 = 23
@@ -588,236 +1688,1109 @@ func (h *Handler) UpdateTask(c echo.Context) error {
 		}
 	}
 
-	err := h.Queries.UpdateTask(c.Request().Context(), database.UpdateTaskParams{
-		Name:             req.Name,
-		TargetUrl:        req.TargetURL,
-		FilenameTemplate: req.FilenameTemplate,
-		CustomCss:        req.CustomCSS,
-		Fps:              fps,
-		Crf:              crf,
-		ID:               taskID,
-	})
+	// 5. Time Overlay Validation
+	overlayConfig, err := normalizeTimeOverlayConfig(req.TimeOverlayConfig)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// 6. Rotation limit (0 = unlimited)
+	if req.MaxRecordings < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "max_recordings must be >= 0"})
+	}
+
+	// 7. Encoder preset/tune (empty uses the server default)
+	if err := recorder.ValidateEncoderSettings(req.Preset, req.Tune); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// 8. Capture JPEG quality override (0 = derive from CRF) and frame format
+	if err := validateCaptureQuality(req.CaptureQuality); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := recorder.ValidateCaptureFormat(req.CaptureFormat); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if req.CaptureFormat == "" {
+		req.CaptureFormat = recorder.CaptureFormatJPEG
+	}
+
+	// 9. File size alert threshold (0 = off) and capture start delay
+	if req.NotifySizeBytes < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "notify_size_bytes must be >= 0"})
+	}
+	if err := validateStartDelay(req.StartDelayMs); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Output subdirectory (path traversal prevention, "" = default layout)
+	outputDir, err := normalizeOutputDir(req.OutputDir)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Egress proxy (TASK_PROXY_ALLOWED_HOSTS), encrypted at rest; a redacted password
+	// keeps the stored one, and only an explicit "" switches the task to direct
+	sealedProxy := task.ProxyUrl
+	if req.ProxyURL != nil {
+		proxy, err := h.normalizeTaskProxy(c.Request().Context(), taskID, *req.ProxyURL)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if sealedProxy, err = h.sealTaskProxy(proxy); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+	}
+
+	// 10. Navigation referer and context options
+	pageOpts, err := buildPageOptions(req.Referer, req.JavaScriptEnabled, req.Offline, req.BlockedResources)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	viewports, err := recorder.EncodeViewports(req.Viewports)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	regions, err := recorder.EncodeRegions(req.Regions)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	keepAlive, err := recorder.EncodeKeepAlive(req.KeepAlive)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	watermark, err := recorder.EncodeWatermark(req.Watermark)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	err = h.Queries.UpdateTask(c.Request().Context(), database.UpdateTaskParams{
+		Name:              req.Name,
+		TargetUrl:         req.TargetURL,
+		FilenameTemplate:  req.FilenameTemplate,
+		CustomCss:         req.CustomCSS,
+		Fps:               fps,
+		Crf:               crf,
+		TimeOverlay:       req.TimeOverlay,
+		TimeOverlayConfig: overlayConfig,
+		MaxRecordings:     req.MaxRecordings,
+		CaptureConsole:    req.CaptureConsole,
+		EncoderPreset:     req.Preset,
+		EncoderTune:       req.Tune,
+		CaptureQuality:    req.CaptureQuality,
+		NotifySizeBytes:   req.NotifySizeBytes,
+		Referer:           pageOpts.Referer,
+		JavaScriptEnabled: pageOpts.JavaScriptEnabled,
+		Offline:           pageOpts.Offline,
+		Viewports:         viewports,
+		RecordOnChange:    req.RecordOnChange,
+		CaptureFormat:     req.CaptureFormat,
+		BlockedResources:  strings.Join(pageOpts.BlockedResources, ","),
+		StartDelayMs:      req.StartDelayMs,
+		CaptureMilestones: req.CaptureMilestones,
+		OutputDir:         outputDir,
+		ProxyUrl:          sealedProxy,
+		Regions:           regions,
+		Keepalive:         keepAlive,
+		Watermark:         watermark,
+		ID:                taskID,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "updated"})
+}
+
+func (h *Handler) DeleteTask(c echo.Context) error {
+	idParam := c.Param("id")
+	var taskID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+	}
+
+	// Stop any active recording first
+	_ = h.Recorder.StopRecording(taskID)
+
+	if err := h.Queries.DeleteTask(c.Request().Context(), taskID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (h *Handler) RegisterRoutes(e *echo.Echo) {
+	// Public routes with Rate Limiting
+	e.POST("/api/login", h.Login, h.RateLimitMiddleware)
+	e.GET("/auth/login", h.AuthLogin)          // OIDC Login Start
+	e.GET("/auth/callback", h.AuthCallback)    // OIDC Callback
+	e.GET("/api/ready", h.Ready)               // Readiness probe (unauthenticated)
+	e.GET("/api/capabilities", h.Capabilities) // Feature toggles for the UI (unauthenticated)
+
+	g := e.Group("/api")
+	// Security headers (CSP etc.) are applied globally via SecurityHeaders
+
+	config := echojwt.Config{
+		TokenLookup: "header:Authorization",
+		ParseTokenFunc: func(c echo.Context, auth string) (interface{}, error) {
+			// Support "Bearer " prefix
+			if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+				auth = auth[7:]
+			}
+			return h.tokenKeys().Parse(auth)
+		},
+		Skipper: func(c echo.Context) bool {
+			// Skip for OPTIONS (CORS preflight) and WebSocket Ticket auth
+			if c.Request().Method == "OPTIONS" {
+				return true
+			}
+			if strings.HasSuffix(c.Path(), "/interact") || strings.HasSuffix(c.Path(), "/logs/stream") {
+				return true
+			}
+			return false
+		},
+	}
+
+	g.Use(echojwt.WithConfig(config))
+	g.Use(h.RequirePasswordChanged)
+
+	g.POST("/tasks", h.CreateTask)
+	g.GET("/tasks", h.ListTasks)
+	g.POST("/tasks/reorder", h.ReorderTasks)
+	g.POST("/tasks/estimate", h.EstimateRecording)
+	g.POST("/tasks/:id/start", h.StartTask)
+	g.POST("/tasks/:id/stop", h.StopTask)
+	g.POST("/tasks/:id/restart", h.RestartTask)
+	g.PUT("/tasks/:id", h.UpdateTask)
+	g.DELETE("/tasks/:id", h.DeleteTask)
+	g.GET("/tasks/:id/credentials", h.GetTaskCredentials)
+	g.PUT("/tasks/:id/credentials", h.SetTaskCredentials)
+	g.DELETE("/tasks/:id/credentials", h.DeleteTaskCredentials)
+	g.GET("/archives", h.ListArchives)
+	g.GET("/stats", h.GetStats)
+	g.GET("/metrics", h.GetMetrics)
+	g.GET("/diagnostics/ntp", h.GetNTPDiagnostics, h.RequireAdmin)
+	g.GET("/time", h.GetTimeSource)
+	g.POST("/time/sync", h.SyncTimeSource, h.RequireAdmin)
+	g.GET("/admin/processes", h.GetProcessStats, h.RequireAdmin)
+	g.POST("/admin/recordings/:id/transcode", h.TranscodeRecording, h.RequireAdmin)
+	g.GET("/admin/transcodes", h.ListTranscodes, h.RequireAdmin)
+	g.POST("/admin/stop-all", h.StopAllRecordings, h.RequireAdmin)
+	g.GET("/admin/orphans", h.ListOrphans, h.RequireAdmin)
+	g.POST("/admin/orphans/cleanup", h.CleanupOrphans, h.RequireAdmin)
+
+	// Tickets
+	// Tickets
+	g.POST("/tickets", h.GenerateTicket, h.RateLimitMiddleware)
+
+	// OIDC single logout
+	g.POST("/auth/logout", h.AuthLogout)
+	g.POST("/auth/refresh", h.AuthRefresh, h.RateLimitMiddleware)
+
+	// Password Change with Rate Limiting
+	g.POST("/password", h.ChangePassword, h.RateLimitMiddleware)
+	g.POST("/users/:id/reset-password", h.ResetUserPassword, h.RequireAdmin)
+
+	// Two-factor authentication (TOTP)
+	g.POST("/auth/totp/enroll", h.EnrollTOTP)
+	g.POST("/auth/totp/activate", h.ActivateTOTP, h.RateLimitMiddleware)
+	g.POST("/auth/totp/disable", h.DisableTOTP, h.RateLimitMiddleware)
+
+	// Live monitor endpoints
+	g.GET("/recordings/live", h.GetLiveRecordings)
+	g.GET("/recordings/:id/preview.jpg", h.GetRecordingPreview)
+	g.GET("/recordings/:id/snapshot", h.GetRecordingSnapshot)
+	g.GET("/recordings/:id/frames.zip", h.GetRecordingFrames, h.NoWriteDeadlineMiddleware)
+	g.DELETE("/recordings/:id", h.DeleteRecording)
+	g.POST("/recordings/download", h.DownloadRecordings, h.NoWriteDeadlineMiddleware)
+	g.PATCH("/recordings/:id", h.UpdateRecording)
+	g.GET("/recordings/:id/log", h.GetRecordingLog)
+	g.GET("/recordings/:id/verify", h.VerifyRecording, h.NoWriteDeadlineMiddleware)
+	g.GET("/recordings/:id/milestones", h.ListRecordingMilestones)
+	g.GET("/recordings/:id/milestones/:name", h.GetRecordingMilestone)
+	g.GET("/recordings/:id/live.mp4", h.GetRecordingLive, h.NoWriteDeadlineMiddleware)
+	g.GET("/recordings/:id/logs/stream", h.WsRecordingLogs, h.NoWriteDeadlineMiddleware)
+	g.POST("/recordings/:id/protect", h.ToggleRecordingProtection)
+	g.POST("/recordings/:id/overlay", h.AnnotateRecording)
+	g.POST("/tasks/preview", h.PreviewTask, h.previewLimit)
+	g.GET("/tasks/:id/interact", h.WsInteractive, h.NoWriteDeadlineMiddleware)
+}
+
+func (h *Handler) PreviewTask(c echo.Context) error {
+	type PreviewRequest struct {
+		TargetURL         string   `json:"target_url"`
+		CustomCSS         string   `json:"custom_css"`
+		Referer           string   `json:"referer"`
+		JavaScriptEnabled *bool    `json:"java_script_enabled"`
+		Offline           bool     `json:"offline"`
+		BlockedResources  []string `json:"blocked_resources"`
+		// The remaining fields make the preview match the recording
+		Viewport          *recorder.Viewport  `json:"viewport"`
+		TimeOverlay       bool                `json:"time_overlay"`
+		TimeOverlayConfig string              `json:"time_overlay_config"`
+		Watermark         *recorder.Watermark `json:"watermark"`
+		// TaskID applies the stored session and login credentials of an existing task
+		TaskID int64 `json:"task_id"`
+		// ProxyURL routes the preview like a task proxy; empty uses the proxy of TaskID, if any
+		ProxyURL string `json:"proxy_url"`
+	}
+	var req PreviewRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	targetURL, err := recorder.NormalizeTargetURL(req.TargetURL)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	req.TargetURL = targetURL
+	if err := h.validateCustomCSS(req.CustomCSS); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	pageOpts, err := buildPageOptions(req.Referer, req.JavaScriptEnabled, req.Offline, req.BlockedResources)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	previewOpts := recorder.PreviewOptions{Viewport: req.Viewport, TimeOverlay: req.TimeOverlay}
+	if req.TimeOverlay {
+		if previewOpts.TimeOverlayConfig, err = normalizeTimeOverlayConfig(req.TimeOverlayConfig); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+	if err := previewOpts.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	var previewViewports []recorder.Viewport
+	if req.Viewport != nil {
+		previewViewports = []recorder.Viewport{*req.Viewport}
+	}
+	if err := h.validateTaskWatermark(req.Watermark, previewViewports); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	previewOpts.Watermark = req.Watermark
+	if pageOpts.Proxy, err = h.normalizeTaskProxy(c.Request().Context(), req.TaskID, req.ProxyURL); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if req.TaskID != 0 {
+		task, err := h.Queries.GetTask(c.Request().Context(), req.TaskID)
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "task not found"})
+		}
+		login, err := h.loadFormLogin(c.Request().Context(), req.TaskID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		previewOpts.TaskID, previewOpts.Login = req.TaskID, login
+		if req.ProxyURL == "" {
+			if pageOpts.Proxy, err = h.taskProxy(task.ProxyUrl); err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			}
+		}
+	}
+
+	// Capture preview (returns JPEG bytes)
+	previewData, err := h.Recorder.CapturePreview(req.TargetURL, req.CustomCSS, pageOpts, previewOpts)
+	if err != nil {
+		if errors.Is(err, recorder.ErrTargetRejected) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		if errors.Is(err, recorder.ErrPreviewTooLarge) || errors.Is(err, recorder.ErrNonHTMLTarget) {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		}
+		if errors.Is(err, recorder.ErrPreviewBusy) {
+			c.Response().Header().Set("Retry-After", "5")
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return c.JSON(http.StatusGatewayTimeout, map[string]string{"error": "Failed to capture preview: " + err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to capture preview: " + err.Error()})
+	}
+
+	return c.Blob(http.StatusOK, "image/jpeg", previewData)
+}
+
+// WebSocket handler for interactive session
+func (h *Handler) WsInteractive(c echo.Context) error {
+	// 1. Get Ticket from Query
+	ticketID := c.QueryParam("ticket")
+	if ticketID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing ticket"})
+	}
+
+	// Parse the interactive viewport before burning the ticket so a bad request can be retried
+	options := recorder.DefaultInteractiveOptions()
+	if v := c.QueryParam("width"); v != "" {
+		width, err := strconv.Atoi(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid width"})
+		}
+		options.Width = width
+	}
+	if v := c.QueryParam("height"); v != "" {
+		height, err := strconv.Atoi(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid height"})
+		}
+		options.Height = height
+	}
+	if v := c.QueryParam("format"); v != "" {
+		options.Format = strings.ToLower(v)
+	}
+	if v := c.QueryParam("scale"); v != "" {
+		scale, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid scale"})
+		}
+		options.Scale = scale
+	}
+	if err := options.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// 2. Exchange Ticket (Atomic Check-and-Burn)
+	ticket, err := h.TicketStore.Exchange(ticketID)
+	if err != nil {
+		// Return 401 for invalid/expired tickets
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid or expired ticket"})
+	}
+
+	// 3. Get TaskID
+	idParam := c.Param("id")
+	var taskID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+	}
+
+	// 4. Validate Authorization (RBAC: Ticket must match requested TaskID)
+	if ticket.TaskID != taskID {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "ticket mismatch"})
+	}
+
+	// 5. Check Task Exists and Get Details
+	task, err := h.Queries.GetTask(c.Request().Context(), taskID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "task not found"})
+	}
+	// The session (and the login it stores) goes through the task's proxy, like its recordings
+	pageOpts := recorder.DefaultPageOptions()
+	if pageOpts.Proxy, err = h.taskProxy(task.ProxyUrl); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	// 6. Strict Upgrader (offers the versioned interactive subprotocols)
+	upgrader := websocket.Upgrader{
+		Subprotocols: recorder.InteractiveSubprotocols,
+		CheckOrigin:  checkSameOrigin,
+	}
+
+	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	// Reject clients that only speak protocol versions we don't support
+	if _, ok := recorder.NegotiateInteractiveProtocol(websocket.Subprotocols(c.Request())); !ok {
+		msg := websocket.FormatCloseMessage(websocket.CloseProtocolError, "unsupported protocol version")
+		_ = ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		return nil
+	}
+
+	// 7. Handle Interactive Session
+	return h.Recorder.HandleInteractive(c.Request().Context(), taskID, task.TargetUrl, pageOpts, ws, options)
+}
+
+// checkSameOrigin is the strict same-origin policy for WebSocket upgrades (prevents CSWSH)
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// No Origin header (non-browser client) -> verification relies on Ticket
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	// Compare Host (including port if present)
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// WsRecordingLogs streams console/network log lines and status transitions of a live
+// recording as JSON messages. The socket is closed when the recording ends.
+func (h *Handler) WsRecordingLogs(c echo.Context) error {
+	// 1. Get Ticket from Query
+	ticketID := c.QueryParam("ticket")
+	if ticketID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing ticket"})
+	}
+
+	// 2. Exchange Ticket (Atomic Check-and-Burn)
+	ticket, err := h.TicketStore.Exchange(ticketID)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid or expired ticket"})
+	}
+
+	// 3. Get Recording
+	idParam := c.Param("id")
+	var recID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
+	}
+	rec, err := h.Queries.GetRecording(c.Request().Context(), recID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
+	}
+
+	// 4. Validate Authorization (ticket is issued for the recording's task)
+	if ticket.TaskID != rec.TaskID {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "ticket mismatch"})
+	}
+
+	// 5. Only live recordings have a stream
+	if !recorder.StatusRecording.Is(rec.Status) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "recording is not active"})
+	}
+	events, unsubscribe, ok := h.Recorder.SubscribeLogs(recorder.SessionKey{TaskID: rec.TaskID, Viewport: rec.Viewport})
+	if !ok {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "recording is not active"})
+	}
+	defer unsubscribe()
+
+	upgrader := websocket.Upgrader{CheckOrigin: checkSameOrigin}
+	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	// The stream is one-way; reading only detects the client going away
+	clientGone := make(chan struct{})
+	go func() {
+		defer close(clientGone)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "recording ended")
+				_ = ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+				return nil
+			}
+			if err := ws.WriteJSON(ev); err != nil {
+				return nil
+			}
+		case <-clientGone:
+			return nil
+		}
+	}
+}
+
+func (h *Handler) DeleteRecording(c echo.Context) error {
+	idParam := c.Param("id")
+	var recID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
+	}
+
+	// 1. Get Recording to find file path
+	rec, err := h.Queries.GetRecording(c.Request().Context(), recID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
+	}
+
+	// 2. Delete file from disk
+	if rec.FilePath != "" {
+		if err := os.Remove(rec.FilePath); err != nil {
+			fmt.Printf("Warning: failed to delete file %s: %v\n", rec.FilePath, err)
+			// Continue to delete DB record even if file delete fails (maybe already gone)
+		}
+		// Console/network sidecar log, if the task captured one
+		if err := os.Remove(recorder.PageLogPath(rec.FilePath)); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Warning: failed to delete log %s: %v\n", recorder.PageLogPath(rec.FilePath), err)
+		}
+		// Milestone stills, if the task captured them
+		for _, still := range recorder.MilestonePaths(rec.FilePath) {
+			if err := os.Remove(still); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("Warning: failed to delete milestone %s: %v\n", still, err)
+			}
+		}
+	}
+
+	// 3. Delete from DB
+	if err := h.Queries.DeleteRecording(c.Request().Context(), recID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// UpdateRecording edits a recording: renames its file on disk (within its current
+// directory) and/or sets its note and tags. Omitted fields are left unchanged.
+func (h *Handler) UpdateRecording(c echo.Context) error {
+	idParam := c.Param("id")
+	var recID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
+	}
+
+	type UpdateRecordingRequest struct {
+		Filename string    `json:"filename"` // empty keeps the current name
+		Note     *string   `json:"note"`
+		Tags     *[]string `json:"tags"`
+	}
+	var req UpdateRecordingRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	if strings.TrimSpace(req.Filename) == "" && req.Note == nil && req.Tags == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "nothing to update"})
+	}
+
+	// Validate metadata up front so a bad tag doesn't leave a half-applied rename
+	var note string
+	if req.Note != nil {
+		var err error
+		if note, err = validateRecordingNote(*req.Note); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+	var tags []string
+	if req.Tags != nil {
+		var err error
+		if tags, err = normalizeRecordingTags(*req.Tags); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	ctx := c.Request().Context()
+	rec, err := h.Queries.GetRecording(ctx, recID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
+	}
+
+	filePath := rec.FilePath
+	if strings.TrimSpace(req.Filename) != "" {
+		newPath, status, err := h.renameRecordingFile(ctx, rec, req.Filename)
+		if err != nil {
+			return c.JSON(status, map[string]string{"error": err.Error()})
+		}
+		filePath = newPath
+	}
+
+	// Notes and tags may be edited at any time, including while still recording
+	if req.Note != nil || req.Tags != nil {
+		params := database.UpdateRecordingMetadataParams{Note: rec.Note, Tags: rec.Tags, ID: rec.ID}
+		if req.Note != nil {
+			params.Note = note
+		}
+		if req.Tags != nil {
+			params.Tags = strings.Join(tags, ",")
+		}
+		if err := h.Queries.UpdateRecordingMetadata(ctx, params); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		rec.Note, rec.Tags = params.Note, params.Tags
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":        rec.ID,
+		"file_path": filePath,
+		"note":      rec.Note,
+		"tags":      splitRecordingTags(rec.Tags),
+	})
+}
+
+// renameRecordingFile renames a finished recording's file and points the DB row at
+// the new path. On failure it returns the HTTP status to report.
+func (h *Handler) renameRecordingFile(ctx context.Context, rec database.Recording, name string) (string, int, error) {
+	if recorder.StatusRecording.Is(rec.Status) {
+		return "", http.StatusConflict, fmt.Errorf("recording is still in progress")
+	}
+	if rec.FilePath == "" {
+		return "", http.StatusConflict, fmt.Errorf("recording has no file")
+	}
+
+	filename, err := validateRecordingRename(name, rec.FilePath)
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
+
+	// Stay inside the recordings tree and the recording's own directory
+	dir := filepath.Clean(filepath.Dir(rec.FilePath))
+	if dir != "/app/recordings" && !strings.HasPrefix(dir, "/app/recordings/") {
+		return "", http.StatusConflict, fmt.Errorf("recording file is outside the recordings directory")
+	}
+	newPath := filepath.Join(dir, filename)
+	if filepath.Dir(newPath) != dir {
+		return "", http.StatusBadRequest, fmt.Errorf("invalid filename")
+	}
+	if newPath == rec.FilePath {
+		return rec.FilePath, http.StatusOK, nil
+	}
+
+	if _, err := os.Lstat(newPath); err == nil {
+		return "", http.StatusConflict, fmt.Errorf("a file with that name already exists")
+	}
+	if err := os.Rename(rec.FilePath, newPath); err != nil {
+		if os.IsNotExist(err) {
+			return "", http.StatusNotFound, fmt.Errorf("recording file not found on disk")
+		}
+		return "", http.StatusInternalServerError, fmt.Errorf("failed to rename file")
+	}
+
+	if err := h.Queries.UpdateRecordingFilePath(ctx, database.UpdateRecordingFilePathParams{
+		FilePath: newPath,
+		ID:       rec.ID,
+	}); err != nil {
+		// Keep disk and DB consistent
+		if rbErr := os.Rename(newPath, rec.FilePath); rbErr != nil {
+			fmt.Printf("Warning: failed to roll back rename of %s: %v\n", newPath, rbErr)
+		}
+		return "", http.StatusInternalServerError, err
+	}
+
+	// Keep the sidecar log paired with the recording (best effort)
+	if err := os.Rename(recorder.PageLogPath(rec.FilePath), recorder.PageLogPath(newPath)); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Warning: failed to rename log for recording %d: %v\n", rec.ID, err)
+	}
+	for _, m := range recorder.Milestones {
+		if err := os.Rename(recorder.MilestonePath(rec.FilePath, m), recorder.MilestonePath(newPath, m)); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Warning: failed to rename %s milestone for recording %d: %v\n", m, rec.ID, err)
+		}
+	}
+
+	return newPath, http.StatusOK, nil
+}
+
+// GetRecordingLog returns the console/network log captured alongside a recording
+// (tasks with capture_console enabled). While recording, it reflects what was written so far.
+func (h *Handler) GetRecordingLog(c echo.Context) error {
+	idParam := c.Param("id")
+	var recID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
+	}
+
+	rec, err := h.Queries.GetRecording(c.Request().Context(), recID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
+	}
+	if rec.FilePath == "" {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "no log captured for this recording"})
+	}
+
+	data, err := os.ReadFile(recorder.PageLogPath(rec.FilePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "no log captured for this recording"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to read log"})
+	}
+
+	return c.Blob(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
+// VerifyRecording re-hashes a recording's file and reports whether it still matches
+// the checksum (and signature) stored when it was finished. A mismatch is a result,
+// not an error, so it is returned with 200; so is a transcoded file, which is
+// flagged as such and never verified against the capture checksum.
+func (h *Handler) VerifyRecording(c echo.Context) error {
+	var recID int64
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
+	}
+
+	rec, err := h.Queries.GetRecording(c.Request().Context(), recID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
+	}
+
+	result, err := h.Recorder.VerifyRecording(rec)
+	if err != nil {
+		if errors.Is(err, recorder.ErrNoChecksum) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "recording file not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if !result.Verified {
+		fmt.Printf("Warning: recording %d failed verification (checksum match: %t)\n", recID, result.Match)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// ListRecordingMilestones lists the navigation milestone stills saved with a recording
+// (tasks with capture_milestones enabled), in the order they were reached
+func (h *Handler) ListRecordingMilestones(c echo.Context) error {
+	idParam := c.Param("id")
+	var recID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
+	}
+
+	rec, err := h.Queries.GetRecording(c.Request().Context(), recID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
+	}
+
+	type MilestoneDTO struct {
+		Name       string    `json:"name"`
+		URL        string    `json:"url"`
+		CapturedAt time.Time `json:"captured_at"`
+	}
+	milestones := []MilestoneDTO{}
+	if rec.FilePath != "" {
+		for _, m := range recorder.Milestones {
+			info, err := os.Stat(recorder.MilestonePath(rec.FilePath, m))
+			if err != nil {
+				continue
+			}
+			milestones = append(milestones, MilestoneDTO{
+				Name:       m,
+				URL:        fmt.Sprintf("/api/recordings/%d/milestones/%s", recID, m),
+				CapturedAt: info.ModTime().UTC(),
+			})
+		}
+	}
+	return c.JSON(http.StatusOK, milestones)
+}
+
+// GetRecordingMilestone serves one milestone still of a recording as PNG
+func (h *Handler) GetRecordingMilestone(c echo.Context) error {
+	idParam := c.Param("id")
+	var recID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
+	}
+	name := c.Param("name")
+	if !recorder.IsMilestone(name) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown milestone %q (valid: %s)", name, strings.Join(recorder.Milestones, ", "))})
+	}
+
+	rec, err := h.Queries.GetRecording(c.Request().Context(), recID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
+	}
+	if rec.FilePath == "" {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "milestone not captured for this recording"})
+	}
+	path := recorder.MilestonePath(rec.FilePath, name)
+	if _, err := os.Stat(path); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "milestone not captured for this recording"})
+	}
+	return c.File(path)
+}
+
+// GetRecordingLive streams an in-progress recording as fragmented MP4, following the
+// live copy as it grows, and serves the finished file for completed recordings
+func (h *Handler) GetRecordingLive(c echo.Context) error {
+	idParam := c.Param("id")
+	var recID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
+	}
+
+	rec, err := h.Queries.GetRecording(c.Request().Context(), recID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
+	}
+	if rec.FilePath == "" {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording has no file"})
+	}
+
+	if !recorder.StatusRecording.Is(rec.Status) {
+		if _, err := os.Stat(rec.FilePath); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "recording file not found on disk"})
+		}
+		return c.File(rec.FilePath)
+	}
+
+	live, err := h.Recorder.OpenLive(c.Request().Context(), recorder.SessionKey{TaskID: rec.TaskID, Viewport: rec.Viewport}, rec.FilePath)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		if errors.Is(err, recorder.ErrLiveUnavailable) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to open live recording"})
+	}
+	defer live.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "video/mp4")
+	res.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	res.WriteHeader(http.StatusOK)
+
+	// Flush every chunk so the player receives fragments as soon as they are written
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := live.Read(buf)
+		if n > 0 {
+			if _, werr := res.Write(buf[:n]); werr != nil {
+				return nil // client went away
+			}
+			res.Flush()
+		}
+		if err != nil {
+			return nil
+		}
 	}
-
-	return c.JSON(http.StatusOK, map[string]string{"status": "updated"})
 }
 
-func (h *Handler) DeleteTask(c echo.Context) error {
+// ToggleRecordingProtection flips the keep/protect flag so the recording is exempt from automatic cleanup
+func (h *Handler) ToggleRecordingProtection(c echo.Context) error {
 	idParam := c.Param("id")
-	var taskID int64
-	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+	var recID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
 	}
 
-	// Stop any active recording first
-	_ = h.Recorder.StopRecording(taskID)
-
-	if err := h.Queries.DeleteTask(c.Request().Context(), taskID); err != nil {
+	rec, err := h.Queries.ToggleRecordingProtected(c.Request().Context(), recID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+	return c.JSON(http.StatusOK, map[string]interface{}{"id": rec.ID, "is_protected": rec.IsProtected})
 }
 
-func (h *Handler) RegisterRoutes(e *echo.Echo) {
-	// Public routes with Rate Limiting
-	e.POST("/api/login", h.Login, h.RateLimitMiddleware)
-	e.GET("/auth/login", h.AuthLogin)       // OIDC Login Start
-	e.GET("/auth/callback", h.AuthCallback) // OIDC Callback
-
-	g := e.Group("/api")
-	// Security headers are now handled globally in main.go
-
-	config := echojwt.Config{
-		TokenLookup: "header:Authorization",
-		ParseTokenFunc: func(c echo.Context, auth string) (interface{}, error) {
-			// Support "Bearer " prefix
-			if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
-				auth = auth[7:]
-			}
-			return jwt.Parse(auth, func(t *jwt.Token) (interface{}, error) {
-				if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-				}
-				return []byte(h.Config.JWTSecret), nil
-			})
-		},
-		Skipper: func(c echo.Context) bool {
-			// Skip for OPTIONS (CORS preflight) and WebSocket Ticket auth
-			if c.Request().Method == "OPTIONS" {
-				return true
-			}
-			if strings.HasSuffix(c.Path(), "/interact") {
-				return true
-			}
-			return false
-		},
+// AnnotateRecording burns a transient text marker into a running recording
+// (e.g. pushed by an alerting webhook)
+func (h *Handler) AnnotateRecording(c echo.Context) error {
+	idParam := c.Param("id")
+	var recID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
 	}
 
-	g.Use(echojwt.WithConfig(config))
-
-	g.POST("/tasks", h.CreateTask)
-	g.GET("/tasks", h.ListTasks)
-	g.POST("/tasks/:id/start", h.StartTask)
-	g.POST("/tasks/:id/stop", h.StopTask)
-	g.PUT("/tasks/:id", h.UpdateTask)
-	g.DELETE("/tasks/:id", h.DeleteTask)
-	g.GET("/archives", h.ListArchives)
-	g.GET("/stats", h.GetStats)
-
-	// Tickets
-	// Tickets
-	g.POST("/tickets", h.GenerateTicket, h.RateLimitMiddleware)
-
-	// Password Change with Rate Limiting
-	g.POST("/password", h.ChangePassword, h.RateLimitMiddleware)
-
-	// Live monitor endpoints
-	g.GET("/recordings/live", h.GetLiveRecordings)
-	g.GET("/recordings/:id/preview.jpg", h.GetRecordingPreview)
-	g.DELETE("/recordings/:id", h.DeleteRecording)
-	g.POST("/tasks/preview", h.PreviewTask)
-	g.GET("/tasks/:id/interact", h.WsInteractive)
-}
-
-func (h *Handler) PreviewTask(c echo.Context) error {
-	type PreviewRequest struct {
-		TargetURL string `json:"target_url"`
-		CustomCSS string `json:"custom_css"`
+	type AnnotationRequest struct {
+		Text            string `json:"text"`
+		DurationSeconds int64  `json:"duration_seconds"`
 	}
-	var req PreviewRequest
+	req := AnnotationRequest{DurationSeconds: 5}
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
 	}
-
-	if req.TargetURL == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "target_url is required"})
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" || len([]rune(req.Text)) > recorder.MaxAnnotationLength {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("text must be 1-%d characters", recorder.MaxAnnotationLength)})
+	}
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	if duration <= 0 || duration > recorder.MaxAnnotationDuration {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("duration_seconds must be between 1 and %d", int(recorder.MaxAnnotationDuration.Seconds()))})
 	}
 
-	// Capture preview (returns JPEG bytes)
-	previewData, err := h.Recorder.CapturePreview(req.TargetURL, req.CustomCSS)
+	rec, err := h.Queries.GetRecording(c.Request().Context(), recID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to capture preview: " + err.Error()})
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
 	}
-
-	return c.Blob(http.StatusOK, "image/jpeg", previewData)
-}
-
-// WebSocket handler for interactive session
-func (h *Handler) WsInteractive(c echo.Context) error {
-	// 1. Get Ticket from Query
-	ticketID := c.QueryParam("ticket")
-	if ticketID == "" {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing ticket"})
+	if !recorder.StatusRecording.Is(rec.Status) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "recording is not active"})
 	}
 
-	// 2. Exchange Ticket (Atomic Check-and-Burn)
-	ticket, err := h.TicketStore.Exchange(ticketID)
-	if err != nil {
-		// Return 401 for invalid/expired tickets
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid or expired ticket"})
+	if err := h.Recorder.ShowAnnotation(recorder.SessionKey{TaskID: rec.TaskID, Viewport: rec.Viewport}, req.Text, duration); err != nil {
+		if errors.Is(err, recorder.ErrNoActivePage) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "recording is not active"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to show annotation: %v", err)})
 	}
 
-	// 3. Get TaskID
+	return c.JSON(http.StatusOK, map[string]string{"status": "shown"})
+}
+
+// GetRecordingSnapshot captures a fresh full-quality image of an active recording's page
+// (unlike preview.jpg, which serves the cached stream frame)
+func (h *Handler) GetRecordingSnapshot(c echo.Context) error {
 	idParam := c.Param("id")
-	var taskID int64
-	if _, err := fmt.Sscanf(idParam, "%d", &taskID); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid task id"})
+	var recID int64
+	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
 	}
 
-	// 4. Validate Authorization (RBAC: Ticket must match requested TaskID)
-	if ticket.TaskID != taskID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "ticket mismatch"})
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "jpeg" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "format must be png or jpeg"})
 	}
 
-	// 5. Check Task Exists and Get Details
-	task, err := h.Queries.GetTask(c.Request().Context(), taskID)
+	rec, err := h.Queries.GetRecording(c.Request().Context(), recID)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "task not found"})
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
 	}
-
-	// 6. Strict Upgrader
-	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			// Strict same-origin policy to prevent CSWSH
-			origin := r.Header.Get("Origin")
-			if origin == "" {
-				// No Origin header (non-browser client) -> verification relies on Ticket
-				return true
-			}
-			u, err := url.Parse(origin)
-			if err != nil {
-				return false
-			}
-			// Compare Host (including port if present)
-			return strings.EqualFold(u.Host, r.Host)
-		},
+	if !recorder.StatusRecording.Is(rec.Status) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "recording is not active"})
 	}
 
-	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	img, err := h.Recorder.CaptureSnapshot(recorder.SessionKey{TaskID: rec.TaskID, Viewport: rec.Viewport}, format)
 	if err != nil {
-		return err
+		if errors.Is(err, recorder.ErrNoActivePage) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "recording is not active"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to capture snapshot: %v", err)})
 	}
-	defer ws.Close()
 
-	// 7. Handle Interactive Session
-	return h.Recorder.HandleInteractive(c.Request().Context(), taskID, task.TargetUrl, ws)
+	c.Response().Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	return c.Blob(http.StatusOK, "image/"+format, img)
 }
 
-func (h *Handler) DeleteRecording(c echo.Context) error {
+// GetRecordingFrames extracts frames of a finished recording and returns them as a ZIP
+// of images (?fps= frames per second, ?start= and ?duration= in seconds, ?format=jpeg|png).
+// At most recorder.MaxExportFrames are returned; X-Frames-Truncated marks a cut-off export.
+func (h *Handler) GetRecordingFrames(c echo.Context) error {
 	idParam := c.Param("id")
 	var recID int64
 	if _, err := fmt.Sscanf(idParam, "%d", &recID); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid recording id"})
 	}
 
-	// 1. Get Recording to find file path
+	opts := recorder.FrameExportOptions{FPS: recorder.DefaultExportFPS, Format: c.QueryParam("format")}
+	if opts.Format == "" {
+		opts.Format = "jpeg"
+	}
+	for _, p := range []struct {
+		name string
+		set  func(float64)
+	}{
+		{"fps", func(v float64) { opts.FPS = v }},
+		{"start", func(v float64) { opts.Start = time.Duration(v * float64(time.Second)) }},
+		{"duration", func(v float64) { opts.Duration = time.Duration(v * float64(time.Second)) }},
+	} {
+		if raw := c.QueryParam(p.name); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": p.name + " must be a number"})
+			}
+			p.set(v)
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
 	rec, err := h.Queries.GetRecording(c.Request().Context(), recID)
 	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
 	}
-
-	// 2. Delete file from disk
-	if rec.FilePath != "" {
-		if err := os.Remove(rec.FilePath); err != nil {
-			fmt.Printf("Warning: failed to delete file %s: %v\n", rec.FilePath, err)
-			// Continue to delete DB record even if file delete fails (maybe already gone)
-		}
+	if recorder.StatusRecording.Is(rec.Status) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "recording is still in progress"})
+	}
+	if rec.FilePath == "" {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording has no file"})
+	}
+	if _, err := os.Stat(rec.FilePath); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording file not found on disk"})
 	}
 
-	// 3. Delete from DB
-	if err := h.Queries.DeleteRecording(c.Request().Context(), recID); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	export, err := recorder.ExtractFrames(c.Request().Context(), rec.FilePath, opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("failed to extract frames: %v", err)})
+	}
+	defer export.Close()
+	if len(export.Files) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "no frames in the requested range"})
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+	name := strings.TrimSuffix(filepath.Base(rec.FilePath), filepath.Ext(rec.FilePath)) + "-frames.zip"
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/zip")
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+	res.Header().Set("X-Frame-Count", strconv.Itoa(len(export.Files)))
+	if export.Truncated {
+		res.Header().Set("X-Frames-Truncated", "true")
+	}
+	res.WriteHeader(http.StatusOK)
+	if err := export.WriteZip(res); err != nil {
+		fmt.Printf("Frames: export of recording %d aborted: %v\n", recID, err)
+	}
+	return nil
 }
 
 type RecordingDTO struct {
-	ID        int64      `json:"id"`
-	TaskID    int64      `json:"task_id"`
-	Status    string     `json:"status"`
-	StartTime time.Time  `json:"start_time"`
-	EndTime   *time.Time `json:"end_time"`
-	FilePath  string     `json:"file_path"`
-	TaskName  string     `json:"task_name,omitempty"`
-	Size      string     `json:"size"`
+	ID             int64      `json:"id"`
+	TaskID         int64      `json:"task_id"`
+	Status         string     `json:"status"`
+	StartTime      time.Time  `json:"start_time"`
+	EndTime        *time.Time `json:"end_time"`
+	FilePath       string     `json:"file_path"`
+	TaskName       string     `json:"task_name,omitempty"`
+	Size           string     `json:"size"`
+	IsProtected    bool       `json:"is_protected"`
+	DroppedFrames  int64      `json:"dropped_frames"`
+	IsDegraded     bool       `json:"is_degraded"`
+	ContentHash    string     `json:"content_hash,omitempty"`
+	DuplicateOf    *int64     `json:"duplicate_of,omitempty"`
+	Note           string     `json:"note"`
+	Tags           []string   `json:"tags"`
+	Viewport       string     `json:"viewport,omitempty"`
+	Region         string     `json:"region,omitempty"`
+	Checksum       string     `json:"checksum,omitempty"` // SHA-256 of the file when finished
+	ChecksumSigned bool       `json:"checksum_signed"`
+	Transcoded     bool       `json:"transcoded"` // re-encoded since capture; the checksum no longer applies
+	DownloadURL    string     `json:"download_url,omitempty"`
 }
 
+// ListArchives lists recordings, optionally filtered by ?tag= and ?q= (note text)
 func (h *Handler) ListArchives(c echo.Context) error {
+	// Optional status filter (RECORDING, COMPLETED or FAILED)
+	var status recorder.RecordingStatus
+	if s := c.QueryParam("status"); s != "" {
+		var err error
+		if status, err = recorder.ParseRecordingStatus(s); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	// Optional pagination (?page=, ?page_size=), applied after the filters
+	page, paginate, err := parsePagination(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
 	recs, err := h.Queries.ListRecordings(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
-	dtos := make([]RecordingDTO, len(recs))
-	for i, r := range recs {
+	tag, query := c.QueryParam("tag"), c.QueryParam("q")
+	matched := make([]database.ListRecordingsRow, 0, len(recs))
+	for _, r := range recs {
+		if status != "" && !status.Is(r.Status) {
+			continue
+		}
+		if !matchesRecordingFilter(r.Note, r.Tags, tag, query) {
+			continue
+		}
+		matched = append(matched, r)
+	}
+	if paginate {
+		setPaginationHeaders(c, page, len(matched))
+		start, end := page.bounds(len(matched))
+		matched = matched[start:end]
+	}
+
+	dtos := make([]RecordingDTO, 0, len(matched))
+	for _, r := range matched {
 		var endTime *time.Time
 		if r.EndTime.Valid {
 			endTime = &r.EndTime.Time
 		}
+		var duplicateOf *int64
+		if r.DuplicateOf.Valid {
+			duplicateOf = &r.DuplicateOf.Int64
+		}
 
 		// Calculate file size
 		sizeStr := "0 B"
@@ -836,21 +2809,117 @@ func (h *Handler) ListArchives(c echo.Context) error {
 			}
 		}
 
-		dtos[i] = RecordingDTO{
-			ID:        r.ID,
-			TaskID:    r.TaskID,
-			Status:    r.Status,
-			StartTime: r.StartTime,
-			EndTime:   endTime,
-			FilePath:  r.FilePath,
-			TaskName:  r.TaskName,
-			Size:      sizeStr,
+		// Downloads go through the /recordings mount, unless SERVE_RECORDINGS_STATIC is off
+		downloadURL := ""
+		if h.Config.ServeRecordingsStatic {
+			downloadURL = recordingDownloadURL(r.FilePath)
 		}
+
+		dtos = append(dtos, RecordingDTO{
+			ID:             r.ID,
+			TaskID:         r.TaskID,
+			Status:         r.Status,
+			StartTime:      r.StartTime,
+			EndTime:        endTime,
+			FilePath:       r.FilePath,
+			TaskName:       r.TaskName,
+			Size:           sizeStr,
+			IsProtected:    r.IsProtected,
+			DroppedFrames:  r.DroppedFrames,
+			IsDegraded:     r.IsDegraded,
+			ContentHash:    r.ContentHash,
+			DuplicateOf:    duplicateOf,
+			Note:           r.Note,
+			Tags:           splitRecordingTags(r.Tags),
+			Viewport:       r.Viewport,
+			Region:         r.Region,
+			Checksum:       r.Checksum,
+			ChecksumSigned: r.ChecksumSignature != "",
+			Transcoded:     r.TranscodeChecksum != "",
+			DownloadURL:    downloadURL,
+		})
 	}
 
 	return c.JSON(http.StatusOK, dtos)
 }
 
+// readyPingTimeout bounds the database check of a readiness probe
+const readyPingTimeout = 2 * time.Second
+
+// Ready reports whether the recording subsystem (browser + ffmpeg) and the database
+// are usable. Returns 503 when degraded so orchestrators can surface a broken image.
+func (h *Handler) Ready(c echo.Context) error {
+	status, details := h.readiness(c.Request().Context())
+	code := http.StatusOK
+	if status != "ready" {
+		code = http.StatusServiceUnavailable
+	}
+	return c.JSON(code, details)
+}
+
+// readiness checks the backend subsystems: "ready" or "degraded", plus the
+// per-subsystem details served by /api/ready
+func (h *Handler) readiness(ctx context.Context) (string, map[string]interface{}) {
+	ffmpeg := h.Recorder.FFmpegStatus()
+	browser := h.Recorder.BrowserAvailable()
+	db := false
+	if h.DB != nil {
+		ctx, cancel := context.WithTimeout(ctx, readyPingTimeout)
+		db = h.DB.PingContext(ctx) == nil
+		cancel()
+	}
+
+	status := "ready"
+	if !ffmpeg.OK() || !browser || !db {
+		status = "degraded"
+	}
+	return status, map[string]interface{}{
+		"status":         status,
+		"browser":        browser,
+		"browser_engine": h.Recorder.BrowserEngine(),
+		"ffmpeg":         ffmpeg,
+		"database":       db,
+	}
+}
+
+// Capabilities reports which optional features this server has enabled so the UI
+// can hide what isn't available. It is served unauthenticated (the login page needs
+// to know about OIDC), so it only exposes toggles and limits, never settings.
+func (h *Handler) Capabilities(c echo.Context) error {
+	browser := h.Recorder.BrowserAvailable()
+	ffmpeg := h.Recorder.FFmpegStatus().OK()
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"oidc":             h.OIDC() != nil,
+		"oidc_logout":      h.OIDC() != nil && h.OIDC().EndSessionURL != "",
+		"tls":              h.Config.TLSDomain != "",
+		"totp":             true,
+		"recording":        browser && ffmpeg,
+		"preview":          browser,
+		"interactive":      browser,
+		"browser_engine":   h.Recorder.BrowserEngine(),
+		"ntp":              h.Config.NtpServer != "",
+		"console_capture":  true,
+		"form_login":       true,
+		"downloads":        h.Config.ServeRecordingsStatic,
+		"task_proxies":     len(h.Config.TaskProxyAllowedHosts) > 0,
+		"capture_regions":  true,
+		"watermarks":       true,
+		"signed_checksums": h.Config.RecordingSigningKey != "",
+		// Recordings are always software-encoded with libx264, and pages only accept CSS
+		"hardware_encoding": false,
+		"custom_js":         false,
+		"limits": map[string]interface{}{
+			"max_fps":               h.Config.MaxFpsLimit,
+			"max_custom_css_length": h.Config.MaxCustomCSSLength,
+			"max_pixel_rate":        h.Config.MaxPixelRate,
+			"max_tasks":             h.Config.MaxTasks,
+			"max_regions":           recorder.MaxRegions,
+			"max_watermark_bytes":   recorder.MaxWatermarkBytes,
+		},
+	})
+}
+
 func (h *Handler) GetStats(c echo.Context) error {
 	stats := make(map[string]interface{})
 
@@ -878,21 +2947,187 @@ func (h *Handler) GetStats(c echo.Context) error {
 		stats["disk_percent"] = 0.0
 	}
 
+	// Preview slots in use and requests waiting for one
+	stats["preview_queue"] = h.Recorder.PreviewQueueStats()
+
 	// Additional metadata
 	stats["timestamp"] = time.Now().Unix()
 
 	return c.JSON(http.StatusOK, stats)
 }
 
+// processTotals aggregates the processes of one role
+type processTotals struct {
+	Count      int     `json:"count"`
+	CPUPercent float64 `json:"cpu_percent"`
+	MemoryRSS  uint64  `json:"memory_rss_bytes"`
+}
+
+// GetProcessStats reports CPU/memory of the browser, driver and ffmpeg processes,
+// with encoders keyed by task, to find which recording is the resource hog
+func (h *Handler) GetProcessStats(c echo.Context) error {
+	procs, err := h.Recorder.ProcessStats(c.Request().Context(), 200*time.Millisecond)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	totals := make(map[string]*processTotals)
+	for _, p := range procs {
+		t, ok := totals[p.Role]
+		if !ok {
+			t = &processTotals{}
+			totals[p.Role] = t
+		}
+		t.Count++
+		t.CPUPercent += p.CPUPercent
+		t.MemoryRSS += p.MemoryRSS
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"processes": procs,
+		"totals":    totals,
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// TranscodeRecording queues a re-encode of a finished recording to a slower preset
+// (smaller file). Body: {"preset", "crf", "keep_original"}; all optional. With
+// keep_original the result becomes a new recording and the original is kept.
+func (h *Handler) TranscodeRecording(c echo.Context) error {
+	var id int64
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	}
+
+	var req struct {
+		Preset       string `json:"preset"`
+		CRF          *int64 `json:"crf"`
+		KeepOriginal bool   `json:"keep_original"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	opts := recorder.TranscodeOptions{Preset: req.Preset, CRF: recorder.DefaultTranscodeCRF, KeepOriginal: req.KeepOriginal}
+	if req.CRF != nil {
+		opts.CRF = *req.CRF
+	}
+
+	rec, err := h.Queries.GetRecording(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "recording not found"})
+	}
+	// Active (and failed) recordings are skipped: only finished files are safe to replace
+	if !recorder.StatusCompleted.Is(rec.Status) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": fmt.Sprintf("only completed recordings can be transcoded (status %s)", rec.Status)})
+	}
+
+	job, err := h.Recorder.EnqueueTranscode(rec, opts)
+	if err != nil {
+		if errors.Is(err, recorder.ErrTranscodePending) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	username, _ := usernameFromContext(c)
+	fmt.Printf("Transcode: %s queued recording %d (preset %s, crf %d, keep original: %t)\n", username, id, job.Preset, job.CRF, job.KeepOriginal)
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// ListTranscodes reports queued, running and recently finished transcode jobs
+func (h *Handler) ListTranscodes(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.Recorder.TranscodeJobs())
+}
+
+// GetMetrics exposes internal counters for diagnosing auth/session issues
+func (h *Handler) GetMetrics(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tickets":     h.TicketStore.Stats(),
+		"rate_limits": h.rateLimits.snapshot(),
+		"timestamp":   time.Now().Unix(),
+	})
+}
+
+// GetNTPDiagnostics probes the active NTP server so operators can verify
+// connectivity and offset before relying on the time overlay
+func (h *Handler) GetNTPDiagnostics(c echo.Context) error {
+	server := h.Recorder.ClockStatus().Server
+	if server == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "NTP server is not configured"})
+	}
+	return c.JSON(http.StatusOK, recorder.CheckNTP(server))
+}
+
+// GetTimeSource reports the cached NTP offset burned into time overlays, when it
+// was last synced and from which server
+func (h *Handler) GetTimeSource(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.Recorder.ClockStatus())
+}
+
+type SyncTimeSourceRequest struct {
+	// Switches the active NTP server (until restart); empty re-syncs the current one
+	Server string `json:"server"`
+}
+
+// SyncTimeSource forces an NTP re-sync, optionally against another server. Overlays
+// injected from now on use the new offset; running recordings keep theirs.
+func (h *Handler) SyncTimeSource(c echo.Context) error {
+	var req SyncTimeSourceRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+		}
+	}
+	req.Server = strings.TrimSpace(req.Server)
+	if err := validateNTPServer(req.Server); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	username, _ := usernameFromContext(c)
+	status, err := h.Recorder.SyncClock(req.Server)
+	if err != nil {
+		fmt.Printf("Time: NTP sync requested by %s failed: %v\n", username, err)
+		return c.JSON(http.StatusBadGateway, map[string]interface{}{"error": err.Error(), "status": status})
+	}
+	fmt.Printf("Time: %s synced with %s (offset %.1fms)\n", username, status.Server, status.OffsetMs)
+	return c.JSON(http.StatusOK, status)
+}
+
+// validateNTPServer accepts empty (keep the current server), a host name or an IP,
+// optionally with a port
+func validateNTPServer(server string) error {
+	if server == "" {
+		return nil
+	}
+	host := server
+	if h, port, err := net.SplitHostPort(server); err == nil {
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("server has an invalid port")
+		}
+		host = h
+	}
+	if len(host) == 0 || len(host) > 253 || strings.ContainsAny(host, " \t\r\n/\\@?#") {
+		return fmt.Errorf("server must be a host name or IP address")
+	}
+	return nil
+}
+
 // LiveRecordingDTO represents active recording with real-time stats
 type LiveRecordingDTO struct {
 	ID             int64  `json:"id"`
 	TaskID         int64  `json:"task_id"`
 	TaskName       string `json:"task_name"`
+	Viewport       string `json:"viewport,omitempty"`
+	Region         string `json:"region,omitempty"`
 	Status         string `json:"status"`
 	ElapsedSeconds int64  `json:"elapsed_seconds"`
 	FileSizeBytes  int64  `json:"file_size_bytes"`
 	HasPreview     bool   `json:"has_preview"`
+	CapturedFrames int64  `json:"captured_frames"`
+	DroppedFrames  int64  `json:"dropped_frames"`
+	IsDegraded     bool   `json:"is_degraded"`
+	// Frames lost to the full frame buffer while the encoder stalled
+	BufferDroppedFrames int64 `json:"buffer_dropped_frames"`
 }
 
 // GetLiveRecordings returns all active recordings with real-time stats
@@ -907,7 +3142,7 @@ func (h *Handler) GetLiveRecordings(c echo.Context) error {
 	var result []LiveRecordingDTO
 	for _, rec := range recs {
 		// Only include RECORDING status
-		if rec.Status != "RECORDING" {
+		if !recorder.StatusRecording.Is(rec.Status) {
 			continue
 		}
 
@@ -921,16 +3156,27 @@ func (h *Handler) GetLiveRecordings(c echo.Context) error {
 		}
 
 		// Check if preview is available
-		hasPreview := h.Recorder.GetLatestFrame(rec.TaskID) != nil
+		key := recorder.SessionKey{TaskID: rec.TaskID, Viewport: rec.Viewport}
+		hasPreview := h.Recorder.GetLatestFrame(key) != nil
+
+		// Capture health so far
+		frames, _ := h.Recorder.GetFrameStats(key)
 
 		result = append(result, LiveRecordingDTO{
 			ID:             rec.ID,
 			TaskID:         rec.TaskID,
 			TaskName:       rec.TaskName,
+			Viewport:       rec.Viewport,
+			Region:         rec.Region,
 			Status:         rec.Status,
 			ElapsedSeconds: elapsed,
 			FileSizeBytes:  fileSize,
 			HasPreview:     hasPreview,
+			CapturedFrames: frames.Captured,
+			DroppedFrames:  frames.Dropped,
+			IsDegraded:     frames.Degraded(h.Config.DegradedDropRatio),
+
+			BufferDroppedFrames: frames.BufferDropped,
 		})
 	}
 
@@ -950,11 +3196,11 @@ func (h *Handler) GetRecordingPreview(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
-	var taskID int64
+	var key recorder.SessionKey
 	found := false
 	for _, rec := range recs {
 		if rec.ID == recordingID {
-			taskID = rec.TaskID
+			key = recorder.SessionKey{TaskID: rec.TaskID, Viewport: rec.Viewport}
 			found = true
 			break
 		}
@@ -965,13 +3211,14 @@ func (h *Handler) GetRecordingPreview(c echo.Context) error {
 	}
 
 	// Get frame from cache
-	frame := h.Recorder.GetLatestFrame(taskID)
+	frame := h.Recorder.GetLatestFrame(key)
 	if frame == nil {
 		return c.NoContent(http.StatusNotFound)
 	}
 
-	// Serve as JPEG with no-cache headers
-	c.Response().Header().Set("Content-Type", "image/jpeg")
+	// Serve the captured image (JPEG, or PNG for png tasks) with no-cache headers
+	contentType := http.DetectContentType(frame)
+	c.Response().Header().Set("Content-Type", contentType)
 	c.Response().Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	return c.Blob(http.StatusOK, "image/jpeg", frame)
+	return c.Blob(http.StatusOK, contentType, frame)
 }
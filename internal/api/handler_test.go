@@ -1,13 +1,19 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/nullpo7z/dashboard-recorder/internal/config"
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+	"github.com/nullpo7z/dashboard-recorder/internal/recorder"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -64,3 +70,499 @@ func TestCreateTask_Validation_Success(t *testing.T) {
 		h.CreateTask(c)
 	})
 }
+
+func TestCreateTask_Validation_MaxRecordings(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{
+		"name": "Test",
+		"target_url": "http://example.com",
+		"max_recordings": -1
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60},
+	}
+
+	if assert.NoError(t, h.CreateTask(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		// Echo's JSON encoder escapes '>'
+		assert.Contains(t, rec.Body.String(), `max_recordings must be \u003e= 0`)
+	}
+}
+
+func TestCreateTask_Validation_CustomCSSLength(t *testing.T) {
+	e := echo.New()
+	body := `{"name": "Test", "target_url": "http://example.com", "custom_css": "` + strings.Repeat("a", 33) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60, MaxCustomCSSLength: 32},
+	}
+
+	if assert.NoError(t, h.CreateTask(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "custom_css exceeds maximum length")
+	}
+}
+
+func TestUpdateTask_Validation_TimeOverlayConfig(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/tasks/1", strings.NewReader(`{
+		"name": "Test",
+		"target_url": "http://example.com",
+		"time_overlay": true,
+		"time_overlay_config": "center"
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	q := newTestQueries(t)
+	if _, err := q.CreateTask(context.Background(), database.CreateTaskParams{Name: "Test", TargetUrl: "http://example.com", Fps: 5, Crf: 23}); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{
+		Config:  &config.Config{MaxFpsLimit: 60},
+		Queries: q,
+	}
+
+	if assert.NoError(t, h.UpdateTask(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid time_overlay_config")
+	}
+}
+
+func TestNormalizeTimeOverlayConfig(t *testing.T) {
+	got, err := normalizeTimeOverlayConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, "bottom-right", got)
+
+	got, err = normalizeTimeOverlayConfig("top-left")
+	assert.NoError(t, err)
+	assert.Equal(t, "top-left", got)
+
+	_, err = normalizeTimeOverlayConfig("middle")
+	assert.Error(t, err)
+}
+
+func TestCapabilities_NoBrowser(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/capabilities", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &Handler{
+		Config:   &config.Config{MaxFpsLimit: 15, TLSDomain: "rec.example.com"},
+		Recorder: &recorder.Worker{},
+	}
+
+	if assert.NoError(t, h.Capabilities(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, `"oidc":false`)
+		assert.Contains(t, body, `"tls":true`)
+		assert.Contains(t, body, `"recording":false`)
+		assert.Contains(t, body, `"max_fps":15`)
+	}
+}
+
+func TestCreateTask_Validation_Preset(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{
+		"name": "Test",
+		"target_url": "http://example.com",
+		"preset": "placebo"
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60},
+	}
+
+	if assert.NoError(t, h.CreateTask(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid preset")
+	}
+}
+
+func TestCreateTask_Validation_PixelBudget(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{
+		"name": "Test",
+		"target_url": "http://example.com",
+		"fps": 10
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// 1920x1080 at 5 fps fits, 10 fps does not
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60, MaxPixelRate: 1920 * 1080 * 5},
+	}
+
+	if assert.NoError(t, h.CreateTask(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "exceeds the server budget")
+	}
+}
+
+func TestCreateTask_Validation_CaptureQuality(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{
+		"name": "Test",
+		"target_url": "http://example.com",
+		"capture_quality": 101
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60},
+	}
+
+	if assert.NoError(t, h.CreateTask(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "capture_quality")
+	}
+}
+
+func TestSetTaskCredentials_Validation_Selectors(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/tasks/1/credentials", strings.NewReader(`{
+		"username": "operator",
+		"password": "secret",
+		"username_selector": "#user",
+		"password_selector": "#pass"
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60},
+	}
+
+	if assert.NoError(t, h.SetTaskCredentials(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "submit_selector is required")
+	}
+}
+
+func TestCreateTask_Validation_Referer(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{
+		"name": "Test",
+		"target_url": "http://example.com",
+		"referer": "not a url"
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60},
+	}
+
+	if assert.NoError(t, h.CreateTask(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "referer")
+	}
+}
+
+func TestResetUserPassword_Validation_ShortPassword(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/users/2/reset-password", strings.NewReader(`{"password": "short"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("2")
+
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60},
+	}
+
+	if assert.NoError(t, h.ResetUserPassword(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "at least 12 characters")
+	}
+}
+
+func TestPreviewTask_Validation_Viewport(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tasks/preview", strings.NewReader(`{
+		"target_url": "http://example.com",
+		"viewport": {"width": 100, "height": 100}
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60},
+	}
+
+	if assert.NoError(t, h.PreviewTask(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "viewport must be within")
+	}
+}
+
+func TestCreateTask_Validation_StartDelay(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{
+		"name": "Test",
+		"target_url": "http://example.com",
+		"start_delay_ms": 600000
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60},
+	}
+
+	if assert.NoError(t, h.CreateTask(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "start_delay_ms")
+	}
+}
+
+func TestCreateTask_Validation_KeepAlive(t *testing.T) {
+	e := echo.New()
+	// The click fits the default viewport but not the 800x600 profile
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{
+		"name": "Test",
+		"target_url": "http://example.com",
+		"viewports": [{"name": "small", "width": 800, "height": 600}],
+		"keepalive": {"interval_seconds": 60, "actions": [{"type": "click", "x": 1000, "y": 10}]}
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := &Handler{
+		Config: &config.Config{MaxFpsLimit: 60},
+	}
+
+	if assert.NoError(t, h.CreateTask(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "800x600")
+	}
+}
+
+func TestGetRecordingFrames_Validation(t *testing.T) {
+	e := echo.New()
+	for query, want := range map[string]string{
+		"fps=abc":              "fps must be a number",
+		"fps=100":              "fps must be",
+		"fps=10&duration=3600": "at most",
+		"format=gif":           "format",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/recordings/1/frames.zip?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		h := &Handler{}
+		if assert.NoError(t, h.GetRecordingFrames(c)) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+			assert.Contains(t, rec.Body.String(), want, query)
+		}
+	}
+}
+
+func TestSyncTimeSource_Validation(t *testing.T) {
+	e := echo.New()
+	for _, server := range []string{"ntp.example/x", "ntp.example:99999", "user@ntp.example", "a b"} {
+		body := `{"server":"` + server + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/time/sync", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := &Handler{}
+		if assert.NoError(t, h.SyncTimeSource(c)) {
+			assert.Equal(t, http.StatusBadRequest, rec.Code, server)
+		}
+	}
+
+	for _, server := range []string{"", "ntp.nict.jp", "192.0.2.1:123", "::1"} {
+		assert.NoError(t, validateNTPServer(server), server)
+	}
+}
+
+func TestRequirePasswordChanged(t *testing.T) {
+	h := &Handler{Config: &config.Config{JWTSecret: "0123456789abcdef0123456789abcdef"}}
+	limited, err := h.createPasswordChangeJWT("alice")
+	assert.NoError(t, err)
+	full, err := h.createJWT("alice")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token string
+		path  string
+		want  int
+	}{
+		{"Limited Token Changes Password", limited, "/api/password", http.StatusOK},
+		{"Limited Token Elsewhere", limited, "/api/tasks", http.StatusForbidden},
+		{"Full Token", full, "/api/tasks", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := h.tokenKeys().Parse(tt.token)
+			assert.NoError(t, err)
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodPost, tt.path, nil), rec)
+			c.SetPath(tt.path)
+			c.Set("user", token)
+
+			next := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+			if assert.NoError(t, h.RequirePasswordChanged(next)(c)) {
+				assert.Equal(t, tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+// newTestQueries returns queries over an in-memory database with the current schema
+func newTestQueries(t *testing.T) *database.Queries {
+	t.Helper()
+	return database.New(newTestDB(t))
+}
+
+// newTestDB returns an in-memory database with the current schema
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	schema, err := os.ReadFile("../../sql/schema/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1) // every connection would get its own in-memory database
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestCreateTask_MaxTasks(t *testing.T) {
+	db := newTestDB(t)
+	q := database.New(db)
+	if _, err := q.CreateTask(context.Background(), database.CreateTaskParams{Name: "Existing", TargetUrl: "http://example.com", Fps: 5, Crf: 23}); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{Config: &config.Config{MaxFpsLimit: 60, MaxTasks: 2}, Queries: q, DB: db}
+
+	create := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"name": "Test", "target_url": "http://example.com"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		assert.NoError(t, h.CreateTask(echo.New().NewContext(req, rec)))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusCreated, create())
+	assert.Equal(t, http.StatusConflict, create())
+
+	count, err := q.CountTasks(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestUpdateTask_KeepsOmittedSettings(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueries(t)
+	task, err := q.CreateTask(ctx, database.CreateTaskParams{
+		Name:              "Test",
+		TargetUrl:         "http://example.com",
+		Fps:               5,
+		Crf:               23,
+		MaxRecordings:     3,
+		CaptureConsole:    true,
+		EncoderPreset:     "veryfast",
+		CaptureQuality:    70,
+		NotifySizeBytes:   1 << 30,
+		Referer:           "https://portal.example.com/",
+		JavaScriptEnabled: false,
+		Offline:           true,
+		Viewports:         `[{"name":"mobile","width":390,"height":844}]`,
+		RecordOnChange:    true,
+		CaptureFormat:     recorder.CaptureFormatPNG,
+		BlockedResources:  "font",
+		StartDelayMs:      2000,
+		CaptureMilestones: true,
+		OutputDir:         "lobby",
+		ProxyUrl:          "sealed-proxy", // stored as is: an omitted proxy_url is never re-sealed
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The edit form only sends the baseline fields
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/tasks/1", strings.NewReader(`{
+		"name": "Renamed",
+		"target_url": "http://example.com",
+		"filename_template": "",
+		"custom_css": "",
+		"fps": 5,
+		"crf": 23,
+		"time_overlay": false,
+		"time_overlay_config": ""
+	}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	h := &Handler{Config: &config.Config{MaxFpsLimit: 60}, Queries: q}
+	if !assert.NoError(t, h.UpdateTask(c)) || !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		return
+	}
+
+	got, err := q.GetTask(ctx, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Renamed", got.Name)
+	assert.Equal(t, int64(3), got.MaxRecordings)
+	assert.True(t, got.CaptureConsole)
+	assert.Equal(t, "veryfast", got.EncoderPreset)
+	assert.Equal(t, int64(70), got.CaptureQuality)
+	assert.Equal(t, int64(1<<30), got.NotifySizeBytes)
+	assert.Equal(t, "https://portal.example.com/", got.Referer)
+	assert.False(t, got.JavaScriptEnabled)
+	assert.True(t, got.Offline)
+	assert.Equal(t, task.Viewports, got.Viewports)
+	assert.True(t, got.RecordOnChange)
+	assert.Equal(t, recorder.CaptureFormatPNG, got.CaptureFormat)
+	assert.Equal(t, "font", got.BlockedResources)
+	assert.Equal(t, int64(2000), got.StartDelayMs)
+	assert.True(t, got.CaptureMilestones)
+	assert.Equal(t, "lobby", got.OutputDir)
+	assert.Equal(t, "sealed-proxy", got.ProxyUrl)
+}
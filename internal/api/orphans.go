@@ -0,0 +1,113 @@
+package api
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+	"github.com/nullpo7z/dashboard-recorder/internal/recorder"
+)
+
+// orphanGracePeriod skips files touched very recently so a recording that is
+// just starting (file created, row not yet visible) is never reported
+const orphanGracePeriod = time.Minute
+
+// OrphanFile is a file under the recordings directory that no recording row references
+type OrphanFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// OrphanRow is a finished recording row whose file is missing on disk
+type OrphanRow struct {
+	ID          int64  `json:"id"`
+	TaskID      int64  `json:"task_id"`
+	Status      string `json:"status"`
+	FilePath    string `json:"file_path"`
+	IsProtected bool   `json:"is_protected"`
+}
+
+// orphanReport also sums up the finished rows it checked: how many there are, how
+// many still have their file, and the archive's size on disk
+type orphanReport struct {
+	Files      []OrphanFile `json:"files"`
+	Rows       []OrphanRow  `json:"rows"`
+	Scanned    int          `json:"scanned"`
+	Present    int          `json:"present"`
+	TotalBytes int64        `json:"total_bytes"`
+}
+
+// findOrphans compares the files under root with the recording rows. Sidecar console
+// logs and milestone stills count as referenced when their recording is, and
+// in-progress rows are never reported as missing.
+func findOrphans(root string, recs []database.Recording, now time.Time) (orphanReport, error) {
+	report := orphanReport{Files: []OrphanFile{}, Rows: []OrphanRow{}}
+
+	referenced := make(map[string]bool, len(recs)*2)
+	for _, r := range recs {
+		if r.FilePath == "" {
+			continue
+		}
+		path := filepath.Clean(r.FilePath)
+		referenced[path] = true
+		referenced[recorder.PageLogPath(path)] = true
+		for _, still := range recorder.MilestonePaths(path) {
+			referenced[still] = true
+		}
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "perm_check") {
+			return nil
+		}
+		if referenced[filepath.Clean(path)] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// Removed while walking
+			return nil
+		}
+		if now.Sub(info.ModTime()) < orphanGracePeriod {
+			return nil
+		}
+		report.Files = append(report.Files, OrphanFile{Path: path, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for _, r := range recs {
+		if recorder.StatusRecording.Is(r.Status) {
+			continue
+		}
+		report.Scanned++
+		if r.FilePath != "" {
+			info, err := os.Stat(r.FilePath)
+			if err == nil && !info.IsDir() {
+				report.Present++
+				report.TotalBytes += info.Size()
+			}
+			// Only a definite "not found" counts; e.g. a permission error is not proof the file is gone
+			if !os.IsNotExist(err) {
+				continue
+			}
+		}
+		report.Rows = append(report.Rows, OrphanRow{
+			ID:          r.ID,
+			TaskID:      r.TaskID,
+			Status:      r.Status,
+			FilePath:    r.FilePath,
+			IsProtected: r.IsProtected,
+		})
+	}
+	return report, nil
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindOrphans(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel string) string {
+		path := filepath.Join(root, rel)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+		return path
+	}
+
+	kept := write("1_1700000000.mkv")
+	write("1_1700000000.log")      // sidecar of a referenced recording
+	write("1_1700000000.load.png") // milestone still of a referenced recording
+	stray := write("task_2/old.mkv")
+	strayLog := write("task_2/old.log")
+	write(".write_test")
+	missing := filepath.Join(root, "gone.mkv")
+	inProgress := filepath.Join(root, "live.mkv")
+
+	recs := []database.Recording{
+		{ID: 1, TaskID: 1, Status: "COMPLETED", FilePath: kept},
+		{ID: 2, TaskID: 1, Status: "FAILED", FilePath: missing},
+		{ID: 3, TaskID: 2, Status: "RECORDING", FilePath: inProgress},
+		{ID: 4, TaskID: 2, Status: "FAILED", FilePath: ""},
+	}
+
+	// Pretend the scan runs well after the files were written
+	report, err := findOrphans(root, recs, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	files := []string{}
+	for _, f := range report.Files {
+		files = append(files, f.Path)
+	}
+	assert.Equal(t, []string{strayLog, stray}, files)
+
+	rows := []int64{}
+	for _, r := range report.Rows {
+		rows = append(rows, r.ID)
+	}
+	assert.Equal(t, []int64{2, 4}, rows)
+
+	assert.Equal(t, 3, report.Scanned)
+	assert.Equal(t, 1, report.Present)
+	assert.Equal(t, int64(len("data")), report.TotalBytes)
+}
+
+func TestFindOrphans_SkipsRecentFiles(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "starting.mkv"), []byte("data"), 0644))
+
+	report, err := findOrphans(root, nil, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(report.Files))
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// defaultPageSize applies when a client sends ?page= without ?page_size=
+	defaultPageSize = 50
+	// maxPageSize bounds ?page_size=
+	maxPageSize = 500
+)
+
+// PaginationHeaders are the response headers of a paginated list, exposed to
+// cross-origin clients
+var PaginationHeaders = []string{"Link", "X-Total-Count", "X-Page", "X-Page-Size", "X-Has-Next"}
+
+// pagination is the requested page of a list endpoint (1-based)
+type pagination struct {
+	Page     int
+	PageSize int
+}
+
+// parsePagination reads ?page= and ?page_size=. Lists are only paginated when the
+// client asks for it (ok is false otherwise), so existing clients keep full lists.
+func parsePagination(c echo.Context) (p pagination, ok bool, err error) {
+	page, size := c.QueryParam("page"), c.QueryParam("page_size")
+	if page == "" && size == "" {
+		return pagination{}, false, nil
+	}
+	p = pagination{Page: 1, PageSize: defaultPageSize}
+	if page != "" {
+		if p.Page, err = strconv.Atoi(page); err != nil || p.Page < 1 {
+			return pagination{}, false, fmt.Errorf("page must be a positive integer")
+		}
+	}
+	if size != "" {
+		if p.PageSize, err = strconv.Atoi(size); err != nil || p.PageSize < 1 || p.PageSize > maxPageSize {
+			return pagination{}, false, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+		}
+	}
+	return p, true, nil
+}
+
+// bounds returns the slice indexes [start, end) of the page in a list of total items
+// (empty past the last page). A page past the end is detected before multiplying,
+// so a huge ?page= can't overflow into a negative index.
+func (p pagination) bounds(total int) (start, end int) {
+	if p.Page-1 > total/p.PageSize {
+		return total, total
+	}
+	start = (p.Page - 1) * p.PageSize
+	if start > total {
+		start = total
+	}
+	end = start + p.PageSize
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// lastPage is the number of the last page of total items (1 for an empty list)
+func (p pagination) lastPage(total int) int {
+	if total == 0 {
+		return 1
+	}
+	return (total + p.PageSize - 1) / p.PageSize
+}
+
+// setPaginationHeaders describes the page in X-Total-Count, X-Page, X-Page-Size and
+// X-Has-Next, and links the first, previous, next and last pages (RFC 8288 Link)
+// with the request's other query parameters kept
+func setPaginationHeaders(c echo.Context, p pagination, total int) {
+	last := p.lastPage(total)
+	header := c.Response().Header()
+	header.Set("X-Total-Count", strconv.Itoa(total))
+	header.Set("X-Page", strconv.Itoa(p.Page))
+	header.Set("X-Page-Size", strconv.Itoa(p.PageSize))
+	header.Set("X-Has-Next", strconv.FormatBool(p.Page < last))
+
+	links := []string{pageLink(c.Request(), p, 1, "first")}
+	if p.Page > 1 {
+		links = append(links, pageLink(c.Request(), p, min(p.Page-1, last), "prev"))
+	}
+	if p.Page < last {
+		links = append(links, pageLink(c.Request(), p, p.Page+1, "next"))
+	}
+	links = append(links, pageLink(c.Request(), p, last, "last"))
+	header.Set("Link", strings.Join(links, ", "))
+}
+
+// pageLink is one Link header entry pointing at another page of the same request
+func pageLink(r *http.Request, p pagination, page int, rel string) string {
+	query := r.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("page_size", strconv.Itoa(p.PageSize))
+	return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), rel)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nullpo7z/dashboard-recorder/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func paginationContext(target string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	return e.NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec), rec
+}
+
+func TestParsePagination(t *testing.T) {
+	c, _ := paginationContext("/api/archives")
+	if _, ok, err := parsePagination(c); ok || err != nil {
+		t.Errorf("parsePagination() without params = ok %v, %v; want unpaginated", ok, err)
+	}
+
+	c, _ = paginationContext("/api/archives?page=3")
+	p, ok, err := parsePagination(c)
+	if !ok || err != nil || p.Page != 3 || p.PageSize != defaultPageSize {
+		t.Errorf("parsePagination(page=3) = %+v, %v, %v", p, ok, err)
+	}
+
+	for _, query := range []string{"page=0", "page=x", "page_size=0", "page_size=501"} {
+		c, _ = paginationContext("/api/archives?" + query)
+		if _, _, err := parsePagination(c); err == nil {
+			t.Errorf("parsePagination(%s) expected error", query)
+		}
+	}
+}
+
+func TestPagination_Bounds(t *testing.T) {
+	p := pagination{Page: 2, PageSize: 10}
+	if start, end := p.bounds(25); start != 10 || end != 20 {
+		t.Errorf("bounds(25) = %d, %d, want 10, 20", start, end)
+	}
+	if start, end := (pagination{Page: 3, PageSize: 10}).bounds(25); start != 20 || end != 25 {
+		t.Errorf("bounds() last page = %d, %d, want 20, 25", start, end)
+	}
+	if start, end := (pagination{Page: 9, PageSize: 10}).bounds(25); start != 25 || end != 25 {
+		t.Errorf("bounds() past the end = %d, %d, want empty", start, end)
+	}
+
+	// (page-1)*page_size would overflow to a negative start
+	c, _ := paginationContext("/archives?page=9223372036854775807&page_size=500")
+	huge, ok, err := parsePagination(c)
+	if !ok || err != nil {
+		t.Fatalf("parsePagination() = %v, %v", ok, err)
+	}
+	if start, end := huge.bounds(25); start != 25 || end != 25 {
+		t.Errorf("bounds() huge page = %d, %d, want empty", start, end)
+	}
+}
+
+func TestSetPaginationHeaders(t *testing.T) {
+	c, rec := paginationContext("/api/archives?tag=ops&page=2&page_size=10")
+	setPaginationHeaders(c, pagination{Page: 2, PageSize: 10}, 25)
+
+	header := rec.Header()
+	assert.Equal(t, "25", header.Get("X-Total-Count"))
+	assert.Equal(t, "2", header.Get("X-Page"))
+	assert.Equal(t, "10", header.Get("X-Page-Size"))
+	assert.Equal(t, "true", header.Get("X-Has-Next"))
+
+	link := header.Get("Link")
+	for _, want := range []string{
+		`</api/archives?page=1&page_size=10&tag=ops>; rel="first"`,
+		`</api/archives?page=1&page_size=10&tag=ops>; rel="prev"`,
+		`</api/archives?page=3&page_size=10&tag=ops>; rel="next"`,
+		`</api/archives?page=3&page_size=10&tag=ops>; rel="last"`,
+	} {
+		assert.Contains(t, link, want)
+	}
+
+	c, rec = paginationContext("/api/tasks?page=1")
+	setPaginationHeaders(c, pagination{Page: 1, PageSize: 50}, 0)
+	assert.Equal(t, "false", rec.Header().Get("X-Has-Next"))
+	assert.False(t, strings.Contains(rec.Header().Get("Link"), `rel="next"`))
+}
+
+func TestListArchives_Validation_Pagination(t *testing.T) {
+	c, rec := paginationContext("/api/archives?page_size=1000")
+	h := &Handler{Config: &config.Config{}}
+
+	if assert.NoError(t, h.ListArchives(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "page_size")
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/auth"
+	"github.com/nullpo7z/dashboard-recorder/internal/recorder"
+)
+
+// proxyKeyPurpose derives the key task proxies (which may carry credentials) are sealed with
+const proxyKeyPurpose = "task-proxy"
+
+// redactedPassword is what url.URL.Redacted puts in place of a password
+const redactedPassword = "xxxxx"
+
+// normalizeTaskProxy validates a requested task proxy against TASK_PROXY_ALLOWED_HOSTS.
+// A proxy echoed back as the API returned it (password redacted) keeps the stored
+// password of taskID (0 for a new task).
+func (h *Handler) normalizeTaskProxy(ctx context.Context, taskID int64, requested string) (string, error) {
+	requested = strings.TrimSpace(requested)
+	if requested == "" {
+		return "", nil
+	}
+	u, err := recorder.ParseProxyURL(requested)
+	if err != nil {
+		return "", err
+	}
+	proxy := u.String()
+	if password, ok := u.User.Password(); ok && password == redactedPassword && taskID != 0 {
+		task, err := h.Queries.GetTask(ctx, taskID)
+		if err != nil {
+			return "", fmt.Errorf("task not found")
+		}
+		if stored, err := h.taskProxy(task.ProxyUrl); err == nil && stored != "" && recorder.RedactProxyURL(stored) == proxy {
+			proxy = stored
+		}
+	}
+	if err := recorder.ValidateProxy(proxy, h.Config.TaskProxyAllowedHosts); err != nil {
+		return "", err
+	}
+	return proxy, nil
+}
+
+// sealTaskProxy encrypts a task proxy for storage ("" stays "")
+func (h *Handler) sealTaskProxy(proxy string) (string, error) {
+	if proxy == "" {
+		return "", nil
+	}
+	return auth.Encrypt(h.encryptionKey(proxyKeyPurpose), proxy)
+}
+
+// taskProxy decrypts a stored task proxy. A task never falls back to a direct
+// connection: a proxy that can't be decrypted (e.g. after ENCRYPTION_KEY changed)
+// is an error.
+func (h *Handler) taskProxy(stored string) (string, error) {
+	if stored == "" {
+		return "", nil
+	}
+	proxy, err := auth.Decrypt(h.encryptionKey(proxyKeyPurpose), stored)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the task proxy (was ENCRYPTION_KEY changed?): %w", err)
+	}
+	return proxy, nil
+}
+
+// taskProxyDTO returns a stored task proxy for the API, password redacted
+func (h *Handler) taskProxyDTO(stored string) string {
+	proxy, err := h.taskProxy(stored)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return ""
+	}
+	return recorder.RedactProxyURL(proxy)
+}
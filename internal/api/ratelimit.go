@@ -0,0 +1,47 @@
+package api
+
+import "sync"
+
+// RateLimitStats counts the requests one rate-limited route let through and
+// rejected with 429, for tuning the limits on real rejection rates
+type RateLimitStats struct {
+	Allowed  uint64 `json:"allowed_total"`
+	Rejected uint64 `json:"rejected_total"`
+}
+
+// rateLimitCounters keeps RateLimitStats per route (the route pattern, e.g.
+// "/api/login"). The zero value is ready to use; safe for concurrent use.
+type rateLimitCounters struct {
+	mu     sync.Mutex
+	routes map[string]*RateLimitStats
+}
+
+// record counts one request to route
+func (r *rateLimitCounters) record(route string, allowed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.routes == nil {
+		r.routes = make(map[string]*RateLimitStats)
+	}
+	stats, ok := r.routes[route]
+	if !ok {
+		stats = &RateLimitStats{}
+		r.routes[route] = stats
+	}
+	if allowed {
+		stats.Allowed++
+	} else {
+		stats.Rejected++
+	}
+}
+
+// snapshot returns a copy of the counters of every route seen so far
+func (r *rateLimitCounters) snapshot() map[string]RateLimitStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[string]RateLimitStats, len(r.routes))
+	for route, stats := range r.routes {
+		out[route] = *stats
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitMiddleware_Counters(t *testing.T) {
+	e := echo.New()
+	h := &Handler{clients: make(map[string]*rate.Limiter)}
+	next := h.RateLimitMiddleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	// Burst of 5 per client, then 429
+	for i := 0; i < 7; i++ {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/api/login", nil), rec)
+		c.SetPath("/api/login")
+		assert.NoError(t, next(c))
+	}
+
+	stats := h.rateLimits.snapshot()
+	assert.Equal(t, RateLimitStats{Allowed: 5, Rejected: 2}, stats["/api/login"])
+	assert.Len(t, stats, 1)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxRecordingNoteLength bounds a recording's note (in characters)
+	maxRecordingNoteLength = 1000
+	// maxRecordingTags bounds the number of tags on one recording
+	maxRecordingTags = 10
+	// maxRecordingTagLength bounds a single tag
+	maxRecordingTagLength = 32
+)
+
+// recordingTagPattern is the allowed form of a (lowercased) tag. Commas are excluded
+// because tags are stored as a comma-separated list.
+var recordingTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// validateRecordingNote checks a user-supplied note and returns it trimmed
+func validateRecordingNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxRecordingNoteLength {
+		return "", fmt.Errorf("note exceeds %d characters", maxRecordingNoteLength)
+	}
+	return note, nil
+}
+
+// normalizeRecordingTags trims, lowercases and de-duplicates tags (keeping their
+// order) and rejects any that can't be stored or filtered on reliably
+func normalizeRecordingTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxRecordingTagLength {
+			return nil, fmt.Errorf("tag %q exceeds %d characters", tag, maxRecordingTagLength)
+		}
+		if !recordingTagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("tag %q contains invalid characters. Allowed: a-z, 0-9, _, ., -", tag)
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	if len(out) > maxRecordingTags {
+		return nil, fmt.Errorf("a recording can have at most %d tags", maxRecordingTags)
+	}
+	return out, nil
+}
+
+// splitRecordingTags decodes the stored comma-separated tags (never nil, so the
+// DTO always serializes a list)
+func splitRecordingTags(stored string) []string {
+	if stored == "" {
+		return []string{}
+	}
+	return strings.Split(stored, ",")
+}
+
+// matchesRecordingFilter reports whether a recording passes the archive list's
+// ?tag= (exact, case-insensitive) and ?q= (note substring, case-insensitive) filters
+func matchesRecordingFilter(note, tags, tag, query string) bool {
+	if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+		found := false
+		for _, t := range splitRecordingTags(tags) {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if query = strings.TrimSpace(query); query != "" {
+		if !strings.Contains(strings.ToLower(note), strings.ToLower(query)) {
+			return false
+		}
+	}
+	return true
+}
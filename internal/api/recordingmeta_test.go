@@ -0,0 +1,61 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRecordingNote(t *testing.T) {
+	note, err := validateRecordingNote("  recording from the outage \n")
+	assert.NoError(t, err)
+	assert.Equal(t, "recording from the outage", note)
+
+	_, err = validateRecordingNote(strings.Repeat("é", maxRecordingNoteLength))
+	assert.NoError(t, err, "limit counts characters, not bytes")
+
+	_, err = validateRecordingNote(strings.Repeat("a", maxRecordingNoteLength+1))
+	assert.Error(t, err)
+}
+
+func TestNormalizeRecordingTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{"Empty", nil, []string{}, false},
+		{"Normalized", []string{" Outage ", "db-2024.1", "outage", ""}, []string{"outage", "db-2024.1"}, false},
+		{"Comma", []string{"a,b"}, nil, true},
+		{"Space", []string{"two words"}, nil, true},
+		{"Leading Dot", []string{".hidden"}, nil, true},
+		{"Too Long", []string{strings.Repeat("a", maxRecordingTagLength+1)}, nil, true},
+		{"Too Many", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeRecordingTags(tt.tags)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMatchesRecordingFilter(t *testing.T) {
+	note, tags := "Recording from the Outage", "outage,db"
+
+	assert.True(t, matchesRecordingFilter(note, tags, "", ""))
+	assert.True(t, matchesRecordingFilter(note, tags, "DB", ""))
+	assert.True(t, matchesRecordingFilter(note, tags, "outage", "the outage"))
+	assert.False(t, matchesRecordingFilter(note, tags, "out", ""), "tags match exactly")
+	assert.False(t, matchesRecordingFilter(note, tags, "", "deploy"))
+	assert.False(t, matchesRecordingFilter("", "", "outage", ""))
+	assert.Equal(t, []string{}, splitRecordingTags(""))
+}
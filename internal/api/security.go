@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nullpo7z/dashboard-recorder/internal/config"
+)
+
+// BuildCSP assembles the Content-Security-Policy from config.
+// This is the only place the policy is defined.
+func BuildCSP(cfg *config.Config) string {
+	imgSrc := append([]string{"'self'", "blob:", "data:"}, cfg.CSPImgSrc...)
+	styleSrc := []string{"'self'"}
+	if cfg.CSPAllowInlineStyles {
+		styleSrc = append(styleSrc, "'unsafe-inline'")
+	}
+	connectSrc := append([]string{"'self'", "ws:", "wss:"}, cfg.CSPConnectSrc...)
+
+	directives := []string{
+		"default-src 'self'",
+		"img-src " + strings.Join(imgSrc, " "),
+		"style-src " + strings.Join(styleSrc, " "),
+		"script-src 'self'",
+		"connect-src " + strings.Join(connectSrc, " "),
+	}
+	return strings.Join(directives, "; ") + ";"
+}
+
+// IPExtractor determines the client IP for c.RealIP(). X-Forwarded-For is only
+// honored on connections from TRUSTED_PROXIES, and only up to the first untrusted
+// hop, so clients can't spoof their address to evade the per-IP rate limits.
+// Without trusted proxies the peer address is used.
+func IPExtractor(cfg *config.Config) (echo.IPExtractor, error) {
+	proxies, err := config.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range proxies {
+		options = append(options, echo.TrustIPRange(proxy))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// SecurityHeaders sets CSP, HSTS and related headers on every response
+func SecurityHeaders(cfg *config.Config) echo.MiddlewareFunc {
+	csp := BuildCSP(cfg)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set("Content-Security-Policy", csp)
+			c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+			c.Response().Header().Set("X-Frame-Options", "DENY")
+			if cfg.TLSDomain != "" {
+				c.Response().Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+			}
+			return next(c)
+		}
+	}
+}
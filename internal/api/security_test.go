@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nullpo7z/dashboard-recorder/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildCSP_Default(t *testing.T) {
+	cfg := &config.Config{CSPAllowInlineStyles: true}
+	assert.Equal(t,
+		"default-src 'self'; img-src 'self' blob: data:; style-src 'self' 'unsafe-inline'; script-src 'self'; connect-src 'self' ws: wss:;",
+		BuildCSP(cfg))
+}
+
+func TestBuildCSP_Hardened(t *testing.T) {
+	cfg := &config.Config{
+		CSPAllowInlineStyles: false,
+		CSPImgSrc:            []string{"https://cdn.example.com"},
+		CSPConnectSrc:        []string{"https://api.example.com"},
+	}
+	csp := BuildCSP(cfg)
+	assert.NotContains(t, csp, "unsafe-inline")
+	assert.Contains(t, csp, "img-src 'self' blob: data: https://cdn.example.com;")
+	assert.Contains(t, csp, "connect-src 'self' ws: wss: https://api.example.com;")
+}
+
+func TestIPExtractor(t *testing.T) {
+	request := func(remote, xff string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote + ":1234"
+		if xff != "" {
+			req.Header.Set(echo.HeaderXForwardedFor, xff)
+		}
+		return req
+	}
+
+	// No trusted proxies: forwarded headers are ignored
+	extract, err := IPExtractor(&config.Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, "203.0.113.9", extract(request("203.0.113.9", "198.51.100.1")))
+
+	extract, err = IPExtractor(&config.Config{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"}})
+	assert.NoError(t, err)
+	// From a trusted proxy: the client it forwarded for
+	assert.Equal(t, "198.51.100.1", extract(request("10.1.2.3", "198.51.100.1")))
+	// A spoofed hop in front of the real client is not trusted
+	assert.Equal(t, "198.51.100.1", extract(request("192.0.2.1", "6.6.6.6, 198.51.100.1")))
+	// From anywhere else: the peer, whatever the header claims
+	assert.Equal(t, "203.0.113.9", extract(request("203.0.113.9", "198.51.100.1")))
+	// Private ranges are not trusted implicitly
+	assert.Equal(t, "172.16.0.5", extract(request("172.16.0.5", "198.51.100.1")))
+
+	_, err = IPExtractor(&config.Config{TrustedProxies: []string{"not-an-ip"}})
+	assert.Error(t, err)
+}
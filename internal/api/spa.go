@@ -0,0 +1,29 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ServeIndex serves the SPA shell at path with the backend readiness injected as
+// <meta name="backend-status" content="ready|degraded">, so the UI can show a
+// "backend unavailable" state before its first API call fails. /api/ready has the
+// details. The shell is never cached, so a reload picks up the current status.
+func (h *Handler) ServeIndex(path string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		page, err := os.ReadFile(path)
+		if err != nil {
+			return echo.ErrNotFound
+		}
+		status, _ := h.readiness(c.Request().Context())
+		meta := fmt.Sprintf(`<meta name="backend-status" content="%s">`, status)
+		page = bytes.Replace(page, []byte("</head>"), []byte(meta+"</head>"), 1)
+
+		c.Response().Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		return c.HTMLBlob(http.StatusOK, page)
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nullpo7z/dashboard-recorder/internal/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeIndex(t *testing.T) {
+	index := filepath.Join(t.TempDir(), "index.html")
+	assert.NoError(t, os.WriteFile(index, []byte("<html><head><title>x</title></head><body></body></html>"), 0644))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// No browser, ffmpeg or database: the shell still loads, flagged as degraded
+	h := &Handler{Recorder: &recorder.Worker{}}
+	if assert.NoError(t, h.ServeIndex(index)(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `<html><head><title>x</title><meta name="backend-status" content="degraded"></head><body></body></html>`, rec.Body.String())
+		assert.Contains(t, rec.Header().Get("Cache-Control"), "no-store")
+	}
+
+	err := h.ServeIndex(filepath.Join(t.TempDir(), "missing.html"))(e.NewContext(req, httptest.NewRecorder()))
+	assert.Equal(t, echo.ErrNotFound, err)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nullpo7z/dashboard-recorder/internal/auth"
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+)
+
+// totpIssuer is the label authenticator apps show next to the account
+const totpIssuer = "Dashboard Recorder"
+
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// EnrollTOTP generates a new (not yet active) secret and recovery codes for the caller.
+// The secret only takes effect after ActivateTOTP proves the authenticator works.
+func (h *Handler) EnrollTOTP(c echo.Context) error {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+	ctx := c.Request().Context()
+
+	existing, err := h.Queries.GetUserTOTP(ctx, username)
+	if err == nil && existing.Enabled {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "two-factor authentication is already enabled"})
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	codes, err := auth.GenerateRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	sealed, err := auth.Encrypt(h.encryptionKey("totp"), secret)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to store secret"})
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	if err := h.Queries.UpsertUserTOTP(ctx, database.UpsertUserTOTPParams{
+		Username:      username,
+		Secret:        sealed,
+		RecoveryCodes: strings.Join(hashes, ","),
+	}); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"secret":           secret,
+		"provisioning_uri": auth.TOTPProvisioningURI(totpIssuer, username, secret),
+		"recovery_codes":   codes,
+	})
+}
+
+// ActivateTOTP turns on two-factor login once the user proves a valid code
+func (h *Handler) ActivateTOTP(c echo.Context) error {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+	var req TOTPCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	ctx := c.Request().Context()
+
+	record, err := h.Queries.GetUserTOTP(ctx, username)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "no pending enrollment"})
+	}
+	if record.Enabled {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "two-factor authentication is already enabled"})
+	}
+	secret, err := auth.Decrypt(h.encryptionKey("totp"), record.Secret)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to read secret"})
+	}
+	counter, ok := auth.MatchTOTP(secret, req.Code, time.Now())
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid code"})
+	}
+	// The activation code is spent: it can't also be used to log in
+	if _, err := h.Queries.AcceptUserTOTPCounter(ctx, database.AcceptUserTOTPCounterParams{
+		LastCounter: int64(counter),
+		Username:    username,
+		Counter:     int64(counter),
+	}); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+
+	if err := h.Queries.EnableUserTOTP(ctx, username); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "enabled"})
+}
+
+// DisableTOTP removes two-factor login; requires a current code or recovery code
+func (h *Handler) DisableTOTP(c echo.Context) error {
+	username, ok := usernameFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+	}
+	var req TOTPCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	ctx := c.Request().Context()
+
+	required, err := h.verifySecondFactor(ctx, username, req.Code)
+	if errors.Is(err, errInvalidSecondFactor) {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid code"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	if !required {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "two-factor authentication is not enabled"})
+	}
+	if err := h.Queries.DeleteUserTOTP(ctx, username); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "database error"})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "disabled"})
+}
+
+// errInvalidSecondFactor marks a wrong or missing TOTP/recovery code
+var errInvalidSecondFactor = errors.New("invalid two-factor code")
+
+// verifySecondFactor reports whether the user has TOTP enabled and, if so, checks
+// code as either a TOTP code or a one-time recovery code (which is consumed).
+// It returns errInvalidSecondFactor when the code doesn't match or was already
+// used. Both checks are conditional updates, so concurrent logins can't spend the
+// same TOTP period or recovery code twice.
+func (h *Handler) verifySecondFactor(ctx context.Context, username, code string) (bool, error) {
+	record, err := h.Queries.GetUserTOTP(ctx, username)
+	if err == sql.ErrNoRows || (err == nil && !record.Enabled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return true, errInvalidSecondFactor
+	}
+
+	secret, err := auth.Decrypt(h.encryptionKey("totp"), record.Secret)
+	if err != nil {
+		return true, err
+	}
+	if counter, ok := auth.MatchTOTP(secret, code, time.Now()); ok {
+		// Only a code from a later period than the last accepted one counts
+		n, err := h.Queries.AcceptUserTOTPCounter(ctx, database.AcceptUserTOTPCounterParams{
+			LastCounter: int64(counter),
+			Username:    username,
+			Counter:     int64(counter),
+		})
+		if err != nil {
+			return true, err
+		}
+		if n == 0 {
+			return true, errInvalidSecondFactor
+		}
+		return true, nil
+	}
+
+	// Fall back to recovery codes (single use)
+	hash := auth.HashRecoveryCode(code)
+	remaining := []string{}
+	matched := false
+	for _, stored := range strings.Split(record.RecoveryCodes, ",") {
+		if stored == "" {
+			continue
+		}
+		if !matched && stored == hash {
+			matched = true
+			continue
+		}
+		remaining = append(remaining, stored)
+	}
+	if !matched {
+		return true, errInvalidSecondFactor
+	}
+	n, err := h.Queries.UpdateUserTOTPRecoveryCodes(ctx, database.UpdateUserTOTPRecoveryCodesParams{
+		RecoveryCodes: strings.Join(remaining, ","),
+		Username:      username,
+		PreviousCodes: record.RecoveryCodes,
+	})
+	if err != nil {
+		return true, err
+	}
+	if n == 0 {
+		// Another login changed the codes since they were read (possibly spending this one)
+		return true, errInvalidSecondFactor
+	}
+	return true, nil
+}
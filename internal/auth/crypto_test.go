@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestEncryptDecrypt_RoundTrip(t *testing.T) {
+	key := DeriveKey("secret", "test")
+
+	sealed, err := Encrypt(key, "refresh-token-value")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if sealed == "refresh-token-value" {
+		t.Fatalf("Encrypt() returned plaintext")
+	}
+
+	plain, err := Decrypt(key, sealed)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if plain != "refresh-token-value" {
+		t.Errorf("Decrypt() = %q, want %q", plain, "refresh-token-value")
+	}
+}
+
+func TestDecrypt_WrongKey(t *testing.T) {
+	sealed, err := Encrypt(DeriveKey("secret", "a"), "value")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if _, err := Decrypt(DeriveKey("secret", "b"), sealed); err == nil {
+		t.Errorf("Decrypt() with a different purpose key expected error")
+	}
+}
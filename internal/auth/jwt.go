@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms supported for the app's tokens (JWT_ALGORITHM)
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmES256 = "ES256"
+)
+
+// JWTKeys signs and verifies the app's tokens with one algorithm. HS256 uses the
+// shared JWT secret; RS256 and ES256 sign with a private key and verify with the
+// public key, so other services can verify tokens without being able to issue them.
+type JWTKeys struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// HMACKeys returns HS256 keys for the shared secret
+func HMACKeys(secret string) *JWTKeys {
+	return &JWTKeys{method: jwt.SigningMethodHS256, signKey: []byte(secret), verifyKey: []byte(secret)}
+}
+
+// LoadJWTKeys loads the keys of the algorithm. For RS256/ES256 the private key is
+// read from privateKeyPath (PEM); the public key from publicKeyPath, or derived from
+// the private key when that is empty. A public key that doesn't match is rejected.
+func LoadJWTKeys(algorithm, secret, privateKeyPath, publicKeyPath string) (*JWTKeys, error) {
+	switch algorithm {
+	case "", JWTAlgorithmHS256:
+		return HMACKeys(secret), nil
+	case JWTAlgorithmRS256:
+		privPEM, pubPEM, err := readKeyFiles(privateKeyPath, publicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := jwt.ParseRSAPrivateKeyFromPEM(privPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA private key: %w", err)
+		}
+		pub := &priv.PublicKey
+		if pubPEM != nil {
+			if pub, err = jwt.ParseRSAPublicKeyFromPEM(pubPEM); err != nil {
+				return nil, fmt.Errorf("invalid RSA public key: %w", err)
+			}
+			if !pub.Equal(&priv.PublicKey) {
+				return nil, fmt.Errorf("public key does not belong to the private key")
+			}
+		}
+		if priv.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA key must be at least 2048 bits, got %d", priv.N.BitLen())
+		}
+		return &JWTKeys{method: jwt.SigningMethodRS256, signKey: priv, verifyKey: pub}, nil
+	case JWTAlgorithmES256:
+		privPEM, pubPEM, err := readKeyFiles(privateKeyPath, publicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := jwt.ParseECPrivateKeyFromPEM(privPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC private key: %w", err)
+		}
+		if priv.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ES256 requires a P-256 key, got %s", priv.Curve.Params().Name)
+		}
+		pub := &priv.PublicKey
+		if pubPEM != nil {
+			if pub, err = jwt.ParseECPublicKeyFromPEM(pubPEM); err != nil {
+				return nil, fmt.Errorf("invalid EC public key: %w", err)
+			}
+			if !pub.Equal(&priv.PublicKey) {
+				return nil, fmt.Errorf("public key does not belong to the private key")
+			}
+		}
+		return &JWTKeys{method: jwt.SigningMethodES256, signKey: priv, verifyKey: pub}, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q (supported: HS256, RS256, ES256)", algorithm)
+	}
+}
+
+func readKeyFiles(privateKeyPath, publicKeyPath string) (privPEM, pubPEM []byte, err error) {
+	if privateKeyPath == "" {
+		return nil, nil, fmt.Errorf("a private key file is required for asymmetric signing")
+	}
+	if privPEM, err = os.ReadFile(privateKeyPath); err != nil {
+		return nil, nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	if publicKeyPath != "" {
+		if pubPEM, err = os.ReadFile(publicKeyPath); err != nil {
+			return nil, nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+	}
+	return privPEM, pubPEM, nil
+}
+
+// Algorithm returns the JWT "alg" of the keys
+func (k *JWTKeys) Algorithm() string {
+	return k.method.Alg()
+}
+
+// Sign issues a token with the claims
+func (k *JWTKeys) Sign(claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(k.method, claims).SignedString(k.signKey)
+}
+
+// Parse verifies a token. Only the configured algorithm is accepted, so a token
+// can't pick a weaker one (e.g. HS256 "signed" with the public key).
+func (k *JWTKeys) Parse(token string) (*jwt.Token, error) {
+	return jwt.Parse(token, func(*jwt.Token) (interface{}, error) {
+		return k.verifyKey, nil
+	}, jwt.WithValidMethods([]string{k.method.Alg()}))
+}
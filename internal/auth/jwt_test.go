@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writePEM writes der as a PEM block of the type into dir
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func rsaKeyFiles(t *testing.T, dir string) (privPath, pubPath string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		writePEM(t, dir, "rsa.pub.pem", "PUBLIC KEY", pub)
+}
+
+func ecKeyFiles(t *testing.T, dir, prefix string) (privPath, pubPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, prefix+".pem", "EC PRIVATE KEY", der),
+		writePEM(t, dir, prefix+".pub.pem", "PUBLIC KEY", pub)
+}
+
+func TestJWTKeys_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	rsaPriv, rsaPub := rsaKeyFiles(t, dir)
+	ecPriv, ecPub := ecKeyFiles(t, dir, "ec")
+
+	tests := []struct {
+		name      string
+		algorithm string
+		priv, pub string
+	}{
+		{"HS256", JWTAlgorithmHS256, "", ""},
+		{"RS256", JWTAlgorithmRS256, rsaPriv, rsaPub},
+		{"RS256 derived public key", JWTAlgorithmRS256, rsaPriv, ""},
+		{"ES256", JWTAlgorithmES256, ecPriv, ecPub},
+		{"ES256 derived public key", JWTAlgorithmES256, ecPriv, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := LoadJWTKeys(tt.algorithm, "secret", tt.priv, tt.pub)
+			if err != nil {
+				t.Fatalf("LoadJWTKeys() error = %v", err)
+			}
+			if keys.Algorithm() != tt.algorithm {
+				t.Errorf("Algorithm() = %q, want %q", keys.Algorithm(), tt.algorithm)
+			}
+			signed, err := keys.Sign(jwt.MapClaims{"user": "admin"})
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			token, err := keys.Parse(signed)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if user := token.Claims.(jwt.MapClaims)["user"]; user != "admin" {
+				t.Errorf("Parse() user = %v, want admin", user)
+			}
+		})
+	}
+}
+
+func TestJWTKeys_RejectsOtherAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	rsaPriv, _ := rsaKeyFiles(t, dir)
+	keys, err := LoadJWTKeys(JWTAlgorithmRS256, "secret", rsaPriv, "")
+	if err != nil {
+		t.Fatalf("LoadJWTKeys() error = %v", err)
+	}
+
+	hs, err := HMACKeys("secret").Sign(jwt.MapClaims{"user": "admin"})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if _, err := keys.Parse(hs); err == nil {
+		t.Errorf("Parse() accepted an HS256 token with RS256 keys")
+	}
+}
+
+func TestLoadJWTKeys_Errors(t *testing.T) {
+	dir := t.TempDir()
+	ecPriv, _ := ecKeyFiles(t, dir, "a")
+	_, otherPub := ecKeyFiles(t, dir, "b")
+	rsaPriv, _ := rsaKeyFiles(t, dir)
+
+	tests := []struct {
+		name      string
+		algorithm string
+		priv, pub string
+	}{
+		{"Unsupported algorithm", "none", "", ""},
+		{"Missing private key", JWTAlgorithmES256, "", ""},
+		{"Unreadable private key", JWTAlgorithmRS256, filepath.Join(dir, "missing.pem"), ""},
+		{"Mismatched public key", JWTAlgorithmES256, ecPriv, otherPub},
+		{"Wrong key type", JWTAlgorithmES256, rsaPriv, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadJWTKeys(tt.algorithm, "secret", tt.priv, tt.pub); err == nil {
+				t.Errorf("LoadJWTKeys() expected error")
+			}
+		})
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"time"
 )
 
+// DefaultEntropyBytes is the ticket ID entropy used when none is configured (128 bits)
+const DefaultEntropyBytes = 16
+
 // Ticket represents a one-time connection token
 type Ticket struct {
 	TicketID  string
@@ -17,10 +20,20 @@ type Ticket struct {
 	ExpiresAt time.Time
 }
 
+// TicketStats is a point-in-time snapshot of ticket store activity
+type TicketStats struct {
+	Active    int    `json:"active"`
+	Generated uint64 `json:"generated_total"`
+	Exchanged uint64 `json:"exchanged_total"`
+	Expired   uint64 `json:"expired_total"`
+	Invalid   uint64 `json:"invalid_total"`
+}
+
 // TicketStore defines the interface for ticket management
 type TicketStore interface {
-	// Generate creates a new ticket for a specific user and task
-	Generate(userID string, taskID int64, ttl time.Duration) (*Ticket, error)
+	// Generate creates a new ticket for a specific user and task.
+	// entropyBytes controls the size of the random ticket ID (0 uses DefaultEntropyBytes).
+	Generate(userID string, taskID int64, ttl time.Duration, entropyBytes int) (*Ticket, error)
 
 	// Exchange atomically validates and burns (deletes) a ticket.
 	// Returns the ticket if valid, or an error if invalid/expired.
@@ -29,12 +42,21 @@ type TicketStore interface {
 	// StartCleanupLoop starts a background goroutine to remove expired tickets.
 	// Stops when context is cancelled.
 	StartCleanupLoop(ctx context.Context, interval time.Duration)
+
+	// Stats returns the active ticket count and lifetime counters
+	Stats() TicketStats
 }
 
 // InMemoryTicketStore implements TicketStore using a map and RWMutex
 type InMemoryTicketStore struct {
 	mu      sync.RWMutex
 	tickets map[string]Ticket
+
+	// Lifetime counters (guarded by mu)
+	generated uint64
+	exchanged uint64
+	expired   uint64
+	invalid   uint64
 }
 
 // NewInMemoryTicketStore creates a new instance
@@ -45,9 +67,15 @@ func NewInMemoryTicketStore() *InMemoryTicketStore {
 }
 
 // Generate creates a new ticket with cryptographic entropy
-func (s *InMemoryTicketStore) Generate(userID string, taskID int64, ttl time.Duration) (*Ticket, error) {
-	// Generate 16 bytes of entropy (128 bits)
-	bytes := make([]byte, 16)
+func (s *InMemoryTicketStore) Generate(userID string, taskID int64, ttl time.Duration, entropyBytes int) (*Ticket, error) {
+	if entropyBytes <= 0 {
+		entropyBytes = DefaultEntropyBytes
+	}
+	if entropyBytes < DefaultEntropyBytes {
+		return nil, fmt.Errorf("ticket entropy must be at least %d bytes", DefaultEntropyBytes)
+	}
+
+	bytes := make([]byte, entropyBytes)
 	if _, err := rand.Read(bytes); err != nil {
 		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
 	}
@@ -63,6 +91,7 @@ func (s *InMemoryTicketStore) Generate(userID string, taskID int64, ttl time.Dur
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.tickets[ticketID] = ticket
+	s.generated++
 
 	return &ticket, nil
 }
@@ -74,6 +103,7 @@ func (s *InMemoryTicketStore) Exchange(ticketID string) (*Ticket, error) {
 
 	ticket, exists := s.tickets[ticketID]
 	if !exists {
+		s.invalid++
 		return nil, fmt.Errorf("ticket not found or already consumed")
 	}
 
@@ -81,9 +111,11 @@ func (s *InMemoryTicketStore) Exchange(ticketID string) (*Ticket, error) {
 	delete(s.tickets, ticketID)
 
 	if time.Now().After(ticket.ExpiresAt) {
+		s.expired++
 		return nil, fmt.Errorf("ticket expired")
 	}
 
+	s.exchanged++
 	return &ticket, nil
 }
 
@@ -111,6 +143,21 @@ func (s *InMemoryTicketStore) cleanup() {
 	for id, ticket := range s.tickets {
 		if now.After(ticket.ExpiresAt) {
 			delete(s.tickets, id)
+			s.expired++
 		}
 	}
 }
+
+// Stats returns a snapshot of the store's counters
+func (s *InMemoryTicketStore) Stats() TicketStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return TicketStats{
+		Active:    len(s.tickets),
+		Generated: s.generated,
+		Exchanged: s.exchanged,
+		Expired:   s.expired,
+		Invalid:   s.invalid,
+	}
+}
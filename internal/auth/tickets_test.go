@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerate_EntropySize(t *testing.T) {
+	s := NewInMemoryTicketStore()
+
+	tests := []struct {
+		name         string
+		entropyBytes int
+		wantLen      int
+		wantErr      bool
+	}{
+		{"Default", 0, DefaultEntropyBytes * 2, false},
+		{"Larger", 32, 64, false},
+		{"Too Small", 8, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticket, err := s.Generate("admin", 1, 30*time.Second, tt.entropyBytes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(ticket.TicketID) != tt.wantLen {
+				t.Errorf("Generate() ticket length = %d, want %d", len(ticket.TicketID), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestExchange_Expired(t *testing.T) {
+	s := NewInMemoryTicketStore()
+
+	ticket, err := s.Generate("admin", 1, -time.Second, 0)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, err := s.Exchange(ticket.TicketID); err == nil {
+		t.Errorf("Exchange() expected error for expired ticket")
+	}
+	// Ticket must be burned even when expired
+	if _, err := s.Exchange(ticket.TicketID); err == nil {
+		t.Errorf("Exchange() expected error for consumed ticket")
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := NewInMemoryTicketStore()
+
+	valid, _ := s.Generate("admin", 1, time.Minute, 0)
+	expired, _ := s.Generate("admin", 1, -time.Second, 0)
+	s.Generate("admin", 2, time.Minute, 0)
+
+	s.Exchange(valid.TicketID)
+	s.Exchange(expired.TicketID)
+	s.Exchange("does-not-exist")
+
+	got := s.Stats()
+	want := TicketStats{Active: 1, Generated: 3, Exchanged: 1, Expired: 1, Invalid: 1}
+	if got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B SHA1 secret ("12345678901234567890"), truncated to 6 digits
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFCVectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP_Skew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, _ := TOTPCode(rfcSecret, now)
+
+	if !ValidateTOTP(rfcSecret, code, now.Add(TOTPPeriod)) {
+		t.Errorf("ValidateTOTP() should accept a code from the previous period")
+	}
+	if ValidateTOTP(rfcSecret, code, now.Add(3*TOTPPeriod)) {
+		t.Errorf("ValidateTOTP() should reject a code three periods old")
+	}
+	if ValidateTOTP(rfcSecret, "12345", now) {
+		t.Errorf("ValidateTOTP() should reject codes of the wrong length")
+	}
+}
+
+func TestMatchTOTP_Counter(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, _ := TOTPCode(rfcSecret, now)
+	want := uint64(1234567890 / 30)
+
+	if counter, ok := MatchTOTP(rfcSecret, code, now); !ok || counter != want {
+		t.Errorf("MatchTOTP() = %d, %v, want %d", counter, ok, want)
+	}
+	// Accepted a period later, the code still belongs to its own period
+	if counter, ok := MatchTOTP(rfcSecret, code, now.Add(TOTPPeriod)); !ok || counter != want {
+		t.Errorf("MatchTOTP() next period = %d, %v, want %d", counter, ok, want)
+	}
+	if _, ok := MatchTOTP(rfcSecret, "000000", now); ok {
+		t.Errorf("MatchTOTP() accepted a wrong code")
+	}
+}
+
+func TestGenerateTOTPSecret_RoundTrip(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error = %v", err)
+	}
+	now := time.Now()
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("TOTPCode() error = %v", err)
+	}
+	if !ValidateTOTP(secret, code, now) {
+		t.Errorf("ValidateTOTP() rejected a freshly generated code")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Dashboard Recorder", "admin", rfcSecret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Dashboard%20Recorder:admin?") {
+		t.Errorf("TOTPProvisioningURI() = %s, unexpected label", uri)
+	}
+	if !strings.Contains(uri, "secret="+rfcSecret) {
+		t.Errorf("TOTPProvisioningURI() = %s, missing secret", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("GenerateRecoveryCodes() returned %d codes, want %d", len(codes), RecoveryCodeCount)
+	}
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))+" ") {
+		t.Errorf("HashRecoveryCode() should ignore case, dashes and whitespace")
+	}
+}
@@ -3,17 +3,32 @@ package config
 import (
 	"bytes"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+)
+
+const (
+	// MaxTicketTTL bounds TICKET_TTL; tickets only need to survive the WebSocket handshake.
+	MaxTicketTTL = 5 * time.Minute
+	// MaxTicketEntropyBytes bounds TICKET_ENTROPY_BYTES to keep ticket IDs URL-friendly.
+	MaxTicketEntropyBytes = 64
 )
 
 type Config struct {
-	Port              string
-	HTTPPort          string
-	HTTPSPort         string
-	TZ                string
-	JWTSecret         string
+	Port      string
+	HTTPPort  string
+	HTTPSPort string
+	TZ        string
+	JWTSecret string
+	// Token signing: HS256 (JWT_SECRET) by default; RS256/ES256 sign with the private
+	// key and verify with the public key (derived from the private key when unset)
+	JWTAlgorithm      string
+	JWTPrivateKeyFile string
+	JWTPublicKeyFile  string
 	DatabasePath      string
 	PlaywrightPath    string
 	MaxFpsLimit       int
@@ -27,9 +42,207 @@ type Config struct {
 	TLSEmail          string
 	TLSDataDir        string
 	NtpServer         string
+
+	// Where the IdP sends the browser after end-session (optional)
+	OIDCPostLogoutRedirectURL string
+
+	// Where the login callback sends the browser with the app token ("" = BASE_PATH/login).
+	// A path, or an absolute URL whose host is in OIDCRedirectAllowedHosts.
+	OIDCPostLoginRedirect    string
+	OIDCRedirectAllowedHosts []string
+
+	// Path prefix the app is served under behind a reverse proxy ("" for root)
+	BasePath string
+
+	// OIDC flow cookies (state/nonce/PKCE verifier)
+	CookieSecure     bool
+	CookieSameSite   string // lax or none
+	CookieHostPrefix bool   // use the __Host- name prefix (HTTPS at root only)
+
+	// Retry schedule for provider discovery when the IdP is unreachable at startup
+	OIDCDiscoveryBackoff    time.Duration
+	OIDCDiscoveryMaxBackoff time.Duration
+
+	// Bound on each IdP round trip of the login callback (code exchange, key fetch),
+	// and how often a transport failure is retried
+	OIDCExchangeTimeout time.Duration
+	OIDCExchangeRetries int
+
+	// Navigation target policy (SSRF hardening beyond the private IP check)
+	TargetAllowedPorts   []int // empty allows any port
+	TargetAllowedDomains []string
+	TargetDeniedDomains  []string
+	// Re-check the resolved addresses of every request a page makes (redirects,
+	// frames, subresources), not only the target before navigation
+	TargetRecheckRequests bool
+	// Proxies tasks may route through ("host" or "host:port"); empty disables per-task proxies
+	TaskProxyAllowedHosts []string
+	// Whether the private address checks above still apply to targets reached through
+	// a task proxy. The proxy resolves the target, so the local verdict may not match
+	// where traffic goes; turning this off lets proxies reach names only they resolve.
+	TargetIPCheckViaProxy bool
+	// Hosts task watermark images may be loaded from ("host" or "host:port"); empty
+	// allows data URI watermarks only
+	WatermarkAllowedHosts []string
+
+	// Key material for secrets stored at rest (falls back to JWT secret when empty)
+	EncryptionKey string
+
+	// Interactive session tickets
+	TicketTTL          time.Duration
+	TicketEntropyBytes int
+
+	// Inbound events per second an interactive session may send (0 disables the
+	// limit) and the burst allowed on top; excess events are dropped
+	InteractiveEventRate  float64
+	InteractiveEventBurst int
+
+	// Fail startup instead of warning when the ffmpeg self-test fails
+	FFmpegStrict bool
+
+	// Server-wide libx264 preset/tune, used when a task leaves them empty
+	FFmpegPreset string
+	FFmpegTune   string
+
+	// Recording file layout and access control
+	RecordingFileMode    os.FileMode // 0 leaves ffmpeg/umask defaults untouched
+	RecordingFileGID     int         // -1 leaves group ownership untouched
+	RecordingsPerTaskDir bool
+
+	// Defaults for every operation of a recording's browser context (selectors, style
+	// injection, ...) and for its navigations (page loads, reloads, login redirects)
+	BrowserTimeout    time.Duration
+	NavigationTimeout time.Duration
+
+	// Screenshot capture limits for the recording loop
+	ScreenshotTimeout     time.Duration
+	ScreenshotMaxFailures int // consecutive failures before aborting, 0 disables
+	ScreenshotRetries     int // immediate retries of a transient failure before dropping the frame
+	// Consecutive failures after which the page is reloaded and a "page unavailable"
+	// placeholder is recorded instead of the frozen last frame (0 disables)
+	ScreenshotPlaceholderAfter int
+	// Frames buffered between capture and a stalled encoder before the oldest are
+	// dropped (0 writes to the encoder directly, so a stall stalls capture)
+	FrameBufferSize int
+
+	// Share of dropped frames above which a recording is flagged degraded (0 disables)
+	DegradedDropRatio float64
+
+	// Identical consecutive recordings of a task: off, flag, or link (hard link to the earlier file)
+	RecordingDedupe string
+
+	// Key that signs recording checksums (HMAC-SHA256), so a checksum can't be
+	// recomputed by whoever altered the file; empty stores unsigned checksums
+	RecordingSigningKey string
+
+	// Also write a fragmented MP4 copy while recording for /recordings/:id/live.mp4
+	LivePlayback bool
+
+	// record_on_change tasks: percent of the screen that must change before a new frame
+	// is written, and how often an unchanged frame is re-sent to keep playback seekable
+	ChangeThreshold float64
+	ChangeHeartbeat time.Duration
+
+	// Disk usage estimate heuristic (bits per pixel per frame at CRF 23)
+	EstimateBitsPerPixel float64
+
+	// Live preview frames older than this are treated as missing (0 disables)
+	PreviewFrameTTL time.Duration
+
+	// Playwright engine: chromium, firefox or webkit
+	BrowserEngine string
+
+	// Pre-warmed browser contexts kept ready for previews/recordings (0 creates on demand)
+	BrowserContextPoolSize int
+
+	// Preview endpoint limits
+	PreviewWidth    int
+	PreviewHeight   int
+	PreviewQuality  int
+	PreviewMaxBytes int // 0 disables the cap
+	// Previews of targets that aren't HTML pages: "reject" fails them with a clear
+	// error, "image" returns images as they are (other types are still rejected)
+	PreviewNonHTML string
+
+	// Previews capturing at once; others wait up to PreviewQueueTimeout, then get 503
+	PreviewConcurrency  int
+	PreviewQueueTimeout time.Duration
+
+	// HTTP server timeouts (WriteTimeout 0 disables the write deadline)
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// How long shutdown waits for in-flight requests and recording finalization
+	ShutdownTimeout time.Duration
+
+	// SQLite connection pool (WAL allows concurrent readers alongside the single writer);
+	// writers wait up to DBBusyTimeout for the lock instead of failing with "database is locked"
+	DBMaxOpenConns int
+	DBBusyTimeout  time.Duration
+
+	// Bootstrap account created when the users table is empty
+	AdminUsername string
+	AdminPassword string
+	AdminStrict   bool // refuse the admin/admin default when no password is supplied
+
+	// Routes besides the API: the web UI (web/dist) and the /recordings file mount.
+	// API-only deployments behind their own frontend and storage serving turn them off.
+	ServeFrontend         bool
+	ServeRecordingsStatic bool
+
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For is trusted for the client IP used by
+	// rate limiting and logs. Empty trusts no forwarded headers: the peer address is used.
+	TrustedProxies []string
+
+	// Content-Security-Policy tuning
+	CSPAllowInlineStyles bool
+	CSPConnectSrc        []string
+	CSPImgSrc            []string
+
+	// Upper bound for per-task custom CSS (bytes), injected on every recording
+	MaxCustomCSSLength int
+
+	// Per-recording capture budget in pixels/second (width*height*fps), 0 = unlimited
+	MaxPixelRate int
+
+	// Upper bound on non-deleted tasks, 0 = unlimited
+	MaxTasks int
+
+	// Total file size of one bulk recording download (ZIP), 0 = unlimited
+	MaxBulkDownloadBytes int
+
+	// Concurrency caps (0 = unlimited); requests over the cap get 503.
+	// Previews get their own, stricter cap since each opens a browser context;
+	// it bounds previews in flight, i.e. capturing plus queued (PREVIEW_CONCURRENCY).
+	MaxConcurrentRequests int
+	MaxConcurrentPreviews int
+
+	// Recording alerts are POSTed here as JSON (empty only logs them)
+	NotifyWebhookURL string
+
+	// Debug only: log the names (never values) of the cookies a recording sends to its
+	// target at navigation and after login, to diagnose authentication failures
+	DebugCookieNames bool
+
+	// Shell commands run before a recording starts and after it is finalized, with the
+	// recording's metadata in RECORDER_* environment variables. They run with the
+	// server's privileges, so they are refused unless ALLOW_HOOKS is set.
+	AllowHooks        bool
+	PreRecordingHook  string
+	PostRecordingHook string
+	HookTimeout       time.Duration
 }
 
 func Load() *Config {
+	// Optional settings file; environment variables still take precedence
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			panic(fmt.Sprintf("CRITICAL ERROR: failed to load CONFIG_FILE: %v", err))
+		}
+		fileValues = values
+	}
+
 	jwtSecret := getEnvOrFile("JWT_SECRET", "")
 	if jwtSecret == "" {
 		// CRITICAL SECURITY REQUIREMENT: Fail fast if no secret
@@ -42,6 +255,9 @@ func Load() *Config {
 		HTTPSPort:         getEnv("HTTPS_PORT", "8443"),
 		TZ:                getEnv("TZ", "UTC"),
 		JWTSecret:         jwtSecret,
+		JWTAlgorithm:      strings.ToUpper(strings.TrimSpace(getEnv("JWT_ALGORITHM", "HS256"))),
+		JWTPrivateKeyFile: getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTPublicKeyFile:  getEnv("JWT_PUBLIC_KEY_FILE", ""),
 		DatabasePath:      getEnv("DATABASE_PATH", "./data/app.db"),
 		PlaywrightPath:    getEnv("PLAYWRIGHT_PATH", ""),
 		MaxFpsLimit:       getEnvInt("APP_MAX_FPS_LIMIT", 60),
@@ -55,6 +271,128 @@ func Load() *Config {
 		TLSEmail:          getEnv("TLS_EMAIL", ""),
 		TLSDataDir:        getEnv("TLS_DATA_DIR", "/app/data/certs"),
 		NtpServer:         getEnv("NTP_SERVER", "ntp.nict.jp"),
+
+		OIDCPostLogoutRedirectURL: getEnv("OIDC_POST_LOGOUT_REDIRECT_URL", ""),
+
+		OIDCPostLoginRedirect:    strings.TrimSpace(getEnv("OIDC_POST_LOGIN_REDIRECT", "")),
+		OIDCRedirectAllowedHosts: normalizeList(getEnv("OIDC_REDIRECT_ALLOWED_HOSTS", "")),
+
+		BasePath: normalizeBasePath(getEnv("BASE_PATH", "")),
+
+		CookieSecure:     getEnvBool("COOKIE_SECURE", true),
+		CookieSameSite:   strings.ToLower(strings.TrimSpace(getEnv("COOKIE_SAMESITE", "lax"))),
+		CookieHostPrefix: getEnvBool("COOKIE_HOST_PREFIX", false),
+
+		OIDCDiscoveryBackoff:    getEnvDuration("OIDC_DISCOVERY_BACKOFF", 2*time.Second),
+		OIDCDiscoveryMaxBackoff: getEnvDuration("OIDC_DISCOVERY_MAX_BACKOFF", 5*time.Minute),
+
+		OIDCExchangeTimeout: getEnvDuration("OIDC_EXCHANGE_TIMEOUT", 10*time.Second),
+		OIDCExchangeRetries: getEnvInt("OIDC_EXCHANGE_RETRIES", 1),
+
+		TargetAllowedPorts:    parsePortList(getEnv("TARGET_ALLOWED_PORTS", "80,443")),
+		TargetAllowedDomains:  normalizeList(getEnv("TARGET_ALLOWED_DOMAINS", "")),
+		TargetDeniedDomains:   normalizeList(getEnv("TARGET_DENIED_DOMAINS", "")),
+		TargetRecheckRequests: getEnvBool("TARGET_RECHECK_REQUESTS", true),
+		TaskProxyAllowedHosts: normalizeList(getEnv("TASK_PROXY_ALLOWED_HOSTS", "")),
+		TargetIPCheckViaProxy: getEnvBool("TARGET_IP_CHECK_VIA_PROXY", true),
+		WatermarkAllowedHosts: normalizeList(getEnv("WATERMARK_ALLOWED_HOSTS", "")),
+
+		EncryptionKey: getEnvOrFile("ENCRYPTION_KEY", ""),
+
+		TicketTTL:          getEnvDuration("TICKET_TTL", 30*time.Second),
+		TicketEntropyBytes: getEnvInt("TICKET_ENTROPY_BYTES", 16),
+
+		InteractiveEventRate:  getEnvFloat("INTERACTIVE_EVENT_RATE", 20),
+		InteractiveEventBurst: getEnvInt("INTERACTIVE_EVENT_BURST", 40),
+
+		FFmpegStrict: getEnvBool("FFMPEG_STRICT", false),
+
+		FFmpegPreset: strings.ToLower(strings.TrimSpace(getEnv("FFMPEG_PRESET", "ultrafast"))),
+		FFmpegTune:   strings.ToLower(strings.TrimSpace(getEnv("FFMPEG_TUNE", ""))),
+
+		RecordingFileMode:    getEnvFileMode("RECORDING_FILE_MODE", 0),
+		RecordingFileGID:     getEnvInt("RECORDING_FILE_GID", -1),
+		RecordingsPerTaskDir: getEnvBool("RECORDINGS_PER_TASK_DIR", false),
+
+		BrowserTimeout:    getEnvDuration("BROWSER_TIMEOUT", 30*time.Second),
+		NavigationTimeout: getEnvDuration("NAVIGATION_TIMEOUT", 60*time.Second),
+
+		ScreenshotTimeout:     getEnvDuration("SCREENSHOT_TIMEOUT", 5*time.Second),
+		ScreenshotMaxFailures: getEnvInt("SCREENSHOT_MAX_FAILURES", 30),
+		ScreenshotRetries:     getEnvInt("SCREENSHOT_RETRIES", 1),
+
+		ScreenshotPlaceholderAfter: getEnvInt("SCREENSHOT_PLACEHOLDER_AFTER", 5),
+		FrameBufferSize:            getEnvInt("FRAME_BUFFER_SIZE", 30),
+
+		DegradedDropRatio: getEnvFloat("DEGRADED_DROP_RATIO", 0.05),
+
+		RecordingDedupe: strings.ToLower(strings.TrimSpace(getEnv("RECORDING_DEDUPE", "off"))),
+
+		RecordingSigningKey: getEnvOrFile("RECORDING_SIGNING_KEY", ""),
+
+		LivePlayback: getEnvBool("LIVE_PLAYBACK", true),
+
+		ChangeThreshold: getEnvFloat("CHANGE_THRESHOLD", 0),
+		ChangeHeartbeat: getEnvDuration("CHANGE_HEARTBEAT", 2*time.Second),
+
+		EstimateBitsPerPixel: getEnvFloat("ESTIMATE_BITS_PER_PIXEL", 0.02),
+
+		PreviewFrameTTL: getEnvDuration("PREVIEW_FRAME_TTL", 30*time.Second),
+
+		BrowserEngine: strings.ToLower(strings.TrimSpace(getEnv("BROWSER_ENGINE", "chromium"))),
+
+		BrowserContextPoolSize: getEnvInt("BROWSER_CONTEXT_POOL_SIZE", 0),
+
+		PreviewWidth:    getEnvInt("PREVIEW_WIDTH", 1280),
+		PreviewHeight:   getEnvInt("PREVIEW_HEIGHT", 720),
+		PreviewQuality:  getEnvInt("PREVIEW_QUALITY", 80),
+		PreviewMaxBytes: getEnvInt("PREVIEW_MAX_BYTES", 2*1024*1024),
+		PreviewNonHTML:  strings.ToLower(strings.TrimSpace(getEnv("PREVIEW_NON_HTML", "reject"))),
+
+		PreviewConcurrency:  getEnvInt("PREVIEW_CONCURRENCY", 1),
+		PreviewQueueTimeout: getEnvDuration("PREVIEW_QUEUE_TIMEOUT", 10*time.Second),
+
+		ReadTimeout:  getEnvDuration("READ_TIMEOUT", 10*time.Second),
+		WriteTimeout: getEnvDuration("WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:  getEnvDuration("IDLE_TIMEOUT", 120*time.Second),
+
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 10*time.Second),
+
+		DBMaxOpenConns: getEnvInt("DB_MAX_OPEN_CONNS", 4),
+		DBBusyTimeout:  getEnvDuration("DB_BUSY_TIMEOUT", 5*time.Second),
+
+		AdminUsername: strings.TrimSpace(getEnv("ADMIN_USERNAME", "admin")),
+		AdminPassword: getEnvOrFile("ADMIN_PASSWORD", ""),
+		AdminStrict:   getEnvBool("ADMIN_STRICT", false),
+
+		ServeFrontend:         getEnvBool("SERVE_FRONTEND", true),
+		ServeRecordingsStatic: getEnvBool("SERVE_RECORDINGS_STATIC", true),
+
+		TrustedProxies: normalizeList(getEnv("TRUSTED_PROXIES", "")),
+
+		CSPAllowInlineStyles: getEnvBool("CSP_ALLOW_INLINE_STYLES", true),
+		CSPConnectSrc:        normalizeList(getEnv("CSP_CONNECT_SRC", "")),
+		CSPImgSrc:            normalizeList(getEnv("CSP_IMG_SRC", "")),
+
+		MaxCustomCSSLength: getEnvInt("MAX_CUSTOM_CSS_LENGTH", 64*1024),
+
+		MaxPixelRate: getEnvInt("MAX_PIXEL_RATE", 0),
+
+		MaxTasks: getEnvInt("MAX_TASKS", 0),
+
+		MaxBulkDownloadBytes: getEnvInt("MAX_BULK_DOWNLOAD_BYTES", 10*1024*1024*1024),
+
+		MaxConcurrentRequests: getEnvInt("MAX_CONCURRENT_REQUESTS", 256),
+		MaxConcurrentPreviews: getEnvInt("MAX_CONCURRENT_PREVIEWS", 4),
+
+		NotifyWebhookURL: strings.TrimSpace(getEnvOrFile("NOTIFY_WEBHOOK_URL", "")),
+
+		DebugCookieNames: getEnvBool("DEBUG_COOKIE_NAMES", false),
+
+		AllowHooks:        getEnvBool("ALLOW_HOOKS", false),
+		PreRecordingHook:  strings.TrimSpace(getEnv("PRE_RECORDING_HOOK", "")),
+		PostRecordingHook: strings.TrimSpace(getEnv("POST_RECORDING_HOOK", "")),
+		HookTimeout:       getEnvDuration("HOOK_TIMEOUT", time.Minute),
 	}
 }
 
@@ -75,11 +413,207 @@ func (c *Config) Validate() error {
 		}
 		os.Remove(testFile)
 	}
+
+	switch c.JWTAlgorithm {
+	case "HS256":
+	case "RS256", "ES256":
+		if c.JWTPrivateKeyFile == "" {
+			return fmt.Errorf("JWT_PRIVATE_KEY_FILE is required for JWT_ALGORITHM=%s", c.JWTAlgorithm)
+		}
+	default:
+		return fmt.Errorf("JWT_ALGORITHM must be HS256, RS256 or ES256, got %q", c.JWTAlgorithm)
+	}
+
+	// Ticket settings: reject absurd windows that would turn one-time tickets into long-lived credentials
+	if c.TicketTTL <= 0 || c.TicketTTL > MaxTicketTTL {
+		return fmt.Errorf("TICKET_TTL must be between 1s and %s, got %s", MaxTicketTTL, c.TicketTTL)
+	}
+	if c.TicketEntropyBytes < 16 || c.TicketEntropyBytes > MaxTicketEntropyBytes {
+		return fmt.Errorf("TICKET_ENTROPY_BYTES must be between 16 and %d, got %d", MaxTicketEntropyBytes, c.TicketEntropyBytes)
+	}
+	if c.InteractiveEventRate < 0 {
+		return fmt.Errorf("INTERACTIVE_EVENT_RATE must not be negative (0 disables the limit), got %g", c.InteractiveEventRate)
+	}
+	if c.InteractiveEventRate > 0 && c.InteractiveEventBurst < 1 {
+		return fmt.Errorf("INTERACTIVE_EVENT_BURST must be at least 1, got %d", c.InteractiveEventBurst)
+	}
+
+	// OIDC cookies: Strict would drop them on the IdP's cross-site redirect back to the callback
+	switch c.CookieSameSite {
+	case "lax":
+	case "none":
+		if !c.CookieSecure {
+			return fmt.Errorf("COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+		}
+	default:
+		return fmt.Errorf("COOKIE_SAMESITE must be lax or none, got %q", c.CookieSameSite)
+	}
+	if c.CookieHostPrefix && (!c.CookieSecure || c.BasePath != "") {
+		return fmt.Errorf("COOKIE_HOST_PREFIX requires COOKIE_SECURE=true and an empty BASE_PATH")
+	}
+
+	if err := c.validatePostLoginRedirect(); err != nil {
+		return err
+	}
+
+	if c.OIDCDiscoveryBackoff <= 0 {
+		return fmt.Errorf("OIDC_DISCOVERY_BACKOFF must be positive, got %s", c.OIDCDiscoveryBackoff)
+	}
+	if c.OIDCDiscoveryMaxBackoff < c.OIDCDiscoveryBackoff {
+		return fmt.Errorf("OIDC_DISCOVERY_MAX_BACKOFF (%s) must be at least OIDC_DISCOVERY_BACKOFF (%s)", c.OIDCDiscoveryMaxBackoff, c.OIDCDiscoveryBackoff)
+	}
+	if c.OIDCExchangeTimeout <= 0 || c.OIDCExchangeTimeout > 2*time.Minute {
+		return fmt.Errorf("OIDC_EXCHANGE_TIMEOUT must be between 0 and 2m, got %s", c.OIDCExchangeTimeout)
+	}
+	if c.OIDCExchangeRetries < 0 || c.OIDCExchangeRetries > 5 {
+		return fmt.Errorf("OIDC_EXCHANGE_RETRIES must be between 0 and 5, got %d", c.OIDCExchangeRetries)
+	}
+
+	if c.RecordingFileMode&^os.ModePerm != 0 {
+		return fmt.Errorf("RECORDING_FILE_MODE must be a permission mode like 0640, got %o", c.RecordingFileMode)
+	}
+
+	if c.BrowserTimeout < time.Second || c.BrowserTimeout > 10*time.Minute {
+		return fmt.Errorf("BROWSER_TIMEOUT must be between 1s and 10m, got %s", c.BrowserTimeout)
+	}
+	if c.NavigationTimeout < time.Second || c.NavigationTimeout > 10*time.Minute {
+		return fmt.Errorf("NAVIGATION_TIMEOUT must be between 1s and 10m, got %s", c.NavigationTimeout)
+	}
+
+	if c.ScreenshotTimeout <= 0 {
+		return fmt.Errorf("SCREENSHOT_TIMEOUT must be positive, got %s", c.ScreenshotTimeout)
+	}
+	if c.ScreenshotMaxFailures < 0 {
+		return fmt.Errorf("SCREENSHOT_MAX_FAILURES must not be negative, got %d", c.ScreenshotMaxFailures)
+	}
+	if c.ScreenshotRetries < 0 || c.ScreenshotRetries > 5 {
+		return fmt.Errorf("SCREENSHOT_RETRIES must be between 0 and 5, got %d", c.ScreenshotRetries)
+	}
+	if c.ScreenshotPlaceholderAfter < 0 {
+		return fmt.Errorf("SCREENSHOT_PLACEHOLDER_AFTER must not be negative, got %d", c.ScreenshotPlaceholderAfter)
+	}
+	if c.FrameBufferSize < 0 || c.FrameBufferSize > 1000 {
+		return fmt.Errorf("FRAME_BUFFER_SIZE must be between 0 and 1000, got %d", c.FrameBufferSize)
+	}
+	if c.DegradedDropRatio < 0 || c.DegradedDropRatio > 1 {
+		return fmt.Errorf("DEGRADED_DROP_RATIO must be between 0 and 1, got %g", c.DegradedDropRatio)
+	}
+	switch c.RecordingDedupe {
+	case "off", "flag", "link":
+	default:
+		return fmt.Errorf("RECORDING_DEDUPE must be off, flag or link, got %q", c.RecordingDedupe)
+	}
+	if c.RecordingSigningKey != "" && len(c.RecordingSigningKey) < 32 {
+		return fmt.Errorf("RECORDING_SIGNING_KEY must be at least 32 characters")
+	}
+	if c.ChangeThreshold < 0 || c.ChangeThreshold > 100 {
+		return fmt.Errorf("CHANGE_THRESHOLD must be between 0 and 100 (percent of the screen), got %g", c.ChangeThreshold)
+	}
+	if c.ChangeHeartbeat < time.Second || c.ChangeHeartbeat > time.Minute {
+		return fmt.Errorf("CHANGE_HEARTBEAT must be between 1s and 1m, got %s", c.ChangeHeartbeat)
+	}
+
+	switch c.BrowserEngine {
+	case "chromium", "firefox", "webkit":
+	default:
+		return fmt.Errorf("BROWSER_ENGINE must be chromium, firefox or webkit, got %q", c.BrowserEngine)
+	}
+	if c.BrowserContextPoolSize < 0 || c.BrowserContextPoolSize > 8 {
+		return fmt.Errorf("BROWSER_CONTEXT_POOL_SIZE must be between 0 and 8, got %d", c.BrowserContextPoolSize)
+	}
+
+	if c.PreviewWidth < 320 || c.PreviewWidth > 1920 || c.PreviewHeight < 240 || c.PreviewHeight > 1080 {
+		return fmt.Errorf("PREVIEW_WIDTH/PREVIEW_HEIGHT must be within 320x240 and 1920x1080, got %dx%d", c.PreviewWidth, c.PreviewHeight)
+	}
+	if c.PreviewQuality < 30 || c.PreviewQuality > 95 {
+		return fmt.Errorf("PREVIEW_QUALITY must be between 30 and 95, got %d", c.PreviewQuality)
+	}
+	if c.PreviewMaxBytes < 0 {
+		return fmt.Errorf("PREVIEW_MAX_BYTES must not be negative, got %d", c.PreviewMaxBytes)
+	}
+	if c.PreviewNonHTML != "reject" && c.PreviewNonHTML != "image" {
+		return fmt.Errorf("PREVIEW_NON_HTML must be reject or image, got %q", c.PreviewNonHTML)
+	}
+	if c.PreviewConcurrency < 1 {
+		return fmt.Errorf("PREVIEW_CONCURRENCY must be at least 1, got %d", c.PreviewConcurrency)
+	}
+	if c.PreviewQueueTimeout < 0 || c.PreviewQueueTimeout > time.Minute {
+		return fmt.Errorf("PREVIEW_QUEUE_TIMEOUT must be between 0 and 1m, got %s", c.PreviewQueueTimeout)
+	}
+
+	// Server timeouts: read/idle must stay bounded for Slowloris mitigation
+	if c.ReadTimeout < time.Second || c.ReadTimeout > 10*time.Minute {
+		return fmt.Errorf("READ_TIMEOUT must be between 1s and 10m, got %s", c.ReadTimeout)
+	}
+	if c.WriteTimeout != 0 && c.WriteTimeout < time.Second {
+		return fmt.Errorf("WRITE_TIMEOUT must be 0 (disabled) or at least 1s, got %s", c.WriteTimeout)
+	}
+	if c.IdleTimeout < time.Second || c.IdleTimeout > time.Hour {
+		return fmt.Errorf("IDLE_TIMEOUT must be between 1s and 1h, got %s", c.IdleTimeout)
+	}
+	if c.ShutdownTimeout < time.Second || c.ShutdownTimeout > 10*time.Minute {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT must be between 1s and 10m, got %s", c.ShutdownTimeout)
+	}
+
+	if c.DBMaxOpenConns < 1 {
+		return fmt.Errorf("DB_MAX_OPEN_CONNS must be at least 1, got %d", c.DBMaxOpenConns)
+	}
+	if c.DBBusyTimeout < 0 || c.DBBusyTimeout > time.Minute {
+		return fmt.Errorf("DB_BUSY_TIMEOUT must be between 0 and 1m, got %s", c.DBBusyTimeout)
+	}
+
+	if c.AdminUsername == "" || strings.ContainsAny(c.AdminUsername, " \t\r\n") {
+		return fmt.Errorf("ADMIN_USERNAME must be non-empty and contain no whitespace")
+	}
+
+	if c.MaxCustomCSSLength < 1 {
+		return fmt.Errorf("MAX_CUSTOM_CSS_LENGTH must be positive, got %d", c.MaxCustomCSSLength)
+	}
+	if c.MaxPixelRate < 0 {
+		return fmt.Errorf("MAX_PIXEL_RATE must not be negative, got %d", c.MaxPixelRate)
+	}
+	if c.MaxTasks < 0 {
+		return fmt.Errorf("MAX_TASKS must not be negative, got %d", c.MaxTasks)
+	}
+	if c.MaxBulkDownloadBytes < 0 {
+		return fmt.Errorf("MAX_BULK_DOWNLOAD_BYTES must not be negative, got %d", c.MaxBulkDownloadBytes)
+	}
+	if c.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_REQUESTS must not be negative, got %d", c.MaxConcurrentRequests)
+	}
+	if c.MaxConcurrentPreviews < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_PREVIEWS must not be negative, got %d", c.MaxConcurrentPreviews)
+	}
+
+	if c.NotifyWebhookURL != "" {
+		u, err := url.Parse(c.NotifyWebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("NOTIFY_WEBHOOK_URL must be an absolute http(s) URL")
+		}
+	}
+
+	if (c.PreRecordingHook != "" || c.PostRecordingHook != "") && !c.AllowHooks {
+		return fmt.Errorf("PRE_RECORDING_HOOK/POST_RECORDING_HOOK are set but ALLOW_HOOKS is not enabled")
+	}
+	if c.HookTimeout < time.Second || c.HookTimeout > time.Hour {
+		return fmt.Errorf("HOOK_TIMEOUT must be between 1s and 1h, got %s", c.HookTimeout)
+	}
+
+	if _, err := ParseTrustedProxies(c.TrustedProxies); err != nil {
+		return fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+
+	// CSP sources are spliced into a header; reject anything that could add directives
+	for _, src := range append(append([]string{}, c.CSPConnectSrc...), c.CSPImgSrc...) {
+		if strings.ContainsAny(src, " ;,'\"\r\n") {
+			return fmt.Errorf("invalid CSP source %q in CSP_CONNECT_SRC/CSP_IMG_SRC", src)
+		}
+	}
 	return nil
 }
 
 func getEnv(key, defaultVal string) string {
-	if v, ok := os.LookupEnv(key); ok {
+	if v, ok := lookupEnv(key); ok {
 		return v
 	}
 	return defaultVal
@@ -89,7 +623,7 @@ func getEnv(key, defaultVal string) string {
 func getEnvOrFile(key, defaultVal string) string {
 	// 1. Try _FILE variant first (Docker Secrets preferred)
 	fileKey := key + "_FILE"
-	if filePath, ok := os.LookupEnv(fileKey); ok && filePath != "" {
+	if filePath, ok := lookupEnv(fileKey); ok && filePath != "" {
 		content, err := os.ReadFile(filePath)
 		if err == nil {
 			// Trim whitespace/newlines from file content
@@ -101,14 +635,14 @@ func getEnvOrFile(key, defaultVal string) string {
 	}
 
 	// 2. Fallback to direct env var
-	if v, ok := os.LookupEnv(key); ok {
+	if v, ok := lookupEnv(key); ok {
 		return v
 	}
 	return defaultVal
 }
 
 func getEnvInt(key string, defaultVal int) int {
-	v := os.Getenv(key)
+	v, _ := lookupEnv(key)
 	if v == "" {
 		return defaultVal
 	}
@@ -119,6 +653,58 @@ func getEnvInt(key string, defaultVal int) int {
 	return i
 }
 
+func getEnvFloat(key string, defaultVal float64) float64 {
+	v, _ := lookupEnv(key)
+	if v == "" {
+		return defaultVal
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return defaultVal
+	}
+	return f
+}
+
+func getEnvBool(key string, defaultVal bool) bool {
+	v, _ := lookupEnv(key)
+	if v == "" {
+		return defaultVal
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return defaultVal
+	}
+	return b
+}
+
+// getEnvFileMode parses an octal permission string such as "0640"
+func getEnvFileMode(key string, defaultVal os.FileMode) os.FileMode {
+	v, _ := lookupEnv(key)
+	if v == "" {
+		return defaultVal
+	}
+	m, err := strconv.ParseUint(v, 8, 32)
+	if err != nil {
+		return defaultVal
+	}
+	return os.FileMode(m)
+}
+
+// getEnvDuration parses values like "30s" or "2m". Bare integers are treated as seconds.
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	v, _ := lookupEnv(key)
+	if v == "" {
+		return defaultVal
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d
+	}
+	if i, err := strconv.Atoi(v); err == nil {
+		return time.Duration(i) * time.Second
+	}
+	return defaultVal
+}
+
 func normalizeEmailList(input string) []string {
 	if input == "" {
 		return nil
@@ -135,6 +721,95 @@ func normalizeEmailList(input string) []string {
 	return result
 }
 
+// normalizeList splits a comma-separated list, lowercasing and dropping empty entries
+func normalizeList(input string) []string {
+	var result []string
+	for _, p := range strings.Split(input, ",") {
+		if v := strings.ToLower(strings.TrimSpace(p)); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// ParseTrustedProxies parses TRUSTED_PROXIES entries; a bare IP is a single-address range
+func ParseTrustedProxies(entries []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP or CIDR %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP or CIDR %q", entry)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// parsePortList parses "80,443". "*" (or empty) allows any port. Invalid entries are skipped.
+func parsePortList(input string) []int {
+	var ports []int
+	for _, p := range normalizeList(input) {
+		if p == "*" {
+			return nil
+		}
+		port, err := strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			continue
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// validatePostLoginRedirect rejects OIDC_POST_LOGIN_REDIRECT values that would make the
+// login callback an open redirect: absolute URLs must point at an allowlisted host
+func (c *Config) validatePostLoginRedirect() error {
+	target := c.OIDCPostLoginRedirect
+	if target == "" {
+		return nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("OIDC_POST_LOGIN_REDIRECT is not a valid URL: %w", err)
+	}
+	if u.Scheme == "" && u.Host == "" {
+		if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+			return fmt.Errorf("OIDC_POST_LOGIN_REDIRECT must be an absolute path or URL, got %q", target)
+		}
+		return nil
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("OIDC_POST_LOGIN_REDIRECT must use http or https, got %q", target)
+	}
+	for _, host := range c.OIDCRedirectAllowedHosts {
+		if strings.EqualFold(u.Host, host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("OIDC_POST_LOGIN_REDIRECT host %q is not in OIDC_REDIRECT_ALLOWED_HOSTS", u.Host)
+}
+
+// normalizeBasePath turns "app/", "/app" or "/app/" into "/app"; "" and "/" mean root
+func normalizeBasePath(input string) string {
+	p := strings.Trim(strings.TrimSpace(input), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
 func normalizeScopes(input string) []string {
 	parts := strings.Fields(input) // Handles spaces better than Split
 	if len(parts) == 0 {
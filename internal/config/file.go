@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileValues holds settings read from CONFIG_FILE, keyed by environment variable name
+var fileValues map[string]string
+
+// lookupEnv returns the environment variable if set, otherwise the CONFIG_FILE value
+func lookupEnv(key string) (string, bool) {
+	if v, ok := os.LookupEnv(key); ok {
+		return v, true
+	}
+	v, ok := fileValues[key]
+	return v, ok
+}
+
+// readConfigFile reads a flat YAML mapping of settings. Keys are the environment
+// variable names (case-insensitive, so "max_tasks" sets MAX_TASKS); lists are
+// joined with commas to match the comma-separated env format, e.g.
+//
+//	http_port: 8080
+//	oidc_allowed_emails: [alice@example.com, bob@example.com]
+//	jwt_secret_file: /run/secrets/jwt
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, v := range raw {
+		s, err := configFileValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+		values[strings.ToUpper(strings.TrimSpace(key))] = s
+	}
+	return values, nil
+}
+
+// configFileValue renders a YAML scalar or list of scalars as its env var form
+func configFileValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(val), nil
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			if _, nested := item.([]interface{}); nested {
+				return "", fmt.Errorf("nested lists are not supported")
+			}
+			s, err := configFileValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T (use a scalar or a list)", v)
+	}
+}
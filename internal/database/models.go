@@ -9,13 +9,32 @@ import (
 	"time"
 )
 
+type OidcSession struct {
+	Username     string
+	RefreshToken string
+	UpdatedAt    time.Time
+}
+
 type Recording struct {
-	ID        int64
-	TaskID    int64
-	Status    string
-	StartTime time.Time
-	EndTime   sql.NullTime
-	FilePath  string
+	ID                int64
+	TaskID            int64
+	Status            string
+	StartTime         time.Time
+	EndTime           sql.NullTime
+	FilePath          string
+	IsProtected       bool
+	DroppedFrames     int64
+	IsDegraded        bool
+	ContentHash       string
+	DuplicateOf       sql.NullInt64
+	Note              string
+	Tags              string
+	Viewport          string
+	Region            string
+	Checksum          string
+	ChecksumSignature string
+	RunID             string
+	TranscodeChecksum string
 }
 
 type Task struct {
@@ -30,12 +49,53 @@ type Task struct {
 	Crf               int64
 	TimeOverlay       bool
 	TimeOverlayConfig string
+	SortOrder         int64
+	MaxRecordings     int64
+	CaptureConsole    bool
+	EncoderPreset     string
+	EncoderTune       string
+	CaptureQuality    int64
+	NotifySizeBytes   int64
+	Referer           string
+	JavaScriptEnabled bool
+	Offline           bool
+	Viewports         string
+	RecordOnChange    bool
+	CaptureFormat     string
+	BlockedResources  string
+	StartDelayMs      int64
+	CaptureMilestones bool
+	OutputDir         string
+	ProxyUrl          string
+	Regions           string
+	Keepalive         string
+	Watermark         string
 	CreatedAt         time.Time
 }
 
+type TaskCredential struct {
+	TaskID           int64
+	Username         string
+	Password         string
+	UsernameSelector string
+	PasswordSelector string
+	SubmitSelector   string
+	UpdatedAt        time.Time
+}
+
 type User struct {
-	ID           int64
-	Username     string
-	PasswordHash string
-	CreatedAt    time.Time
+	ID                 int64
+	Username           string
+	PasswordHash       string
+	MustChangePassword bool
+	CreatedAt          time.Time
+}
+
+type UserTotp struct {
+	Username      string
+	Secret        string
+	Enabled       bool
+	RecoveryCodes string
+	LastCounter   int64
+	UpdatedAt     time.Time
 }
@@ -11,6 +11,73 @@ import (
 	"time"
 )
 
+const acceptUserTOTPCounter = `-- name: AcceptUserTOTPCounter :execrows
+UPDATE user_totp SET last_counter = ?, updated_at = CURRENT_TIMESTAMP WHERE username = ? AND last_counter < ?
+`
+
+type AcceptUserTOTPCounterParams struct {
+	LastCounter int64
+	Username    string
+	Counter     int64
+}
+
+func (q *Queries) AcceptUserTOTPCounter(ctx context.Context, arg AcceptUserTOTPCounterParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, acceptUserTOTPCounter, arg.LastCounter, arg.Username, arg.Counter)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const copyRecording = `-- name: CopyRecording :one
+INSERT INTO recordings (task_id, status, start_time, end_time, file_path, viewport, region, run_id, note, tags)
+SELECT task_id, status, start_time, end_time, ?, viewport, region, run_id, note, tags FROM recordings WHERE id = ?
+RETURNING id, task_id, status, start_time, end_time, file_path, is_protected, dropped_frames, is_degraded, content_hash, duplicate_of, note, tags, viewport, region, checksum, checksum_signature, run_id, transcode_checksum
+`
+
+type CopyRecordingParams struct {
+	FilePath string
+	ID       int64
+}
+
+func (q *Queries) CopyRecording(ctx context.Context, arg CopyRecordingParams) (Recording, error) {
+	row := q.db.QueryRowContext(ctx, copyRecording, arg.FilePath, arg.ID)
+	var i Recording
+	err := row.Scan(
+		&i.ID,
+		&i.TaskID,
+		&i.Status,
+		&i.StartTime,
+		&i.EndTime,
+		&i.FilePath,
+		&i.IsProtected,
+		&i.DroppedFrames,
+		&i.IsDegraded,
+		&i.ContentHash,
+		&i.DuplicateOf,
+		&i.Note,
+		&i.Tags,
+		&i.Viewport,
+		&i.Region,
+		&i.Checksum,
+		&i.ChecksumSignature,
+		&i.RunID,
+		&i.TranscodeChecksum,
+	)
+	return i, err
+}
+
+const countTasks = `-- name: CountTasks :one
+SELECT COUNT(*) FROM tasks WHERE is_deleted = 0
+`
+
+func (q *Queries) CountTasks(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTasks)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countUsers = `-- name: CountUsers :one
 SELECT COUNT(*) FROM users
 `
@@ -23,18 +90,28 @@ func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
 }
 
 const createRecording = `-- name: CreateRecording :one
-INSERT INTO recordings (task_id, status, file_path, start_time) 
-VALUES (?, ?, ?, CURRENT_TIMESTAMP) RETURNING id, task_id, status, start_time, end_time, file_path
+INSERT INTO recordings (task_id, status, file_path, viewport, region, run_id, start_time) 
+VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP) RETURNING id, task_id, status, start_time, end_time, file_path, is_protected, dropped_frames, is_degraded, content_hash, duplicate_of, note, tags, viewport, region, checksum, checksum_signature, run_id, transcode_checksum
 `
 
 type CreateRecordingParams struct {
 	TaskID   int64
 	Status   string
 	FilePath string
+	Viewport string
+	Region   string
+	RunID    string
 }
 
 func (q *Queries) CreateRecording(ctx context.Context, arg CreateRecordingParams) (Recording, error) {
-	row := q.db.QueryRowContext(ctx, createRecording, arg.TaskID, arg.Status, arg.FilePath)
+	row := q.db.QueryRowContext(ctx, createRecording,
+		arg.TaskID,
+		arg.Status,
+		arg.FilePath,
+		arg.Viewport,
+		arg.Region,
+		arg.RunID,
+	)
 	var i Recording
 	err := row.Scan(
 		&i.ID,
@@ -43,12 +120,25 @@ func (q *Queries) CreateRecording(ctx context.Context, arg CreateRecordingParams
 		&i.StartTime,
 		&i.EndTime,
 		&i.FilePath,
+		&i.IsProtected,
+		&i.DroppedFrames,
+		&i.IsDegraded,
+		&i.ContentHash,
+		&i.DuplicateOf,
+		&i.Note,
+		&i.Tags,
+		&i.Viewport,
+		&i.Region,
+		&i.Checksum,
+		&i.ChecksumSignature,
+		&i.RunID,
+		&i.TranscodeChecksum,
 	)
 	return i, err
 }
 
 const createTask = `-- name: CreateTask :one
-INSERT INTO tasks (name, target_url, is_enabled, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config) VALUES (?, ?, 0, ?, ?, ?, ?, ?, ?) RETURNING id, name, target_url, is_enabled, is_deleted, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config, created_at
+INSERT INTO tasks (name, target_url, is_enabled, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config, max_recordings, capture_console, encoder_preset, encoder_tune, capture_quality, notify_size_bytes, referer, java_script_enabled, offline, viewports, record_on_change, capture_format, blocked_resources, start_delay_ms, capture_milestones, output_dir, proxy_url, regions, keepalive, watermark) VALUES (?, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, name, target_url, is_enabled, is_deleted, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config, sort_order, max_recordings, capture_console, encoder_preset, encoder_tune, capture_quality, notify_size_bytes, referer, java_script_enabled, offline, viewports, record_on_change, capture_format, blocked_resources, start_delay_ms, capture_milestones, output_dir, proxy_url, regions, keepalive, watermark, created_at
 `
 
 type CreateTaskParams struct {
@@ -60,6 +150,26 @@ type CreateTaskParams struct {
 	Crf               int64
 	TimeOverlay       bool
 	TimeOverlayConfig string
+	MaxRecordings     int64
+	CaptureConsole    bool
+	EncoderPreset     string
+	EncoderTune       string
+	CaptureQuality    int64
+	NotifySizeBytes   int64
+	Referer           string
+	JavaScriptEnabled bool
+	Offline           bool
+	Viewports         string
+	RecordOnChange    bool
+	CaptureFormat     string
+	BlockedResources  string
+	StartDelayMs      int64
+	CaptureMilestones bool
+	OutputDir         string
+	ProxyUrl          string
+	Regions           string
+	Keepalive         string
+	Watermark         string
 }
 
 func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error) {
@@ -72,6 +182,26 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		arg.Crf,
 		arg.TimeOverlay,
 		arg.TimeOverlayConfig,
+		arg.MaxRecordings,
+		arg.CaptureConsole,
+		arg.EncoderPreset,
+		arg.EncoderTune,
+		arg.CaptureQuality,
+		arg.NotifySizeBytes,
+		arg.Referer,
+		arg.JavaScriptEnabled,
+		arg.Offline,
+		arg.Viewports,
+		arg.RecordOnChange,
+		arg.CaptureFormat,
+		arg.BlockedResources,
+		arg.StartDelayMs,
+		arg.CaptureMilestones,
+		arg.OutputDir,
+		arg.ProxyUrl,
+		arg.Regions,
+		arg.Keepalive,
+		arg.Watermark,
 	)
 	var i Task
 	err := row.Scan(
@@ -86,13 +216,34 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		&i.Crf,
 		&i.TimeOverlay,
 		&i.TimeOverlayConfig,
+		&i.SortOrder,
+		&i.MaxRecordings,
+		&i.CaptureConsole,
+		&i.EncoderPreset,
+		&i.EncoderTune,
+		&i.CaptureQuality,
+		&i.NotifySizeBytes,
+		&i.Referer,
+		&i.JavaScriptEnabled,
+		&i.Offline,
+		&i.Viewports,
+		&i.RecordOnChange,
+		&i.CaptureFormat,
+		&i.BlockedResources,
+		&i.StartDelayMs,
+		&i.CaptureMilestones,
+		&i.OutputDir,
+		&i.ProxyUrl,
+		&i.Regions,
+		&i.Keepalive,
+		&i.Watermark,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (username, password_hash) VALUES (?, ?) RETURNING id, username, password_hash, created_at
+INSERT INTO users (username, password_hash) VALUES (?, ?) RETURNING id, username, password_hash, must_change_password, created_at
 `
 
 type CreateUserParams struct {
@@ -107,11 +258,21 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.ID,
 		&i.Username,
 		&i.PasswordHash,
+		&i.MustChangePassword,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
+const deleteOIDCSession = `-- name: DeleteOIDCSession :exec
+DELETE FROM oidc_sessions WHERE username = ?
+`
+
+func (q *Queries) DeleteOIDCSession(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteOIDCSession, username)
+	return err
+}
+
 const deleteRecording = `-- name: DeleteRecording :exec
 DELETE FROM recordings WHERE id = ?
 `
@@ -130,6 +291,24 @@ func (q *Queries) DeleteTask(ctx context.Context, id int64) error {
 	return err
 }
 
+const deleteTaskCredentials = `-- name: DeleteTaskCredentials :exec
+DELETE FROM task_credentials WHERE task_id = ?
+`
+
+func (q *Queries) DeleteTaskCredentials(ctx context.Context, taskID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteTaskCredentials, taskID)
+	return err
+}
+
+const deleteUserTOTP = `-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp WHERE username = ?
+`
+
+func (q *Queries) DeleteUserTOTP(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteUserTOTP, username)
+	return err
+}
+
 const disableTask = `-- name: DisableTask :exec
 UPDATE tasks SET is_enabled = 0 WHERE id = ?
 `
@@ -148,8 +327,67 @@ func (q *Queries) EnableTask(ctx context.Context, id int64) error {
 	return err
 }
 
+const enableUserTOTP = `-- name: EnableUserTOTP :exec
+UPDATE user_totp SET enabled = 1, updated_at = CURRENT_TIMESTAMP WHERE username = ?
+`
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, enableUserTOTP, username)
+	return err
+}
+
+const getLatestRecordingByHash = `-- name: GetLatestRecordingByHash :one
+SELECT id, task_id, status, start_time, end_time, file_path, is_protected, dropped_frames, is_degraded, content_hash, duplicate_of, note, tags, viewport, region, checksum, checksum_signature, run_id, transcode_checksum FROM recordings
+WHERE task_id = ? AND content_hash = ? AND id != ? AND status = 'COMPLETED'
+ORDER BY start_time DESC, id DESC LIMIT 1
+`
+
+type GetLatestRecordingByHashParams struct {
+	TaskID      int64
+	ContentHash string
+	ID          int64
+}
+
+func (q *Queries) GetLatestRecordingByHash(ctx context.Context, arg GetLatestRecordingByHashParams) (Recording, error) {
+	row := q.db.QueryRowContext(ctx, getLatestRecordingByHash, arg.TaskID, arg.ContentHash, arg.ID)
+	var i Recording
+	err := row.Scan(
+		&i.ID,
+		&i.TaskID,
+		&i.Status,
+		&i.StartTime,
+		&i.EndTime,
+		&i.FilePath,
+		&i.IsProtected,
+		&i.DroppedFrames,
+		&i.IsDegraded,
+		&i.ContentHash,
+		&i.DuplicateOf,
+		&i.Note,
+		&i.Tags,
+		&i.Viewport,
+		&i.Region,
+		&i.Checksum,
+		&i.ChecksumSignature,
+		&i.RunID,
+		&i.TranscodeChecksum,
+	)
+	return i, err
+}
+
+const getOIDCSession = `-- name: GetOIDCSession :one
+SELECT username, refresh_token, updated_at FROM oidc_sessions WHERE username = ? LIMIT 1
+`
+
+func (q *Queries) GetOIDCSession(ctx context.Context, username string) (OidcSession, error) {
+	row := q.db.QueryRowContext(ctx, getOIDCSession, username)
+	var i OidcSession
+	err := row.Scan(&i.Username, &i.RefreshToken, &i.UpdatedAt)
+	return i, err
+}
+
 const getRecording = `-- name: GetRecording :one
-SELECT id, task_id, status, start_time, end_time, file_path FROM recordings WHERE id = ? LIMIT 1
+SELECT id, task_id, status, start_time, end_time, file_path, is_protected, dropped_frames, is_degraded, content_hash, duplicate_of, note, tags, viewport, region, checksum, checksum_signature, run_id, transcode_checksum FROM recordings WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetRecording(ctx context.Context, id int64) (Recording, error) {
@@ -162,12 +400,25 @@ func (q *Queries) GetRecording(ctx context.Context, id int64) (Recording, error)
 		&i.StartTime,
 		&i.EndTime,
 		&i.FilePath,
+		&i.IsProtected,
+		&i.DroppedFrames,
+		&i.IsDegraded,
+		&i.ContentHash,
+		&i.DuplicateOf,
+		&i.Note,
+		&i.Tags,
+		&i.Viewport,
+		&i.Region,
+		&i.Checksum,
+		&i.ChecksumSignature,
+		&i.RunID,
+		&i.TranscodeChecksum,
 	)
 	return i, err
 }
 
 const getTask = `-- name: GetTask :one
-SELECT id, name, target_url, is_enabled, is_deleted, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config, created_at FROM tasks WHERE id = ? LIMIT 1
+SELECT id, name, target_url, is_enabled, is_deleted, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config, sort_order, max_recordings, capture_console, encoder_preset, encoder_tune, capture_quality, notify_size_bytes, referer, java_script_enabled, offline, viewports, record_on_change, capture_format, blocked_resources, start_delay_ms, capture_milestones, output_dir, proxy_url, regions, keepalive, watermark, created_at FROM tasks WHERE id = ? LIMIT 1
 `
 
 func (q *Queries) GetTask(ctx context.Context, id int64) (Task, error) {
@@ -185,13 +436,70 @@ func (q *Queries) GetTask(ctx context.Context, id int64) (Task, error) {
 		&i.Crf,
 		&i.TimeOverlay,
 		&i.TimeOverlayConfig,
+		&i.SortOrder,
+		&i.MaxRecordings,
+		&i.CaptureConsole,
+		&i.EncoderPreset,
+		&i.EncoderTune,
+		&i.CaptureQuality,
+		&i.NotifySizeBytes,
+		&i.Referer,
+		&i.JavaScriptEnabled,
+		&i.Offline,
+		&i.Viewports,
+		&i.RecordOnChange,
+		&i.CaptureFormat,
+		&i.BlockedResources,
+		&i.StartDelayMs,
+		&i.CaptureMilestones,
+		&i.OutputDir,
+		&i.ProxyUrl,
+		&i.Regions,
+		&i.Keepalive,
+		&i.Watermark,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTaskCredentials = `-- name: GetTaskCredentials :one
+SELECT task_id, username, password, username_selector, password_selector, submit_selector, updated_at FROM task_credentials WHERE task_id = ? LIMIT 1
+`
+
+func (q *Queries) GetTaskCredentials(ctx context.Context, taskID int64) (TaskCredential, error) {
+	row := q.db.QueryRowContext(ctx, getTaskCredentials, taskID)
+	var i TaskCredential
+	err := row.Scan(
+		&i.TaskID,
+		&i.Username,
+		&i.Password,
+		&i.UsernameSelector,
+		&i.PasswordSelector,
+		&i.SubmitSelector,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, username, password_hash, must_change_password, created_at FROM users WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetUser(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.PasswordHash,
+		&i.MustChangePassword,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, password_hash, created_at FROM users WHERE username = ? LIMIT 1
+SELECT id, username, password_hash, must_change_password, created_at FROM users WHERE username = ? LIMIT 1
 `
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
@@ -201,13 +509,79 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.ID,
 		&i.Username,
 		&i.PasswordHash,
+		&i.MustChangePassword,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
+const getUserTOTP = `-- name: GetUserTOTP :one
+SELECT username, secret, enabled, recovery_codes, last_counter, updated_at FROM user_totp WHERE username = ? LIMIT 1
+`
+
+func (q *Queries) GetUserTOTP(ctx context.Context, username string) (UserTotp, error) {
+	row := q.db.QueryRowContext(ctx, getUserTOTP, username)
+	var i UserTotp
+	err := row.Scan(
+		&i.Username,
+		&i.Secret,
+		&i.Enabled,
+		&i.RecoveryCodes,
+		&i.LastCounter,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listAllRecordings = `-- name: ListAllRecordings :many
+SELECT id, task_id, status, start_time, end_time, file_path, is_protected, dropped_frames, is_degraded, content_hash, duplicate_of, note, tags, viewport, region, checksum, checksum_signature, run_id, transcode_checksum FROM recordings ORDER BY id
+`
+
+func (q *Queries) ListAllRecordings(ctx context.Context) ([]Recording, error) {
+	rows, err := q.db.QueryContext(ctx, listAllRecordings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Recording
+	for rows.Next() {
+		var i Recording
+		if err := rows.Scan(
+			&i.ID,
+			&i.TaskID,
+			&i.Status,
+			&i.StartTime,
+			&i.EndTime,
+			&i.FilePath,
+			&i.IsProtected,
+			&i.DroppedFrames,
+			&i.IsDegraded,
+			&i.ContentHash,
+			&i.DuplicateOf,
+			&i.Note,
+			&i.Tags,
+			&i.Viewport,
+			&i.Region,
+			&i.Checksum,
+			&i.ChecksumSignature,
+			&i.RunID,
+			&i.TranscodeChecksum,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEnabledTasks = `-- name: ListEnabledTasks :many
-SELECT id, name, target_url, is_enabled, is_deleted, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config, created_at FROM tasks WHERE is_enabled = 1
+SELECT id, name, target_url, is_enabled, is_deleted, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config, sort_order, max_recordings, capture_console, encoder_preset, encoder_tune, capture_quality, notify_size_bytes, referer, java_script_enabled, offline, viewports, record_on_change, capture_format, blocked_resources, start_delay_ms, capture_milestones, output_dir, proxy_url, regions, keepalive, watermark, created_at FROM tasks WHERE is_enabled = 1
 `
 
 func (q *Queries) ListEnabledTasks(ctx context.Context) ([]Task, error) {
@@ -231,6 +605,27 @@ func (q *Queries) ListEnabledTasks(ctx context.Context) ([]Task, error) {
 			&i.Crf,
 			&i.TimeOverlay,
 			&i.TimeOverlayConfig,
+			&i.SortOrder,
+			&i.MaxRecordings,
+			&i.CaptureConsole,
+			&i.EncoderPreset,
+			&i.EncoderTune,
+			&i.CaptureQuality,
+			&i.NotifySizeBytes,
+			&i.Referer,
+			&i.JavaScriptEnabled,
+			&i.Offline,
+			&i.Viewports,
+			&i.RecordOnChange,
+			&i.CaptureFormat,
+			&i.BlockedResources,
+			&i.StartDelayMs,
+			&i.CaptureMilestones,
+			&i.OutputDir,
+			&i.ProxyUrl,
+			&i.Regions,
+			&i.Keepalive,
+			&i.Watermark,
 			&i.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -247,20 +642,33 @@ func (q *Queries) ListEnabledTasks(ctx context.Context) ([]Task, error) {
 }
 
 const listRecordings = `-- name: ListRecordings :many
-SELECT r.id, r.task_id, r.status, r.start_time, r.end_time, r.file_path, t.name as task_name 
+SELECT r.id, r.task_id, r.status, r.start_time, r.end_time, r.file_path, r.is_protected, r.dropped_frames, r.is_degraded, r.content_hash, r.duplicate_of, r.note, r.tags, r.viewport, r.region, r.checksum, r.checksum_signature, r.run_id, r.transcode_checksum, t.name as task_name 
 FROM recordings r 
 JOIN tasks t ON r.task_id = t.id 
 ORDER BY r.start_time DESC
 `
 
 type ListRecordingsRow struct {
-	ID        int64
-	TaskID    int64
-	Status    string
-	StartTime time.Time
-	EndTime   sql.NullTime
-	FilePath  string
-	TaskName  string
+	ID                int64
+	TaskID            int64
+	Status            string
+	StartTime         time.Time
+	EndTime           sql.NullTime
+	FilePath          string
+	IsProtected       bool
+	DroppedFrames     int64
+	IsDegraded        bool
+	ContentHash       string
+	DuplicateOf       sql.NullInt64
+	Note              string
+	Tags              string
+	Viewport          string
+	Region            string
+	Checksum          string
+	ChecksumSignature string
+	RunID             string
+	TranscodeChecksum string
+	TaskName          string
 }
 
 func (q *Queries) ListRecordings(ctx context.Context) ([]ListRecordingsRow, error) {
@@ -279,6 +687,14 @@ func (q *Queries) ListRecordings(ctx context.Context) ([]ListRecordingsRow, erro
 			&i.StartTime,
 			&i.EndTime,
 			&i.FilePath,
+			&i.IsProtected,
+			&i.DroppedFrames,
+			&i.IsDegraded,
+			&i.ContentHash,
+			&i.DuplicateOf,
+			&i.Note,
+			&i.Tags,
+			&i.Viewport,
 			&i.TaskName,
 		); err != nil {
 			return nil, err
@@ -294,8 +710,66 @@ func (q *Queries) ListRecordings(ctx context.Context) ([]ListRecordingsRow, erro
 	return items, nil
 }
 
+const listRecordingsToRotate = `-- name: ListRecordingsToRotate :many
+SELECT id, task_id, status, start_time, end_time, file_path, is_protected, dropped_frames, is_degraded, content_hash, duplicate_of, note, tags, viewport, region, checksum, checksum_signature, run_id, transcode_checksum FROM recordings
+WHERE task_id = ? AND is_protected = 0 AND status != 'RECORDING'
+  AND COALESCE(NULLIF(run_id, ''), 'id:' || id) NOT IN (
+    SELECT COALESCE(NULLIF(run_id, ''), 'id:' || id) AS run FROM recordings WHERE task_id = ?
+    GROUP BY run ORDER BY MAX(start_time) DESC, MAX(id) DESC LIMIT ?)
+ORDER BY start_time ASC, id ASC
+`
+
+type ListRecordingsToRotateParams struct {
+	TaskID   int64
+	TaskID_2 int64
+	Limit    int64
+}
+
+func (q *Queries) ListRecordingsToRotate(ctx context.Context, arg ListRecordingsToRotateParams) ([]Recording, error) {
+	rows, err := q.db.QueryContext(ctx, listRecordingsToRotate, arg.TaskID, arg.TaskID_2, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Recording
+	for rows.Next() {
+		var i Recording
+		if err := rows.Scan(
+			&i.ID,
+			&i.TaskID,
+			&i.Status,
+			&i.StartTime,
+			&i.EndTime,
+			&i.FilePath,
+			&i.IsProtected,
+			&i.DroppedFrames,
+			&i.IsDegraded,
+			&i.ContentHash,
+			&i.DuplicateOf,
+			&i.Note,
+			&i.Tags,
+			&i.Viewport,
+			&i.Region,
+			&i.Checksum,
+			&i.ChecksumSignature,
+			&i.RunID,
+			&i.TranscodeChecksum,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTasks = `-- name: ListTasks :many
-SELECT id, name, target_url, is_enabled, is_deleted, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config, created_at FROM tasks WHERE is_deleted = 0 ORDER BY created_at DESC
+SELECT id, name, target_url, is_enabled, is_deleted, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config, sort_order, max_recordings, capture_console, encoder_preset, encoder_tune, capture_quality, notify_size_bytes, referer, java_script_enabled, offline, viewports, record_on_change, capture_format, blocked_resources, start_delay_ms, capture_milestones, output_dir, proxy_url, regions, keepalive, watermark, created_at FROM tasks WHERE is_deleted = 0 ORDER BY sort_order ASC, created_at DESC
 `
 
 func (q *Queries) ListTasks(ctx context.Context) ([]Task, error) {
@@ -319,6 +793,27 @@ func (q *Queries) ListTasks(ctx context.Context) ([]Task, error) {
 			&i.Crf,
 			&i.TimeOverlay,
 			&i.TimeOverlayConfig,
+			&i.SortOrder,
+			&i.MaxRecordings,
+			&i.CaptureConsole,
+			&i.EncoderPreset,
+			&i.EncoderTune,
+			&i.CaptureQuality,
+			&i.NotifySizeBytes,
+			&i.Referer,
+			&i.JavaScriptEnabled,
+			&i.Offline,
+			&i.Viewports,
+			&i.RecordOnChange,
+			&i.CaptureFormat,
+			&i.BlockedResources,
+			&i.StartDelayMs,
+			&i.CaptureMilestones,
+			&i.OutputDir,
+			&i.ProxyUrl,
+			&i.Regions,
+			&i.Keepalive,
+			&i.Watermark,
 			&i.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -334,23 +829,161 @@ func (q *Queries) ListTasks(ctx context.Context) ([]Task, error) {
 	return items, nil
 }
 
-const updateRecordingStatus = `-- name: UpdateRecordingStatus :exec
-UPDATE recordings SET status = ?, end_time = CURRENT_TIMESTAMP WHERE id = ?
+const resetUserPassword = `-- name: ResetUserPassword :exec
+UPDATE users SET password_hash = ?, must_change_password = ? WHERE id = ?
+`
+
+type ResetUserPasswordParams struct {
+	PasswordHash       string
+	MustChangePassword bool
+	ID                 int64
+}
+
+func (q *Queries) ResetUserPassword(ctx context.Context, arg ResetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, resetUserPassword, arg.PasswordHash, arg.MustChangePassword, arg.ID)
+	return err
+}
+
+const toggleRecordingProtected = `-- name: ToggleRecordingProtected :one
+UPDATE recordings SET is_protected = NOT is_protected WHERE id = ? RETURNING id, task_id, status, start_time, end_time, file_path, is_protected, dropped_frames, is_degraded, content_hash, duplicate_of, note, tags, viewport, region, checksum, checksum_signature, run_id, transcode_checksum
+`
+
+func (q *Queries) ToggleRecordingProtected(ctx context.Context, id int64) (Recording, error) {
+	row := q.db.QueryRowContext(ctx, toggleRecordingProtected, id)
+	var i Recording
+	err := row.Scan(
+		&i.ID,
+		&i.TaskID,
+		&i.Status,
+		&i.StartTime,
+		&i.EndTime,
+		&i.FilePath,
+		&i.IsProtected,
+		&i.DroppedFrames,
+		&i.IsDegraded,
+		&i.ContentHash,
+		&i.DuplicateOf,
+		&i.Note,
+		&i.Tags,
+		&i.Viewport,
+		&i.Region,
+		&i.Checksum,
+		&i.ChecksumSignature,
+		&i.RunID,
+		&i.TranscodeChecksum,
+	)
+	return i, err
+}
+
+const updateRecordingChecksum = `-- name: UpdateRecordingChecksum :exec
+UPDATE recordings SET checksum = ?, checksum_signature = ? WHERE id = ?
+`
+
+type UpdateRecordingChecksumParams struct {
+	Checksum          string
+	ChecksumSignature string
+	ID                int64
+}
+
+func (q *Queries) UpdateRecordingChecksum(ctx context.Context, arg UpdateRecordingChecksumParams) error {
+	_, err := q.db.ExecContext(ctx, updateRecordingChecksum, arg.Checksum, arg.ChecksumSignature, arg.ID)
+	return err
+}
+
+const updateRecordingContentHash = `-- name: UpdateRecordingContentHash :exec
+UPDATE recordings SET content_hash = ?, duplicate_of = ? WHERE id = ?
+`
+
+type UpdateRecordingContentHashParams struct {
+	ContentHash string
+	DuplicateOf sql.NullInt64
+	ID          int64
+}
+
+func (q *Queries) UpdateRecordingContentHash(ctx context.Context, arg UpdateRecordingContentHashParams) error {
+	_, err := q.db.ExecContext(ctx, updateRecordingContentHash, arg.ContentHash, arg.DuplicateOf, arg.ID)
+	return err
+}
+
+const updateRecordingFilePath = `-- name: UpdateRecordingFilePath :exec
+UPDATE recordings SET file_path = ? WHERE id = ?
+`
+
+type UpdateRecordingFilePathParams struct {
+	FilePath string
+	ID       int64
+}
+
+func (q *Queries) UpdateRecordingFilePath(ctx context.Context, arg UpdateRecordingFilePathParams) error {
+	_, err := q.db.ExecContext(ctx, updateRecordingFilePath, arg.FilePath, arg.ID)
+	return err
+}
+
+const updateRecordingFrameStats = `-- name: UpdateRecordingFrameStats :exec
+UPDATE recordings SET dropped_frames = ?, is_degraded = ? WHERE id = ?
+`
+
+type UpdateRecordingFrameStatsParams struct {
+	DroppedFrames int64
+	IsDegraded    bool
+	ID            int64
+}
+
+func (q *Queries) UpdateRecordingFrameStats(ctx context.Context, arg UpdateRecordingFrameStatsParams) error {
+	_, err := q.db.ExecContext(ctx, updateRecordingFrameStats, arg.DroppedFrames, arg.IsDegraded, arg.ID)
+	return err
+}
+
+const updateRecordingMetadata = `-- name: UpdateRecordingMetadata :exec
+UPDATE recordings SET note = ?, tags = ? WHERE id = ?
+`
+
+type UpdateRecordingMetadataParams struct {
+	Note string
+	Tags string
+	ID   int64
+}
+
+func (q *Queries) UpdateRecordingMetadata(ctx context.Context, arg UpdateRecordingMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateRecordingMetadata, arg.Note, arg.Tags, arg.ID)
+	return err
+}
+
+const updateRecordingStatus = `-- name: UpdateRecordingStatus :execrows
+UPDATE recordings SET status = ?, end_time = CURRENT_TIMESTAMP WHERE id = ? AND status = ?
 `
 
 type UpdateRecordingStatusParams struct {
-	Status string
-	ID     int64
+	Status     string
+	ID         int64
+	FromStatus string
+}
+
+func (q *Queries) UpdateRecordingStatus(ctx context.Context, arg UpdateRecordingStatusParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateRecordingStatus, arg.Status, arg.ID, arg.FromStatus)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-func (q *Queries) UpdateRecordingStatus(ctx context.Context, arg UpdateRecordingStatusParams) error {
-	_, err := q.db.ExecContext(ctx, updateRecordingStatus, arg.Status, arg.ID)
+const updateRecordingTranscodeChecksum = `-- name: UpdateRecordingTranscodeChecksum :exec
+UPDATE recordings SET transcode_checksum = ? WHERE id = ?
+`
+
+type UpdateRecordingTranscodeChecksumParams struct {
+	TranscodeChecksum string
+	ID                int64
+}
+
+func (q *Queries) UpdateRecordingTranscodeChecksum(ctx context.Context, arg UpdateRecordingTranscodeChecksumParams) error {
+	_, err := q.db.ExecContext(ctx, updateRecordingTranscodeChecksum, arg.TranscodeChecksum, arg.ID)
 	return err
 }
 
 const updateTask = `-- name: UpdateTask :exec
 UPDATE tasks 
-SET name = ?, target_url = ?, filename_template = ?, custom_css = ?, fps = ?, crf = ?, time_overlay = ?, time_overlay_config = ?
+SET name = ?, target_url = ?, filename_template = ?, custom_css = ?, fps = ?, crf = ?, time_overlay = ?, time_overlay_config = ?, max_recordings = ?, capture_console = ?, encoder_preset = ?, encoder_tune = ?, capture_quality = ?, notify_size_bytes = ?, referer = ?, java_script_enabled = ?, offline = ?, viewports = ?, record_on_change = ?, capture_format = ?, blocked_resources = ?, start_delay_ms = ?, capture_milestones = ?, output_dir = ?, proxy_url = ?, regions = ?, keepalive = ?, watermark = ?
 WHERE id = ?
 `
 
@@ -363,6 +996,26 @@ type UpdateTaskParams struct {
 	Crf               int64
 	TimeOverlay       bool
 	TimeOverlayConfig string
+	MaxRecordings     int64
+	CaptureConsole    bool
+	EncoderPreset     string
+	EncoderTune       string
+	CaptureQuality    int64
+	NotifySizeBytes   int64
+	Referer           string
+	JavaScriptEnabled bool
+	Offline           bool
+	Viewports         string
+	RecordOnChange    bool
+	CaptureFormat     string
+	BlockedResources  string
+	StartDelayMs      int64
+	CaptureMilestones bool
+	OutputDir         string
+	ProxyUrl          string
+	Regions           string
+	Keepalive         string
+	Watermark         string
 	ID                int64
 }
 
@@ -376,13 +1029,50 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) error {
 		arg.Crf,
 		arg.TimeOverlay,
 		arg.TimeOverlayConfig,
+		arg.MaxRecordings,
+		arg.CaptureConsole,
+		arg.EncoderPreset,
+		arg.EncoderTune,
+		arg.CaptureQuality,
+		arg.NotifySizeBytes,
+		arg.Referer,
+		arg.JavaScriptEnabled,
+		arg.Offline,
+		arg.Viewports,
+		arg.RecordOnChange,
+		arg.CaptureFormat,
+		arg.BlockedResources,
+		arg.StartDelayMs,
+		arg.CaptureMilestones,
+		arg.OutputDir,
+		arg.ProxyUrl,
+		arg.Regions,
+		arg.Keepalive,
+		arg.Watermark,
 		arg.ID,
 	)
 	return err
 }
 
+const updateTaskSortOrder = `-- name: UpdateTaskSortOrder :execrows
+UPDATE tasks SET sort_order = ? WHERE id = ? AND is_deleted = 0
+`
+
+type UpdateTaskSortOrderParams struct {
+	SortOrder int64
+	ID        int64
+}
+
+func (q *Queries) UpdateTaskSortOrder(ctx context.Context, arg UpdateTaskSortOrderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateTaskSortOrder, arg.SortOrder, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUserPassword = `-- name: UpdateUserPassword :exec
-UPDATE users SET password_hash = ? WHERE username = ?
+UPDATE users SET password_hash = ?, must_change_password = 0 WHERE username = ?
 `
 
 type UpdateUserPasswordParams struct {
@@ -394,3 +1084,78 @@ func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPassword
 	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.PasswordHash, arg.Username)
 	return err
 }
+
+const updateUserTOTPRecoveryCodes = `-- name: UpdateUserTOTPRecoveryCodes :execrows
+UPDATE user_totp SET recovery_codes = ?, updated_at = CURRENT_TIMESTAMP WHERE username = ? AND recovery_codes = ?
+`
+
+type UpdateUserTOTPRecoveryCodesParams struct {
+	RecoveryCodes string
+	Username      string
+	PreviousCodes string
+}
+
+func (q *Queries) UpdateUserTOTPRecoveryCodes(ctx context.Context, arg UpdateUserTOTPRecoveryCodesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserTOTPRecoveryCodes, arg.RecoveryCodes, arg.Username, arg.PreviousCodes)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsertOIDCSession = `-- name: UpsertOIDCSession :exec
+INSERT INTO oidc_sessions (username, refresh_token, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(username) DO UPDATE SET refresh_token = excluded.refresh_token, updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertOIDCSessionParams struct {
+	Username     string
+	RefreshToken string
+}
+
+func (q *Queries) UpsertOIDCSession(ctx context.Context, arg UpsertOIDCSessionParams) error {
+	_, err := q.db.ExecContext(ctx, upsertOIDCSession, arg.Username, arg.RefreshToken)
+	return err
+}
+
+const upsertTaskCredentials = `-- name: UpsertTaskCredentials :exec
+INSERT INTO task_credentials (task_id, username, password, username_selector, password_selector, submit_selector, updated_at) VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(task_id) DO UPDATE SET username = excluded.username, password = excluded.password, username_selector = excluded.username_selector, password_selector = excluded.password_selector, submit_selector = excluded.submit_selector, updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertTaskCredentialsParams struct {
+	TaskID           int64
+	Username         string
+	Password         string
+	UsernameSelector string
+	PasswordSelector string
+	SubmitSelector   string
+}
+
+func (q *Queries) UpsertTaskCredentials(ctx context.Context, arg UpsertTaskCredentialsParams) error {
+	_, err := q.db.ExecContext(ctx, upsertTaskCredentials,
+		arg.TaskID,
+		arg.Username,
+		arg.Password,
+		arg.UsernameSelector,
+		arg.PasswordSelector,
+		arg.SubmitSelector,
+	)
+	return err
+}
+
+const upsertUserTOTP = `-- name: UpsertUserTOTP :exec
+INSERT INTO user_totp (username, secret, enabled, recovery_codes, updated_at) VALUES (?, ?, 0, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(username) DO UPDATE SET secret = excluded.secret, enabled = 0, recovery_codes = excluded.recovery_codes, updated_at = CURRENT_TIMESTAMP
+`
+
+type UpsertUserTOTPParams struct {
+	Username      string
+	Secret        string
+	RecoveryCodes string
+}
+
+func (q *Queries) UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, upsertUserTOTP, arg.Username, arg.Secret, arg.RecoveryCodes)
+	return err
+}
@@ -0,0 +1,81 @@
+package recorder
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const (
+	// MaxAnnotationLength bounds the overlay text so it stays readable in the frame
+	MaxAnnotationLength = 200
+	// MaxAnnotationDuration bounds how long a single annotation stays visible
+	MaxAnnotationDuration = time.Minute
+)
+
+// ErrNoActivePage is returned when a task has no running recording page to annotate
+var ErrNoActivePage = errors.New("no active recording page")
+
+// annotationScript shows a transient banner. Text is set via textContent (never HTML),
+// and a newer annotation replaces the previous one and resets its timer.
+const annotationScript = `
+	([text, durationMs]) => {
+		let div = document.getElementById('uniqueannotationoverlay');
+		if (!div) {
+			div = document.createElement('div');
+			div.id = 'uniqueannotationoverlay';
+			div.style.position = 'fixed';
+			div.style.top = '10px';
+			div.style.left = '50%';
+			div.style.transform = 'translateX(-50%)';
+			div.style.padding = '6px 12px';
+			div.style.backgroundColor = 'rgba(200, 0, 0, 0.8)';
+			div.style.color = 'white';
+			div.style.fontSize = '18px';
+			div.style.fontFamily = 'sans-serif';
+			div.style.zIndex = '10000';
+			div.style.pointerEvents = 'none';
+			document.body.appendChild(div);
+		}
+		div.textContent = text;
+		div.style.display = 'block';
+		clearTimeout(window.__annotationTimer);
+		window.__annotationTimer = setTimeout(() => { div.style.display = 'none'; }, durationMs);
+	}
+`
+
+// registerPage makes a recording page reachable for annotations while it records
+func (w *Worker) registerPage(key SessionKey, page playwright.Page) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pages[key] = page
+}
+
+func (w *Worker) unregisterPage(key SessionKey) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.pages, key)
+}
+
+// ShowAnnotation overlays text on the session's recording page for the given duration,
+// so it is burned into the frames captured meanwhile
+func (w *Worker) ShowAnnotation(key SessionKey, text string, duration time.Duration) error {
+	if text == "" || len([]rune(text)) > MaxAnnotationLength {
+		return fmt.Errorf("annotation text must be 1-%d characters", MaxAnnotationLength)
+	}
+	if duration <= 0 || duration > MaxAnnotationDuration {
+		return fmt.Errorf("annotation duration must be between 1s and %s", MaxAnnotationDuration)
+	}
+
+	w.mu.Lock()
+	page, ok := w.pages[key]
+	w.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w for %s", ErrNoActivePage, key)
+	}
+
+	_, err := page.Evaluate(annotationScript, []interface{}{text, duration.Milliseconds()})
+	return err
+}
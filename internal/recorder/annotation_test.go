@@ -0,0 +1,27 @@
+package recorder
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestShowAnnotation_Validation(t *testing.T) {
+	w := &Worker{pages: make(map[SessionKey]playwright.Page)}
+
+	if err := w.ShowAnnotation(SessionKey{TaskID: 1}, "", 5*time.Second); err == nil {
+		t.Errorf("ShowAnnotation() with empty text expected error")
+	}
+	if err := w.ShowAnnotation(SessionKey{TaskID: 1}, strings.Repeat("x", MaxAnnotationLength+1), 5*time.Second); err == nil {
+		t.Errorf("ShowAnnotation() with oversized text expected error")
+	}
+	if err := w.ShowAnnotation(SessionKey{TaskID: 1}, "deploy", 2*MaxAnnotationDuration); err == nil {
+		t.Errorf("ShowAnnotation() with excessive duration expected error")
+	}
+	if err := w.ShowAnnotation(SessionKey{TaskID: 1}, "deploy", 5*time.Second); !errors.Is(err, ErrNoActivePage) {
+		t.Errorf("ShowAnnotation() without page error = %v, want ErrNoActivePage", err)
+	}
+}
@@ -0,0 +1,71 @@
+package recorder
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/config"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Supported BROWSER_ENGINE values
+const (
+	EngineChromium = "chromium"
+	EngineFirefox  = "firefox"
+	EngineWebKit   = "webkit"
+)
+
+// launchBrowser starts the configured engine, falling back to Chromium when the
+// requested engine is not installed. It returns the engine actually launched.
+func launchBrowser(pw *playwright.Playwright, cfg *config.Config) (playwright.Browser, string, error) {
+	engine := cfg.BrowserEngine
+	if engine == "" {
+		engine = EngineChromium
+	}
+
+	if engine != EngineChromium {
+		var browserType playwright.BrowserType
+		switch engine {
+		case EngineFirefox:
+			browserType = pw.Firefox
+		case EngineWebKit:
+			browserType = pw.WebKit
+		default:
+			return nil, "", fmt.Errorf("unsupported browser engine: %s", engine)
+		}
+
+		// Chromium-only flags and executable path don't apply to the other engines
+		browser, err := browserType.Launch(playwright.BrowserTypeLaunchOptions{
+			Headless: playwright.Bool(true),
+		})
+		if err == nil {
+			return browser, engine, nil
+		}
+		log.Printf("WARNING: Could not launch %s: %v. Falling back to chromium.", engine, err)
+	}
+
+	browser, err := pw.Chromium.Launch(chromiumLaunchOptions(cfg))
+	if err != nil {
+		return nil, "", err
+	}
+	return browser, EngineChromium, nil
+}
+
+func chromiumLaunchOptions(cfg *config.Config) playwright.BrowserTypeLaunchOptions {
+	opts := playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(true),
+		Args: []string{
+			"--no-sandbox",
+			"--disable-setuid-sandbox",
+			"--disable-dev-shm-usage",
+		},
+	}
+
+	if cfg.PlaywrightPath != "" {
+		opts.ExecutablePath = playwright.String(cfg.PlaywrightPath)
+	} else if _, err := os.Stat("/usr/bin/chromium"); err == nil {
+		opts.ExecutablePath = playwright.String("/usr/bin/chromium")
+	}
+	return opts
+}
@@ -0,0 +1,122 @@
+package recorder
+
+import (
+	"bytes"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// Change detection for record_on_change: frames are reduced to a coarse grid of
+// average luminance, so comparing two frames costs one JPEG decode and a few
+// thousand byte comparisons.
+const (
+	signatureCols = 64
+	signatureRows = 36
+	// cellChangeDelta is the average luminance shift (0-255) at which a grid cell
+	// counts as changed; JPEG noise of an unchanged page never reaches it
+	cellChangeDelta = 4
+	// changeKeyframeSeconds bounds the keyframe distance of variable frame rate output
+	changeKeyframeSeconds = 10
+)
+
+// frameSignature is the average luminance of each grid cell of a frame
+type frameSignature []uint8
+
+// signatureOf decodes a JPEG or PNG frame into its luminance grid
+func signatureOf(frame []byte) (frameSignature, error) {
+	img, _, err := image.Decode(bytes.NewReader(frame))
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	var luma func(x, y int) uint32
+	switch m := img.(type) {
+	case *image.YCbCr:
+		luma = func(x, y int) uint32 { return uint32(m.Y[m.YOffset(x, y)]) }
+	case *image.Gray:
+		luma = func(x, y int) uint32 { return uint32(m.Pix[m.PixOffset(x, y)]) }
+	case *image.NRGBA: // PNG screenshots (opaque, so no alpha to apply)
+		luma = func(x, y int) uint32 { return rgbLuma(m.Pix[m.PixOffset(x, y):]) }
+	case *image.RGBA:
+		luma = func(x, y int) uint32 { return rgbLuma(m.Pix[m.PixOffset(x, y):]) }
+	default:
+		luma = func(x, y int) uint32 {
+			r, g, bl, _ := m.At(x, y).RGBA()
+			return (299*r + 587*g + 114*bl) / 1000 >> 8
+		}
+	}
+
+	sig := make(frameSignature, signatureCols*signatureRows)
+	for row := 0; row < signatureRows; row++ {
+		y0 := b.Min.Y + row*b.Dy()/signatureRows
+		y1 := b.Min.Y + (row+1)*b.Dy()/signatureRows
+		for col := 0; col < signatureCols; col++ {
+			x0 := b.Min.X + col*b.Dx()/signatureCols
+			x1 := b.Min.X + (col+1)*b.Dx()/signatureCols
+			var sum, n uint32
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					sum += luma(x, y)
+					n++
+				}
+			}
+			if n > 0 {
+				sig[row*signatureCols+col] = uint8(sum / n)
+			}
+		}
+	}
+	return sig, nil
+}
+
+// rgbLuma returns the luminance of the 8-bit RGB pixel at the start of p
+func rgbLuma(p []uint8) uint32 {
+	return (299*uint32(p[0]) + 587*uint32(p[1]) + 114*uint32(p[2])) / 1000
+}
+
+// changedPercent returns the share of grid cells (0-100) that differ between two signatures
+func changedPercent(a, b frameSignature) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 100
+	}
+	changed := 0
+	for i := range a {
+		d := int(a[i]) - int(b[i])
+		if d < 0 {
+			d = -d
+		}
+		if d >= cellChangeDelta {
+			changed++
+		}
+	}
+	return float64(changed) * 100 / float64(len(a))
+}
+
+// changeDetector decides which captured frames a record_on_change recording writes
+type changeDetector struct {
+	threshold float64 // percent of cells that must change; 0 = any change
+	last      []byte
+	lastSig   frameSignature
+}
+
+// changed reports whether frame differs from the last accepted frame by more than
+// the threshold, and remembers it if so. Byte-identical frames skip the decode.
+func (d *changeDetector) changed(frame []byte) bool {
+	if d.last != nil && bytes.Equal(frame, d.last) {
+		return false
+	}
+	sig, err := signatureOf(frame)
+	if err != nil {
+		// Undecodable frames can't be compared; write them rather than lose content
+		d.last, d.lastSig = frame, nil
+		return true
+	}
+	if d.lastSig != nil {
+		if p := changedPercent(d.lastSig, sig); p == 0 || p < d.threshold {
+			return false
+		}
+	}
+	d.last, d.lastSig = frame, sig
+	return true
+}
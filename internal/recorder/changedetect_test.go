@@ -0,0 +1,99 @@
+package recorder
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage is a 640x360 gray frame with a white box covering w x h pixels
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 640, 360))
+	for y := 0; y < 360; y++ {
+		for x := 0; x < 640; x++ {
+			c := uint8(64)
+			if x < w && y < h {
+				c = 255
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: c, G: c, B: c, A: 255})
+		}
+	}
+	return img
+}
+
+// testFrame encodes testImage as a JPEG
+func testFrame(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := testImage(w, h)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestChangeDetector(t *testing.T) {
+	base := testFrame(t, 0, 0)
+	small := testFrame(t, 20, 20)   // ~0.2% of the screen
+	large := testFrame(t, 320, 180) // a quarter of the screen
+
+	d := &changeDetector{}
+	if !d.changed(base) {
+		t.Errorf("changed() first frame = false, want true")
+	}
+	if d.changed(base) {
+		t.Errorf("changed() identical frame = true, want false")
+	}
+	if !d.changed(small) {
+		t.Errorf("changed() small change with threshold 0 = false, want true")
+	}
+
+	d = &changeDetector{threshold: 5}
+	d.changed(base)
+	if d.changed(small) {
+		t.Errorf("changed() change below threshold = true, want false")
+	}
+	if !d.changed(large) {
+		t.Errorf("changed() change above threshold = false, want true")
+	}
+	if d.changed(large) {
+		t.Errorf("changed() repeated frame = true, want false")
+	}
+}
+
+func TestChangedPercent(t *testing.T) {
+	a := make(frameSignature, 100)
+	b := make(frameSignature, 100)
+	b[0], b[1] = 200, cellChangeDelta-1
+	if got := changedPercent(a, b); got != 1 {
+		t.Errorf("changedPercent() = %g, want 1", got)
+	}
+	if got := changedPercent(a, b[:50]); got != 100 {
+		t.Errorf("changedPercent() with mismatched sizes = %g, want 100", got)
+	}
+}
+
+func TestChangeDetector_PNG(t *testing.T) {
+	encode := func(w, h int) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, testImage(w, h)); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	d := &changeDetector{threshold: 5}
+	d.changed(encode(0, 0))
+	if d.lastSig == nil {
+		t.Fatalf("changed() could not decode a PNG frame")
+	}
+	if d.changed(encode(20, 20)) {
+		t.Errorf("changed() PNG change below threshold = true, want false")
+	}
+	if !d.changed(encode(320, 180)) {
+		t.Errorf("changed() PNG change above threshold = false, want true")
+	}
+}
@@ -0,0 +1,140 @@
+package recorder
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+)
+
+// ErrNoChecksum is returned when verifying a recording that was never checksummed
+// (e.g. one finished before checksums were stored) nor transcoded since
+var ErrNoChecksum = errors.New("recording has no checksum")
+
+// fileChecksum returns the hex SHA-256 of the whole file. Unlike contentHash it
+// covers every byte, container metadata included, so any change to the file shows.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// signChecksum returns the hex HMAC-SHA256 of a checksum under key ("" without a
+// key). The recording ID is signed too, so a signature can't vouch for another row.
+func signChecksum(key string, recordingID int64, checksum string) string {
+	if key == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "recording:%d:sha256:%s", recordingID, checksum)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// storeChecksum records the checksum (and, with RECORDING_SIGNING_KEY, its
+// signature) of a recording's file as it is now. A recording without a file (e.g.
+// one that failed before the first frame) is left without a checksum.
+func (w *Worker) storeChecksum(ctx context.Context, recordingID int64, path string) {
+	if w.queries == nil {
+		return
+	}
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Checksum: failed to hash recording %d: %v", recordingID, err)
+		}
+		return
+	}
+	if err := w.queries.UpdateRecordingChecksum(ctx, database.UpdateRecordingChecksumParams{
+		Checksum:          checksum,
+		ChecksumSignature: signChecksum(w.config.RecordingSigningKey, recordingID, checksum),
+		ID:                recordingID,
+	}); err != nil {
+		log.Printf("Checksum: failed to store checksum of recording %d: %v", recordingID, err)
+	}
+}
+
+// storeTranscodeChecksum records the checksum of the file a transcode wrote. It is
+// kept apart from the capture checksum, which a re-encoded file can never match.
+func (w *Worker) storeTranscodeChecksum(ctx context.Context, recordingID int64, path string) {
+	if w.queries == nil {
+		return
+	}
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		log.Printf("Checksum: failed to hash transcoded recording %d: %v", recordingID, err)
+		return
+	}
+	if err := w.queries.UpdateRecordingTranscodeChecksum(ctx, database.UpdateRecordingTranscodeChecksumParams{
+		TranscodeChecksum: checksum,
+		ID:                recordingID,
+	}); err != nil {
+		log.Printf("Checksum: failed to store transcode checksum of recording %d: %v", recordingID, err)
+	}
+}
+
+// ChecksumVerification is the result of re-hashing a recording's file
+type ChecksumVerification struct {
+	RecordingID int64  `json:"recording_id"`
+	Checksum    string `json:"checksum"` // stored at capture ("" for a keep_original transcode)
+	Actual      string `json:"actual"`   // of the file now
+	Match       bool   `json:"match"`
+	Signed      bool   `json:"signed"`
+	// SignatureValid is nil when the checksum is unsigned or no key is configured
+	SignatureValid *bool `json:"signature_valid"`
+	// Transcoded means the file was re-encoded after capture, so it can't match the
+	// capture checksum; TranscodeMatch tells whether it is still the transcode's output
+	Transcoded        bool   `json:"transcoded"`
+	TranscodeChecksum string `json:"transcode_checksum,omitempty"`
+	TranscodeMatch    bool   `json:"transcode_match"`
+	// Verified means the file is unchanged since capture and, if signed, the
+	// signature holds. A transcoded recording is never verified.
+	Verified bool `json:"verified"`
+}
+
+// VerifyRecording re-hashes a recording's file and compares it with the checksum
+// stored when it was finished. A signed checksum is also checked against
+// RECORDING_SIGNING_KEY, which catches a checksum rewritten along with the file.
+// A transcoded file is compared with the transcode's checksum too, but only for
+// information: it is no longer the file that was captured.
+func (w *Worker) VerifyRecording(rec database.Recording) (ChecksumVerification, error) {
+	if rec.Checksum == "" && rec.TranscodeChecksum == "" {
+		return ChecksumVerification{}, ErrNoChecksum
+	}
+	actual, err := fileChecksum(rec.FilePath)
+	if err != nil {
+		return ChecksumVerification{}, fmt.Errorf("failed to hash recording file: %w", err)
+	}
+
+	v := ChecksumVerification{
+		RecordingID: rec.ID,
+		Checksum:    rec.Checksum,
+		Actual:      actual,
+		Match:       rec.Checksum != "" && actual == rec.Checksum,
+		Signed:      rec.ChecksumSignature != "",
+
+		Transcoded:        rec.TranscodeChecksum != "",
+		TranscodeChecksum: rec.TranscodeChecksum,
+		TranscodeMatch:    rec.TranscodeChecksum != "" && actual == rec.TranscodeChecksum,
+	}
+	if key := w.config.RecordingSigningKey; v.Signed && key != "" {
+		valid := hmac.Equal([]byte(signChecksum(key, rec.ID, rec.Checksum)), []byte(rec.ChecksumSignature))
+		v.SignatureValid = &valid
+	}
+	v.Verified = v.Match && (v.SignatureValid == nil || *v.SignatureValid)
+	return v, nil
+}
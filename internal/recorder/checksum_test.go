@@ -0,0 +1,82 @@
+package recorder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/config"
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+)
+
+func TestFileChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.mkv")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := fileChecksum(path)
+	if err != nil {
+		t.Fatalf("fileChecksum() error = %v", err)
+	}
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got != want {
+		t.Errorf("fileChecksum() = %s, want %s", got, want)
+	}
+}
+
+func TestSignChecksum(t *testing.T) {
+	key := "0123456789abcdef0123456789abcdef"
+	if sig := signChecksum("", 1, "abc"); sig != "" {
+		t.Errorf("signChecksum() without a key = %q, want empty", sig)
+	}
+	sig := signChecksum(key, 1, "abc")
+	if sig == "" || sig != signChecksum(key, 1, "abc") {
+		t.Errorf("signChecksum() = %q, want a stable signature", sig)
+	}
+	if sig == signChecksum(key, 2, "abc") {
+		t.Error("signChecksum() signs the same for another recording")
+	}
+}
+
+func TestVerifyRecording(t *testing.T) {
+	key := "0123456789abcdef0123456789abcdef"
+	w := &Worker{config: &config.Config{RecordingSigningKey: key}}
+	path := filepath.Join(t.TempDir(), "rec.mkv")
+	if err := os.WriteFile(path, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := database.Recording{ID: 7, FilePath: path, Checksum: checksum, ChecksumSignature: signChecksum(key, 7, checksum)}
+
+	if v, err := w.VerifyRecording(rec); err != nil || !v.Verified || v.SignatureValid == nil || !*v.SignatureValid {
+		t.Errorf("VerifyRecording() = %+v, %v, want verified", v, err)
+	}
+
+	// A checksum rewritten without the key fails the signature check
+	forged := rec
+	forged.ChecksumSignature = signChecksum("another-key-another-key-another-", 7, checksum)
+	if v, err := w.VerifyRecording(forged); err != nil || v.Verified || v.SignatureValid == nil || *v.SignatureValid {
+		t.Errorf("VerifyRecording(forged) = %+v, %v, want invalid signature", v, err)
+	}
+
+	if err := os.WriteFile(path, []byte("altered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := w.VerifyRecording(rec); err != nil || v.Match || v.Verified {
+		t.Errorf("VerifyRecording(altered) = %+v, %v, want mismatch", v, err)
+	}
+
+	// A transcode keeps the signed capture checksum and is reported, not verified
+	transcoded := rec
+	transcoded.TranscodeChecksum, _ = fileChecksum(path)
+	if v, err := w.VerifyRecording(transcoded); err != nil || v.Verified || !v.Transcoded || !v.TranscodeMatch || v.SignatureValid == nil || !*v.SignatureValid {
+		t.Errorf("VerifyRecording(transcoded) = %+v, %v, want transcoded and unverified", v, err)
+	}
+
+	if _, err := w.VerifyRecording(database.Recording{ID: 8, FilePath: path}); !errors.Is(err, ErrNoChecksum) {
+		t.Errorf("VerifyRecording(unchecksummed) error = %v, want ErrNoChecksum", err)
+	}
+}
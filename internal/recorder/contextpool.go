@@ -0,0 +1,221 @@
+package recorder
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// contextPoolRetryDelay throttles refills while the browser keeps refusing new contexts
+const contextPoolRetryDelay = 5 * time.Second
+
+// warmContext is a browser context with an open blank page, created ahead of time
+type warmContext struct {
+	ctx  playwright.BrowserContext
+	page playwright.Page
+}
+
+// contextPool keeps up to size pre-warmed contexts ready to hand out.
+// Contexts are single-use: a caller closes its context when done and the pool
+// replaces it in the background. Discarding is the only reset that reliably
+// drops cookies, localStorage, IndexedDB and service workers between tasks.
+type contextPool struct {
+	create func() (warmContext, error)
+	size   int
+
+	mu     sync.Mutex
+	idle   []warmContext
+	closed bool
+	refill chan struct{}
+	done   chan struct{}
+}
+
+func newContextPool(size int, create func() (warmContext, error)) *contextPool {
+	p := &contextPool{
+		create: create,
+		size:   size,
+		refill: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	p.signal()
+	return p
+}
+
+// acquire pops a warm context; ok is false when the pool is empty or closed
+func (p *contextPool) acquire() (warmContext, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || len(p.idle) == 0 {
+		return warmContext{}, false
+	}
+	wc := p.idle[len(p.idle)-1]
+	p.idle = p.idle[:len(p.idle)-1]
+	p.signal()
+	return wc, true
+}
+
+// signal asks the refill loop to top the pool up (non-blocking)
+func (p *contextPool) signal() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+func (p *contextPool) run() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-p.refill:
+		}
+
+		for p.missing() > 0 {
+			wc, err := p.create()
+			if err != nil {
+				log.Printf("Context pool: failed to warm context: %v", err)
+				select {
+				case <-p.done:
+					return
+				case <-time.After(contextPoolRetryDelay):
+				}
+				continue
+			}
+
+			p.mu.Lock()
+			if p.closed {
+				p.mu.Unlock()
+				wc.ctx.Close()
+				return
+			}
+			p.idle = append(p.idle, wc)
+			p.mu.Unlock()
+		}
+	}
+}
+
+func (p *contextPool) missing() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0
+	}
+	return p.size - len(p.idle)
+}
+
+// close stops refilling and discards the idle contexts
+func (p *contextPool) close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	close(p.done)
+	for _, wc := range idle {
+		wc.ctx.Close()
+	}
+}
+
+// baseContextOptions are shared by every context the worker opens
+func baseContextOptions() playwright.BrowserNewContextOptions {
+	return playwright.BrowserNewContextOptions{
+		BypassCSP:         playwright.Bool(true),
+		IgnoreHttpsErrors: playwright.Bool(true),
+	}
+}
+
+// openPage returns a context and page with the given viewport. A warm context is
+// used when the pool has one and no storage state or proxy has to be set up (both
+// can only be applied at context creation); otherwise a context is created on demand.
+// Either way the context gets the BROWSER_TIMEOUT/NAVIGATION_TIMEOUT defaults.
+// Callers own the context and must Close it.
+func (w *Worker) openPage(width, height int, storageStatePath string, pageOpts PageOptions) (playwright.BrowserContext, playwright.Page, error) {
+	guard := w.requestGuard()
+	if w.skipTargetIPCheck(pageOpts.Proxy) {
+		guard = nil
+	}
+
+	// Warm contexts are created with JavaScript on; other settings need a fresh context
+	if w.pool != nil && storageStatePath == "" && pageOpts.JavaScriptEnabled && pageOpts.Proxy == "" {
+		if wc, ok := w.pool.acquire(); ok {
+			if err := wc.page.SetViewportSize(width, height); err == nil {
+				if err := pageOpts.routeRequests(wc.ctx, guard); err == nil {
+					w.applyContextTimeouts(wc.ctx)
+					return wc.ctx, wc.page, nil
+				}
+			}
+			// A broken warm context is not worth debugging here; fall through
+			wc.ctx.Close()
+		}
+	}
+
+	opts := baseContextOptions()
+	opts.Viewport = &playwright.Size{Width: width, Height: height}
+	if storageStatePath != "" {
+		opts.StorageStatePath = playwright.String(storageStatePath)
+	}
+	opts.JavaScriptEnabled = playwright.Bool(pageOpts.JavaScriptEnabled)
+	if pageOpts.Proxy != "" {
+		proxy, err := playwrightProxy(pageOpts.Proxy)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.Proxy = proxy
+	}
+
+	bCtx, err := w.browser.NewContext(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.applyContextTimeouts(bCtx)
+	if err := pageOpts.routeRequests(bCtx, guard); err != nil {
+		bCtx.Close()
+		return nil, nil, err
+	}
+	page, err := bCtx.NewPage()
+	if err != nil {
+		bCtx.Close()
+		return nil, nil, err
+	}
+	return bCtx, page, nil
+}
+
+// applyContextTimeouts bounds every operation and navigation of the context's pages
+// that doesn't set its own timeout (Playwright's defaults are generous). Unset
+// (zero) settings keep Playwright's defaults.
+func (w *Worker) applyContextTimeouts(bCtx playwright.BrowserContext) {
+	if w.config == nil {
+		return
+	}
+	if d := w.config.BrowserTimeout; d > 0 {
+		bCtx.SetDefaultTimeout(float64(d.Milliseconds()))
+	}
+	if d := w.config.NavigationTimeout; d > 0 {
+		bCtx.SetDefaultNavigationTimeout(float64(d.Milliseconds()))
+	}
+}
+
+// warmContextFactory creates pool entries from the worker's browser
+func (w *Worker) warmContextFactory() func() (warmContext, error) {
+	return func() (warmContext, error) {
+		bCtx, err := w.browser.NewContext(baseContextOptions())
+		if err != nil {
+			return warmContext{}, err
+		}
+		page, err := bCtx.NewPage()
+		if err != nil {
+			bCtx.Close()
+			return warmContext{}, err
+		}
+		return warmContext{ctx: bCtx, page: page}, nil
+	}
+}
@@ -0,0 +1,73 @@
+package recorder
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// fakeContext satisfies playwright.BrowserContext; only Close is exercised
+type fakeContext struct {
+	playwright.BrowserContext
+	closed *int32
+}
+
+func (f fakeContext) Close(options ...playwright.BrowserContextCloseOptions) error {
+	atomic.AddInt32(f.closed, 1)
+	return nil
+}
+
+func waitForIdle(t *testing.T, p *contextPool, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		n := len(p.idle)
+		p.mu.Unlock()
+		if n == want {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("pool did not reach %d idle contexts", want)
+}
+
+func TestContextPool_AcquireRefills(t *testing.T) {
+	var created, closed int32
+	p := newContextPool(2, func() (warmContext, error) {
+		atomic.AddInt32(&created, 1)
+		return warmContext{ctx: fakeContext{closed: &closed}}, nil
+	})
+	defer p.close()
+
+	waitForIdle(t, p, 2)
+
+	if _, ok := p.acquire(); !ok {
+		t.Fatalf("acquire() on a warm pool returned ok=false")
+	}
+	// The handed-out context is replaced, never returned
+	waitForIdle(t, p, 2)
+	if got := atomic.LoadInt32(&created); got != 3 {
+		t.Errorf("created = %d, want 3", got)
+	}
+}
+
+func TestContextPool_CloseDiscardsIdle(t *testing.T) {
+	var closed int32
+	p := newContextPool(2, func() (warmContext, error) {
+		return warmContext{ctx: fakeContext{closed: &closed}}, nil
+	})
+	waitForIdle(t, p, 2)
+
+	p.close()
+	p.close() // idempotent
+
+	if got := atomic.LoadInt32(&closed); got != 2 {
+		t.Errorf("closed = %d, want 2", got)
+	}
+	if _, ok := p.acquire(); ok {
+		t.Errorf("acquire() after close returned ok=true")
+	}
+}
@@ -0,0 +1,53 @@
+package recorder
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// cookieNames returns the sorted, de-duplicated names of cookies. Values are
+// dropped here so they can't reach a log by accident.
+func cookieNames(cookies []playwright.Cookie) []string {
+	seen := make(map[string]bool, len(cookies))
+	names := make([]string, 0, len(cookies))
+	for _, c := range cookies {
+		if !seen[c.Name] {
+			seen[c.Name] = true
+			names = append(names, c.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// logCookieNames reports the names of the cookies the context sends to url, so an
+// operator can confirm a session cookie was present when a recording fails to
+// authenticate. Debug-only (DEBUG_COOKIE_NAMES); never logs values. The line goes
+// to the server log, the live log and, when console capture is on, the page log.
+func (w *Worker) logCookieNames(bCtx playwright.BrowserContext, key SessionKey, url, stage string, plog *pageLog) {
+	if w.config == nil || !w.config.DebugCookieNames {
+		return
+	}
+	cookies, err := bCtx.Cookies(url)
+	if err != nil {
+		log.Printf("Debug: failed to list cookies for %s: %v", key, err)
+		return
+	}
+	names := cookieNames(cookies)
+	list := "(none)"
+	if len(names) > 0 {
+		list = strings.Join(names, ", ")
+	}
+	message := "cookies " + stage + ": " + list
+
+	log.Printf("Debug: %s %s", key, message)
+	if plog != nil {
+		plog.writef("debug", "%s", message) // also published to the live log
+		return
+	}
+	w.logs.publish(key, LogEvent{Type: "log", Time: time.Now().UTC(), Kind: "debug", Message: message})
+}
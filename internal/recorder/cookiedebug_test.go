@@ -0,0 +1,22 @@
+package recorder
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestCookieNames(t *testing.T) {
+	got := cookieNames([]playwright.Cookie{
+		{Name: "session", Value: "secret", Domain: "a.example"},
+		{Name: "csrf", Value: "token"},
+		{Name: "session", Value: "other", Domain: "b.example"},
+	})
+	if want := []string{"csrf", "session"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cookieNames() = %v, want %v", got, want)
+	}
+	if got := cookieNames(nil); len(got) != 0 {
+		t.Errorf("cookieNames(nil) = %v, want empty", got)
+	}
+}
@@ -0,0 +1,105 @@
+package recorder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+)
+
+// contentHashTimeout bounds hashing a finished recording (stream copy, no decoding)
+const contentHashTimeout = 5 * time.Minute
+
+// contentHash returns the SHA-256 of the file's video packets. Unlike a plain file
+// hash it ignores container metadata (segment UIDs, muxing dates) that differs
+// between otherwise identical recordings.
+func contentHash(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), contentHashTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-v", "error",
+		"-i", path, "-map", "0:v", "-c", "copy", "-f", "hash", "-hash", "sha256", "-").Output()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg hash failed: %w", err)
+	}
+	return parseHashOutput(out)
+}
+
+// parseHashOutput extracts the digest from ffmpeg's hash muxer output ("SHA256=<hex>")
+func parseHashOutput(out []byte) (string, error) {
+	for _, line := range strings.Split(string(out), "\n") {
+		if digest, ok := strings.CutPrefix(strings.TrimSpace(line), "SHA256="); ok && digest != "" {
+			return strings.ToLower(digest), nil
+		}
+	}
+	return "", fmt.Errorf("unexpected ffmpeg hash output: %q", strings.TrimSpace(string(out)))
+}
+
+// linkDuplicate replaces path with a hard link to existing, so identical recordings
+// share storage while each row keeps its own (independently deletable) file
+func linkDuplicate(existing, path string) error {
+	tmp := path + ".dedupe"
+	if err := os.Link(existing, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// dedupeRecording stores the content hash of a finished recording and, per
+// RECORDING_DEDUPE, flags ("flag") or hard-links ("link") it when the task's
+// latest completed recording has identical content
+func (w *Worker) dedupeRecording(ctx context.Context, taskID, recordingID int64, outputPath string) {
+	if w.queries == nil || w.config.RecordingDedupe == "" || w.config.RecordingDedupe == "off" {
+		return
+	}
+
+	hash, err := contentHash(outputPath)
+	if err != nil {
+		log.Printf("Dedupe: failed to hash recording %d: %v", recordingID, err)
+		return
+	}
+
+	var duplicateOf sql.NullInt64
+	match, err := w.queries.GetLatestRecordingByHash(ctx, database.GetLatestRecordingByHashParams{
+		TaskID:      taskID,
+		ContentHash: hash,
+		ID:          recordingID,
+	})
+	switch {
+	case err == nil:
+		duplicateOf = sql.NullInt64{Int64: match.ID, Valid: true}
+	case !errors.Is(err, sql.ErrNoRows):
+		log.Printf("Dedupe: failed to look up duplicates of recording %d: %v", recordingID, err)
+	}
+
+	if err := w.queries.UpdateRecordingContentHash(ctx, database.UpdateRecordingContentHashParams{
+		ContentHash: hash,
+		DuplicateOf: duplicateOf,
+		ID:          recordingID,
+	}); err != nil {
+		log.Printf("Dedupe: failed to store hash of recording %d: %v", recordingID, err)
+		return
+	}
+	if !duplicateOf.Valid {
+		return
+	}
+
+	log.Printf("Dedupe: recording %d is identical to recording %d", recordingID, match.ID)
+	if w.config.RecordingDedupe == "link" {
+		if err := linkDuplicate(match.FilePath, outputPath); err != nil {
+			// e.g. the original moved to another filesystem; the duplicate stays flagged
+			log.Printf("Dedupe: failed to link recording %d to %s: %v", recordingID, match.FilePath, err)
+		}
+	}
+}
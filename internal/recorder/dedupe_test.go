@@ -0,0 +1,50 @@
+package recorder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseHashOutput(t *testing.T) {
+	got, err := parseHashOutput([]byte("SHA256=ABCDEF0123\n"))
+	if err != nil || got != "abcdef0123" {
+		t.Errorf("parseHashOutput() = %q, %v; want %q", got, err, "abcdef0123")
+	}
+
+	if _, err := parseHashOutput([]byte("garbage\n")); err == nil {
+		t.Errorf("parseHashOutput() accepted output without a digest")
+	}
+}
+
+func TestLinkDuplicate(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "1_first.mkv")
+	duplicate := filepath.Join(dir, "1_second.mkv")
+	if err := os.WriteFile(original, []byte("same frames"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(duplicate, []byte("same frames, other container"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := linkDuplicate(original, duplicate); err != nil {
+		t.Fatalf("linkDuplicate() error = %v", err)
+	}
+
+	a, _ := os.Stat(original)
+	b, _ := os.Stat(duplicate)
+	if !os.SameFile(a, b) {
+		t.Errorf("duplicate is not a hard link to the original")
+	}
+	// Deleting the original must leave the duplicate playable
+	if err := os.Remove(original); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(duplicate); err != nil || string(data) != "same frames" {
+		t.Errorf("duplicate after deleting original = %q, %v", data, err)
+	}
+	if _, err := os.Stat(duplicate + ".dedupe"); !os.IsNotExist(err) {
+		t.Errorf("temporary link left behind")
+	}
+}
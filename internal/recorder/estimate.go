@@ -0,0 +1,63 @@
+package recorder
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// RecordingWidth/Height is the fixed viewport used by recordLoop
+	RecordingWidth  = 1920
+	RecordingHeight = 1080
+
+	// DefaultBitsPerPixel is a rough x264 ultrafast rate at CRF 23 for mostly-static dashboards
+	DefaultBitsPerPixel = 0.02
+
+	// referenceCRF is the CRF the bits-per-pixel figure is calibrated for
+	referenceCRF = 23
+)
+
+// PixelRate is the capture throughput of a recording in pixels per second
+func PixelRate(width, height int, fps int64) int64 {
+	return int64(width) * int64(height) * fps
+}
+
+// EstimateInput describes a recording for disk usage estimation
+type EstimateInput struct {
+	Width  int
+	Height int
+	Fps    int64
+	Crf    int64
+	// BitsPerPixel at CRF 23; <= 0 uses DefaultBitsPerPixel
+	BitsPerPixel float64
+}
+
+// EstimateBytesPerHour returns a rough output size for one hour of recording.
+// It uses the x264 rule of thumb that every 6 CRF steps halve (or double) the bitrate.
+// Real output depends heavily on how much the page moves; treat this as an order of magnitude.
+func EstimateBytesPerHour(in EstimateInput) int64 {
+	if in.Width <= 0 || in.Height <= 0 || in.Fps <= 0 {
+		return 0
+	}
+	bpp := in.BitsPerPixel
+	if bpp <= 0 {
+		bpp = DefaultBitsPerPixel
+	}
+
+	crf := in.Crf
+	if crf < 0 {
+		crf = 0
+	}
+	if crf > 51 {
+		crf = 51
+	}
+	bpp *= math.Pow(2, float64(referenceCRF-crf)/6)
+
+	bitsPerSecond := float64(in.Width*in.Height) * float64(in.Fps) * bpp
+	return int64(bitsPerSecond * 3600 / 8)
+}
+
+// EstimateBytes scales the hourly estimate to the given duration
+func EstimateBytes(in EstimateInput, d time.Duration) int64 {
+	return int64(float64(EstimateBytesPerHour(in)) * d.Hours())
+}
@@ -0,0 +1,37 @@
+package recorder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateBytesPerHour(t *testing.T) {
+	base := EstimateInput{Width: 1920, Height: 1080, Fps: 5, Crf: 23, BitsPerPixel: 0.02}
+
+	// 1920*1080*5*0.02 bits/s * 3600 / 8
+	assert.Equal(t, int64(93312000), EstimateBytesPerHour(base))
+
+	// 6 CRF steps lower doubles the estimate
+	higherQuality := base
+	higherQuality.Crf = 17
+	assert.Equal(t, 2*EstimateBytesPerHour(base), EstimateBytesPerHour(higherQuality))
+
+	// Default bits per pixel applies when unset
+	unset := base
+	unset.BitsPerPixel = 0
+	assert.Equal(t, EstimateBytesPerHour(base), EstimateBytesPerHour(unset))
+
+	assert.Equal(t, int64(0), EstimateBytesPerHour(EstimateInput{}))
+}
+
+func TestEstimateBytes(t *testing.T) {
+	in := EstimateInput{Width: 1920, Height: 1080, Fps: 5, Crf: 23}
+	assert.Equal(t, EstimateBytesPerHour(in)/2, EstimateBytes(in, 30*time.Minute))
+}
+
+func TestPixelRate(t *testing.T) {
+	assert.Equal(t, int64(1920*1080*15), PixelRate(1920, 1080, 15))
+	assert.Equal(t, int64(0), PixelRate(1920, 1080, 0))
+}
@@ -0,0 +1,223 @@
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// RequiredEncoders lists the ffmpeg encoders recordLoop depends on
+var RequiredEncoders = []string{"libx264"}
+
+// FFmpegStatus is the result of the startup self-test
+type FFmpegStatus struct {
+	Available       bool     `json:"available"`
+	Version         string   `json:"version,omitempty"`
+	MissingEncoders []string `json:"missing_encoders,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// OK reports whether ffmpeg is present and has every required encoder
+func (s FFmpegStatus) OK() bool {
+	return s.Available && len(s.MissingEncoders) == 0
+}
+
+// CheckFFmpeg runs `ffmpeg -version` and `ffmpeg -encoders` to verify the binary
+// is usable and that all the given encoders are compiled in.
+func CheckFFmpeg(encoders []string) FFmpegStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-version").Output()
+	if err != nil {
+		return FFmpegStatus{Error: fmt.Sprintf("ffmpeg not runnable: %v", err)}
+	}
+
+	status := FFmpegStatus{Available: true}
+	if line, _, _ := strings.Cut(string(out), "\n"); line != "" {
+		status.Version = strings.TrimSpace(line)
+	}
+
+	out, err = exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-encoders").Output()
+	if err != nil {
+		status.Error = fmt.Sprintf("failed to list ffmpeg encoders: %v", err)
+		status.MissingEncoders = encoders
+		return status
+	}
+
+	available := parseEncoders(out)
+	for _, enc := range encoders {
+		if !available[enc] {
+			status.MissingEncoders = append(status.MissingEncoders, enc)
+		}
+	}
+	return status
+}
+
+// parseEncoders extracts encoder names from `ffmpeg -encoders` output.
+// Lines look like " V....D libx264              libx264 H.264 / AVC ..."
+func parseEncoders(out []byte) map[string]bool {
+	encoders := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || len(fields[0]) != 6 {
+			continue
+		}
+		encoders[fields[1]] = true
+	}
+	return encoders
+}
+
+// DefaultPreset keeps CPU usage minimal at the cost of larger files
+const DefaultPreset = "ultrafast"
+
+// Presets are the libx264 presets, fastest (largest files) first
+var Presets = []string{"ultrafast", "superfast", "veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow"}
+
+// Tunes are the libx264 -tune values; "stillimage" suits mostly static dashboards
+var Tunes = []string{"film", "animation", "grain", "stillimage", "fastdecode", "zerolatency"}
+
+// ValidateEncoderSettings checks a preset/tune pair. Empty values mean "use the default"
+// (server preset, no tune).
+func ValidateEncoderSettings(preset, tune string) error {
+	if preset != "" && !containsString(Presets, preset) {
+		return fmt.Errorf("invalid preset %q. Allowed: %s", preset, strings.Join(Presets, ", "))
+	}
+	if tune != "" && !containsString(Tunes, tune) {
+		return fmt.Errorf("invalid tune %q. Allowed: %s", tune, strings.Join(Tunes, ", "))
+	}
+	return nil
+}
+
+// Capture formats of the frames recordLoop pipes to ffmpeg
+const (
+	CaptureFormatJPEG = "jpeg" // default: lossy, cheap to capture and pipe
+	CaptureFormatPNG  = "png"  // lossless: sharp text and lines at more CPU and pipe bandwidth
+)
+
+// CaptureFormats lists the allowed capture formats
+var CaptureFormats = []string{CaptureFormatJPEG, CaptureFormatPNG}
+
+// ValidateCaptureFormat checks a task's capture format (empty means jpeg)
+func ValidateCaptureFormat(format string) error {
+	if format != "" && !containsString(CaptureFormats, format) {
+		return fmt.Errorf("invalid capture_format %q. Allowed: %s", format, strings.Join(CaptureFormats, ", "))
+	}
+	return nil
+}
+
+// encodeArgs builds the ffmpeg command line that turns piped frames (JPEG, or PNG
+// when format is CaptureFormatPNG) into outputPath.
+// With a livePath, the tee muxer also writes the same encoded stream there as
+// fragmented MP4, which is playable while the recording is still growing.
+//
+// changeOnly (record_on_change) stamps frames with the time they arrive instead of
+// a fixed rate and keeps the output variable frame rate, so a frame written once
+// lasts until the next one; a keyframe every changeKeyframeSeconds keeps it seekable.
+func encodeArgs(fps, crf int64, preset, tune, format, outputPath, livePath string, changeOnly bool) []string {
+	args := append(inputArgs(fps, format, changeOnly), outputArgs(fps, crf, preset, tune, changeOnly)...)
+	if livePath == "" {
+		return append(args, outputPath)
+	}
+	// onfail=ignore: a broken live copy must never stop the recording itself
+	return append(args,
+		"-map", "0:v",
+		"-f", "tee",
+		teeEscape(outputPath)+"|[f=mp4:movflags=+frag_keyframe+empty_moov+default_base_moof:frag_duration=2000000:onfail=ignore]"+teeEscape(livePath),
+	)
+}
+
+// encodeRegionArgs builds the ffmpeg command line of a session recording regions:
+// every piped frame is split and cropped once per output, and each crop is encoded
+// into its own file with the same settings as encodeArgs. Regions have no live copy.
+func encodeRegionArgs(fps, crf int64, preset, tune, format string, changeOnly bool, outputs []RecordingOutput) []string {
+	args := inputArgs(fps, format, changeOnly)
+
+	var graph strings.Builder
+	if len(outputs) > 1 {
+		graph.WriteString(fmt.Sprintf("[0:v]split=%d", len(outputs)))
+		for i := range outputs {
+			graph.WriteString(fmt.Sprintf("[s%d]", i))
+		}
+		graph.WriteString(";")
+	}
+	for i, o := range outputs {
+		in := fmt.Sprintf("[s%d]", i)
+		if len(outputs) == 1 {
+			in = "[0:v]"
+		}
+		if i > 0 {
+			graph.WriteString(";")
+		}
+		graph.WriteString(fmt.Sprintf("%s%s[r%d]", in, o.Region.cropFilter(), i))
+	}
+	args = append(args, "-filter_complex", graph.String())
+
+	for i, o := range outputs {
+		args = append(args, "-map", fmt.Sprintf("[r%d]", i))
+		args = append(args, outputArgs(fps, crf, preset, tune, changeOnly)...)
+		args = append(args, o.Path)
+	}
+	return args
+}
+
+// inputArgs reads frames (JPEG, or PNG when format is CaptureFormatPNG) from stdin.
+// Change-only input is timed by the wall clock: an input -r would replace those
+// timestamps with a constant rate, so each frame would last 1/fps instead of until
+// the next change.
+func inputArgs(fps int64, format string, changeOnly bool) []string {
+	args := []string{"-y"}
+	if changeOnly {
+		args = append(args, "-use_wallclock_as_timestamps", "1")
+	}
+	if format == CaptureFormatPNG {
+		args = append(args, "-f", "png_pipe")
+	} else {
+		args = append(args, "-f", "image2pipe", "-vcodec", "mjpeg")
+	}
+	if !changeOnly {
+		args = append(args, "-r", fmt.Sprintf("%d", fps))
+	}
+	return append(args, "-i", "-")
+}
+
+// outputArgs are the encoder settings of one output file
+func outputArgs(fps, crf int64, preset, tune string, changeOnly bool) []string {
+	args := []string{
+		"-c:v", "libx264",
+		"-preset", preset,
+	}
+	if tune != "" {
+		args = append(args, "-tune", tune)
+	}
+	args = append(args,
+		"-pix_fmt", "yuv420p",
+		"-crf", fmt.Sprintf("%d", crf),
+	)
+	if changeOnly {
+		return append(args,
+			"-fps_mode", "vfr",
+			"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", changeKeyframeSeconds),
+		)
+	}
+	return append(args, "-r", fmt.Sprintf("%d", fps))
+}
+
+// teeEscape escapes the characters the tee muxer treats specially in a slave filename
+func teeEscape(path string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, `[`, `\[`, `]`, `\]`).Replace(path)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
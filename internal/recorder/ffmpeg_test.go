@@ -0,0 +1,139 @@
+package recorder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEncoders(t *testing.T) {
+	out := []byte(`Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D mjpeg                MJPEG (Motion JPEG)
+ A....D aac                  AAC (Advanced Audio Coding)
+`)
+
+	encoders := parseEncoders(out)
+	for _, want := range []string{"libx264", "mjpeg", "aac"} {
+		if !encoders[want] {
+			t.Errorf("parseEncoders() missing %q", want)
+		}
+	}
+	if encoders["libx265"] {
+		t.Errorf("parseEncoders() reported libx265 which is not in the output")
+	}
+}
+
+func TestValidateEncoderSettings(t *testing.T) {
+	tests := []struct {
+		preset, tune string
+		wantErr      bool
+	}{
+		{"", "", false},
+		{"ultrafast", "", false},
+		{"veryslow", "stillimage", false},
+		{"placebo", "", true},
+		{"medium", "cartoon", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateEncoderSettings(tt.preset, tt.tune); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateEncoderSettings(%q, %q) error = %v, wantErr %v", tt.preset, tt.tune, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEncodeArgs(t *testing.T) {
+	args := strings.Join(encodeArgs(5, 23, "slow", "stillimage", CaptureFormatJPEG, "/app/recordings/1.mkv", "", false), " ")
+	for _, want := range []string{"-preset slow", "-tune stillimage", "-crf 23", "-r 5"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("encodeArgs() = %q, missing %q", args, want)
+		}
+	}
+	if !strings.HasSuffix(args, "/app/recordings/1.mkv") {
+		t.Errorf("encodeArgs() = %q, output path must be last", args)
+	}
+
+	if args := strings.Join(encodeArgs(5, 23, "ultrafast", "", CaptureFormatJPEG, "out.mkv", "", false), " "); strings.Contains(args, "-tune") {
+		t.Errorf("encodeArgs() without tune = %q, should not pass -tune", args)
+	}
+}
+
+func TestEncodeArgs_Live(t *testing.T) {
+	args := encodeArgs(5, 23, "ultrafast", "", CaptureFormatJPEG, "/app/recordings/1.mkv", "/app/recordings/1.live.mp4", false)
+	joined := strings.Join(args, " ")
+	if !strings.Contains(joined, "-map 0:v -f tee") {
+		t.Errorf("encodeArgs() with live path = %q, want tee muxer", joined)
+	}
+	out := args[len(args)-1]
+	if !strings.HasPrefix(out, "/app/recordings/1.mkv|[f=mp4:") || !strings.HasSuffix(out, "]/app/recordings/1.live.mp4") {
+		t.Errorf("tee output = %q", out)
+	}
+	if !strings.Contains(out, "onfail=ignore") {
+		t.Errorf("tee output = %q, live copy must not fail the recording", out)
+	}
+
+	if got := teeEscape(`a|b[c]\d`); got != `a\|b\[c\]\\d` {
+		t.Errorf("teeEscape() = %q", got)
+	}
+}
+
+func TestEncodeArgs_ChangeOnly(t *testing.T) {
+	args := strings.Join(encodeArgs(5, 23, "ultrafast", "", CaptureFormatJPEG, "/app/recordings/1.mkv", "", true), " ")
+	for _, want := range []string{"-use_wallclock_as_timestamps 1 -f image2pipe", "-fps_mode vfr", "-force_key_frames"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("encodeArgs() change only = %q, missing %q", args, want)
+		}
+	}
+	if strings.Contains(args, "-crf 23 -r 5") {
+		t.Errorf("encodeArgs() change only = %q, must not force a constant output rate", args)
+	}
+	input, _, _ := strings.Cut(args, "-i -")
+	if strings.Contains(input, "-r ") {
+		t.Errorf("encodeArgs() change only = %q, must not override the wallclock input timestamps", args)
+	}
+}
+
+func TestEncodeArgs_CaptureFormat(t *testing.T) {
+	jpeg := strings.Join(encodeArgs(5, 23, "ultrafast", "", CaptureFormatJPEG, "out.mkv", "", false), " ")
+	if !strings.Contains(jpeg, "-f image2pipe -vcodec mjpeg -r 5 -i -") {
+		t.Errorf("encodeArgs() jpeg = %q, want an mjpeg pipe", jpeg)
+	}
+	png := strings.Join(encodeArgs(5, 23, "ultrafast", "", CaptureFormatPNG, "out.mkv", "", false), " ")
+	if !strings.Contains(png, "-f png_pipe -r 5 -i -") || strings.Contains(png, "mjpeg") {
+		t.Errorf("encodeArgs() png = %q, want a png pipe", png)
+	}
+
+	if err := ValidateCaptureFormat(""); err != nil {
+		t.Errorf("ValidateCaptureFormat(\"\") error = %v", err)
+	}
+	if err := ValidateCaptureFormat("webp"); err == nil {
+		t.Errorf("ValidateCaptureFormat(\"webp\") error = nil, want error")
+	}
+}
+
+func TestEncodeRegionArgs(t *testing.T) {
+	outputs := []RecordingOutput{
+		{Path: "/app/recordings/1_cpu.mkv", Region: &Region{Name: "cpu", Width: 640, Height: 360}},
+		{Path: "/app/recordings/1_mem.mkv", Region: &Region{Name: "mem", X: 640, Width: 640, Height: 360}},
+	}
+	args := strings.Join(encodeRegionArgs(5, 23, "ultrafast", "", CaptureFormatJPEG, false, outputs), " ")
+	for _, want := range []string{
+		"-filter_complex [0:v]split=2[s0][s1];[s0]crop=640:360:0:0[r0];[s1]crop=640:360:640:0[r1]",
+		"-map [r0] -c:v libx264",
+		"-r 5 /app/recordings/1_cpu.mkv -map [r1]",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("encodeRegionArgs() = %q, missing %q", args, want)
+		}
+	}
+	if !strings.HasSuffix(args, "/app/recordings/1_mem.mkv") {
+		t.Errorf("encodeRegionArgs() = %q, last output path must be last", args)
+	}
+
+	single := strings.Join(encodeRegionArgs(5, 23, "ultrafast", "", CaptureFormatJPEG, false, outputs[:1]), " ")
+	if !strings.Contains(single, "-filter_complex [0:v]crop=640:360:0:0[r0]") {
+		t.Errorf("encodeRegionArgs() single region = %q, want no split", single)
+	}
+}
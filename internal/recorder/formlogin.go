@@ -0,0 +1,85 @@
+package recorder
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// MaxLoginSelectorLength bounds each login form selector
+const MaxLoginSelectorLength = 256
+
+// loginStepTimeout bounds each fill/click so a wrong selector fails the start quickly
+const loginStepTimeout = 15000 // ms
+
+// FormLogin holds decrypted credentials for a dashboard's login form.
+// String masks the secrets so the struct is safe to log.
+type FormLogin struct {
+	Username         string
+	Password         string
+	UsernameSelector string
+	PasswordSelector string
+	SubmitSelector   string
+}
+
+func (l FormLogin) String() string {
+	return fmt.Sprintf("FormLogin{Username: %s, Password: ***, UsernameSelector: %q, PasswordSelector: %q, SubmitSelector: %q}",
+		MaskCredential(l.Username), l.UsernameSelector, l.PasswordSelector, l.SubmitSelector)
+}
+
+// MaskCredential hides all but the first character of a credential for logging
+func MaskCredential(s string) string {
+	if s == "" {
+		return ""
+	}
+	r := []rune(s)
+	return string(r[0]) + "***"
+}
+
+// ValidateLoginSelectors checks the three form selectors are present, bounded and
+// free of control characters
+func ValidateLoginSelectors(usernameSelector, passwordSelector, submitSelector string) error {
+	for _, s := range []struct{ name, value string }{
+		{"username_selector", usernameSelector},
+		{"password_selector", passwordSelector},
+		{"submit_selector", submitSelector},
+	} {
+		if strings.TrimSpace(s.value) == "" {
+			return fmt.Errorf("%s is required", s.name)
+		}
+		if len(s.value) > MaxLoginSelectorLength {
+			return fmt.Errorf("%s exceeds %d characters", s.name, MaxLoginSelectorLength)
+		}
+		if strings.IndexFunc(s.value, unicode.IsControl) >= 0 {
+			return fmt.Errorf("%s must not contain control characters", s.name)
+		}
+	}
+	return nil
+}
+
+// performFormLogin fills and submits the login form, then waits for the page to settle.
+// Errors name the failing selector but never include the credentials.
+func performFormLogin(page playwright.Page, taskID int64, login *FormLogin) error {
+	log.Printf("Submitting login form for task %d as %s", taskID, MaskCredential(login.Username))
+
+	fillOpts := playwright.PageFillOptions{Timeout: playwright.Float(loginStepTimeout)}
+	if err := page.Fill(login.UsernameSelector, login.Username, fillOpts); err != nil {
+		return fmt.Errorf("login: failed to fill username field %q: %w", login.UsernameSelector, err)
+	}
+	if err := page.Fill(login.PasswordSelector, login.Password, fillOpts); err != nil {
+		return fmt.Errorf("login: failed to fill password field %q: %w", login.PasswordSelector, err)
+	}
+	if err := page.Click(login.SubmitSelector, playwright.PageClickOptions{Timeout: playwright.Float(loginStepTimeout)}); err != nil {
+		return fmt.Errorf("login: failed to click submit %q: %w", login.SubmitSelector, err)
+	}
+	// Bounded by the context's NAVIGATION_TIMEOUT
+	if err := page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+		State: playwright.LoadStateNetworkidle,
+	}); err != nil {
+		return fmt.Errorf("login: page did not settle after submit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package recorder
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateLoginSelectors(t *testing.T) {
+	tests := []struct {
+		name                       string
+		username, password, submit string
+		wantErr                    bool
+	}{
+		{"Valid", "#user", "input[name=password]", "button[type=submit]", false},
+		{"Missing Submit", "#user", "#pass", " ", true},
+		{"Control Character", "#user\n", "#pass", "#go", true},
+		{"Too Long", strings.Repeat("a", MaxLoginSelectorLength+1), "#pass", "#go", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLoginSelectors(tt.username, tt.password, tt.submit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateLoginSelectors() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormLogin_StringMasksSecrets(t *testing.T) {
+	login := FormLogin{Username: "operator", Password: "hunter2", UsernameSelector: "#user"}
+	for _, out := range []string{login.String(), fmt.Sprintf("%v", login), fmt.Sprintf("%+v", &login)} {
+		if strings.Contains(out, "operator") || strings.Contains(out, "hunter2") {
+			t.Errorf("formatted login leaks credentials: %s", out)
+		}
+	}
+	if got := MaskCredential("operator"); got != "o***" {
+		t.Errorf("MaskCredential() = %q, want %q", got, "o***")
+	}
+}
@@ -0,0 +1,124 @@
+package recorder
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// frameDrainTimeout bounds how long a stopping recording waits for buffered frames
+// to reach ffmpeg
+const frameDrainTimeout = 5 * time.Second
+
+// frameWrite is one captured frame, written count times (duplicates keep the
+// constant frame rate timeline in step with the wall clock)
+type frameWrite struct {
+	data  []byte
+	count int64
+}
+
+// frameWriter feeds frames to the encoder from its own goroutine, so an encoder
+// stall fills a bounded buffer instead of stalling capture. When the buffer is full
+// the oldest frame is dropped. A size of 0 writes synchronously, as before.
+type frameWriter struct {
+	out    io.Writer
+	frames chan frameWrite // nil when unbuffered
+	done   chan struct{}
+	onDrop func(frames int64)
+
+	dropped atomic.Int64 // frames (duplicates included) dropped by a full buffer
+	errMu   sync.Mutex
+	err     error
+	once    sync.Once
+}
+
+// newFrameWriter starts a writer with room for size frames. onDrop, if set, is called
+// from write with the number of frames dropped.
+func newFrameWriter(out io.Writer, size int, onDrop func(frames int64)) *frameWriter {
+	fw := &frameWriter{out: out, done: make(chan struct{}), onDrop: onDrop}
+	if size <= 0 {
+		close(fw.done)
+		return fw
+	}
+	fw.frames = make(chan frameWrite, size)
+	go fw.run()
+	return fw
+}
+
+func (fw *frameWriter) run() {
+	defer close(fw.done)
+	for f := range fw.frames {
+		if fw.failed() != nil {
+			continue // drain so write never blocks
+		}
+		if err := fw.writeOut(f); err != nil {
+			fw.errMu.Lock()
+			fw.err = err
+			fw.errMu.Unlock()
+		}
+	}
+}
+
+func (fw *frameWriter) writeOut(f frameWrite) error {
+	for i := int64(0); i < f.count; i++ {
+		if _, err := fw.out.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fw *frameWriter) failed() error {
+	fw.errMu.Lock()
+	defer fw.errMu.Unlock()
+	return fw.err
+}
+
+// write queues the frame count times. It returns the error of an earlier failed
+// write, after which the encoder is gone and the recording should end.
+// write must only be called from one goroutine.
+func (fw *frameWriter) write(data []byte, count int64) error {
+	if fw.frames == nil {
+		return fw.writeOut(frameWrite{data: data, count: count})
+	}
+	if err := fw.failed(); err != nil {
+		return err
+	}
+	f := frameWrite{data: data, count: count}
+	for {
+		select {
+		case fw.frames <- f:
+			return nil
+		default:
+		}
+		// Full: make room by dropping the oldest frame (unless the writer just took it)
+		select {
+		case old := <-fw.frames:
+			fw.dropped.Add(old.count)
+			if fw.onDrop != nil {
+				fw.onDrop(old.count)
+			}
+		default:
+		}
+	}
+}
+
+// droppedFrames returns the frames dropped by a full buffer so far
+func (fw *frameWriter) droppedFrames() int64 {
+	return fw.dropped.Load()
+}
+
+// flush stops accepting frames and waits up to timeout for the buffered ones to be
+// written. It is safe to call more than once.
+func (fw *frameWriter) flush(timeout time.Duration) {
+	fw.once.Do(func() {
+		if fw.frames != nil {
+			close(fw.frames)
+		}
+	})
+	select {
+	case <-fw.done:
+	case <-time.After(timeout):
+	}
+}
@@ -0,0 +1,93 @@
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter blocks every write until release is closed
+type gatedWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	<-g.release
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.Write(p)
+}
+
+func (g *gatedWriter) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.String()
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestFrameWriter_DropsOldestWhenFull(t *testing.T) {
+	out := &gatedWriter{release: make(chan struct{})}
+	var dropped int64
+	fw := newFrameWriter(out, 2, func(n int64) { dropped += n })
+
+	// The first frame is taken by the writer goroutine and blocks there
+	if err := fw.write([]byte("a"), 1); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(fw.frames) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// b (x2) and c fill the buffer; d pushes out b, e pushes out c
+	for _, f := range []frameWrite{{[]byte("b"), 2}, {[]byte("c"), 1}, {[]byte("d"), 1}, {[]byte("e"), 1}} {
+		if err := fw.write(f.data, f.count); err != nil {
+			t.Fatalf("write() error = %v", err)
+		}
+	}
+	if fw.droppedFrames() != 3 || dropped != 3 {
+		t.Errorf("droppedFrames() = %d, onDrop total = %d, want 3", fw.droppedFrames(), dropped)
+	}
+
+	close(out.release)
+	fw.flush(time.Second)
+	if got := out.String(); got != "ade" {
+		t.Errorf("written frames = %q, want %q", got, "ade")
+	}
+}
+
+func TestFrameWriter_Unbuffered(t *testing.T) {
+	var out bytes.Buffer
+	fw := newFrameWriter(&out, 0, nil)
+	if err := fw.write([]byte("x"), 3); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	if out.String() != "xxx" {
+		t.Errorf("written frames = %q, want written synchronously", out.String())
+	}
+	fw.flush(0)
+}
+
+func TestFrameWriter_Error(t *testing.T) {
+	fw := newFrameWriter(failingWriter{}, 4, nil)
+	fw.write([]byte("a"), 1)
+
+	deadline := time.Now().Add(time.Second)
+	var err error
+	for err == nil && time.Now().Before(deadline) {
+		err = fw.write([]byte("b"), 1)
+		time.Sleep(time.Millisecond)
+	}
+	if err == nil {
+		t.Errorf("write() after a failed encoder write expected error")
+	}
+	fw.flush(time.Second)
+	fw.flush(time.Second) // idempotent
+}
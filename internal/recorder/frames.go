@@ -0,0 +1,164 @@
+package recorder
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxExportFrames bounds the images in one frame export
+	MaxExportFrames = 1000
+	// MaxExportFPS bounds the sampling rate of a frame export
+	MaxExportFPS = 30
+	// DefaultExportFPS samples one frame per second of recording
+	DefaultExportFPS = 1
+	// frameExportTimeout bounds the extraction of one export
+	frameExportTimeout = 5 * time.Minute
+)
+
+// FrameExportOptions selects which frames of a recording are extracted
+type FrameExportOptions struct {
+	FPS      float64       // frames per second of recording time
+	Start    time.Duration // offset into the recording
+	Duration time.Duration // 0 = until the end (capped at MaxExportFrames)
+	Format   string        // "jpeg" or "png"
+}
+
+// Validate checks the options; an explicit window must fit into MaxExportFrames
+func (o FrameExportOptions) Validate() error {
+	if o.FPS <= 0 || o.FPS > MaxExportFPS {
+		return fmt.Errorf("fps must be greater than 0 and at most %d", MaxExportFPS)
+	}
+	if o.Start < 0 {
+		return fmt.Errorf("start must be >= 0")
+	}
+	if o.Duration < 0 {
+		return fmt.Errorf("duration must be >= 0")
+	}
+	if o.Format != "jpeg" && o.Format != "png" {
+		return fmt.Errorf("format must be png or jpeg")
+	}
+	if n := o.FPS * o.Duration.Seconds(); n > MaxExportFrames {
+		return fmt.Errorf("%.0f frames requested, at most %d per export (lower fps or duration)", n, MaxExportFrames)
+	}
+	return nil
+}
+
+// FrameExport is a set of frames extracted into a temporary directory.
+// Close removes it.
+type FrameExport struct {
+	dir   string
+	Files []string // frame file names, in order
+	// Truncated is set when the recording had more frames than MaxExportFrames
+	Truncated bool
+}
+
+// ExtractFrames decodes the finished recording at input with ffmpeg and writes the
+// frames sampled by opts into a temporary directory
+func ExtractFrames(ctx context.Context, input string, opts FrameExportOptions) (*FrameExport, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "frames-")
+	if err != nil {
+		return nil, err
+	}
+	export := &FrameExport{dir: dir}
+
+	ctx, cancel := context.WithTimeout(ctx, frameExportTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "ffmpeg", frameExportArgs(input, dir, opts)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		export.Close()
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		export.Close()
+		return nil, err
+	}
+	for _, entry := range entries {
+		export.Files = append(export.Files, entry.Name())
+	}
+	sort.Strings(export.Files)
+	// One frame past the limit is extracted to tell a truncated export from an exact fit
+	if len(export.Files) > MaxExportFrames {
+		for _, name := range export.Files[MaxExportFrames:] {
+			os.Remove(filepath.Join(dir, name))
+		}
+		export.Files = export.Files[:MaxExportFrames]
+		export.Truncated = true
+	}
+	return export, nil
+}
+
+// WriteZip streams the frames as a ZIP archive. Images are stored uncompressed:
+// they are compressed already.
+func (e *FrameExport) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, name := range e.Files {
+		if err := e.addFile(zw, name); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (e *FrameExport) addFile(zw *zip.Writer, name string) error {
+	f, err := os.Open(filepath.Join(e.dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dst, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, f)
+	return err
+}
+
+// Close removes the extracted frames
+func (e *FrameExport) Close() error {
+	return os.RemoveAll(e.dir)
+}
+
+// frameExportArgs builds the ffmpeg command line that samples frames of input at
+// opts.FPS into numbered images in dir
+func frameExportArgs(input, dir string, opts FrameExportOptions) []string {
+	args := []string{"-hide_banner", "-v", "error"}
+	if opts.Start > 0 {
+		args = append(args, "-ss", exportSeconds(opts.Start)) // input seeking: skips decoding up to start
+	}
+	args = append(args, "-i", input)
+	if opts.Duration > 0 {
+		args = append(args, "-t", exportSeconds(opts.Duration))
+	}
+	args = append(args,
+		"-map", "0:v",
+		"-vf", "fps="+strconv.FormatFloat(opts.FPS, 'f', -1, 64),
+		"-frames:v", strconv.Itoa(MaxExportFrames+1),
+	)
+	ext := "png"
+	if opts.Format == "jpeg" {
+		args = append(args, "-q:v", "2")
+		ext = "jpg"
+	}
+	return append(args, filepath.Join(dir, "frame_%05d."+ext))
+}
+
+func exportSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,90 @@
+package recorder
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrameExportOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    FrameExportOptions
+		wantErr string
+	}{
+		{"defaults", FrameExportOptions{FPS: 1, Format: "jpeg"}, ""},
+		{"window at the limit", FrameExportOptions{FPS: 10, Duration: 100 * time.Second, Format: "png"}, ""},
+		{"zero fps", FrameExportOptions{Format: "jpeg"}, "fps"},
+		{"fps too high", FrameExportOptions{FPS: MaxExportFPS + 1, Format: "jpeg"}, "fps"},
+		{"negative start", FrameExportOptions{FPS: 1, Start: -time.Second, Format: "jpeg"}, "start"},
+		{"bad format", FrameExportOptions{FPS: 1, Format: "gif"}, "format"},
+		{"too many frames", FrameExportOptions{FPS: 10, Duration: 101 * time.Second, Format: "jpeg"}, "at most"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want mention of %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFrameExportArgs(t *testing.T) {
+	args := strings.Join(frameExportArgs("in.mp4", "/tmp/x", FrameExportOptions{
+		FPS: 0.5, Start: 90 * time.Second, Duration: 30 * time.Second, Format: "jpeg",
+	}), " ")
+	for _, want := range []string{"-ss 90.000 -i in.mp4 -t 30.000", "-vf fps=0.5", "-frames:v 1001", "/tmp/x/frame_%05d.jpg"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("frameExportArgs() = %q, missing %q", args, want)
+		}
+	}
+
+	args = strings.Join(frameExportArgs("in.mp4", "/tmp/x", FrameExportOptions{FPS: 1, Format: "png"}), " ")
+	if strings.Contains(args, "-ss") || strings.Contains(args, "-t ") || !strings.HasSuffix(args, "frame_%05d.png") {
+		t.Errorf("frameExportArgs() whole recording as png = %q", args)
+	}
+}
+
+func TestFrameExport_WriteZip(t *testing.T) {
+	dir := t.TempDir()
+	export := &FrameExport{dir: dir, Files: []string{"frame_00001.jpg", "frame_00002.jpg"}}
+	for i, name := range export.Files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte{byte(i)}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteZip(&buf); err != nil {
+		t.Fatalf("WriteZip() error = %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("WriteZip() output is not a ZIP: %v", err)
+	}
+	if len(zr.File) != 2 || zr.File[1].Name != "frame_00002.jpg" {
+		t.Fatalf("WriteZip() entries = %v", zr.File)
+	}
+	f, _ := zr.File[1].Open()
+	data, _ := io.ReadAll(f)
+	if !bytes.Equal(data, []byte{1}) {
+		t.Errorf("WriteZip() frame_00002.jpg = %v, want [1]", data)
+	}
+
+	export.Close()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Close() left the frame directory behind")
+	}
+}
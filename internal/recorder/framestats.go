@@ -0,0 +1,76 @@
+package recorder
+
+import (
+	"errors"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// FrameStats summarizes capture health for an active recording
+type FrameStats struct {
+	Captured int64 `json:"captured_frames"`
+	Dropped  int64 `json:"dropped_frames"`
+	// Frames discarded by the full frame buffer while the encoder stalled
+	BufferDropped int64 `json:"buffer_dropped_frames"`
+}
+
+// Degraded reports whether the share of dropped captures exceeds ratio (0 disables)
+func (s FrameStats) Degraded(ratio float64) bool {
+	total := s.Captured + s.Dropped
+	if ratio <= 0 || total == 0 {
+		return false
+	}
+	return float64(s.Dropped)/float64(total) > ratio
+}
+
+// GetFrameStats returns the capture counters of an active recording
+func (w *Worker) GetFrameStats(key SessionKey) (FrameStats, bool) {
+	w.framesMu.RLock()
+	defer w.framesMu.RUnlock()
+
+	stats, ok := w.frameStats[key]
+	return stats, ok
+}
+
+// countFrame records the outcome of one capture attempt (after retries)
+func (w *Worker) countFrame(key SessionKey, dropped bool) {
+	w.framesMu.Lock()
+	defer w.framesMu.Unlock()
+
+	stats := w.frameStats[key]
+	if dropped {
+		stats.Dropped++
+	} else {
+		stats.Captured++
+	}
+	w.frameStats[key] = stats
+}
+
+// countBufferDropped records frames the full frame buffer discarded
+func (w *Worker) countBufferDropped(key SessionKey, frames int64) {
+	w.framesMu.Lock()
+	defer w.framesMu.Unlock()
+
+	stats := w.frameStats[key]
+	stats.BufferDropped += frames
+	w.frameStats[key] = stats
+}
+
+// isTransientScreenshotError reports whether retrying the capture can help.
+// A closed page/context will fail every retry, so it is given up on immediately.
+func isTransientScreenshotError(err error) bool {
+	return !errors.Is(err, playwright.ErrTargetClosed)
+}
+
+// captureWithRetry runs capture, retrying transient failures up to retries times.
+// It returns the number of attempts made along with the final result.
+func captureWithRetry(capture func() ([]byte, error), retries int, transient func(error) bool) ([]byte, int, error) {
+	attempts := 0
+	for {
+		attempts++
+		buf, err := capture()
+		if err == nil || attempts > retries || !transient(err) {
+			return buf, attempts, err
+		}
+	}
+}
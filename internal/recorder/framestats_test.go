@@ -0,0 +1,66 @@
+package recorder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFrameStats_Degraded(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats FrameStats
+		ratio float64
+		want  bool
+	}{
+		{"No Frames", FrameStats{}, 0.05, false},
+		{"Below Threshold", FrameStats{Captured: 99, Dropped: 1}, 0.05, false},
+		{"At Threshold", FrameStats{Captured: 95, Dropped: 5}, 0.05, false},
+		{"Above Threshold", FrameStats{Captured: 90, Dropped: 10}, 0.05, true},
+		{"Disabled", FrameStats{Captured: 0, Dropped: 10}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.Degraded(tt.ratio); got != tt.want {
+				t.Errorf("Degraded(%v) = %v, want %v", tt.ratio, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaptureWithRetry(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	errFatal := errors.New("fatal")
+	transient := func(err error) bool { return err == errFlaky }
+
+	// Succeeds on the second attempt
+	calls := 0
+	buf, attempts, err := captureWithRetry(func() ([]byte, error) {
+		calls++
+		if calls == 1 {
+			return nil, errFlaky
+		}
+		return []byte("frame"), nil
+	}, 2, transient)
+	if err != nil || string(buf) != "frame" || attempts != 2 {
+		t.Errorf("captureWithRetry() = %q, %d, %v; want frame, 2, nil", buf, attempts, err)
+	}
+
+	// Gives up after retries are exhausted
+	calls = 0
+	_, attempts, err = captureWithRetry(func() ([]byte, error) {
+		calls++
+		return nil, errFlaky
+	}, 1, transient)
+	if !errors.Is(err, errFlaky) || attempts != 2 {
+		t.Errorf("captureWithRetry() attempts = %d, err = %v; want 2, flaky", attempts, err)
+	}
+
+	// Non-transient errors are not retried
+	_, attempts, err = captureWithRetry(func() ([]byte, error) {
+		return nil, errFatal
+	}, 2, transient)
+	if !errors.Is(err, errFatal) || attempts != 1 {
+		t.Errorf("captureWithRetry() attempts = %d, err = %v; want 1, fatal", attempts, err)
+	}
+}
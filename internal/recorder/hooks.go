@@ -0,0 +1,131 @@
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Hook kinds, passed to the command as RECORDER_HOOK
+const (
+	HookPreRecording  = "pre_recording"
+	HookPostRecording = "post_recording"
+)
+
+const (
+	// maxHookOutput bounds the hook output kept for the log
+	maxHookOutput = 64 * 1024
+	// hookWaitDelay bounds the wait for output pipes held open by processes that left
+	// the hook's process group
+	hookWaitDelay = 5 * time.Second
+)
+
+// HookInfo is the recording metadata handed to a hook
+type HookInfo struct {
+	TaskID      int64
+	RecordingID int64
+	Viewport    string
+	URL         string
+	OutputPath  string
+	Status      string // post_recording only: COMPLETED or FAILED
+	Error       string // post_recording only: why the recording failed
+}
+
+// env returns the metadata as RECORDER_* environment variables
+func (i HookInfo) env(kind string) []string {
+	env := []string{
+		"RECORDER_HOOK=" + kind,
+		"RECORDER_TASK_ID=" + strconv.FormatInt(i.TaskID, 10),
+		"RECORDER_RECORDING_ID=" + strconv.FormatInt(i.RecordingID, 10),
+		"RECORDER_VIEWPORT=" + i.Viewport,
+		"RECORDER_URL=" + i.URL,
+		"RECORDER_OUTPUT_PATH=" + i.OutputPath,
+	}
+	if kind == HookPostRecording {
+		env = append(env, "RECORDER_STATUS="+i.Status, "RECORDER_ERROR="+i.Error)
+	}
+	return env
+}
+
+// hookCommand returns the configured command of the kind; hooks never run without ALLOW_HOOKS
+func (w *Worker) hookCommand(kind string) string {
+	if !w.config.AllowHooks {
+		return ""
+	}
+	switch kind {
+	case HookPreRecording:
+		return w.config.PreRecordingHook
+	case HookPostRecording:
+		return w.config.PostRecordingHook
+	}
+	return ""
+}
+
+// runHook runs the configured hook of the kind with sh -c, bounded by HOOK_TIMEOUT
+// and ctx, and logs its output. It is a no-op when no hook is configured.
+func (w *Worker) runHook(ctx context.Context, kind string, info HookInfo) error {
+	command := w.hookCommand(kind)
+	if command == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, w.config.HookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), info.env(kind)...)
+	// Own process group, so a timeout kills whatever the shell started too
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+	cmd.WaitDelay = hookWaitDelay
+	out := &limitedBuffer{max: maxHookOutput}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	start := time.Now()
+	err := cmd.Run()
+	logHookOutput(kind, info.RecordingID, out)
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s hook timed out after %s", kind, w.config.HookTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook failed: %w", kind, err)
+	}
+	log.Printf("Hook %s for recording %d finished in %s", kind, info.RecordingID, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func logHookOutput(kind string, recordingID int64, out *limitedBuffer) {
+	scanner := bufio.NewScanner(bytes.NewReader(out.buf.Bytes()))
+	scanner.Buffer(make([]byte, 0, 4096), maxHookOutput)
+	for scanner.Scan() {
+		log.Printf("Hook %s for recording %d: %s", kind, recordingID, scanner.Text())
+	}
+	if out.truncated {
+		log.Printf("Hook %s for recording %d: output truncated at %d bytes", kind, recordingID, maxHookOutput)
+	}
+}
+
+// limitedBuffer keeps the first max bytes written to it and discards the rest
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
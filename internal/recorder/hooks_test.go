@@ -0,0 +1,77 @@
+package recorder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/config"
+)
+
+func TestRunHook(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "env.txt")
+	info := HookInfo{TaskID: 7, RecordingID: 42, Viewport: "mobile", URL: "https://example.com", OutputPath: "/data/x.mkv", Status: "FAILED", Error: "boom"}
+
+	w := &Worker{config: &config.Config{
+		AllowHooks:        true,
+		PostRecordingHook: `echo "$RECORDER_HOOK $RECORDER_TASK_ID $RECORDER_RECORDING_ID $RECORDER_VIEWPORT $RECORDER_STATUS $RECORDER_ERROR" > ` + out,
+		HookTimeout:       10 * time.Second,
+	}}
+	if err := w.runHook(context.Background(), HookPostRecording, info); err != nil {
+		t.Fatalf("runHook() error = %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "post_recording 7 42 mobile FAILED boom"; strings.TrimSpace(string(got)) != want {
+		t.Errorf("hook environment = %q, want %q", strings.TrimSpace(string(got)), want)
+	}
+
+	// Unconfigured kind is a no-op
+	if err := w.runHook(context.Background(), HookPreRecording, info); err != nil {
+		t.Errorf("runHook() without a pre_recording hook error = %v", err)
+	}
+}
+
+func TestRunHook_Errors(t *testing.T) {
+	info := HookInfo{TaskID: 1, RecordingID: 1}
+
+	w := &Worker{config: &config.Config{AllowHooks: true, PreRecordingHook: "exit 3", HookTimeout: 10 * time.Second}}
+	if err := w.runHook(context.Background(), HookPreRecording, info); err == nil {
+		t.Errorf("runHook() with a failing command expected error")
+	}
+
+	w = &Worker{config: &config.Config{AllowHooks: true, PreRecordingHook: "sleep 10", HookTimeout: 100 * time.Millisecond}}
+	start := time.Now()
+	err := w.runHook(context.Background(), HookPreRecording, info)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("runHook() past HOOK_TIMEOUT error = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("runHook() took %s past the timeout", elapsed)
+	}
+
+	// Without ALLOW_HOOKS nothing runs
+	w = &Worker{config: &config.Config{PreRecordingHook: "exit 3", HookTimeout: 10 * time.Second}}
+	if err := w.runHook(context.Background(), HookPreRecording, info); err != nil {
+		t.Errorf("runHook() without ALLOW_HOOKS error = %v, want no-op", err)
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := &limitedBuffer{max: 4}
+	if n, err := b.Write([]byte("abc")); n != 3 || err != nil {
+		t.Fatalf("Write() = %d, %v", n, err)
+	}
+	if n, err := b.Write([]byte("def")); n != 3 || err != nil {
+		t.Fatalf("Write() past the limit = %d, %v, want the write to be swallowed", n, err)
+	}
+	if b.buf.String() != "abcd" || !b.truncated {
+		t.Errorf("buffer = %q truncated=%v, want \"abcd\" truncated", b.buf.String(), b.truncated)
+	}
+}
@@ -0,0 +1,309 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/jpeg"
+	"log"
+	"math"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultInteractiveWidth/Height match the recording viewport
+	DefaultInteractiveWidth  = 1920
+	DefaultInteractiveHeight = 1080
+
+	// Interactive viewport bounds (keep screenshots cheap and pages usable)
+	MinInteractiveWidth  = 320
+	MinInteractiveHeight = 240
+	MaxInteractiveWidth  = 3840
+	MaxInteractiveHeight = 2160
+
+	// MinInteractiveScale bounds frame downscaling (1 sends frames at viewport size)
+	MinInteractiveScale = 0.25
+
+	// Interactive frame encodings
+	FrameFormatJPEG = "jpeg"
+	FrameFormatWebP = "webp"
+
+	// interactiveQuality is low on purpose; remote control favours latency over fidelity
+	interactiveQuality = 60
+
+	// keyframeInterval forces a full frame even when the page looks static
+	keyframeInterval = 10 * time.Second
+	// keepaliveInterval bounds the silence between messages during static periods
+	keepaliveInterval = 2 * time.Second
+)
+
+// InteractiveOptions configures a remote control session independently of recording
+type InteractiveOptions struct {
+	Width  int
+	Height int
+	Format string  // FrameFormatJPEG or FrameFormatWebP
+	Scale  float64 // frame size relative to the viewport; 0 or 1 sends full-size frames
+}
+
+// DefaultInteractiveOptions returns the options used when the client sends none
+func DefaultInteractiveOptions() InteractiveOptions {
+	return InteractiveOptions{
+		Width:  DefaultInteractiveWidth,
+		Height: DefaultInteractiveHeight,
+		Format: FrameFormatJPEG,
+		Scale:  1,
+	}
+}
+
+// Validate checks the requested viewport and frame scale against the allowed bounds
+func (o InteractiveOptions) Validate() error {
+	if o.Width < MinInteractiveWidth || o.Width > MaxInteractiveWidth {
+		return fmt.Errorf("width must be between %d and %d", MinInteractiveWidth, MaxInteractiveWidth)
+	}
+	if o.Height < MinInteractiveHeight || o.Height > MaxInteractiveHeight {
+		return fmt.Errorf("height must be between %d and %d", MinInteractiveHeight, MaxInteractiveHeight)
+	}
+	if o.Format != FrameFormatJPEG && o.Format != FrameFormatWebP {
+		return fmt.Errorf("format must be %q or %q", FrameFormatJPEG, FrameFormatWebP)
+	}
+	if o.Scale != 0 && (math.IsNaN(o.Scale) || o.Scale < MinInteractiveScale || o.Scale > 1) {
+		return fmt.Errorf("scale must be between %g and 1", MinInteractiveScale)
+	}
+	return nil
+}
+
+// frameScale returns the effective frame scale (unset means full size)
+func (o InteractiveOptions) frameScale() float64 {
+	if o.Scale <= 0 || o.Scale > 1 {
+		return 1
+	}
+	return o.Scale
+}
+
+// frameSize returns the pixel size of the frames sent to the client
+func (o InteractiveOptions) frameSize() (int, int) {
+	return scaledSize(o.Width, o.Height, o.frameScale())
+}
+
+func scaledSize(width, height int, scale float64) (int, int) {
+	w := int(math.Round(float64(width) * scale))
+	h := int(math.Round(float64(height) * scale))
+	return max(w, 1), max(h, 1)
+}
+
+// InteractiveProtocolVersion is the current /interact protocol version.
+// Clients negotiate it with the "dashboard-recorder.v<N>" WebSocket subprotocol;
+// clients that offer no subprotocol are treated as version 1.
+const InteractiveProtocolVersion = 1
+
+// InteractiveSubprotocols lists the subprotocols the server accepts, preferred first
+var InteractiveSubprotocols = []string{"dashboard-recorder.v1"}
+
+// InteractiveEvents are the InteractionEvent types understood by this server
+var InteractiveEvents = []string{"click", "type", "key", "save"}
+
+// NegotiateInteractiveProtocol picks a supported subprotocol from the client's offer.
+// An empty offer is a legacy client and is accepted with no subprotocol.
+func NegotiateInteractiveProtocol(offered []string) (subprotocol string, ok bool) {
+	if len(offered) == 0 {
+		return "", true
+	}
+	for _, supported := range InteractiveSubprotocols {
+		for _, o := range offered {
+			if o == supported {
+				return supported, true
+			}
+		}
+	}
+	return "", false
+}
+
+// eventLimiter drops inbound events beyond the configured rate so a flooding
+// client can't hammer the browser. "save" ends the session and is never dropped.
+type eventLimiter struct {
+	limiter *rate.Limiter // nil = unlimited
+	dropped int
+}
+
+func newEventLimiter(perSecond float64, burst int) *eventLimiter {
+	if perSecond <= 0 {
+		return &eventLimiter{}
+	}
+	return &eventLimiter{limiter: rate.NewLimiter(rate.Limit(perSecond), burst)}
+}
+
+// allow reports whether an event of the given type received at now is processed
+func (l *eventLimiter) allow(eventType string, now time.Time) bool {
+	if l.limiter == nil || eventType == "save" || l.limiter.AllowN(now, 1) {
+		return true
+	}
+	l.dropped++
+	return false
+}
+
+// interactiveEventLimits returns INTERACTIVE_EVENT_RATE and INTERACTIVE_EVENT_BURST
+func (w *Worker) interactiveEventLimits() (float64, int) {
+	if w.config == nil {
+		return 0, 0
+	}
+	return w.config.InteractiveEventRate, w.config.InteractiveEventBurst
+}
+
+// InteractiveHello is the first (text) message of a session so the client can size its canvas.
+// Width/Height are the viewport (the coordinate space of click events); frames are
+// FrameWidth x FrameHeight, i.e. viewport * Scale, so clients divide canvas
+// coordinates by Scale before sending them.
+type InteractiveHello struct {
+	Type        string   `json:"type"` // always "hello"
+	Version     int      `json:"version"`
+	Width       int      `json:"width"`
+	Height      int      `json:"height"`
+	FrameWidth  int      `json:"frame_width"`
+	FrameHeight int      `json:"frame_height"`
+	Scale       float64  `json:"scale"`
+	Format      string   `json:"format"` // encoding of the binary frames that follow
+	Events      []string `json:"events"`
+	Features    []string `json:"features"`
+}
+
+// frameCapturer returns a screenshot function for the requested format and scale.
+// Playwright's screenshot API only knows full-size PNG/JPEG, so WebP and scaled
+// frames go through the Chromium DevTools protocol, which downscales in the browser.
+// If that is unavailable (or the first capture fails) it falls back to JPEG, resized
+// in Go when scaled, and reports the format actually used.
+func frameCapturer(bCtx playwright.BrowserContext, page playwright.Page, options InteractiveOptions) (func() ([]byte, error), string) {
+	scale := options.frameScale()
+	jpegFrame := func() ([]byte, error) {
+		img, err := page.Screenshot(playwright.PageScreenshotOptions{
+			Type:    playwright.ScreenshotTypeJpeg,
+			Quality: playwright.Int(interactiveQuality),
+		})
+		if err != nil || scale == 1 {
+			return img, err
+		}
+		return downscaleJPEG(img, scale)
+	}
+	if options.Format != FrameFormatWebP && scale == 1 {
+		return jpegFrame, FrameFormatJPEG
+	}
+
+	session, err := bCtx.NewCDPSession(page)
+	if err != nil {
+		log.Printf("DevTools %s frames unavailable, falling back to JPEG: %v", options.Format, err)
+		return jpegFrame, FrameFormatJPEG
+	}
+	params := map[string]interface{}{
+		"format":  options.Format,
+		"quality": interactiveQuality,
+	}
+	if scale != 1 {
+		params["clip"] = map[string]interface{}{
+			"x":      0,
+			"y":      0,
+			"width":  options.Width,
+			"height": options.Height,
+			"scale":  scale,
+		}
+	}
+	cdpFrame := func() ([]byte, error) {
+		result, err := session.Send("Page.captureScreenshot", params)
+		if err != nil {
+			return nil, err
+		}
+		fields, ok := result.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected captureScreenshot result")
+		}
+		data, ok := fields["data"].(string)
+		if !ok {
+			return nil, fmt.Errorf("captureScreenshot returned no data")
+		}
+		return base64.StdEncoding.DecodeString(data)
+	}
+
+	// Probe once so the handshake never promises a format we cannot deliver
+	if _, err := cdpFrame(); err != nil {
+		log.Printf("DevTools %s capture failed, falling back to JPEG: %v", options.Format, err)
+		session.Detach()
+		return jpegFrame, FrameFormatJPEG
+	}
+	return cdpFrame, options.Format
+}
+
+// downscaleJPEG shrinks a JPEG frame by scale using a box filter. It is the
+// fallback when the browser can't downscale screenshots itself.
+func downscaleJPEG(data []byte, scale float64) ([]byte, error) {
+	src, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode frame: %w", err)
+	}
+	b := src.Bounds()
+	dw, dh := scaledSize(b.Dx(), b.Dy(), scale)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		y0 := b.Min.Y + y*b.Dy()/dh
+		y1 := max(b.Min.Y+(y+1)*b.Dy()/dh, y0+1)
+		for x := 0; x < dw; x++ {
+			x0 := b.Min.X + x*b.Dx()/dw
+			x1 := max(b.Min.X+(x+1)*b.Dx()/dw, x0+1)
+
+			var r, g, bl, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := src.At(sx, sy).RGBA()
+					r, g, bl, n = r+cr, g+cg, bl+cb, n+1
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(bl / n >> 8)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: interactiveQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode frame: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// frameFilter suppresses frames identical to the last one sent, except for a
+// periodic keyframe so late decoders and lossy links recover.
+type frameFilter struct {
+	lastHash    uint64
+	lastFrameAt time.Time // last binary frame
+	lastSent    time.Time // last message of any kind
+}
+
+// shouldSend reports whether frame must be transmitted and records it if so
+func (f *frameFilter) shouldSend(frame []byte, now time.Time) bool {
+	h := fnv.New64a()
+	h.Write(frame)
+	sum := h.Sum64()
+
+	if !f.lastFrameAt.IsZero() && sum == f.lastHash && now.Sub(f.lastFrameAt) < keyframeInterval {
+		return false
+	}
+
+	f.lastHash = sum
+	f.lastFrameAt = now
+	f.lastSent = now
+	return true
+}
+
+// needsKeepalive reports whether the client has gone too long without a message
+func (f *frameFilter) needsKeepalive(now time.Time) bool {
+	if now.Sub(f.lastSent) < keepaliveInterval {
+		return false
+	}
+	f.lastSent = now
+	return true
+}
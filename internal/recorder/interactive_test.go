@@ -0,0 +1,139 @@
+package recorder
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInteractiveOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    InteractiveOptions
+		wantErr bool
+	}{
+		{"Default", DefaultInteractiveOptions(), false},
+		{"Small Window", InteractiveOptions{Width: 1280, Height: 720, Format: FrameFormatJPEG}, false},
+		{"Minimum", InteractiveOptions{Width: MinInteractiveWidth, Height: MinInteractiveHeight, Format: FrameFormatJPEG}, false},
+		{"Too Narrow", InteractiveOptions{Width: 100, Height: 720, Format: FrameFormatJPEG}, true},
+		{"Too Tall", InteractiveOptions{Width: 1280, Height: 10000, Format: FrameFormatJPEG}, true},
+		{"Zero", InteractiveOptions{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestInteractiveOptions_Format(t *testing.T) {
+	opts := DefaultInteractiveOptions()
+	assert.Equal(t, FrameFormatJPEG, opts.Format)
+
+	opts.Format = FrameFormatWebP
+	assert.NoError(t, opts.Validate())
+
+	opts.Format = "png"
+	assert.Error(t, opts.Validate())
+}
+
+func TestInteractiveOptions_Scale(t *testing.T) {
+	opts := DefaultInteractiveOptions()
+	w, h := opts.frameSize()
+	assert.Equal(t, 1920, w)
+	assert.Equal(t, 1080, h)
+
+	opts.Scale = 2.0 / 3.0
+	assert.NoError(t, opts.Validate())
+	w, h = opts.frameSize()
+	assert.Equal(t, 1280, w)
+	assert.Equal(t, 720, h)
+
+	opts.Scale = 0
+	assert.NoError(t, opts.Validate(), "unset scale means full size")
+	assert.Equal(t, 1.0, opts.frameScale())
+
+	for _, bad := range []float64{0.1, 1.5, -1} {
+		opts.Scale = bad
+		assert.Error(t, opts.Validate(), "scale %v", bad)
+	}
+}
+
+func TestDownscaleJPEG(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 64, 36))
+	for i := range src.Pix {
+		src.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, src, nil))
+
+	out, err := downscaleJPEG(buf.Bytes(), 0.5)
+	assert.NoError(t, err)
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 32, 18), img.Bounds())
+
+	_, err = downscaleJPEG([]byte("not a jpeg"), 0.5)
+	assert.Error(t, err)
+}
+
+func TestFrameFilter(t *testing.T) {
+	f := &frameFilter{}
+	start := time.Now()
+	frame := []byte("frame-a")
+
+	assert.True(t, f.shouldSend(frame, start), "first frame is always sent")
+	assert.False(t, f.shouldSend(frame, start.Add(time.Second)), "unchanged frame is suppressed")
+	assert.True(t, f.shouldSend([]byte("frame-b"), start.Add(2*time.Second)), "changed frame is sent")
+	assert.False(t, f.shouldSend([]byte("frame-b"), start.Add(3*time.Second)))
+	assert.True(t, f.shouldSend([]byte("frame-b"), start.Add(2*time.Second+keyframeInterval)), "keyframe after interval")
+}
+
+func TestFrameFilter_Keepalive(t *testing.T) {
+	start := time.Now()
+	f := &frameFilter{lastSent: start}
+
+	assert.False(t, f.needsKeepalive(start.Add(time.Second)))
+	assert.True(t, f.needsKeepalive(start.Add(keepaliveInterval)))
+	assert.False(t, f.needsKeepalive(start.Add(keepaliveInterval+time.Second)), "keepalive resets the timer")
+}
+
+func TestNegotiateInteractiveProtocol(t *testing.T) {
+	sub, ok := NegotiateInteractiveProtocol(nil)
+	assert.True(t, ok, "legacy clients without a subprotocol are accepted")
+	assert.Equal(t, "", sub)
+
+	sub, ok = NegotiateInteractiveProtocol([]string{"dashboard-recorder.v9", "dashboard-recorder.v1"})
+	assert.True(t, ok)
+	assert.Equal(t, "dashboard-recorder.v1", sub)
+
+	_, ok = NegotiateInteractiveProtocol([]string{"dashboard-recorder.v9"})
+	assert.False(t, ok)
+}
+
+func TestEventLimiter(t *testing.T) {
+	now := time.Now()
+	l := newEventLimiter(10, 2)
+
+	assert.True(t, l.allow("click", now))
+	assert.True(t, l.allow("click", now))
+	assert.False(t, l.allow("click", now), "burst exhausted")
+	assert.True(t, l.allow("save", now), "save is never dropped")
+	assert.True(t, l.allow("key", now.Add(100*time.Millisecond)), "tokens refill at the rate")
+	assert.Equal(t, 1, l.dropped)
+
+	unlimited := newEventLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		assert.True(t, unlimited.allow("click", now))
+	}
+}
@@ -0,0 +1,102 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const (
+	// MaxKeepAliveActions bounds the actions of one keep-alive
+	MaxKeepAliveActions = 10
+	// MinKeepAliveInterval/MaxKeepAliveInterval bound the time between keep-alive runs
+	MinKeepAliveInterval = 10 * time.Second
+	MaxKeepAliveInterval = time.Hour
+	// maxKeepAliveScroll bounds the wheel delta of a scroll action, in pixels
+	maxKeepAliveScroll = 10000
+	// maxKeepAliveKeyLength bounds a key name (e.g. "Shift+ArrowDown")
+	maxKeepAliveKeyLength = 32
+)
+
+// KeepAliveActions are the InteractionEvent types a keep-alive may perform
+var KeepAliveActions = []string{"click", "key", "scroll"}
+
+// KeepAlive is a task's list of interactions performed every IntervalSeconds while
+// recording, for dashboards that log out or pause when idle. Actions run between
+// frames on the recorded page, so their effect (e.g. a scroll) is recorded too.
+type KeepAlive struct {
+	IntervalSeconds int64              `json:"interval_seconds"`
+	Actions         []InteractionEvent `json:"actions"`
+}
+
+// interval is the time between keep-alive runs
+func (k KeepAlive) interval() time.Duration {
+	return time.Duration(k.IntervalSeconds) * time.Second
+}
+
+// Validate checks the interval and that every action is a click or scroll inside the
+// viewport, or a named key press
+func (k KeepAlive) Validate(viewport Viewport) error {
+	if k.interval() < MinKeepAliveInterval || k.interval() > MaxKeepAliveInterval {
+		return fmt.Errorf("keepalive interval_seconds must be between %d and %d", int64(MinKeepAliveInterval.Seconds()), int64(MaxKeepAliveInterval.Seconds()))
+	}
+	if len(k.Actions) == 0 || len(k.Actions) > MaxKeepAliveActions {
+		return fmt.Errorf("keepalive must have between 1 and %d actions", MaxKeepAliveActions)
+	}
+	for i, a := range k.Actions {
+		switch a.Type {
+		case "click", "scroll":
+			if a.X < 0 || a.Y < 0 || a.X >= float64(viewport.Width) || a.Y >= float64(viewport.Height) {
+				return fmt.Errorf("keepalive action %d must be within the %dx%d viewport", i+1, viewport.Width, viewport.Height)
+			}
+			if a.Type == "scroll" && (math.Abs(a.DeltaX) > maxKeepAliveScroll || math.Abs(a.DeltaY) > maxKeepAliveScroll || (a.DeltaX == 0 && a.DeltaY == 0)) {
+				return fmt.Errorf("keepalive action %d must scroll by a non-zero delta of at most %d pixels", i+1, maxKeepAliveScroll)
+			}
+		case "key":
+			if a.Key == "" || len(a.Key) > maxKeepAliveKeyLength {
+				return fmt.Errorf("keepalive action %d needs a key of at most %d characters", i+1, maxKeepAliveKeyLength)
+			}
+		default:
+			return fmt.Errorf("keepalive action %d has invalid type %q. Allowed: click, key, scroll", i+1, a.Type)
+		}
+	}
+	return nil
+}
+
+// EncodeKeepAlive serializes a keep-alive for the tasks.keepalive column ("" when nil)
+func EncodeKeepAlive(k *KeepAlive) (string, error) {
+	if k == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(k)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// DecodeKeepAlive parses the tasks.keepalive column; nil when the task has none
+func DecodeKeepAlive(stored string) (*KeepAlive, error) {
+	if stored == "" {
+		return nil, nil
+	}
+	var k KeepAlive
+	if err := json.Unmarshal([]byte(stored), &k); err != nil {
+		return nil, fmt.Errorf("invalid stored keepalive: %w", err)
+	}
+	return &k, nil
+}
+
+// runKeepAlive performs the keep-alive actions in order. A failed action is logged
+// and skipped: a dashboard that ignores one must not stop the recording.
+func runKeepAlive(page playwright.Page, key SessionKey, k *KeepAlive) {
+	for _, a := range k.Actions {
+		if err := applyInteraction(page, a); err != nil {
+			log.Printf("Keep-alive %s for %s failed: %v", a.Type, key, err)
+		}
+	}
+}
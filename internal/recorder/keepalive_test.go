@@ -0,0 +1,54 @@
+package recorder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeepAlive_Validate(t *testing.T) {
+	viewport := Viewport{Width: 800, Height: 600}
+	click := InteractionEvent{Type: "click", X: 10, Y: 10}
+	tests := []struct {
+		name      string
+		keepAlive KeepAlive
+		wantError string
+	}{
+		{"Valid", KeepAlive{IntervalSeconds: 60, Actions: []InteractionEvent{click, {Type: "key", Key: "Shift"}, {Type: "scroll", X: 400, Y: 300, DeltaY: 100}}}, ""},
+		{"Interval Too Short", KeepAlive{IntervalSeconds: 5, Actions: []InteractionEvent{click}}, "interval_seconds"},
+		{"Interval Too Long", KeepAlive{IntervalSeconds: 7200, Actions: []InteractionEvent{click}}, "interval_seconds"},
+		{"No Actions", KeepAlive{IntervalSeconds: 60}, "between 1 and"},
+		{"Click Outside Viewport", KeepAlive{IntervalSeconds: 60, Actions: []InteractionEvent{{Type: "click", X: 800, Y: 10}}}, "within"},
+		{"Empty Key", KeepAlive{IntervalSeconds: 60, Actions: []InteractionEvent{{Type: "key"}}}, "needs a key"},
+		{"Zero Scroll", KeepAlive{IntervalSeconds: 60, Actions: []InteractionEvent{{Type: "scroll", X: 1, Y: 1}}}, "non-zero"},
+		{"Typing Not Allowed", KeepAlive{IntervalSeconds: 60, Actions: []InteractionEvent{{Type: "type", Text: "x"}}}, "invalid type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.keepAlive.Validate(viewport)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Validate() error = %v, want substring %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestKeepAlive_RoundTrip(t *testing.T) {
+	stored, err := EncodeKeepAlive(&KeepAlive{IntervalSeconds: 120, Actions: []InteractionEvent{{Type: "scroll", X: 5, Y: 5, DeltaY: -50}}})
+	if err != nil {
+		t.Fatalf("EncodeKeepAlive() error = %v", err)
+	}
+	k, err := DecodeKeepAlive(stored)
+	if err != nil || k == nil || k.IntervalSeconds != 120 || k.Actions[0].DeltaY != -50 {
+		t.Errorf("DecodeKeepAlive() = %+v, %v", k, err)
+	}
+	if k, err := DecodeKeepAlive(""); k != nil || err != nil {
+		t.Errorf("DecodeKeepAlive(\"\") = %+v, %v, want nil", k, err)
+	}
+}
@@ -0,0 +1,78 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrLiveUnavailable is returned when a recording has no live copy to stream
+// (not recording, LIVE_PLAYBACK disabled, or ffmpeg hasn't created it yet)
+var ErrLiveUnavailable = errors.New("live playback is not available")
+
+// liveFollowInterval is how often a live stream polls for newly written fragments
+const liveFollowInterval = 500 * time.Millisecond
+
+// LivePath returns the fragmented MP4 copy written while recording ("foo.mkv" -> "foo.live.mp4")
+func LivePath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".live.mp4"
+}
+
+// OpenLive opens the live copy of the session's in-progress recording. Reads follow the
+// file as ffmpeg appends to it and return io.EOF once the recording has finished and
+// everything written has been read.
+func (w *Worker) OpenLive(ctx context.Context, key SessionKey, outputPath string) (io.ReadCloser, error) {
+	if !w.config.LivePlayback || !w.isRecording(key) {
+		return nil, ErrLiveUnavailable
+	}
+	f, err := os.Open(LivePath(outputPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrLiveUnavailable
+		}
+		return nil, err
+	}
+	return &liveReader{ctx: ctx, f: f, active: func() bool { return w.isRecording(key) }}, nil
+}
+
+// isRecording reports whether the session is running
+func (w *Worker) isRecording(key SessionKey) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.sessions[key]
+	return ok
+}
+
+// liveReader tails a file that is still being written
+type liveReader struct {
+	ctx    context.Context
+	f      *os.File
+	active func() bool
+}
+
+func (r *liveReader) Read(p []byte) (int, error) {
+	for {
+		// Checked before reading: once the writer is gone, an EOF really is the end
+		active := r.active()
+		n, err := r.f.Read(p)
+		if n > 0 || !errors.Is(err, io.EOF) {
+			return n, err
+		}
+		if !active {
+			return 0, io.EOF
+		}
+		select {
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		case <-time.After(liveFollowInterval):
+		}
+	}
+}
+
+func (r *liveReader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,68 @@
+package recorder
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLivePath(t *testing.T) {
+	if got := LivePath("/app/recordings/task_1.mkv"); got != "/app/recordings/task_1.live.mp4" {
+		t.Errorf("LivePath() = %q", got)
+	}
+}
+
+func TestLiveReader_FollowsUntilInactive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.live.mp4")
+	if err := os.WriteFile(path, []byte("init"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var active atomic.Bool
+	active.Store(true)
+	r := &liveReader{ctx: context.Background(), f: f, active: active.Load}
+
+	go func() {
+		time.Sleep(2 * liveFollowInterval)
+		out, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		out.Write([]byte("+frag"))
+		out.Close()
+		active.Store(false)
+	}()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(data) != "init+frag" {
+		t.Errorf("ReadAll() = %q, want data appended while active", data)
+	}
+}
+
+func TestLiveReader_ContextCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.live.mp4")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := &liveReader{ctx: ctx, f: f, active: func() bool { return true }}
+	if _, err := r.Read(make([]byte, 8)); err != context.Canceled {
+		t.Errorf("Read() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,95 @@
+package recorder
+
+import (
+	"sync"
+	"time"
+)
+
+// logSubscriberBuffer is how many events a slow subscriber may lag before events are dropped
+const logSubscriberBuffer = 64
+
+// LogEvent is one entry pushed to live log subscribers of a recording
+type LogEvent struct {
+	Type    string    `json:"type"` // "log" (page console/network) or "status"
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind,omitempty"` // e.g. console.error, requestfailed
+	Message string    `json:"message"`
+}
+
+// logHub fans recording events out to WebSocket subscribers. The zero value is ready to use.
+// Only sessions with a running recording accept subscribers; ending the recording closes
+// every subscriber channel so streams terminate with it.
+type logHub struct {
+	mu     sync.Mutex
+	active map[SessionKey]map[chan LogEvent]struct{}
+}
+
+// open marks a session as recording so subscribers can attach
+func (h *logHub) open(key SessionKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.active == nil {
+		h.active = make(map[SessionKey]map[chan LogEvent]struct{})
+	}
+	if _, ok := h.active[key]; !ok {
+		h.active[key] = make(map[chan LogEvent]struct{})
+	}
+}
+
+// close ends the session's stream and closes all subscriber channels
+func (h *logHub) close(key SessionKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.active[key] {
+		close(ch)
+	}
+	delete(h.active, key)
+}
+
+// publish delivers ev without blocking; subscribers that fall behind miss events
+func (h *logHub) publish(key SessionKey, ev LogEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.active[key] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (h *logHub) subscribe(key SessionKey) (<-chan LogEvent, func(), bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs, ok := h.active[key]
+	if !ok {
+		return nil, nil, false
+	}
+	ch := make(chan LogEvent, logSubscriberBuffer)
+	subs[ch] = struct{}{}
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if subs, ok := h.active[key]; ok {
+			if _, ok := subs[ch]; ok {
+				delete(subs, ch)
+				close(ch)
+			}
+		}
+	}
+	return ch, unsubscribe, true
+}
+
+// SubscribeLogs streams console/network lines and status transitions of the session's
+// running recording. ok is false when nothing is recording. The channel is closed when
+// the recording ends; call unsubscribe when done listening.
+func (w *Worker) SubscribeLogs(key SessionKey) (events <-chan LogEvent, unsubscribe func(), ok bool) {
+	return w.logs.subscribe(key)
+}
+
+// publishStatus announces a recording status transition to live subscribers
+func (w *Worker) publishStatus(key SessionKey, message string) {
+	w.logs.publish(key, LogEvent{Type: "status", Time: time.Now().UTC(), Message: message})
+}
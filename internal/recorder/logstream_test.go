@@ -0,0 +1,67 @@
+package recorder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogHub_SubscribeRequiresActiveRecording(t *testing.T) {
+	var h logHub
+	if _, _, ok := h.subscribe(SessionKey{TaskID: 1}); ok {
+		t.Errorf("subscribe() before open returned ok=true")
+	}
+
+	h.open(SessionKey{TaskID: 1})
+	h.close(SessionKey{TaskID: 1})
+	if _, _, ok := h.subscribe(SessionKey{TaskID: 1}); ok {
+		t.Errorf("subscribe() after close returned ok=true")
+	}
+}
+
+func TestLogHub_PublishAndClose(t *testing.T) {
+	var h logHub
+	h.open(SessionKey{TaskID: 1})
+	h.open(SessionKey{TaskID: 2})
+
+	events, unsubscribe, ok := h.subscribe(SessionKey{TaskID: 1})
+	if !ok {
+		t.Fatalf("subscribe() on an open task returned ok=false")
+	}
+	defer unsubscribe()
+
+	h.publish(SessionKey{TaskID: 2}, LogEvent{Type: "log", Message: "other task"})
+	h.publish(SessionKey{TaskID: 1}, LogEvent{Type: "status", Time: time.Now(), Message: "recording"})
+
+	select {
+	case ev := <-events:
+		if ev.Type != "status" || ev.Message != "recording" {
+			t.Errorf("received %+v, want the task 1 status event", ev)
+		}
+	default:
+		t.Fatalf("no event delivered")
+	}
+
+	h.close(SessionKey{TaskID: 1})
+	if _, ok := <-events; ok {
+		t.Errorf("channel still open after close")
+	}
+	// Unsubscribing after the stream ended must not double-close
+	unsubscribe()
+}
+
+func TestLogHub_SlowSubscriberDoesNotBlock(t *testing.T) {
+	var h logHub
+	h.open(SessionKey{TaskID: 1})
+	events, unsubscribe, _ := h.subscribe(SessionKey{TaskID: 1})
+
+	for i := 0; i < logSubscriberBuffer+10; i++ {
+		h.publish(SessionKey{TaskID: 1}, LogEvent{Type: "log", Message: "spam"})
+	}
+	if got := len(events); got != logSubscriberBuffer {
+		t.Errorf("buffered = %d, want %d", got, logSubscriberBuffer)
+	}
+
+	unsubscribe()
+	h.publish(SessionKey{TaskID: 1}, LogEvent{Type: "log", Message: "after unsubscribe"})
+	h.close(SessionKey{TaskID: 1})
+}
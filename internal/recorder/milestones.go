@@ -0,0 +1,63 @@
+package recorder
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Navigation milestones saved as stills next to the recording (capture_milestones)
+const (
+	MilestoneLoad        = "load"        // the load event fired
+	MilestoneNetworkIdle = "networkidle" // no network activity for 500ms
+	MilestoneStart       = "start"       // decorated and past start_delay_ms, as the first frame is captured
+)
+
+// Milestones lists the milestones in the order they are reached
+var Milestones = []string{MilestoneLoad, MilestoneNetworkIdle, MilestoneStart}
+
+// IsMilestone reports whether name is one of Milestones
+func IsMilestone(name string) bool {
+	for _, m := range Milestones {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MilestonePath returns the sidecar still of a milestone ("foo.mkv" -> "foo.load.png")
+func MilestonePath(outputPath, milestone string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "." + milestone + ".png"
+}
+
+// MilestonePaths returns the sidecar stills of every milestone, whether captured or not
+func MilestonePaths(outputPath string) []string {
+	paths := make([]string, len(Milestones))
+	for i, m := range Milestones {
+		paths[i] = MilestonePath(outputPath, m)
+	}
+	return paths
+}
+
+// saveMilestone writes a lossless still of the page. A failed still is logged and
+// never affects the recording.
+func (w *Worker) saveMilestone(page playwright.Page, key SessionKey, outputPath, milestone string) {
+	img, err := page.Screenshot(playwright.PageScreenshotOptions{
+		Type:    playwright.ScreenshotTypePng,
+		Timeout: playwright.Float(float64(snapshotTimeout.Milliseconds())),
+	})
+	if err != nil {
+		log.Printf("Failed to capture %s milestone for %s: %v", milestone, key, err)
+		return
+	}
+	path := MilestonePath(outputPath, milestone)
+	if err := os.WriteFile(path, img, 0644); err != nil {
+		log.Printf("Failed to save %s milestone for %s: %v", milestone, key, err)
+		return
+	}
+	w.applyFilePermissions(path)
+}
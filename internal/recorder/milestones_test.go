@@ -0,0 +1,34 @@
+package recorder
+
+import "testing"
+
+func TestMilestonePath(t *testing.T) {
+	tests := map[string]string{
+		"/app/recordings/1_1700000000.mkv":   "/app/recordings/1_1700000000.load.png",
+		"/app/recordings/ops.board.v2.mkv":   "/app/recordings/ops.board.v2.load.png",
+		"/app/recordings/no_extension_found": "/app/recordings/no_extension_found.load.png",
+	}
+	for in, want := range tests {
+		if got := MilestonePath(in, MilestoneLoad); got != want {
+			t.Errorf("MilestonePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	paths := MilestonePaths("/app/recordings/r.mkv")
+	if len(paths) != len(Milestones) || paths[2] != "/app/recordings/r.start.png" {
+		t.Errorf("MilestonePaths() = %v", paths)
+	}
+}
+
+func TestIsMilestone(t *testing.T) {
+	for _, m := range Milestones {
+		if !IsMilestone(m) {
+			t.Errorf("IsMilestone(%q) = false, want true", m)
+		}
+	}
+	for _, name := range []string{"", "../../etc/passwd", "LOAD"} {
+		if IsMilestone(name) {
+			t.Errorf("IsMilestone(%q) = true, want false", name)
+		}
+	}
+}
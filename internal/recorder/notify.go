@@ -0,0 +1,77 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// sizeCheckInterval is how often recordLoop stats the output file for notify_size_bytes
+const sizeCheckInterval = 10 * time.Second
+
+// notifyTimeout bounds a single webhook delivery
+const notifyTimeout = 10 * time.Second
+
+// Notification is the JSON body POSTed to NOTIFY_WEBHOOK_URL
+type Notification struct {
+	Event    string                 `json:"event"`
+	TaskID   int64                  `json:"task_id"`
+	Viewport string                 `json:"viewport,omitempty"`
+	Message  string                 `json:"message"`
+	Time     time.Time              `json:"time"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+// notify logs the alert and, when a webhook is configured, delivers it in the background
+// so a slow receiver never stalls the recording loop
+func (w *Worker) notify(n Notification) {
+	if n.Time.IsZero() {
+		n.Time = time.Now().UTC()
+	}
+	log.Printf("Notification %s for task %d: %s", n.Event, n.TaskID, n.Message)
+	w.publishStatus(SessionKey{TaskID: n.TaskID, Viewport: n.Viewport}, n.Message)
+
+	if w.config.NotifyWebhookURL == "" {
+		return
+	}
+	go func() {
+		client := &http.Client{Timeout: notifyTimeout}
+		if err := postNotification(client, w.config.NotifyWebhookURL, n); err != nil {
+			log.Printf("Notification %s for task %d: webhook delivery failed: %v", n.Event, n.TaskID, err)
+		}
+	}()
+}
+
+func postNotification(client *http.Client, url string, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// notifySizeExceeded fires the one-shot notify_size_bytes alert for a recording
+func (w *Worker) notifySizeExceeded(key SessionKey, outputPath string, size, threshold int64) {
+	w.notify(Notification{
+		Event:    "recording.size_exceeded",
+		TaskID:   key.TaskID,
+		Viewport: key.Viewport,
+		Message:  fmt.Sprintf("recording file reached %d bytes (threshold %d)", size, threshold),
+		Details: map[string]interface{}{
+			"file_path":       outputPath,
+			"size_bytes":      size,
+			"threshold_bytes": threshold,
+		},
+	})
+}
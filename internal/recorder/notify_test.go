@@ -0,0 +1,40 @@
+package recorder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostNotification(t *testing.T) {
+	var got Notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	n := Notification{Event: "recording.size_exceeded", TaskID: 7, Message: "too big"}
+	if err := postNotification(srv.Client(), srv.URL, n); err != nil {
+		t.Fatalf("postNotification() error = %v", err)
+	}
+	if got.Event != n.Event || got.TaskID != 7 {
+		t.Errorf("received %+v, want %+v", got, n)
+	}
+}
+
+func TestPostNotification_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	if err := postNotification(srv.Client(), srv.URL, Notification{Event: "test"}); err == nil {
+		t.Errorf("postNotification() accepted a 502 response")
+	}
+}
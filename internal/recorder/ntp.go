@@ -2,6 +2,8 @@ package recorder
 
 import (
 	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/beevik/ntp"
@@ -28,3 +30,158 @@ func GetNTPTime(server string) (time.Duration, error) {
 
 	return 0, fmt.Errorf("failed to query NTP server %s after 3 attempts: %w", server, err)
 }
+
+// NTPDiagnostic is the outcome of a single NTP probe, used by the diagnostics endpoint.
+type NTPDiagnostic struct {
+	Server      string  `json:"server"`
+	OK          bool    `json:"ok"`
+	OffsetMs    float64 `json:"offset_ms"`
+	RoundTripMs float64 `json:"round_trip_ms"`
+	Stratum     uint8   `json:"stratum"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// CheckNTP queries the server once (no retries) and reports offset and round-trip time.
+func CheckNTP(server string) NTPDiagnostic {
+	result := NTPDiagnostic{Server: server}
+
+	response, err := ntp.Query(server)
+	if err == nil {
+		// Query only checks the transport; Validate rejects unsynchronized or kiss-of-death replies
+		err = response.Validate()
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.OK = true
+	result.OffsetMs = float64(response.ClockOffset) / float64(time.Millisecond)
+	result.RoundTripMs = float64(response.RTT) / float64(time.Millisecond)
+	result.Stratum = response.Stratum
+	return result
+}
+
+// ntpOffsetTTL is how long a synced offset is reused before the time overlay queries
+// the server again
+const ntpOffsetTTL = 15 * time.Minute
+
+// ClockStatus describes the time source of the overlay burned into recordings
+type ClockStatus struct {
+	Source      string     `json:"source"` // "ntp", or "system" before the first successful sync
+	Server      string     `json:"server,omitempty"`
+	OffsetMs    float64    `json:"offset_ms"` // NTP time - system time
+	LastSync    *time.Time `json:"last_sync,omitempty"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	LastError   string     `json:"last_error,omitempty"` // of the last attempt, if it failed
+	Stale       bool       `json:"stale"`                // the offset is older than the cache TTL
+}
+
+// ntpClock caches the NTP offset shared by every overlay. The zero value uses
+// NTP_SERVER and has not synced yet.
+type ntpClock struct {
+	mu        sync.Mutex
+	server    string // set at runtime, overrides NTP_SERVER
+	offset    time.Duration
+	synced    time.Time
+	attempted time.Time
+	err       string
+
+	query func(server string) (time.Duration, error) // GetNTPTime unless set by tests
+}
+
+// timeServer returns the active NTP server ("" = none, system time is used)
+func (w *Worker) timeServer() string {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	return w.timeServerLocked()
+}
+
+func (w *Worker) timeServerLocked() string {
+	if w.clock.server != "" {
+		return w.clock.server
+	}
+	if w.config != nil {
+		return w.config.NtpServer
+	}
+	return ""
+}
+
+// ClockStatus reports the cached offset and its source
+func (w *Worker) ClockStatus() ClockStatus {
+	c := &w.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := ClockStatus{Source: "system", Server: w.timeServerLocked(), LastError: c.err}
+	if !c.synced.IsZero() {
+		synced := c.synced
+		status.Source = "ntp"
+		status.OffsetMs = float64(c.offset) / float64(time.Millisecond)
+		status.LastSync = &synced
+		status.Stale = time.Since(c.synced) > ntpOffsetTTL
+	}
+	if !c.attempted.IsZero() {
+		attempted := c.attempted
+		status.LastAttempt = &attempted
+	}
+	return status
+}
+
+// SyncClock queries the NTP server now and caches the offset. A non-empty server
+// replaces the active one (until restart) if the sync succeeds.
+func (w *Worker) SyncClock(server string) (ClockStatus, error) {
+	if server == "" {
+		server = w.timeServer()
+	}
+	if server == "" {
+		return w.ClockStatus(), fmt.Errorf("no NTP server is configured")
+	}
+	if _, err := w.syncClock(server); err != nil {
+		return w.ClockStatus(), err
+	}
+	return w.ClockStatus(), nil
+}
+
+// syncClock queries server outside the lock (it can take seconds) and records the outcome
+func (w *Worker) syncClock(server string) (time.Duration, error) {
+	c := &w.clock
+	query := c.query
+	if query == nil {
+		query = GetNTPTime
+	}
+	offset, err := query(server)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempted = time.Now().UTC()
+	if err != nil {
+		c.err = err.Error()
+		return c.offset, err
+	}
+	if server != w.timeServerLocked() {
+		// A new source: offsets of the previous one no longer apply
+		c.server = server
+	}
+	c.offset, c.synced, c.err = offset, c.attempted, ""
+	return offset, nil
+}
+
+// clockOffset returns the NTP offset for the overlay: the cached one while fresh,
+// otherwise a new sync. A failed sync keeps the last known offset (0 if none).
+func (w *Worker) clockOffset() time.Duration {
+	c := &w.clock
+	c.mu.Lock()
+	server := w.timeServerLocked()
+	offset, fresh := c.offset, !c.synced.IsZero() && time.Since(c.synced) <= ntpOffsetTTL
+	c.mu.Unlock()
+	if fresh || server == "" {
+		return offset
+	}
+
+	offset, err := w.syncClock(server)
+	if err != nil {
+		slog.Error("NTP query failed, using the last known offset", "server", server, "offset_ms", offset.Milliseconds(), "error", err)
+	}
+	return offset
+}
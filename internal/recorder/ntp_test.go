@@ -1,8 +1,11 @@
 package recorder
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/nullpo7z/dashboard-recorder/internal/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,3 +27,56 @@ func TestInjectTimeOverlay_Validation(t *testing.T) {
 	// just trusting the integration test.
 	// The current InjectTimeOverlay does validation internally.
 }
+
+func TestCheckNTP_InvalidServer(t *testing.T) {
+	result := CheckNTP("invalid.server.local")
+	assert.False(t, result.OK)
+	assert.NotEmpty(t, result.Error)
+	assert.Equal(t, "invalid.server.local", result.Server)
+}
+
+func TestClock_SyncAndCache(t *testing.T) {
+	queries := 0
+	w := &Worker{config: &config.Config{NtpServer: "ntp.example"}}
+	w.clock.query = func(server string) (time.Duration, error) {
+		queries++
+		if server == "bad.example" {
+			return 0, errors.New("timeout")
+		}
+		return 250 * time.Millisecond, nil
+	}
+
+	status := w.ClockStatus()
+	assert.Equal(t, "system", status.Source)
+	assert.Equal(t, "ntp.example", status.Server)
+
+	// The first overlay syncs, later ones reuse the cached offset
+	assert.Equal(t, 250*time.Millisecond, w.clockOffset())
+	assert.Equal(t, 250*time.Millisecond, w.clockOffset())
+	assert.Equal(t, 1, queries)
+
+	status = w.ClockStatus()
+	assert.Equal(t, "ntp", status.Source)
+	assert.Equal(t, 250.0, status.OffsetMs)
+	assert.NotNil(t, status.LastSync)
+	assert.False(t, status.Stale)
+
+	// A failed switch keeps the server and offset
+	status, err := w.SyncClock("bad.example")
+	assert.Error(t, err)
+	assert.Equal(t, "ntp.example", status.Server)
+	assert.Equal(t, 250.0, status.OffsetMs)
+	assert.Equal(t, "timeout", status.LastError)
+
+	status, err = w.SyncClock("other.example")
+	assert.NoError(t, err)
+	assert.Equal(t, "other.example", status.Server)
+	assert.Empty(t, status.LastError)
+}
+
+func TestClock_NoServer(t *testing.T) {
+	w := &Worker{}
+	_, err := w.SyncClock("")
+	assert.Error(t, err)
+	assert.Equal(t, time.Duration(0), w.clockOffset())
+}
@@ -0,0 +1,106 @@
+package recorder
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// MaxPageLogBytes caps the sidecar log so a chatty dashboard can't fill the disk
+const MaxPageLogBytes = 10 * 1024 * 1024
+
+// PageLogPath returns the sidecar log path for a recording ("foo.mkv" -> "foo.log")
+func PageLogPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".log"
+}
+
+// pageLog appends browser console messages and network failures to a sidecar file.
+// Playwright delivers events from its own goroutine, so writes are serialized.
+type pageLog struct {
+	mu        sync.Mutex
+	f         *os.File
+	written   int64
+	truncated bool
+	// onLine also receives every entry (live log streaming), even past the file cap
+	onLine func(kind, message string)
+}
+
+func newPageLog(path string, onLine func(kind, message string)) (*pageLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page log: %w", err)
+	}
+	return &pageLog{f: f, onLine: onLine}, nil
+}
+
+// writef writes one timestamped line, dropping everything past MaxPageLogBytes
+func (l *pageLog) writef(kind, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return
+	}
+
+	message := fmt.Sprintf(format, args...)
+	if l.onLine != nil {
+		l.onLine(kind, message)
+	}
+	if l.truncated {
+		return
+	}
+
+	line := fmt.Sprintf("%s [%s] %s\n", time.Now().UTC().Format(time.RFC3339Nano), kind, message)
+	if l.written+int64(len(line)) > MaxPageLogBytes {
+		l.truncated = true
+		fmt.Fprintf(l.f, "%s [log] truncated at %d bytes\n", time.Now().UTC().Format(time.RFC3339Nano), l.written)
+		return
+	}
+	n, _ := l.f.WriteString(line)
+	l.written += int64(n)
+}
+
+func (l *pageLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// attachPageLog subscribes to console output, uncaught page errors, failed requests
+// and HTTP error responses. Events arriving after Close are ignored.
+func attachPageLog(page playwright.Page, path string, onLine func(kind, message string)) (*pageLog, error) {
+	l, err := newPageLog(path, onLine)
+	if err != nil {
+		return nil, err
+	}
+
+	page.OnConsole(func(msg playwright.ConsoleMessage) {
+		l.writef("console."+msg.Type(), "%s", msg.Text())
+	})
+	page.OnPageError(func(err error) {
+		l.writef("pageerror", "%v", err)
+	})
+	page.OnRequestFailed(func(req playwright.Request) {
+		l.writef("requestfailed", "%s %s: %v", req.Method(), req.URL(), req.Failure())
+	})
+	page.OnResponse(func(resp playwright.Response) {
+		if resp.Status() >= 400 {
+			l.writef("response", "%d %s %s", resp.Status(), resp.Request().Method(), resp.URL())
+		}
+	})
+
+	log.Printf("Capturing page console and network errors to %s", path)
+	return l, nil
+}
@@ -0,0 +1,62 @@
+package recorder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPageLogPath(t *testing.T) {
+	tests := map[string]string{
+		"/app/recordings/1_1700000000.mkv":   "/app/recordings/1_1700000000.log",
+		"/app/recordings/task_2/report.mkv":  "/app/recordings/task_2/report.log",
+		"/app/recordings/ops.board.v2.mkv":   "/app/recordings/ops.board.v2.log",
+		"/app/recordings/no_extension_found": "/app/recordings/no_extension_found.log",
+	}
+	for in, want := range tests {
+		if got := PageLogPath(in); got != want {
+			t.Errorf("PageLogPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPageLog_WriteAndTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.log")
+	var streamed []string
+	l, err := newPageLog(path, func(kind, message string) {
+		streamed = append(streamed, kind+" "+message)
+	})
+	if err != nil {
+		t.Fatalf("newPageLog() error = %v", err)
+	}
+
+	l.writef("console.error", "boom %d", 1)
+	l.written = MaxPageLogBytes // simulate a full log
+	l.writef("console.log", "dropped")
+	l.writef("console.log", "dropped again")
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	// Events delivered after Close must not panic
+	l.writef("console.log", "late")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "[console.error] boom 1") {
+		t.Errorf("log missing console entry: %q", content)
+	}
+	if strings.Contains(content, "dropped") || strings.Contains(content, "late") {
+		t.Errorf("log contains entries past the limit: %q", content)
+	}
+	if strings.Count(content, "truncated") != 1 {
+		t.Errorf("expected exactly one truncation marker: %q", content)
+	}
+	// Live subscribers keep receiving entries past the file cap, but not after Close
+	if len(streamed) != 3 || streamed[0] != "console.error boom 1" {
+		t.Errorf("streamed = %q, want the 3 entries written before Close", streamed)
+	}
+}
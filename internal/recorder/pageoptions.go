@@ -0,0 +1,140 @@
+package recorder
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// MaxBlockedResources bounds the block list of one task
+const MaxBlockedResources = 50
+
+// BlockableResourceTypes are the Playwright request types a task may block. The
+// page document itself is never blocked.
+var BlockableResourceTypes = []string{"image", "font", "media", "stylesheet", "script", "xhr", "fetch", "websocket", "eventsource", "manifest", "texttrack", "other"}
+
+// blockedDomainPattern accepts a host name with at least one dot, optionally as "*.domain"
+var blockedDomainPattern = regexp.MustCompile(`^(\*\.)?[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+
+// PageOptions are per-task navigation and browser context settings
+type PageOptions struct {
+	Referer           string // sent with the initial navigation
+	JavaScriptEnabled bool
+	Offline           bool // cut the network once the page has loaded (freezes the dashboard)
+	// BlockedResources lists resource types (BlockableResourceTypes) and domains
+	// (subdomains match) whose requests are aborted, e.g. "font", "analytics.example.com"
+	BlockedResources []string
+	// Proxy routes the context's traffic (see ParseProxyURL); "" connects directly.
+	// May carry credentials: log it via RedactProxyURL only.
+	Proxy string
+}
+
+// ParseBlockedResources splits the stored comma-separated block list
+func ParseBlockedResources(stored string) []string {
+	var result []string
+	for _, p := range strings.Split(stored, ",") {
+		if v := strings.ToLower(strings.TrimSpace(p)); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// DefaultPageOptions returns the options used when a task sets none
+func DefaultPageOptions() PageOptions {
+	return PageOptions{JavaScriptEnabled: true}
+}
+
+// Validate checks the referer is an absolute http(s) URL and every block list entry
+// is a known resource type or a domain name
+func (o PageOptions) Validate() error {
+	if o.Referer != "" {
+		u, err := url.Parse(o.Referer)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("referer must be an absolute http(s) URL")
+		}
+	}
+	if o.Proxy != "" {
+		if _, err := ParseProxyURL(o.Proxy); err != nil {
+			return err
+		}
+	}
+	if len(o.BlockedResources) > MaxBlockedResources {
+		return fmt.Errorf("blocked_resources can have at most %d entries", MaxBlockedResources)
+	}
+	for _, entry := range o.BlockedResources {
+		if !containsString(BlockableResourceTypes, entry) && (len(entry) > 253 || !blockedDomainPattern.MatchString(entry)) {
+			return fmt.Errorf("invalid blocked resource %q: must be a domain or one of %s", entry, strings.Join(BlockableResourceTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// blocks reports whether a request of the given resource type and URL is on the block list
+func (o PageOptions) blocks(resourceType, rawURL string) bool {
+	var host string
+	for _, entry := range o.BlockedResources {
+		if entry == resourceType {
+			return true
+		}
+		if !strings.Contains(entry, ".") {
+			continue
+		}
+		if host == "" {
+			u, err := url.Parse(rawURL)
+			if err != nil {
+				return false
+			}
+			host = strings.ToLower(u.Hostname())
+		}
+		if matchDomain(host, strings.TrimPrefix(entry, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeRequests aborts the context's requests that the guard rejects (non-public
+// addresses; nil disables the check) or that match the block list. The block list
+// never applies to the top level document, even when its own domain is listed.
+func (o PageOptions) routeRequests(bCtx playwright.BrowserContext, guard *requestGuard) error {
+	if guard == nil && len(o.BlockedResources) == 0 {
+		return nil
+	}
+	return bCtx.Route("**/*", func(route playwright.Route) {
+		req := route.Request()
+		if guard != nil {
+			if err := guard.check(req.URL()); err != nil {
+				log.Printf("Blocked request to %s: %v", req.URL(), err)
+				route.Abort("blockedbyclient")
+				return
+			}
+		}
+		mainDocument := req.IsNavigationRequest() && req.Frame() != nil && req.Frame().ParentFrame() == nil
+		if !mainDocument && o.blocks(req.ResourceType(), req.URL()) {
+			route.Abort("blockedbyclient")
+			return
+		}
+		route.Continue()
+	})
+}
+
+// gotoOptions applies the referer to a navigation
+func (o PageOptions) gotoOptions(opts playwright.PageGotoOptions) playwright.PageGotoOptions {
+	if o.Referer != "" {
+		opts.Referer = playwright.String(o.Referer)
+	}
+	return opts
+}
+
+// goOffline cuts the context's network after navigation when the task asks for it
+func (o PageOptions) goOffline(bCtx playwright.BrowserContext) error {
+	if !o.Offline {
+		return nil
+	}
+	return bCtx.SetOffline(true)
+}
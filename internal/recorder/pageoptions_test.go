@@ -0,0 +1,63 @@
+package recorder
+
+import "testing"
+
+func TestPageOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		referer string
+		wantErr bool
+	}{
+		{"Empty", "", false},
+		{"HTTPS", "https://portal.example.com/home", false},
+		{"Relative", "/home", true},
+		{"JavaScript Scheme", "javascript:alert(1)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultPageOptions()
+			opts.Referer = tt.referer
+			if err := opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if !DefaultPageOptions().JavaScriptEnabled {
+		t.Errorf("DefaultPageOptions() disables JavaScript")
+	}
+}
+
+func TestPageOptions_BlockedResources(t *testing.T) {
+	opts := DefaultPageOptions()
+	opts.BlockedResources = ParseBlockedResources(" Font, analytics.example.com ,*.ads.example.net")
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		resourceType string
+		url          string
+		want         bool
+	}{
+		{"font", "https://dashboard.example.org/font.woff2", true},
+		{"image", "https://dashboard.example.org/logo.png", false},
+		{"script", "https://analytics.example.com/track.js", true},
+		{"script", "https://eu.analytics.example.com/track.js", true},
+		{"script", "https://notanalytics.example.com/track.js", false},
+		{"sub_frame", "https://banner.ads.example.net/slot", true},
+	}
+	for _, tt := range tests {
+		if got := opts.blocks(tt.resourceType, tt.url); got != tt.want {
+			t.Errorf("blocks(%q, %q) = %v, want %v", tt.resourceType, tt.url, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"document", "localhost", "https://example.com", "example.com/path", "exa mple.com"} {
+		opts.BlockedResources = []string{invalid}
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Validate() with blocked resource %q = nil, want error", invalid)
+		}
+	}
+}
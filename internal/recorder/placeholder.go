@@ -0,0 +1,99 @@
+package recorder
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// pageReloadInterval is how often a recording retries reloading an unavailable page
+	pageReloadInterval = 30 * time.Second
+	// pageReloadTimeout bounds one reload; the recording loop is blocked meanwhile
+	pageReloadTimeout = 15 * time.Second
+)
+
+var (
+	placeholderBackground = color.RGBA{R: 0x20, G: 0x20, B: 0x24, A: 0xff}
+	placeholderForeground = color.RGBA{R: 0xff, G: 0x6b, B: 0x6b, A: 0xff}
+)
+
+// placeholderRenderer produces the "page unavailable" frames written while the
+// recorded page can't be captured. The frame only changes once per second, so the
+// last rendering is reused between ticks.
+type placeholderRenderer struct {
+	width, height int
+	quality       int
+	format        string // the recording's capture format, so ffmpeg can decode it
+
+	text  string
+	frame []byte
+}
+
+// render returns the placeholder for an outage that began at since
+func (r *placeholderRenderer) render(since, now time.Time) ([]byte, error) {
+	lines := []string{
+		"PAGE UNAVAILABLE",
+		"since " + since.UTC().Format("2006-01-02 15:04:05") + " UTC",
+		"now   " + now.UTC().Format("2006-01-02 15:04:05") + " UTC",
+	}
+	text := strings.Join(lines, "\n")
+	if text == r.text && r.frame != nil {
+		return r.frame, nil
+	}
+
+	frame, err := placeholderFrame(r.width, r.height, lines, r.format, r.quality)
+	if err != nil {
+		return nil, err
+	}
+	r.text, r.frame = text, frame
+	return frame, nil
+}
+
+// placeholderFrame draws centered lines of text onto a width x height JPEG (or PNG
+// for CaptureFormatPNG). The text is drawn with the built-in 7x13 bitmap font on a
+// small canvas and scaled up, so no font files are needed in the image.
+func placeholderFrame(width, height int, lines []string, format string, quality int) ([]byte, error) {
+	face := basicfont.Face7x13
+	const lineHeight = 13 + 6
+
+	scale := width / 320
+	if scale < 1 {
+		scale = 1
+	}
+	small := image.NewRGBA(image.Rect(0, 0, width/scale, height/scale))
+	draw.Draw(small, small.Bounds(), image.NewUniform(placeholderBackground), image.Point{}, draw.Src)
+
+	d := &font.Drawer{Dst: small, Src: image.NewUniform(placeholderForeground), Face: face}
+	top := (small.Bounds().Dy()-lineHeight*len(lines))/2 + face.Ascent
+	for i, line := range lines {
+		x := (small.Bounds().Dx() - d.MeasureString(line).Ceil()) / 2
+		d.Dot = fixed.P(x, top+i*lineHeight)
+		d.DrawString(line)
+	}
+
+	full := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(full, full.Bounds(), image.NewUniform(placeholderBackground), image.Point{}, draw.Src)
+	draw.NearestNeighbor.Scale(full, image.Rect(0, 0, small.Bounds().Dx()*scale, small.Bounds().Dy()*scale), small, small.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	if format == CaptureFormatPNG {
+		if err := png.Encode(&buf, full); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	if err := jpeg.Encode(&buf, full, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,55 @@
+package recorder
+
+import (
+	"bytes"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestPlaceholderRenderer(t *testing.T) {
+	r := &placeholderRenderer{width: 1280, height: 720, quality: 70}
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	frame, err := r.render(since, since.Add(500*time.Millisecond))
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(frame))
+	if err != nil {
+		t.Fatalf("render() output is not a JPEG: %v", err)
+	}
+	if cfg.Width != 1280 || cfg.Height != 720 {
+		t.Errorf("render() size = %dx%d, want 1280x720", cfg.Width, cfg.Height)
+	}
+
+	// Same second: the cached frame is reused
+	again, _ := r.render(since, since.Add(900*time.Millisecond))
+	if &again[0] != &frame[0] {
+		t.Errorf("render() within the same second re-rendered the frame")
+	}
+
+	// The timestamp moves on, so the frame changes
+	later, _ := r.render(since, since.Add(2*time.Second))
+	if bytes.Equal(later, frame) {
+		t.Errorf("render() a second later returned the same frame")
+	}
+}
+
+func TestPlaceholderRenderer_PNG(t *testing.T) {
+	r := &placeholderRenderer{width: 640, height: 360, quality: 70, format: CaptureFormatPNG}
+	now := time.Now()
+
+	frame, err := r.render(now, now)
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(frame))
+	if err != nil {
+		t.Fatalf("render() output of a png recording is not a PNG: %v", err)
+	}
+	if cfg.Width != 640 || cfg.Height != 360 {
+		t.Errorf("render() size = %dx%d, want 640x360", cfg.Width, cfg.Height)
+	}
+}
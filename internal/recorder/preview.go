@@ -0,0 +1,124 @@
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders for image targets (PREVIEW_NON_HTML=image)
+	"image/jpeg"
+	_ "image/png"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrPreviewTooLarge is returned when a preview cannot be brought under the size cap
+var ErrPreviewTooLarge = errors.New("preview exceeds maximum size")
+
+// ErrNonHTMLTarget is returned when a preview target is not a web page (an image,
+// PDF, JSON...) and PREVIEW_NON_HTML doesn't allow it
+var ErrNonHTMLTarget = errors.New("target is not an HTML page")
+
+// Kinds of preview targets, by the Content-Type of the navigation response
+const (
+	targetHTML  = "html"
+	targetImage = "image"
+	targetOther = "other"
+)
+
+// previewQualityStep is how much JPEG quality drops per re-encode attempt
+const previewQualityStep = 20
+
+// allowedPreviewTypes are the content types the preview endpoint may return
+var allowedPreviewTypes = map[string]bool{
+	"image/jpeg": true,
+}
+
+// previewTargetKind classifies a navigation response by its Content-Type. A missing
+// or unparsable type is left to the browser to sniff, like a page.
+func previewTargetKind(contentType string) (kind, mediaType string) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		return targetHTML, ""
+	}
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return targetHTML, mediaType
+	case strings.HasPrefix(mediaType, "image/"):
+		return targetImage, mediaType
+	}
+	return targetOther, mediaType
+}
+
+// maxPreviewImageBytes bounds an image target read into memory for imagePreview:
+// an encoded image within the decode bounds is smaller than its raw RGBA pixels
+const maxPreviewImageBytes = MaxInteractiveWidth * MaxInteractiveHeight * 4
+
+// checkPreviewImageLength vets an image target's Content-Length before its body is
+// read. It reports false when the length is unknown, so the image is screenshotted
+// instead of buffering a body of any size.
+func checkPreviewImageLength(contentLength string) (bool, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(contentLength), 10, 64)
+	if err != nil || n < 0 {
+		return false, nil
+	}
+	if n > maxPreviewImageBytes {
+		return false, fmt.Errorf("%w: %d byte image (limit %d)", ErrPreviewTooLarge, n, maxPreviewImageBytes)
+	}
+	return true, nil
+}
+
+// imagePreview returns an image target itself as the preview, re-encoded as JPEG
+// under the same size cap as screenshots. Images larger than the interactive
+// bounds are refused before they are decoded.
+func imagePreview(body []byte, quality, maxBytes int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width > MaxInteractiveWidth || cfg.Height > MaxInteractiveHeight {
+		return nil, fmt.Errorf("%w: %dx%d image (limit %dx%d)", ErrPreviewTooLarge, cfg.Width, cfg.Height, MaxInteractiveWidth, MaxInteractiveHeight)
+	}
+	img, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return fitPreview(func(q int) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: q}); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}, quality, maxBytes)
+}
+
+// fitPreview captures at the requested quality and, while the image is over
+// maxBytes, re-captures at lower quality down to MinJpegQuality.
+// maxBytes <= 0 disables the cap.
+func fitPreview(capture func(quality int) ([]byte, error), quality int, maxBytes int) ([]byte, error) {
+	for {
+		img, err := capture(quality)
+		if err != nil {
+			return nil, err
+		}
+		if len(img) == 0 {
+			return nil, fmt.Errorf("captured empty screenshot")
+		}
+		if contentType := http.DetectContentType(img); !allowedPreviewTypes[contentType] {
+			return nil, fmt.Errorf("unexpected preview content type %s", contentType)
+		}
+		if maxBytes <= 0 || len(img) <= maxBytes {
+			return img, nil
+		}
+		if quality <= MinJpegQuality {
+			return nil, fmt.Errorf("%w: %d bytes at quality %d (limit %d)", ErrPreviewTooLarge, len(img), quality, maxBytes)
+		}
+
+		quality -= previewQualityStep
+		if quality < MinJpegQuality {
+			quality = MinJpegQuality
+		}
+	}
+}
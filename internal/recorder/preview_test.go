@@ -0,0 +1,113 @@
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"testing"
+)
+
+// fakeJPEG returns a buffer with a JPEG signature whose size grows with quality
+func fakeJPEG(quality int) []byte {
+	img := make([]byte, quality*10)
+	copy(img, []byte{0xFF, 0xD8, 0xFF, 0xE0})
+	return img
+}
+
+func TestFitPreview(t *testing.T) {
+	var qualities []int
+	capture := func(q int) ([]byte, error) {
+		qualities = append(qualities, q)
+		return fakeJPEG(q), nil
+	}
+
+	// Fits at first try
+	img, err := fitPreview(capture, 80, 1000)
+	if err != nil || len(img) != 800 {
+		t.Fatalf("fitPreview() = %d bytes, %v; want 800 bytes", len(img), err)
+	}
+
+	// Needs re-encoding: 80 -> 60 -> 40
+	qualities = nil
+	img, err = fitPreview(capture, 80, 450)
+	if err != nil || len(img) != 400 {
+		t.Fatalf("fitPreview() = %d bytes, %v; want 400 bytes", len(img), err)
+	}
+	if len(qualities) != 3 || qualities[2] != 40 {
+		t.Errorf("fitPreview() qualities = %v, want [80 60 40]", qualities)
+	}
+
+	// Cannot fit even at minimum quality
+	if _, err := fitPreview(capture, 80, 100); !errors.Is(err, ErrPreviewTooLarge) {
+		t.Errorf("fitPreview() error = %v, want ErrPreviewTooLarge", err)
+	}
+
+	// Cap disabled
+	if _, err := fitPreview(capture, 80, 0); err != nil {
+		t.Errorf("fitPreview() with no cap error = %v", err)
+	}
+}
+
+func TestFitPreview_RejectsNonJPEG(t *testing.T) {
+	capture := func(q int) ([]byte, error) {
+		return []byte("<html>not an image</html>"), nil
+	}
+	if _, err := fitPreview(capture, 80, 0); err == nil {
+		t.Errorf("fitPreview() expected error for non-JPEG data")
+	}
+}
+
+func TestPreviewTargetKind(t *testing.T) {
+	for contentType, want := range map[string]string{
+		"text/html; charset=utf-8": targetHTML,
+		"application/xhtml+xml":    targetHTML,
+		"":                         targetHTML, // left to the browser
+		"image/png":                targetImage,
+		"image/svg+xml":            targetImage,
+		"application/pdf":          targetOther,
+		"application/json":         targetOther,
+	} {
+		if got, _ := previewTargetKind(contentType); got != want {
+			t.Errorf("previewTargetKind(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
+
+func TestImagePreview(t *testing.T) {
+	var src bytes.Buffer
+	if err := png.Encode(&src, image.NewRGBA(image.Rect(0, 0, 64, 48))); err != nil {
+		t.Fatal(err)
+	}
+	img, err := imagePreview(src.Bytes(), 80, 0)
+	if err != nil {
+		t.Fatalf("imagePreview() error = %v", err)
+	}
+	if contentType := http.DetectContentType(img); contentType != "image/jpeg" {
+		t.Errorf("imagePreview() content type = %s, want image/jpeg", contentType)
+	}
+
+	src.Reset()
+	if err := png.Encode(&src, image.NewGray(image.Rect(0, 0, MaxInteractiveWidth+1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imagePreview(src.Bytes(), 80, 0); !errors.Is(err, ErrPreviewTooLarge) {
+		t.Errorf("imagePreview() oversized error = %v, want ErrPreviewTooLarge", err)
+	}
+	if _, err := imagePreview([]byte("<svg/>"), 80, 0); err == nil {
+		t.Errorf("imagePreview() of an undecodable image expected error")
+	}
+}
+
+func TestCheckPreviewImageLength(t *testing.T) {
+	if ok, err := checkPreviewImageLength("1024"); !ok || err != nil {
+		t.Errorf("checkPreviewImageLength(1024) = %v, %v, want readable", ok, err)
+	}
+	if ok, err := checkPreviewImageLength(""); ok || err != nil {
+		t.Errorf("checkPreviewImageLength(\"\") = %v, %v, want unknown length", ok, err)
+	}
+	if _, err := checkPreviewImageLength("99999999999"); !errors.Is(err, ErrPreviewTooLarge) {
+		t.Errorf("checkPreviewImageLength(huge) error = %v, want ErrPreviewTooLarge", err)
+	}
+}
@@ -0,0 +1,78 @@
+package recorder
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPreviewBusy is returned when no preview slot frees up within PREVIEW_QUEUE_TIMEOUT
+var ErrPreviewBusy = errors.New("preview queue is full, try again")
+
+// PreviewQueueStats reports preview slot usage for /api/stats
+type PreviewQueueStats struct {
+	Running     int   `json:"running"`
+	Waiting     int64 `json:"waiting"`
+	Concurrency int   `json:"concurrency"`
+}
+
+// previewQueue bounds how many previews hold a browser context at once; callers
+// beyond that wait in line for a slot. The zero value is ready to use.
+type previewQueue struct {
+	once    sync.Once
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+// acquire waits up to timeout for one of concurrency slots
+func (q *previewQueue) acquire(concurrency int, timeout time.Duration) (release func(), err error) {
+	q.once.Do(func() {
+		q.slots = make(chan struct{}, max(concurrency, 1))
+	})
+
+	release = func() { <-q.slots }
+	select {
+	case q.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	q.waiting.Add(1)
+	defer q.waiting.Add(-1)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case q.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrPreviewBusy
+	}
+}
+
+func (q *previewQueue) stats() PreviewQueueStats {
+	return PreviewQueueStats{
+		Running:     len(q.slots),
+		Waiting:     q.waiting.Load(),
+		Concurrency: cap(q.slots),
+	}
+}
+
+// previewQueueLimits returns PREVIEW_CONCURRENCY and PREVIEW_QUEUE_TIMEOUT
+func (w *Worker) previewQueueLimits() (concurrency int, timeout time.Duration) {
+	if w.config == nil {
+		return 1, 10 * time.Second
+	}
+	return w.config.PreviewConcurrency, w.config.PreviewQueueTimeout
+}
+
+// PreviewQueueStats reports how many previews are capturing and how many are queued
+func (w *Worker) PreviewQueueStats() PreviewQueueStats {
+	stats := w.previews.stats()
+	if stats.Concurrency == 0 {
+		// No preview has run yet, so the queue hasn't been sized
+		stats.Concurrency, _ = w.previewQueueLimits()
+	}
+	return stats
+}
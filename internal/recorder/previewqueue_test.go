@@ -0,0 +1,43 @@
+package recorder
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPreviewQueue(t *testing.T) {
+	var q previewQueue
+
+	release, err := q.acquire(1, 0)
+	if err != nil {
+		t.Fatalf("first acquire error = %v, want a free slot even without waiting", err)
+	}
+	if s := q.stats(); s.Running != 1 || s.Waiting != 0 || s.Concurrency != 1 {
+		t.Errorf("stats() = %+v, want 1 running", s)
+	}
+
+	if _, err := q.acquire(1, 10*time.Millisecond); !errors.Is(err, ErrPreviewBusy) {
+		t.Errorf("acquire on a full queue error = %v, want ErrPreviewBusy", err)
+	}
+
+	// A waiter gets the slot once it is released
+	got := make(chan error, 1)
+	go func() {
+		r, err := q.acquire(1, time.Second)
+		if err == nil {
+			r()
+		}
+		got <- err
+	}()
+	for q.stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	release()
+	if err := <-got; err != nil {
+		t.Errorf("queued acquire error = %v", err)
+	}
+	if s := q.stats(); s.Running != 0 || s.Waiting != 0 {
+		t.Errorf("stats() after release = %+v, want idle", s)
+	}
+}
@@ -0,0 +1,143 @@
+package recorder
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Process roles reported by ProcessStats
+const (
+	ProcessRoleBrowser = "browser" // browser engine processes (renderers, GPU, ...)
+	ProcessRoleDriver  = "driver"  // the Playwright driver
+	ProcessRoleFFmpeg  = "ffmpeg"  // encoders (keyed by task when recording)
+	ProcessRoleOther   = "other"
+)
+
+// ProcessStat is the resource usage of one process spawned by the recorder
+type ProcessStat struct {
+	PID           int32   `json:"pid"`
+	Role          string  `json:"role"`
+	Name          string  `json:"name"`
+	TaskID        int64   `json:"task_id,omitempty"`
+	Viewport      string  `json:"viewport,omitempty"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryRSS     uint64  `json:"memory_rss_bytes"`
+	MemoryPercent float32 `json:"memory_percent"`
+}
+
+func (w *Worker) registerFFmpeg(key SessionKey, pid int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ffmpegPIDs[key] = pid
+}
+
+func (w *Worker) unregisterFFmpeg(key SessionKey) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.ffmpegPIDs, key)
+}
+
+// ProcessStats reports CPU and memory of every descendant of the server process:
+// the browser, the Playwright driver and the ffmpeg encoders. CPU is measured over
+// window. Encoders of active recordings carry their task; browser processes are
+// shared between recordings and can't be attributed.
+func (w *Worker) ProcessStats(ctx context.Context, window time.Duration) ([]ProcessStat, error) {
+	procs, err := descendantProcesses(ctx, int32(os.Getpid()))
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	sessionsByPID := make(map[int32]SessionKey, len(w.ffmpegPIDs))
+	for key, pid := range w.ffmpegPIDs {
+		sessionsByPID[int32(pid)] = key
+	}
+	w.mu.Unlock()
+
+	// Prime the CPU counters, then measure over the window
+	for _, p := range procs {
+		p.PercentWithContext(ctx, 0)
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(window):
+	}
+
+	stats := make([]ProcessStat, 0, len(procs))
+	for _, p := range procs {
+		name, err := p.NameWithContext(ctx)
+		if err != nil {
+			continue // exited meanwhile
+		}
+		stat := ProcessStat{PID: p.Pid, Name: name, Role: processRole(name)}
+		if key, ok := sessionsByPID[p.Pid]; ok {
+			stat.Role = ProcessRoleFFmpeg
+			stat.TaskID = key.TaskID
+			stat.Viewport = key.Viewport
+		}
+		if cpu, err := p.PercentWithContext(ctx, 0); err == nil {
+			stat.CPUPercent = cpu
+		}
+		if mem, err := p.MemoryInfoWithContext(ctx); err == nil {
+			stat.MemoryRSS = mem.RSS
+		}
+		if pct, err := p.MemoryPercentWithContext(ctx); err == nil {
+			stat.MemoryPercent = pct
+		}
+		stats = append(stats, stat)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].PID < stats[j].PID })
+	return stats, nil
+}
+
+// descendantProcesses walks the process table for all (transitive) children of root.
+// The parent links are read from /proc rather than via pgrep, which slim images lack.
+func descendantProcesses(ctx context.Context, root int32) ([]*process.Process, error) {
+	all, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int32][]*process.Process)
+	for _, p := range all {
+		ppid, err := p.PpidWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		children[ppid] = append(children[ppid], p)
+	}
+
+	var result []*process.Process
+	queue := []int32{root}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		for _, child := range children[pid] {
+			result = append(result, child)
+			queue = append(queue, child.Pid)
+		}
+	}
+	return result, nil
+}
+
+// processRole classifies a process by its executable name
+func processRole(name string) string {
+	n := strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(n, "ffmpeg"):
+		return ProcessRoleFFmpeg
+	case strings.Contains(n, "chrom"), strings.Contains(n, "headless_shell"),
+		strings.Contains(n, "firefox"), strings.Contains(n, "webkit"), strings.Contains(n, "minibrowser"):
+		return ProcessRoleBrowser
+	case n == "node", strings.Contains(n, "playwright"):
+		return ProcessRoleDriver
+	default:
+		return ProcessRoleOther
+	}
+}
@@ -0,0 +1,49 @@
+package recorder
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestProcessRole(t *testing.T) {
+	tests := map[string]string{
+		"ffmpeg":         ProcessRoleFFmpeg,
+		"chrome":         ProcessRoleBrowser,
+		"headless_shell": ProcessRoleBrowser,
+		"firefox":        ProcessRoleBrowser,
+		"node":           ProcessRoleDriver,
+		"sh":             ProcessRoleOther,
+	}
+	for name, want := range tests {
+		if got := processRole(name); got != want {
+			t.Errorf("processRole(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestProcessStats_KeysEncodersByTask(t *testing.T) {
+	cmd := exec.Command("sleep", "5")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start child process: %v", err)
+	}
+	defer cmd.Process.Kill()
+
+	w := &Worker{ffmpegPIDs: make(map[SessionKey]int)}
+	w.registerFFmpeg(SessionKey{TaskID: 7, Viewport: "mobile"}, cmd.Process.Pid)
+
+	stats, err := w.ProcessStats(context.Background(), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ProcessStats() error = %v", err)
+	}
+	for _, s := range stats {
+		if int(s.PID) == cmd.Process.Pid {
+			if s.Role != ProcessRoleFFmpeg || s.TaskID != 7 || s.Viewport != "mobile" {
+				t.Errorf("ProcessStats() child = %+v, want ffmpeg of task 7 (mobile)", s)
+			}
+			return
+		}
+	}
+	t.Errorf("ProcessStats() = %+v, missing child %d", stats, cmd.Process.Pid)
+}
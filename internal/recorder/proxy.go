@@ -0,0 +1,117 @@
+package recorder
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// ProxySchemes are the proxy protocols a task may route its browser context through
+var ProxySchemes = []string{"http", "https", "socks4", "socks5"}
+
+// ParseProxyURL checks a task proxy of the form scheme://[user:pass@]host:port.
+// Chromium can't authenticate to SOCKS proxies, so those take no credentials.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !containsString(ProxySchemes, u.Scheme) {
+		return nil, fmt.Errorf("proxy must use one of %s", strings.Join(ProxySchemes, ", "))
+	}
+	if u.Opaque != "" || u.Hostname() == "" {
+		return nil, fmt.Errorf("proxy must include a host")
+	}
+	if port, err := strconv.Atoi(u.Port()); err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("proxy must include a valid port")
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("proxy must not include a path, query or fragment")
+	}
+	if u.User != nil && strings.HasPrefix(u.Scheme, "socks") {
+		return nil, fmt.Errorf("SOCKS proxies do not support credentials")
+	}
+	return u, nil
+}
+
+// ValidateProxy checks the proxy URL and that its host (or host:port) is on the
+// allowlist. An empty allowlist disables per-task proxies.
+func ValidateProxy(raw string, allowedHosts []string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := ParseProxyURL(raw)
+	if err != nil {
+		return err
+	}
+	if len(allowedHosts) == 0 {
+		return fmt.Errorf("per-task proxies are disabled (TASK_PROXY_ALLOWED_HOSTS is empty)")
+	}
+	if hostAllowed(u, allowedHosts) {
+		return nil
+	}
+	return fmt.Errorf("proxy %s is not in TASK_PROXY_ALLOWED_HOSTS", net.JoinHostPort(strings.ToLower(u.Hostname()), u.Port()))
+}
+
+// hostAllowed reports whether the URL's host (any port) or host:port is on the allowlist
+func hostAllowed(u *url.URL, allowedHosts []string) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if allowed == host || allowed == strings.ToLower(u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactProxyURL hides the proxy password for logs and API responses
+func RedactProxyURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(invalid proxy URL)"
+	}
+	return u.Redacted()
+}
+
+// playwrightProxy converts a validated proxy URL into context options. Credentials
+// are passed separately, so they never appear in the server string of browser errors.
+func playwrightProxy(raw string) (*playwright.Proxy, error) {
+	u, err := ParseProxyURL(raw)
+	if err != nil {
+		return nil, err
+	}
+	proxy := &playwright.Proxy{Server: u.Scheme + "://" + u.Host}
+	if u.User != nil {
+		proxy.Username = playwright.String(u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			proxy.Password = playwright.String(password)
+		}
+	}
+	return proxy, nil
+}
+
+// validateProxy applies TASK_PROXY_ALLOWED_HOSTS to a task's proxy
+func (w *Worker) validateProxy(raw string) error {
+	var allowed []string
+	if w.config != nil {
+		allowed = w.config.TaskProxyAllowedHosts
+	}
+	return ValidateProxy(raw, allowed)
+}
+
+// skipTargetIPCheck reports whether the private address checks are waived for a
+// target reached through the proxy. The proxy, not this host, resolves and connects
+// to the target, so the local check may not describe where traffic goes; it stays
+// on unless TARGET_IP_CHECK_VIA_PROXY is turned off.
+func (w *Worker) skipTargetIPCheck(proxy string) bool {
+	return proxy != "" && w.config != nil && !w.config.TargetIPCheckViaProxy
+}
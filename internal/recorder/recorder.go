@@ -3,17 +3,17 @@ package recorder
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
-	"net"
-	"net/url"
-
 	"github.com/gorilla/websocket"
 	"github.com/nullpo7z/dashboard-recorder/internal/config"
 	"github.com/nullpo7z/dashboard-recorder/internal/database"
@@ -21,6 +21,15 @@ import (
 	"golang.org/x/exp/slog"
 )
 
+// ErrAlreadyRecording is returned when a task already has an active or starting session
+var ErrAlreadyRecording = errors.New("recording already in progress")
+
+// ErrNotRecording is returned when a task that should be recording has no session
+var ErrNotRecording = errors.New("no active recording")
+
+// restartPollInterval is how often StopRecordingAndWait checks for finalized sessions
+const restartPollInterval = 50 * time.Millisecond
+
 const (
 	// DefaultJpegQuality is the fallback quality if calculation fails
 	DefaultJpegQuality = 70
@@ -34,19 +43,65 @@ const (
 type Worker struct {
 	pw      *playwright.Playwright
 	browser playwright.Browser
+	engine  string // engine actually launched (after fallback)
 	config  *config.Config
 	queries *database.Queries
 
-	// Active sessions
+	// Active sessions (one per task and viewport profile)
 	mu       sync.Mutex
-	sessions map[int64]context.CancelFunc
+	sessions map[SessionKey]context.CancelFunc
+	starting map[int64]bool // reserved by ReserveStart, session not registered yet
+	// Live recording pages, reachable for annotations while recording
+	pages map[SessionKey]playwright.Page
+	// Encoder process of each session, for per-process resource stats
+	ffmpegPIDs map[SessionKey]int
+	// Recording goroutines, until their file is finalized and their row updated
+	running sync.WaitGroup
 
 	// Live preview frame cache (zero-overhead: reuse recording frames)
 	framesMu     sync.RWMutex
-	latestFrames map[int64][]byte // taskID -> latest JPEG bytes
+	latestFrames map[SessionKey]cachedFrame // session -> latest JPEG bytes
+	frameStats   map[SessionKey]FrameStats  // session -> capture counters of the active recording
+
+	// Live log/status subscribers per recording session
+	logs logHub
+
+	// Serializes preview captures (PREVIEW_CONCURRENCY at a time)
+	previews previewQueue
+
+	// Background re-encodes of finished recordings (one at a time)
+	transcodes transcodeQueue
+
+	// Cached NTP offset of the time overlay
+	clock ntpClock
+
+	// Pre-warmed contexts (nil when BROWSER_CONTEXT_POOL_SIZE is 0)
+	pool *contextPool
+
+	// Startup self-test result
+	ffmpegStatus FFmpegStatus
 }
 
 func New(cfg *config.Config, q *database.Queries) (*Worker, error) {
+	// The server-wide defaults must be valid; tasks fall back to them
+	if cfg.FFmpegPreset == "" {
+		return nil, fmt.Errorf("FFMPEG_PRESET must not be empty")
+	}
+	if err := ValidateEncoderSettings(cfg.FFmpegPreset, cfg.FFmpegTune); err != nil {
+		return nil, fmt.Errorf("FFMPEG_PRESET/FFMPEG_TUNE: %w", err)
+	}
+
+	// Verify ffmpeg up front so a broken image is visible at boot, not at first recording
+	ffmpegStatus := CheckFFmpeg(RequiredEncoders)
+	if !ffmpegStatus.OK() {
+		if cfg.FFmpegStrict {
+			return nil, fmt.Errorf("ffmpeg self-test failed: available=%v missing_encoders=%v error=%s", ffmpegStatus.Available, ffmpegStatus.MissingEncoders, ffmpegStatus.Error)
+		}
+		log.Printf("WARNING: ffmpeg self-test failed (available=%v, missing encoders=%v): %s. Recordings will fail.", ffmpegStatus.Available, ffmpegStatus.MissingEncoders, ffmpegStatus.Error)
+	} else {
+		log.Printf("ffmpeg self-test passed: %s", ffmpegStatus.Version)
+	}
+
 	// Initialize Playwright
 	// Use RunWithOptions to preventing it from trying to download browsers or install drivers if they are missing
 	// since we manually installed them or are using system ones.
@@ -59,27 +114,17 @@ func New(cfg *config.Config, q *database.Queries) (*Worker, error) {
 		return &Worker{
 			config:       cfg,
 			queries:      q,
-			sessions:     make(map[int64]context.CancelFunc),
-			latestFrames: make(map[int64][]byte),
+			sessions:     make(map[SessionKey]context.CancelFunc),
+			starting:     make(map[int64]bool),
+			pages:        make(map[SessionKey]playwright.Page),
+			ffmpegPIDs:   make(map[SessionKey]int),
+			latestFrames: make(map[SessionKey]cachedFrame),
+			frameStats:   make(map[SessionKey]FrameStats),
+			ffmpegStatus: ffmpegStatus,
 		}, nil
 	}
 
-	opts := playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(true),
-		Args: []string{
-			"--no-sandbox",
-			"--disable-setuid-sandbox",
-			"--disable-dev-shm-usage",
-		},
-	}
-
-	if cfg.PlaywrightPath != "" {
-		opts.ExecutablePath = playwright.String(cfg.PlaywrightPath)
-	} else if _, err := os.Stat("/usr/bin/chromium"); err == nil {
-		opts.ExecutablePath = playwright.String("/usr/bin/chromium")
-	}
-
-	browser, err := pw.Chromium.Launch(opts)
+	browser, engine, err := launchBrowser(pw, cfg)
 	if err != nil {
 		log.Printf("WARNING: Could not launch browser: %v. Recorder features will be disabled.", err)
 		pw.Stop()
@@ -87,19 +132,52 @@ func New(cfg *config.Config, q *database.Queries) (*Worker, error) {
 			pw:           pw,
 			config:       cfg,
 			queries:      q,
-			sessions:     make(map[int64]context.CancelFunc),
-			latestFrames: make(map[int64][]byte),
+			sessions:     make(map[SessionKey]context.CancelFunc),
+			starting:     make(map[int64]bool),
+			pages:        make(map[SessionKey]playwright.Page),
+			ffmpegPIDs:   make(map[SessionKey]int),
+			latestFrames: make(map[SessionKey]cachedFrame),
+			frameStats:   make(map[SessionKey]FrameStats),
+			ffmpegStatus: ffmpegStatus,
 		}, nil
 	}
 
-	return &Worker{
+	log.Printf("Browser engine: %s", engine)
+
+	w := &Worker{
 		pw:           pw,
 		browser:      browser,
+		engine:       engine,
 		config:       cfg,
 		queries:      q,
-		sessions:     make(map[int64]context.CancelFunc),
-		latestFrames: make(map[int64][]byte),
-	}, nil
+		sessions:     make(map[SessionKey]context.CancelFunc),
+		starting:     make(map[int64]bool),
+		pages:        make(map[SessionKey]playwright.Page),
+		ffmpegPIDs:   make(map[SessionKey]int),
+		latestFrames: make(map[SessionKey]cachedFrame),
+		frameStats:   make(map[SessionKey]FrameStats),
+		ffmpegStatus: ffmpegStatus,
+	}
+	if cfg.BrowserContextPoolSize > 0 {
+		w.pool = newContextPool(cfg.BrowserContextPoolSize, w.warmContextFactory())
+		log.Printf("Browser context pool: %d warm context(s)", cfg.BrowserContextPoolSize)
+	}
+	return w, nil
+}
+
+// FFmpegStatus returns the result of the startup ffmpeg self-test
+func (w *Worker) FFmpegStatus() FFmpegStatus {
+	return w.ffmpegStatus
+}
+
+// BrowserAvailable reports whether Playwright launched a browser successfully
+func (w *Worker) BrowserAvailable() bool {
+	return w.browser != nil
+}
+
+// BrowserEngine returns the launched engine name, or "" if no browser is running
+func (w *Worker) BrowserEngine() string {
+	return w.engine
 }
 
 func (w *Worker) Stop() {
@@ -110,6 +188,9 @@ func (w *Worker) Stop() {
 	}
 	w.mu.Unlock()
 
+	if w.pool != nil {
+		w.pool.close()
+	}
 	if w.browser != nil {
 		w.browser.Close()
 	}
@@ -118,165 +199,485 @@ func (w *Worker) Stop() {
 	}
 }
 
-// StartRecording initiates a recording session.
-func (w *Worker) StartRecording(ctx context.Context, taskID int64, url string, recordingID int64, outputPath string, customCSS string, fps int64, crf int64, timeOverlay bool, timeOverlayConfig string) error {
+// FinishRecordings stops every session and waits until each has finalized its file
+// and recording row, or until ctx is done. On timeout the error names the sessions
+// still in flight. Call it before Stop, which tears down the browser.
+func (w *Worker) FinishRecordings(ctx context.Context) error {
 	w.mu.Lock()
-	if _, exists := w.sessions[taskID]; exists {
+	for _, cancel := range w.sessions {
+		cancel()
+	}
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	w.mu.Lock()
+	pending := make([]string, 0, len(w.sessions))
+	for key := range w.sessions {
+		pending = append(pending, key.String())
+	}
+	w.mu.Unlock()
+	sort.Strings(pending)
+	return fmt.Errorf("%w: %d recording(s) still finalizing: %s", ctx.Err(), len(pending), strings.Join(pending, ", "))
+}
+
+// RecordingOptions are a task's capture settings, shared by the sessions of all
+// its viewports
+type RecordingOptions struct {
+	TaskID            int64
+	URL               string
+	CustomCSS         string
+	FPS               int64
+	CRF               int64
+	TimeOverlay       bool
+	TimeOverlayConfig string
+	CaptureConsole    bool
+	Preset            string // "" falls back to FFMPEG_PRESET
+	Tune              string // "" falls back to FFMPEG_TUNE
+	CaptureQuality    int64
+	NotifySizeBytes   int64 // 0 disables the size notification
+	RecordOnChange    bool
+	CaptureFormat     string
+	StartDelayMs      int64
+	CaptureMilestones bool
+	Page              PageOptions
+	Login             *FormLogin // nil when the task has no stored login
+	KeepAlive         *KeepAlive
+	Watermark         *Watermark
+}
+
+// StartRecording initiates a recording session of the task at the given viewport.
+// A task records one session per viewport profile, each to its own output file.
+func (w *Worker) StartRecording(ctx context.Context, opts RecordingOptions, outputs []RecordingOutput, viewport Viewport) error {
+	key := SessionKey{TaskID: opts.TaskID, Viewport: viewport.Name}
+	w.mu.Lock()
+	if _, exists := w.sessions[key]; exists {
 		w.mu.Unlock()
-		return fmt.Errorf("recording already in progress for task %d", taskID)
+		return fmt.Errorf("%w for %s", ErrAlreadyRecording, key)
 	}
 	w.mu.Unlock()
 
-	// Pre-flight Check: Write Permissions
-	dir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	// Pre-flight Check: One file of the whole page, or one per region. Session files
+	// (page log, milestones) are named after the first output.
+	if err := validateOutputs(outputs, viewport); err != nil {
+		return err
 	}
-	f, err := os.CreateTemp(dir, "perm_check")
-	if err != nil {
-		return fmt.Errorf("write permission denied for %s: %w", dir, err)
+	outputPath := outputs[0].Path
+
+	// Pre-flight Check: Target policy (re-resolved now, not just at task creation)
+	if err := w.validateTargetVia(opts.URL, opts.Page.Proxy); err != nil {
+		return err
+	}
+	if err := w.validateProxy(opts.Page.Proxy); err != nil {
+		return err
+	}
+
+	// Pre-flight Check: Encoder settings (empty preset falls back to FFMPEG_PRESET)
+	if err := ValidateEncoderSettings(opts.Preset, opts.Tune); err != nil {
+		return err
+	}
+	if err := opts.Page.Validate(); err != nil {
+		return err
+	}
+	if opts.KeepAlive != nil {
+		if err := opts.KeepAlive.Validate(viewport); err != nil {
+			return err
+		}
+	}
+	if opts.Watermark != nil {
+		if err := opts.Watermark.Validate(w.config.WatermarkAllowedHosts, viewport); err != nil {
+			return err
+		}
+	}
+	if opts.Preset == "" {
+		opts.Preset = w.config.FFmpegPreset
+	}
+	if opts.Tune == "" {
+		opts.Tune = w.config.FFmpegTune
+	}
+
+	// Pre-flight Check: Storage is writable (distinguishes full / read-only / permission)
+	if err := checkWritable(filepath.Dir(outputPath)); err != nil {
+		return err
 	}
-	f.Close()
-	os.Remove(f.Name())
 
 	// Use WithCancel for the recording lifecycle (controlled by StopRecording or internal error)
 	// We detach from the caller's request context because recording runs in background.
 	recCtx, cancel := context.WithCancel(context.Background())
 
 	w.mu.Lock()
-	w.sessions[taskID] = cancel
+	w.sessions[key] = cancel
 	w.mu.Unlock()
+	w.logs.open(key)
 
 	// Launch storage path (provided by caller now)
 
+	w.running.Add(1)
 	go func() {
-		defer func() {
-			w.mu.Lock()
-			delete(w.sessions, taskID)
-			w.mu.Unlock()
+		defer w.running.Done()
 
-			// Clean up frame cache to prevent memory leaks
-			w.framesMu.Lock()
-			delete(w.latestFrames, taskID)
-			w.framesMu.Unlock()
-		}()
+		if opts.FPS > 30 {
+			slog.Info("High FPS recording started", "task_id", opts.TaskID, "fps", opts.FPS, "warning", "Significant disk usage expected")
+		}
 
-		if fps > 30 {
-			slog.Info("High FPS recording started", "task_id", taskID, "fps", fps, "warning", "Significant disk usage expected")
+		hook := HookInfo{TaskID: opts.TaskID, RecordingID: outputs[0].RecordingID, Viewport: viewport.Name, URL: opts.URL, OutputPath: outputPath}
+		// A failing pre-recording hook vetoes the recording; stopping the task cancels it
+		err := w.runHook(recCtx, HookPreRecording, hook)
+		if err == nil {
+			err = w.recordLoop(recCtx, opts, outputs, viewport)
 		}
 
-		err := w.recordLoop(recCtx, taskID, url, outputPath, customCSS, fps, crf, timeOverlay, timeOverlayConfig)
+		// The live copy is only for playback during recording; open streams finish reading it
+		for _, o := range outputs {
+			if err := os.Remove(LivePath(o.Path)); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove live copy of recording %d: %v", o.RecordingID, err)
+			}
+		}
 
-		status := "COMPLETED"
+		status := StatusCompleted
 		if err != nil {
-			log.Printf("Recording %d failed: %v", recordingID, err)
-			status = "FAILED"
+			log.Printf("Recording of %s failed: %v", key, err)
+			status = StatusFailed
 			// In a real app we'd save error message too
+			w.publishStatus(key, fmt.Sprintf("%s: %v", status, err))
+		} else {
+			w.publishStatus(key, string(status))
 		}
+		w.logs.close(key)
+		stats, _ := w.GetFrameStats(key)
 
-		// Update DB
-		// Note: We need a background context here as the session ctx is cancelled
-		_ = w.queries.UpdateRecordingStatus(context.Background(), database.UpdateRecordingStatusParams{
-			Status: status,
-			ID:     recordingID,
-		})
+		// The file is final: release the session so the task can be started again
+		// (e.g. by a restart) while the recording is post-processed below
+		w.mu.Lock()
+		delete(w.sessions, key)
+		w.mu.Unlock()
+
+		// Clean up frame cache to prevent memory leaks
+		w.framesMu.Lock()
+		delete(w.latestFrames, key)
+		delete(w.frameStats, key)
+		w.framesMu.Unlock()
+
+		// Persist capture health so archives show gaps from dropped frames (regions
+		// share the session's frames, so they share its counters)
+		degraded := stats.Degraded(w.config.DegradedDropRatio)
+		if degraded {
+			log.Printf("Recording of %s degraded: %d of %d frames dropped", key, stats.Dropped, stats.Captured+stats.Dropped)
+		}
+
+		for _, o := range outputs {
+			// Update DB
+			// Note: We need a background context here as the session ctx is cancelled
+			if err := SetRecordingStatus(context.Background(), w.queries, o.RecordingID, StatusRecording, status); err != nil {
+				log.Printf("Failed to set status of recording %d: %v", o.RecordingID, err)
+			}
+			_ = w.queries.UpdateRecordingFrameStats(context.Background(), database.UpdateRecordingFrameStatsParams{
+				DroppedFrames: stats.Dropped,
+				IsDegraded:    degraded,
+				ID:            o.RecordingID,
+			})
+
+			// Flag or hard-link recordings identical to the previous one (RECORDING_DEDUPE)
+			if status == StatusCompleted {
+				w.dedupeRecording(context.Background(), opts.TaskID, o.RecordingID, o.Path)
+			}
+			// Checksum the final file (after any dedupe link, before hooks can touch it)
+			w.storeChecksum(context.Background(), o.RecordingID, o.Path)
+		}
+
+		// Keep only the newest max_recordings for this task
+		w.rotateRecordings(context.Background(), opts.TaskID)
+
+		hook.Status = string(status)
+		if err != nil {
+			hook.Error = err.Error()
+		}
+		for _, o := range outputs {
+			hook.RecordingID, hook.OutputPath = o.RecordingID, o.Path
+			if err := w.runHook(context.Background(), HookPostRecording, hook); err != nil {
+				log.Printf("Recording %d: %v", o.RecordingID, err)
+			}
+		}
 	}()
 
 	return nil
 }
 
+// ReserveStart claims the task before the caller does any work (e.g. inserting the
+// recording row), so concurrent starts fail fast instead of leaving orphans.
+// The returned release must be called once StartRecording has returned.
+func (w *Worker) ReserveStart(taskID int64) (release func(), err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.hasSessionLocked(taskID) || w.starting[taskID] {
+		return nil, fmt.Errorf("%w for task %d", ErrAlreadyRecording, taskID)
+	}
+	w.starting[taskID] = true
+
+	return func() {
+		w.mu.Lock()
+		delete(w.starting, taskID)
+		w.mu.Unlock()
+	}, nil
+}
+
+// ReserveRestart claims a recording task for a restart. Like ReserveStart it keeps
+// other starts out, but the task must be recording: the claim bridges the moment
+// between the old sessions ending and the new ones being registered.
+// The returned release must be called once the new sessions are started.
+func (w *Worker) ReserveRestart(taskID int64) (release func(), err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.starting[taskID] {
+		return nil, fmt.Errorf("%w for task %d", ErrAlreadyRecording, taskID)
+	}
+	if !w.hasSessionLocked(taskID) {
+		return nil, fmt.Errorf("%w for task %d", ErrNotRecording, taskID)
+	}
+	w.starting[taskID] = true
+
+	return func() {
+		w.mu.Lock()
+		delete(w.starting, taskID)
+		w.mu.Unlock()
+	}, nil
+}
+
+// StopRecordingAndWait stops every session of the task and waits until their files
+// are finalized and the sessions released, so the task can be started again
+func (w *Worker) StopRecordingAndWait(ctx context.Context, taskID int64) error {
+	if err := w.StopRecording(taskID); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(restartPollInterval)
+	defer ticker.Stop()
+	for {
+		w.mu.Lock()
+		active := w.hasSessionLocked(taskID)
+		w.mu.Unlock()
+		if !active {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: task %d is still finalizing", ctx.Err(), taskID)
+		case <-ticker.C:
+		}
+	}
+}
+
+// StopRecording stops every session (viewport) of the task
 func (w *Worker) StopRecording(taskID int64) error {
 	w.mu.Lock()
-	cancel, exists := w.sessions[taskID]
+	var cancels []context.CancelFunc
+	for key, cancel := range w.sessions {
+		if key.TaskID == taskID {
+			cancels = append(cancels, cancel)
+		}
+	}
 	w.mu.Unlock()
 
-	if !exists {
-		return fmt.Errorf("no active recording for task %d", taskID)
+	if len(cancels) == 0 {
+		return fmt.Errorf("%w for task %d", ErrNotRecording, taskID)
 	}
 
-	cancel() // Signal loop to stop
+	for _, cancel := range cancels {
+		cancel() // Signal loop to stop
+	}
 	return nil
 }
 
-func (w *Worker) recordLoop(ctx context.Context, taskID int64, url, outputPath, customCSS string, fps int64, crf int64, timeOverlay bool, timeOverlayConfig string) error {
-	opts := playwright.BrowserNewContextOptions{
-		Viewport:          &playwright.Size{Width: 1920, Height: 1080},
-		BypassCSP:         playwright.Bool(true),
-		IgnoreHttpsErrors: playwright.Bool(true),
+// hasSessionLocked reports whether any viewport of the task is recording; w.mu must be held
+func (w *Worker) hasSessionLocked(taskID int64) bool {
+	for key := range w.sessions {
+		if key.TaskID == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveTaskIDs returns the tasks that currently have a recording session, in ascending order
+func (w *Worker) ActiveTaskIDs() []int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ids := make([]int64, 0, len(w.sessions))
+	seen := make(map[int64]bool, len(w.sessions))
+	for key := range w.sessions {
+		if !seen[key.TaskID] {
+			seen[key.TaskID] = true
+			ids = append(ids, key.TaskID)
+		}
 	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (w *Worker) recordLoop(ctx context.Context, opts RecordingOptions, outputs []RecordingOutput, viewport Viewport) error {
+	key := SessionKey{TaskID: opts.TaskID, Viewport: viewport.Name}
+	outputPath := outputs[0].Path
 
 	// Load session if exists
-	sessionFile := fmt.Sprintf("/app/data/sessions/task_%d.json", taskID)
-	if _, err := os.Stat(sessionFile); err == nil {
-		opts.StorageStatePath = playwright.String(sessionFile)
-		log.Printf("Loaded session from %s", sessionFile)
+	storageState := storedSessionState(opts.TaskID)
+	if storageState != "" {
+		log.Printf("Loaded session from %s", storageState)
 	}
 
-	bCtx, err := w.browser.NewContext(opts)
+	bCtx, page, err := w.openPage(viewport.Width, viewport.Height, storageState, opts.Page)
 	if err != nil {
 		return err
 	}
 	defer bCtx.Close()
 
-	page, err := bCtx.NewPage()
-	if err != nil {
-		return err
+	// Capture console/network errors next to the recording (attached before Goto so load errors are kept)
+	var plog *pageLog
+	if opts.CaptureConsole {
+		plog, err = attachPageLog(page, PageLogPath(outputPath), func(kind, message string) {
+			w.logs.publish(key, LogEvent{Type: "log", Time: time.Now().UTC(), Kind: kind, Message: message})
+		})
+		if err != nil {
+			log.Printf("Failed to start page log for task %d: %v", opts.TaskID, err)
+			plog = nil
+		} else {
+			defer plog.Close()
+		}
 	}
 
-	// Navigate
-	if _, err := page.Goto(url, playwright.PageGotoOptions{
+	// Navigate (policy re-checked immediately before Goto to narrow the rebinding window);
+	// navigations are bounded by NAVIGATION_TIMEOUT, set on the context
+	if err := w.validateTargetVia(opts.URL, opts.Page.Proxy); err != nil {
+		return err
+	}
+	w.publishStatus(key, "navigating")
+	w.logCookieNames(bCtx, key, opts.URL, "at navigation", plog)
+	if opts.CaptureMilestones {
+		// Stop at the load event for its still, then wait for the network to settle as usual
+		if _, err := page.Goto(opts.URL, opts.Page.gotoOptions(playwright.PageGotoOptions{
+			WaitUntil: playwright.WaitUntilStateLoad,
+		})); err != nil {
+			return fmt.Errorf("nav failed: %w", err)
+		}
+		w.saveMilestone(page, key, outputPath, MilestoneLoad)
+		if err := page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+			State: playwright.LoadStateNetworkidle,
+		}); err != nil {
+			return fmt.Errorf("nav failed: %w", err)
+		}
+		w.saveMilestone(page, key, outputPath, MilestoneNetworkIdle)
+	} else if _, err := page.Goto(opts.URL, opts.Page.gotoOptions(playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateNetworkidle,
-		Timeout:   playwright.Float(60000),
-	}); err != nil {
+	})); err != nil {
 		return fmt.Errorf("nav failed: %w", err)
 	}
 
-	// Inject Time Overlay if enabled
-	if timeOverlay {
-		if err := w.InjectTimeOverlay(page, timeOverlayConfig, w.config.NtpServer); err != nil {
-			log.Printf("Failed to inject time overlay for task %d: %v", taskID, err)
-			// Continue recording even if overlay fails
+	// Log in with stored credentials before capturing (form-auth dashboards)
+	if opts.Login != nil {
+		w.publishStatus(key, "logging in")
+		if err := performFormLogin(page, opts.TaskID, opts.Login); err != nil {
+			return err
 		}
+		w.logCookieNames(bCtx, key, opts.URL, "after login", plog)
 	}
 
-	// Inject Custom CSS if present
-	if customCSS != "" {
-		if _, err := page.AddStyleTag(playwright.PageAddStyleTagOptions{
-			Content: playwright.String(customCSS),
-		}); err != nil {
-			log.Printf("Failed to inject custom CSS for task %d: %v", taskID, err)
-			// Continue recording even if CSS fails
+	if err := opts.Page.goOffline(bCtx); err != nil {
+		return fmt.Errorf("failed to switch context offline: %w", err)
+	}
+
+	// The watermark marks every frame, so a recording without it must not start; its
+	// init script re-applies it after reloads
+	if opts.Watermark != nil {
+		if err := InjectWatermark(page, opts.Watermark); err != nil {
+			return err
+		}
+	}
+
+	// Page decorations, re-applied when an unavailable page is reloaded
+	decorate := func() {
+		// Inject Time Overlay if enabled
+		if opts.TimeOverlay {
+			if err := w.InjectTimeOverlay(page, opts.TimeOverlayConfig); err != nil {
+				log.Printf("Failed to inject time overlay for task %d: %v", opts.TaskID, err)
+				// Continue recording even if overlay fails
+			}
+		}
+
+		// Inject Custom CSS if present
+		if opts.CustomCSS != "" {
+			if _, err := page.AddStyleTag(playwright.PageAddStyleTagOptions{
+				Content: playwright.String(opts.CustomCSS),
+			}); err != nil {
+				log.Printf("Failed to inject custom CSS for task %d: %v", opts.TaskID, err)
+				// Continue recording even if CSS fails
+			}
+		}
+	}
+	decorate()
+
+	// start_delay_ms: let splash screens and loading animations finish before the first frame
+	if opts.StartDelayMs > 0 {
+		w.publishStatus(key, "waiting")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped during the %dms start delay", opts.StartDelayMs)
+		case <-time.After(time.Duration(opts.StartDelayMs) * time.Millisecond):
 		}
 	}
+	if opts.CaptureMilestones {
+		w.saveMilestone(page, key, outputPath, MilestoneStart)
+	}
 
-	// Calculate JPEG quality based on CRF
-	jpegQuality := calculateJpegQuality(crf)
+	// Expose the page for live annotations until the loop exits
+	w.registerPage(key, page)
+	defer w.unregisterPage(key)
+
+	// Calculate JPEG quality based on CRF (unless the task overrides it)
+	jpegQuality := captureJpegQuality(opts.CRF, opts.CaptureQuality)
 	slog.Info("Starting recording loop",
-		"task_id", taskID,
-		"crf", crf,
+		"task_id", opts.TaskID,
+		"crf", opts.CRF,
 		"jpeg_quality", jpegQuality,
-		"time_overlay", timeOverlay,
+		"time_overlay", opts.TimeOverlay,
+		"preset", opts.Preset,
+		"tune", opts.Tune,
+		"record_on_change", opts.RecordOnChange,
+		"capture_format", opts.CaptureFormat,
+		"start_delay_ms", opts.StartDelayMs,
+		"capture_milestones", opts.CaptureMilestones,
+		"proxy", RedactProxyURL(opts.Page.Proxy),
+		"keepalive", opts.KeepAlive != nil,
 	)
 
 	// Start FFmpeg
-	// Using "ultrafast" and configurable CRF for cpu/quality balance
-	// Use exec.Command instead of CommandContext so context cancellation doesn't kill it immediately
-	// Start FFmpeg
+	// Preset/tune and CRF are configurable for cpu/size/quality balance
 	// Use exec.Command instead of CommandContext so we can manage graceful shutdown manually
-	// FPS is configurable.
-	ffmpegCmd := exec.Command("ffmpeg",
-		"-y",
-		"-f", "image2pipe",
-		"-vcodec", "mjpeg",
-		"-r", fmt.Sprintf("%d", fps),
-		"-i", "-",
-		"-c:v", "libx264",
-		"-preset", "ultrafast",
-		"-pix_fmt", "yuv420p",
-		"-crf", fmt.Sprintf("%d", crf),
-		"-r", fmt.Sprintf("%d", fps),
-		outputPath,
-	)
+	var args []string
+	if outputs[0].Region != nil {
+		// Regions: each screenshot is cropped into one file per region. Selector
+		// regions are measured now, on the decorated page.
+		if outputs, err = resolveRegions(page, outputs, viewport); err != nil {
+			return err
+		}
+		args = encodeRegionArgs(opts.FPS, opts.CRF, opts.Preset, opts.Tune, opts.CaptureFormat, opts.RecordOnChange, outputs)
+	} else {
+		livePath := ""
+		if w.config.LivePlayback {
+			livePath = LivePath(outputPath)
+		}
+		args = encodeArgs(opts.FPS, opts.CRF, opts.Preset, opts.Tune, opts.CaptureFormat, outputPath, livePath, opts.RecordOnChange)
+	}
+	ffmpegCmd := exec.Command("ffmpeg", args...)
 
 	stdin, err := ffmpegCmd.StdinPipe()
 	if err != nil {
@@ -286,6 +687,18 @@ func (w *Worker) recordLoop(ctx context.Context, taskID int64, url, outputPath,
 	if err := ffmpegCmd.Start(); err != nil {
 		return err
 	}
+	w.registerFFmpeg(key, ffmpegCmd.Process.Pid)
+	defer w.unregisterFFmpeg(key)
+	w.publishStatus(key, "recording")
+
+	// Frames reach ffmpeg through a bounded buffer, so encoder stalls don't stall capture
+	frames := newFrameWriter(stdin, w.config.FrameBufferSize, func(n int64) { w.countBufferDropped(key, n) })
+	defer frames.flush(0)
+	defer func() {
+		if n := frames.droppedFrames(); n > 0 {
+			log.Printf("Frame buffer for %s dropped %d frame(s) while the encoder stalled", key, n)
+		}
+	}()
 
 	// Wait for FFmpeg in a separate goroutine to avoid blocking close
 	ffmpegDone := make(chan error)
@@ -296,22 +709,77 @@ func (w *Worker) recordLoop(ctx context.Context, taskID int64, url, outputPath,
 	// Ticker for screenshots
 	// We aim for the target FPS, but if capture is slow, we calculate how many frames
 	// "should" have passed and duplicate the screenshot to maintain A/V sync (wall clock time).
-	frameIntervalMs := 1000.0 / float64(fps)
+	frameIntervalMs := 1000.0 / float64(opts.FPS)
 	ticker := time.NewTicker(time.Duration(frameIntervalMs) * time.Millisecond)
 	defer ticker.Stop()
 
 	startTime := time.Now()
 	var framesSent int64 = 0
 
+	// A hung screenshot must not stall the ticker; reuse the last good frame instead
+	screenshotTimeoutMs := float64(w.config.ScreenshotTimeout.Milliseconds())
+	var lastFrame []byte
+	consecutiveFailures := 0
+
+	// Page outage (crash/hang): after SCREENSHOT_PLACEHOLDER_AFTER failures the page is
+	// reloaded and a "page unavailable" frame is recorded instead of a frozen one
+	placeholder := &placeholderRenderer{width: viewport.Width, height: viewport.Height, quality: jpegQuality, format: opts.CaptureFormat}
+	var outageStart, lastReload time.Time
+
+	// record_on_change: only frames that differ from the last written one reach ffmpeg
+	var change *changeDetector
+	var lastWrite time.Time
+	if opts.RecordOnChange {
+		change = &changeDetector{threshold: w.config.ChangeThreshold}
+	}
+
+	// notify_size_bytes alert: checked periodically, fires once per recording
+	var sizeCheck <-chan time.Time
+	if opts.NotifySizeBytes > 0 {
+		sizeTicker := time.NewTicker(sizeCheckInterval)
+		defer sizeTicker.Stop()
+		sizeCheck = sizeTicker.C
+	}
+
+	// keepalive: interactions that keep idle-sensitive dashboards logged in, run
+	// between frames so they never race a screenshot
+	var keepAliveTick <-chan time.Time
+	if opts.KeepAlive != nil {
+		keepAliveTicker := time.NewTicker(opts.KeepAlive.interval())
+		defer keepAliveTicker.Stop()
+		keepAliveTick = keepAliveTicker.C
+	}
+
 	for {
 		select {
+		case <-keepAliveTick:
+			runKeepAlive(page, key, opts.KeepAlive)
+		case <-sizeCheck:
+			for _, o := range outputs {
+				if info, err := os.Stat(o.Path); err == nil && info.Size() >= opts.NotifySizeBytes {
+					w.notifySizeExceeded(key, o.Path, info.Size(), opts.NotifySizeBytes)
+					sizeCheck = nil
+					break
+				}
+			}
 		case <-ctx.Done():
+			// A variable frame rate recording ends at its last frame; repeat it so the
+			// final picture lasts until the stop
+			if change != nil && change.last != nil {
+				frames.write(change.last, 1)
+			}
 			// Stop signal received. Close stdin to flush FFmpeg.
+			frames.flush(frameDrainTimeout)
 			stdin.Close()
 
 			// Wait for FFmpeg to finish gracefully, with a timeout
 			select {
 			case err := <-ffmpegDone:
+				if err == nil {
+					for _, o := range outputs {
+						w.applyFilePermissions(o.Path)
+					}
+				}
 				return err
 			case <-time.After(5 * time.Second):
 				// Force kill if it doesn't shut down
@@ -321,177 +789,423 @@ func (w *Worker) recordLoop(ctx context.Context, taskID int64, url, outputPath,
 				return fmt.Errorf("ffmpeg shutdown timed out")
 			}
 		case <-ticker.C:
-			// Capture
-			buf, err := page.Screenshot(playwright.PageScreenshotOptions{
-				Type:    playwright.ScreenshotTypeJpeg,
-				Quality: playwright.Int(jpegQuality),
-			})
+			// Capture (transient errors get a short retry before the frame is dropped)
+			buf, attempts, err := captureWithRetry(func() ([]byte, error) {
+				return page.Screenshot(captureScreenshotOptions(opts.CaptureFormat, jpegQuality, screenshotTimeoutMs))
+			}, w.config.ScreenshotRetries, isTransientScreenshotError)
+			w.countFrame(key, err != nil)
+			if err == nil && attempts > 1 {
+				log.Printf("screenshot for task %d succeeded after %d attempts", opts.TaskID, attempts)
+			}
 			if err != nil {
-				log.Printf("screenshot error: %v", err)
-				continue
+				consecutiveFailures++
+				log.Printf("screenshot error for task %d (%d consecutive): %v", opts.TaskID, consecutiveFailures, err)
+				if limit := w.config.ScreenshotMaxFailures; limit > 0 && consecutiveFailures >= limit {
+					// Finalize what we have so the partial recording stays playable
+					frames.flush(frameDrainTimeout)
+					stdin.Close()
+					select {
+					case <-ffmpegDone:
+					case <-time.After(5 * time.Second):
+						if ffmpegCmd.Process != nil {
+							ffmpegCmd.Process.Kill()
+						}
+					}
+					return fmt.Errorf("aborting after %d consecutive screenshot failures: %w", consecutiveFailures, err)
+				}
+				if after := w.config.ScreenshotPlaceholderAfter; after > 0 && consecutiveFailures >= after {
+					now := time.Now()
+					if outageStart.IsZero() {
+						outageStart = now
+						w.notify(Notification{
+							Event:    "recording.page_unavailable",
+							TaskID:   opts.TaskID,
+							Viewport: viewport.Name,
+							Message:  fmt.Sprintf("page unavailable after %d failed screenshots: %v", consecutiveFailures, err),
+						})
+					}
+					// Offline contexts can't reload; otherwise retry periodically during the outage
+					if !opts.Page.Offline && now.Sub(lastReload) >= pageReloadInterval {
+						lastReload = now
+						if _, err := page.Reload(playwright.PageReloadOptions{
+							WaitUntil: playwright.WaitUntilStateLoad,
+							Timeout:   playwright.Float(float64(pageReloadTimeout.Milliseconds())),
+						}); err != nil {
+							log.Printf("reload of unavailable page for %s failed: %v", key, err)
+						} else {
+							decorate()
+						}
+					}
+					if frame, err := placeholder.render(outageStart, time.Now()); err == nil {
+						buf = frame
+					} else if lastFrame != nil {
+						buf = lastFrame
+					} else {
+						continue
+					}
+				} else {
+					if lastFrame == nil {
+						continue
+					}
+					buf = lastFrame
+				}
+			} else {
+				consecutiveFailures = 0
+				lastFrame = buf
+				if !outageStart.IsZero() {
+					w.notify(Notification{
+						Event:    "recording.page_recovered",
+						TaskID:   opts.TaskID,
+						Viewport: viewport.Name,
+						Message:  fmt.Sprintf("page available again after %s", time.Since(outageStart).Round(time.Second)),
+					})
+					outageStart, lastReload = time.Time{}, time.Time{}
+				}
+
+				// Cache frame for live preview (zero-overhead: reuse same bytes)
+				w.framesMu.Lock()
+				w.latestFrames[key] = cachedFrame{data: buf, capturedAt: time.Now()}
+				w.framesMu.Unlock()
 			}
 
-			// Cache frame for live preview (zero-overhead: reuse same bytes)
-			w.framesMu.Lock()
-			w.latestFrames[taskID] = buf
-			w.framesMu.Unlock()
+			if change != nil {
+				// Unchanged frames are skipped, apart from a heartbeat that keeps playback
+				// seekable; ffmpeg stamps frames on arrival, so the timeline holds
+				if !change.changed(buf) {
+					if time.Since(lastWrite) < w.config.ChangeHeartbeat {
+						continue
+					}
+					buf = change.last
+				}
+				if err := frames.write(buf, 1); err != nil {
+					return err
+				}
+				lastWrite = time.Now()
+				framesSent++
+				continue
+			}
 
 			// Calculate how many frames we need to send to match wall clock time
 			elapsed := time.Since(startTime).Seconds()
-			expectedFrames := int64(elapsed * float64(fps))
+			expectedFrames := int64(elapsed * float64(opts.FPS))
 
 			// Always send at least one frame if we captured one, to ensure progress,
 			// but theoretically if we are super fast we might skip?
 			// In practice, capture is slow, so we usually need >= 1.
-			duplicates := expectedFrames - framesSent
+			// Frames the buffer dropped never reached ffmpeg; duplicates make up for them.
+			duplicates := expectedFrames - (framesSent - frames.droppedFrames())
 			if duplicates < 1 {
 				duplicates = 1
 			}
 
 			// Write to FFmpeg stdin (duplicated as needed)
-			for i := int64(0); i < duplicates; i++ {
-				if _, err := stdin.Write(buf); err != nil {
-					return err
-				}
+			if err := frames.write(buf, duplicates); err != nil {
+				return err
 			}
 			framesSent += duplicates
 		}
 	}
 }
 
+// applyFilePermissions sets the configured mode/group on a finalized recording.
+// Failures are logged only; the recording itself is still valid.
+func (w *Worker) applyFilePermissions(path string) {
+	if w.config.RecordingFileMode != 0 {
+		if err := os.Chmod(path, w.config.RecordingFileMode); err != nil {
+			log.Printf("Failed to chmod recording %s: %v", path, err)
+		}
+	}
+	if w.config.RecordingFileGID >= 0 {
+		if err := os.Chown(path, -1, w.config.RecordingFileGID); err != nil {
+			log.Printf("Failed to chown recording %s: %v", path, err)
+		}
+	}
+}
+
+// cachedFrame is a live preview frame with its capture time
+type cachedFrame struct {
+	data       []byte
+	capturedAt time.Time
+}
+
 // GetLatestFrame returns the latest cached frame for a task (thread-safe)
-// Returns nil if no frame is available
-func (w *Worker) GetLatestFrame(taskID int64) []byte {
+// Returns nil if no frame is available or the frame is older than PREVIEW_FRAME_TTL
+func (w *Worker) GetLatestFrame(key SessionKey) []byte {
 	w.framesMu.RLock()
 	defer w.framesMu.RUnlock()
 
-	frame, exists := w.latestFrames[taskID]
+	frame, exists := w.latestFrames[key]
 	if !exists {
 		return nil
 	}
+	// A frame that stopped updating means the recording hung or died; don't show it frozen
+	if w.config != nil && w.config.PreviewFrameTTL > 0 && time.Since(frame.capturedAt) > w.config.PreviewFrameTTL {
+		return nil
+	}
 
 	// Return a copy to prevent concurrent modification issues
-	frameCopy := make([]byte, len(frame))
-	copy(frameCopy, frame)
+	frameCopy := make([]byte, len(frame.data))
+	copy(frameCopy, frame.data)
 	return frameCopy
 }
 
-// validateURL performs strict validation to prevent SSRF
+// validateURL performs strict validation to prevent SSRF using the default policy
 func validateURL(targetURL string) error {
-	u, err := url.Parse(targetURL)
-	if err != nil {
-		return fmt.Errorf("invalid url format")
+	return DefaultURLPolicy.Validate(targetURL)
+}
+
+// remainingMs returns the time left before ctx's deadline in milliseconds, capped at limit
+func remainingMs(ctx context.Context, limit time.Duration) float64 {
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline); left < limit {
+			limit = left
+		}
+	}
+	if limit < time.Millisecond {
+		limit = time.Millisecond
 	}
+	return float64(limit.Milliseconds())
+}
 
-	// 1. Check Protocol
-	if u.Scheme != "http" && u.Scheme != "https" {
-		return fmt.Errorf("invalid protocol: %s", u.Scheme)
+// previewError reports an expired preview budget as such instead of the
+// "target closed" error Playwright returns after the context is torn down
+func previewError(ctx context.Context, step string, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("preview timed out during %s: %w", step, ctx.Err())
 	}
+	return fmt.Errorf("%s: %w", step, err)
+}
 
-	// 2. Resolve Hostname
-	hostname := u.Hostname()
-	ips, err := net.LookupIP(hostname)
-	if err != nil {
-		return fmt.Errorf("failed to resolve hostname: %w", err)
+// storedSessionState returns the browser state saved by the interactive session of
+// a task (cookies, local storage), or "" when there is none
+func storedSessionState(taskID int64) string {
+	sessionFile := fmt.Sprintf("/app/data/sessions/task_%d.json", taskID)
+	if _, err := os.Stat(sessionFile); err != nil {
+		return ""
 	}
+	return sessionFile
+}
+
+// PreviewOptions make a preview render the page the way a recording of the task
+// would. The zero value previews at the configured preview size with no task state.
+type PreviewOptions struct {
+	// TaskID selects the stored browser session (cookies) of an existing task; 0 = none
+	TaskID int64
+	// Viewport overrides the preview size with the recording viewport
+	Viewport *Viewport
+	// Login is performed after navigation, as StartRecording does
+	Login             *FormLogin
+	TimeOverlay       bool
+	TimeOverlayConfig string
+	// Watermark is burned in as in the recording (validated by the caller)
+	Watermark *Watermark
+}
 
-	// 3. Check IP Addresses
-	for _, ip := range ips {
-		if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() {
-			return fmt.Errorf("access to private IP %s is denied", ip.String())
+// Validate checks the viewport is within the interactive bounds
+func (o PreviewOptions) Validate() error {
+	if v := o.Viewport; v != nil {
+		if v.Width < MinInteractiveWidth || v.Width > MaxInteractiveWidth || v.Height < MinInteractiveHeight || v.Height > MaxInteractiveHeight {
+			return fmt.Errorf("viewport must be within %dx%d and %dx%d", MinInteractiveWidth, MinInteractiveHeight, MaxInteractiveWidth, MaxInteractiveHeight)
 		}
 	}
-
 	return nil
 }
 
 // CapturePreview captures a single JPEG screenshot of the target URL with optional custom CSS.
 // It includes strict URL validation and timeouts.
-func (w *Worker) CapturePreview(targetURL, customCSS string) ([]byte, error) {
+func (w *Worker) CapturePreview(targetURL, customCSS string, pageOpts PageOptions, opts PreviewOptions) ([]byte, error) {
 	// 1. SSRF Protect
-	if err := validateURL(targetURL); err != nil {
-		return nil, fmt.Errorf("security check failed: %w", err)
+	if err := w.validateTargetVia(targetURL, pageOpts.Proxy); err != nil {
+		return nil, err
+	}
+	if err := pageOpts.Validate(); err != nil {
+		return nil, err
+	}
+	if err := w.validateProxy(pageOpts.Proxy); err != nil {
+		return nil, err
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
 	}
 
-	// 2. Setup Context with Timeout
+	// 2. Wait for a preview slot so simultaneous previews don't pile up browser contexts
+	release, err := w.previews.acquire(w.previewQueueLimits())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// 3. Setup Context with Timeout (the budget starts once the slot is ours)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// 3. Launch Browser Context (Incognito)
-	// We use the context for the browser context creation if the library supported it,
-	// but playwright-go's NewContext doesn't take a context.Context.
-	// However, we should respect the timeout for the overall operation.
-	// Since NewContext is fast, we just proceed.
-	// We will use the context for the page navigation if possible, or just rely on the defer cancel.
-
-	bCtx, err := w.browser.NewContext(playwright.BrowserNewContextOptions{
-		Viewport:          &playwright.Size{Width: 1280, Height: 720},
-		BypassCSP:         playwright.Bool(true),
-		IgnoreHttpsErrors: playwright.Bool(true),
-	})
+	// 4. Launch Browser Context (Incognito unless the task has a stored session)
+	width, height, quality, maxBytes := w.previewLimits()
+	if opts.Viewport != nil {
+		width, height = opts.Viewport.Width, opts.Viewport.Height
+	}
+	storageState := ""
+	if opts.TaskID != 0 {
+		storageState = storedSessionState(opts.TaskID)
+	}
+	bCtx, page, err := w.openPage(width, height, storageState, pageOpts)
 	if err != nil {
 		return nil, err
 	}
 	defer bCtx.Close()
 
-	page, err := bCtx.NewPage()
+	// playwright-go calls don't take a context.Context. Closing the browser context
+	// aborts whatever call is in flight, so the 30s budget is enforced for real.
+	stop := context.AfterFunc(ctx, func() {
+		bCtx.Close()
+	})
+	defer stop()
+
+	// 5. Navigate (20s cap, or less if the overall budget is nearly spent)
+	resp, err := page.Goto(targetURL, pageOpts.gotoOptions(playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateNetworkidle,
+		Timeout:   playwright.Float(remainingMs(ctx, 20*time.Second)),
+	}))
 	if err != nil {
-		return nil, err
+		// Chromium downloads what it can't display (e.g. PDFs) instead of navigating
+		if strings.Contains(err.Error(), "Download is starting") {
+			return nil, fmt.Errorf("%w: the browser downloads it instead of displaying it", ErrNonHTMLTarget)
+		}
+		return nil, previewError(ctx, "nav failed", err)
 	}
 
-	// 4. Navigate
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+	// 5b. Non-page targets: images are returned directly with PREVIEW_NON_HTML=image
+	// (types Go can't decode, e.g. SVG, or of unknown length are screenshotted);
+	// everything else is rejected
+	if resp != nil {
+		contentType, _ := resp.HeaderValue("content-type")
+		switch kind, mediaType := previewTargetKind(contentType); kind {
+		case targetImage:
+			if w.config.PreviewNonHTML != "image" {
+				return nil, fmt.Errorf("%w: %s (set PREVIEW_NON_HTML=image to preview images)", ErrNonHTMLTarget, mediaType)
+			}
+			length, _ := resp.HeaderValue("content-length")
+			readable, err := checkPreviewImageLength(length)
+			if err != nil {
+				return nil, err
+			}
+			if readable {
+				body, err := resp.Body()
+				if err != nil {
+					return nil, previewError(ctx, "reading image failed", err)
+				}
+				if img, err := imagePreview(body, quality, maxBytes); err == nil || errors.Is(err, ErrPreviewTooLarge) {
+					return img, err
+				}
+			}
+		case targetOther:
+			return nil, fmt.Errorf("%w: %s", ErrNonHTMLTarget, mediaType)
+		}
 	}
-	if _, err := page.Goto(targetURL, playwright.PageGotoOptions{
-		WaitUntil: playwright.WaitUntilStateNetworkidle,
-		Timeout:   playwright.Float(20000), // 20s navigation timeout
-	}); err != nil {
-		return nil, fmt.Errorf("nav failed: %w", err)
+	if opts.Login != nil {
+		if err := performFormLogin(page, opts.TaskID, opts.Login); err != nil {
+			if ctx.Err() != nil {
+				return nil, previewError(ctx, "login", err)
+			}
+			return nil, err // already prefixed with "login:"
+		}
+	}
+	if err := pageOpts.goOffline(bCtx); err != nil {
+		return nil, previewError(ctx, "offline switch failed", err)
 	}
 
-	// 5. Inject CSS
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+	// 6. Inject overlay and CSS (same order as the recording)
+	if opts.TimeOverlay {
+		if err := w.InjectTimeOverlay(page, opts.TimeOverlayConfig); err != nil {
+			return nil, previewError(ctx, "time overlay injection failed", err)
+		}
 	}
 	if customCSS != "" {
 		if _, err := page.AddStyleTag(playwright.PageAddStyleTagOptions{
 			Content: playwright.String(customCSS),
 		}); err != nil {
-			return nil, fmt.Errorf("css injection failed: %w", err)
+			return nil, previewError(ctx, "css injection failed", err)
 		}
 	}
-
-	// 6. Capture Screenshot
-	// We use a high quality JPEG for preview
-	screenshot, err := page.Screenshot(playwright.PageScreenshotOptions{
-		Type:    playwright.ScreenshotTypeJpeg,
-		Quality: playwright.Int(80),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("screenshot failed: %w", err)
+	if opts.Watermark != nil {
+		if err := InjectWatermark(page, opts.Watermark); err != nil {
+			return nil, previewError(ctx, "watermark injection failed", err)
+		}
 	}
 
-	if len(screenshot) == 0 {
-		return nil, fmt.Errorf("captured empty screenshot")
+	// 7. Capture Screenshot (re-encoded at lower quality if over the size cap)
+	screenshot, err := fitPreview(func(q int) ([]byte, error) {
+		img, err := page.Screenshot(playwright.PageScreenshotOptions{
+			Type:    playwright.ScreenshotTypeJpeg,
+			Quality: playwright.Int(q),
+			Timeout: playwright.Float(remainingMs(ctx, 20*time.Second)),
+		})
+		if err != nil {
+			return nil, previewError(ctx, "screenshot failed", err)
+		}
+		return img, nil
+	}, quality, maxBytes)
+	if err != nil {
+		return nil, err
 	}
 
 	return screenshot, nil
 }
 
+// previewLimits returns the configured preview viewport, JPEG quality and byte cap
+func (w *Worker) previewLimits() (width, height, quality, maxBytes int) {
+	if w.config == nil {
+		return 1280, 720, 80, 0
+	}
+	return w.config.PreviewWidth, w.config.PreviewHeight, w.config.PreviewQuality, w.config.PreviewMaxBytes
+}
+
 // Interactive Event Types
 type InteractionEvent struct {
-	Type string  `json:"type"`
-	X    float64 `json:"x"`
-	Y    float64 `json:"y"`
-	Text string  `json:"text"`
-	Key  string  `json:"key"`
+	Type   string  `json:"type"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Text   string  `json:"text"`
+	Key    string  `json:"key"`
+	DeltaX float64 `json:"delta_x,omitempty"` // scroll only
+	DeltaY float64 `json:"delta_y,omitempty"` // scroll only
+}
+
+// applyInteraction performs a click, type, key or scroll event on the page; used by
+// remote control sessions and keep-alive actions
+func applyInteraction(page playwright.Page, event InteractionEvent) error {
+	switch event.Type {
+	case "click":
+		return page.Mouse().Click(event.X, event.Y)
+	case "type":
+		return page.Keyboard().Type(event.Text)
+	case "key":
+		return page.Keyboard().Press(event.Key)
+	case "scroll":
+		// The wheel scrolls whatever is under the pointer
+		if err := page.Mouse().Move(event.X, event.Y); err != nil {
+			return err
+		}
+		return page.Mouse().Wheel(event.DeltaX, event.DeltaY)
+	}
+	return fmt.Errorf("unknown interaction %q", event.Type)
 }
 
 // HandleInteractive manages a remote control session via WebSocket.
-func (w *Worker) HandleInteractive(ctx context.Context, taskID int64, url string, conn *websocket.Conn) error {
+func (w *Worker) HandleInteractive(ctx context.Context, taskID int64, url string, pageOpts PageOptions, conn *websocket.Conn, options InteractiveOptions) error {
 	defer conn.Close()
 
+	// 0. SSRF Protect (same policy as recording, through the task's proxy if any)
+	if err := w.validateTargetVia(url, pageOpts.Proxy); err != nil {
+		return err
+	}
+	if err := w.validateProxy(pageOpts.Proxy); err != nil {
+		return err
+	}
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
 	// 1. Setup Browser Context with Persistent Storage
 	storageDir := "/app/data/sessions"
 	if err := os.MkdirAll(storageDir, 0755); err != nil {
@@ -499,60 +1213,82 @@ func (w *Worker) HandleInteractive(ctx context.Context, taskID int64, url string
 	}
 	stateFile := fmt.Sprintf("%s/task_%d.json", storageDir, taskID)
 
-	opts := playwright.BrowserNewContextOptions{
-		Viewport:          &playwright.Size{Width: 1920, Height: 1080},
-		BypassCSP:         playwright.Bool(true),
-		IgnoreHttpsErrors: playwright.Bool(true),
-	}
 	// Load storage state if exists
+	storageState := ""
 	if _, err := os.Stat(stateFile); err == nil {
-		opts.StorageStatePath = playwright.String(stateFile)
+		storageState = stateFile
 	}
 
-	bCtx, err := w.browser.NewContext(opts)
+	bCtx, page, err := w.openPage(options.Width, options.Height, storageState, pageOpts)
 	if err != nil {
 		return fmt.Errorf("context creation failed: %w", err)
 	}
 	defer bCtx.Close()
 
-	page, err := bCtx.NewPage()
-	if err != nil {
-		return fmt.Errorf("page creation failed: %w", err)
-	}
-
+	// Bounded by NAVIGATION_TIMEOUT like every other navigation (see applyContextTimeouts)
 	if _, err := page.Goto(url, playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateNetworkidle,
-		Timeout:   playwright.Float(30000),
 	}); err != nil {
 		return fmt.Errorf("nav failed: %w", err)
 	}
 
+	capture, format := frameCapturer(bCtx, page, options)
+
+	// Announce viewport, frame size and encoding before any frame so the client can
+	// size its canvas, decode, and map clicks back to viewport coordinates
+	frameWidth, frameHeight := options.frameSize()
+	if err := conn.WriteJSON(InteractiveHello{
+		Type:        "hello",
+		Version:     InteractiveProtocolVersion,
+		Width:       options.Width,
+		Height:      options.Height,
+		FrameWidth:  frameWidth,
+		FrameHeight: frameHeight,
+		Scale:       options.frameScale(),
+		Format:      format,
+		Events:      InteractiveEvents,
+		Features:    []string{"changed_frames", "keepalive", "scaled_frames"},
+	}); err != nil {
+		return err
+	}
+
 	// 2. Stream Loop (Send Screenshots)
 	go func() {
 		ticker := time.NewTicker(100 * time.Millisecond) // 10 FPS
 		defer ticker.Stop()
 
+		// Only changed frames are sent; static pages get periodic keyframes and keepalives
+		filter := &frameFilter{lastSent: time.Now()}
+
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				screenshot, err := page.Screenshot(playwright.PageScreenshotOptions{
-					Type:    playwright.ScreenshotTypeJpeg,
-					Quality: playwright.Int(60), // Low quality for speed
-				})
-				if err != nil {
+			case now := <-ticker.C:
+				screenshot, err := capture()
+				if err == nil && filter.shouldSend(screenshot, now) {
+					// Send as Binary Message
+					if err := conn.WriteMessage(websocket.BinaryMessage, screenshot); err != nil {
+						return
+					}
 					continue
 				}
-				// Send as Binary Message
-				if err := conn.WriteMessage(websocket.BinaryMessage, screenshot); err != nil {
-					return
+				if filter.needsKeepalive(now) {
+					if err := conn.WriteJSON(map[string]string{"type": "keepalive"}); err != nil {
+						return
+					}
 				}
 			}
 		}
 	}()
 
-	// 3. Command Loop (Receive Inputs)
+	// 3. Command Loop (Receive Inputs), rate limited per connection
+	limiter := newEventLimiter(w.interactiveEventLimits())
+	defer func() {
+		if limiter.dropped > 0 {
+			log.Printf("Interactive session for task %d dropped %d event(s) over the rate limit", taskID, limiter.dropped)
+		}
+	}()
 	for {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
@@ -564,19 +1300,17 @@ func (w *Worker) HandleInteractive(ctx context.Context, taskID int64, url string
 			log.Printf("Invalid event: %v", err)
 			continue
 		}
+		if !limiter.allow(event.Type, time.Now()) {
+			if limiter.dropped == 1 {
+				log.Printf("Interactive session for task %d exceeds the event rate limit; dropping events", taskID)
+			}
+			continue
+		}
 
 		switch event.Type {
-		case "click":
-			if err := page.Mouse().Click(event.X, event.Y); err != nil {
-				log.Printf("Click failed: %v", err)
-			}
-		case "type":
-			if err := page.Keyboard().Type(event.Text); err != nil {
-				log.Printf("Type failed: %v", err)
-			}
-		case "key":
-			if err := page.Keyboard().Press(event.Key); err != nil {
-				log.Printf("Key press failed: %v", err)
+		case "click", "type", "key":
+			if err := applyInteraction(page, event); err != nil {
+				log.Printf("Interaction %q failed: %v", event.Type, err)
 			}
 		case "save":
 			// Save Storage State
@@ -623,14 +1357,41 @@ func calculateJpegQuality(crf int64) int {
 	return qInt
 }
 
-// InjectTimeOverlay injects a time overlay into the page, synchronized with NTP.
-func (w *Worker) InjectTimeOverlay(page playwright.Page, config string, ntpServer string) error {
-	// 1. Get NTP Offset
-	offset, err := GetNTPTime(ntpServer)
-	if err != nil {
-		slog.Error("NTP query failed, falling back to system time", "error", err)
-		offset = 0
+// captureScreenshotOptions returns the recording's screenshot options: JPEG at
+// quality, or lossless PNG (which takes no quality) for CaptureFormatPNG
+func captureScreenshotOptions(format string, quality int, timeoutMs float64) playwright.PageScreenshotOptions {
+	if format == CaptureFormatPNG {
+		return playwright.PageScreenshotOptions{
+			Type:    playwright.ScreenshotTypePng,
+			Timeout: playwright.Float(timeoutMs),
+		}
 	}
+	return playwright.PageScreenshotOptions{
+		Type:    playwright.ScreenshotTypeJpeg,
+		Quality: playwright.Int(quality),
+		Timeout: playwright.Float(timeoutMs),
+	}
+}
+
+// captureJpegQuality returns the task's capture_quality override when set (> 0),
+// clamped to MinJpegQuality..MaxJpegQuality, and the CRF-derived quality otherwise.
+func captureJpegQuality(crf, override int64) int {
+	if override <= 0 {
+		return calculateJpegQuality(crf)
+	}
+	if override > MaxJpegQuality {
+		return MaxJpegQuality
+	}
+	if override < MinJpegQuality {
+		return MinJpegQuality
+	}
+	return int(override)
+}
+
+// InjectTimeOverlay injects a time overlay into the page, synchronized with NTP.
+func (w *Worker) InjectTimeOverlay(page playwright.Page, config string) error {
+	// 1. Get NTP Offset (cached; see ClockStatus)
+	offset := w.clockOffset()
 
 	// 2. Validate Config
 	validConfigs := map[string]bool{
@@ -27,7 +27,7 @@ func TestCapturePreview_SecurityIntegration(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := w.CapturePreview(tt.url, "")
+			_, err := w.CapturePreview(tt.url, "", DefaultPageOptions(), PreviewOptions{})
 			if err == nil {
 				t.Errorf("CapturePreview(%q) expected error, got nil", tt.url)
 				return
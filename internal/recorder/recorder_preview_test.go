@@ -1,7 +1,11 @@
 package recorder
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/config"
 )
 
 func TestValidateURL(t *testing.T) {
@@ -32,3 +36,47 @@ func TestValidateURL(t *testing.T) {
 		})
 	}
 }
+
+func TestGetLatestFrame_Staleness(t *testing.T) {
+	w := &Worker{
+		config:       &config.Config{PreviewFrameTTL: 10 * time.Second},
+		latestFrames: make(map[SessionKey]cachedFrame),
+	}
+
+	w.latestFrames[SessionKey{TaskID: 1}] = cachedFrame{data: []byte("fresh"), capturedAt: time.Now()}
+	w.latestFrames[SessionKey{TaskID: 2}] = cachedFrame{data: []byte("stale"), capturedAt: time.Now().Add(-time.Minute)}
+
+	if got := w.GetLatestFrame(SessionKey{TaskID: 1}); string(got) != "fresh" {
+		t.Errorf("GetLatestFrame(1) = %q, want %q", got, "fresh")
+	}
+	if got := w.GetLatestFrame(SessionKey{TaskID: 2}); got != nil {
+		t.Errorf("GetLatestFrame(2) = %q, want nil for stale frame", got)
+	}
+	if got := w.GetLatestFrame(SessionKey{TaskID: 3}); got != nil {
+		t.Errorf("GetLatestFrame(3) = %q, want nil for missing frame", got)
+	}
+
+	// TTL of 0 disables the staleness check
+	w.config.PreviewFrameTTL = 0
+	if got := w.GetLatestFrame(SessionKey{TaskID: 2}); string(got) != "stale" {
+		t.Errorf("GetLatestFrame(2) with TTL disabled = %q, want %q", got, "stale")
+	}
+}
+
+func TestRemainingMs(t *testing.T) {
+	if got := remainingMs(context.Background(), 20*time.Second); got != 20000 {
+		t.Errorf("remainingMs() without deadline = %v, want 20000", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if got := remainingMs(ctx, 20*time.Second); got > 5000 || got < 4000 {
+		t.Errorf("remainingMs() with 5s deadline = %v, want ~5000", got)
+	}
+
+	expired, cancelExpired := context.WithTimeout(context.Background(), -time.Second)
+	defer cancelExpired()
+	if got := remainingMs(expired, 20*time.Second); got != 1 {
+		t.Errorf("remainingMs() with expired deadline = %v, want 1", got)
+	}
+}
@@ -1,7 +1,11 @@
 package recorder
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCalculateJpegQuality(t *testing.T) {
@@ -44,3 +48,167 @@ func TestCalculateJpegQuality_Bounds(t *testing.T) {
 	// If allow CRF 100 -> clamped to 51 -> 74.
 	// So we are safe.
 }
+
+func TestCaptureJpegQuality(t *testing.T) {
+	tests := []struct {
+		name     string
+		crf      int64
+		override int64
+		want     int
+	}{
+		{"No Override Uses CRF", 23, 0, calculateJpegQuality(23)},
+		{"Override Decouples From CRF", 40, 90, 90},
+		{"Override Clamped To Max", 23, 100, MaxJpegQuality},
+		{"Override Clamped To Min", 23, 5, MinJpegQuality},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := captureJpegQuality(tt.crf, tt.override); got != tt.want {
+				t.Errorf("captureJpegQuality(%d, %d) = %d; want %d", tt.crf, tt.override, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReserveStart(t *testing.T) {
+	w := &Worker{
+		sessions: make(map[SessionKey]context.CancelFunc),
+		starting: make(map[int64]bool),
+	}
+
+	release, err := w.ReserveStart(1)
+	if err != nil {
+		t.Fatalf("ReserveStart(1) error = %v", err)
+	}
+	if _, err := w.ReserveStart(1); !errors.Is(err, ErrAlreadyRecording) {
+		t.Errorf("second ReserveStart(1) error = %v, want ErrAlreadyRecording", err)
+	}
+
+	// Other tasks are unaffected
+	releaseOther, err := w.ReserveStart(2)
+	if err != nil {
+		t.Fatalf("ReserveStart(2) error = %v", err)
+	}
+	releaseOther()
+
+	release()
+	release, err = w.ReserveStart(1)
+	if err != nil {
+		t.Fatalf("ReserveStart(1) after release error = %v", err)
+	}
+	release()
+
+	// An active session also blocks the reservation
+	w.sessions[SessionKey{TaskID: 1}] = func() {}
+	if _, err := w.ReserveStart(1); !errors.Is(err, ErrAlreadyRecording) {
+		t.Errorf("ReserveStart(1) with active session error = %v, want ErrAlreadyRecording", err)
+	}
+}
+
+func TestReserveRestart(t *testing.T) {
+	w := &Worker{
+		sessions: make(map[SessionKey]context.CancelFunc),
+		starting: make(map[int64]bool),
+	}
+
+	if _, err := w.ReserveRestart(1); !errors.Is(err, ErrNotRecording) {
+		t.Errorf("ReserveRestart(1) without session error = %v, want ErrNotRecording", err)
+	}
+
+	key := SessionKey{TaskID: 1}
+	w.sessions[key] = func() {
+		// Finalizing releases the session shortly after the stop
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			w.mu.Lock()
+			delete(w.sessions, key)
+			w.mu.Unlock()
+		}()
+	}
+	release, err := w.ReserveRestart(1)
+	if err != nil {
+		t.Fatalf("ReserveRestart(1) error = %v", err)
+	}
+	if _, err := w.ReserveStart(1); !errors.Is(err, ErrAlreadyRecording) {
+		t.Errorf("ReserveStart(1) during restart error = %v, want ErrAlreadyRecording", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.StopRecordingAndWait(ctx, 1); err != nil {
+		t.Fatalf("StopRecordingAndWait(1) error = %v", err)
+	}
+	// The old session is gone but the claim still keeps other starts out
+	if _, err := w.ReserveStart(1); !errors.Is(err, ErrAlreadyRecording) {
+		t.Errorf("ReserveStart(1) between stop and start error = %v, want ErrAlreadyRecording", err)
+	}
+	release()
+	if release, err := w.ReserveStart(1); err != nil {
+		t.Errorf("ReserveStart(1) after restart error = %v", err)
+	} else {
+		release()
+	}
+}
+
+func TestStopRecording_AllViewports(t *testing.T) {
+	w := &Worker{sessions: make(map[SessionKey]context.CancelFunc)}
+
+	stopped := 0
+	w.sessions[SessionKey{TaskID: 1}] = func() { stopped++ }
+	w.sessions[SessionKey{TaskID: 1, Viewport: "mobile"}] = func() { stopped++ }
+	w.sessions[SessionKey{TaskID: 2}] = func() { t.Errorf("StopRecording(1) stopped task 2") }
+
+	if got := w.ActiveTaskIDs(); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("ActiveTaskIDs() = %v, want [1 2]", got)
+	}
+	if err := w.StopRecording(1); err != nil {
+		t.Fatalf("StopRecording(1) error = %v", err)
+	}
+	if stopped != 2 {
+		t.Errorf("StopRecording(1) stopped %d sessions, want 2", stopped)
+	}
+	if err := w.StopRecording(3); !errors.Is(err, ErrNotRecording) {
+		t.Errorf("StopRecording(3) without session error = %v, want ErrNotRecording", err)
+	}
+}
+
+func TestFinishRecordings(t *testing.T) {
+	w := &Worker{sessions: make(map[SessionKey]context.CancelFunc)}
+
+	// A session that finalizes once cancelled, like recordLoop's goroutine
+	start := func(key SessionKey, finalize bool) {
+		ctx, cancel := context.WithCancel(context.Background())
+		w.sessions[key] = cancel
+		w.running.Add(1)
+		go func() {
+			defer w.running.Done()
+			<-ctx.Done()
+			if !finalize {
+				time.Sleep(time.Hour) // stuck in finalization
+			}
+			w.mu.Lock()
+			delete(w.sessions, key)
+			w.mu.Unlock()
+		}()
+	}
+
+	start(SessionKey{TaskID: 1}, true)
+	start(SessionKey{TaskID: 1, Viewport: "mobile"}, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.FinishRecordings(ctx); err != nil {
+		t.Fatalf("FinishRecordings() error = %v", err)
+	}
+	if len(w.sessions) != 0 {
+		t.Errorf("FinishRecordings() left %d sessions", len(w.sessions))
+	}
+
+	start(SessionKey{TaskID: 2}, false)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := w.FinishRecordings(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "task 2") {
+		t.Errorf("FinishRecordings() stuck session error = %v, want deadline naming task 2", err)
+	}
+}
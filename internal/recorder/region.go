@@ -0,0 +1,161 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// MaxRegions bounds the capture regions of one task (each is a recording)
+const MaxRegions = 8
+
+// MinRegionSize is the smallest width and height of a capture region, in pixels
+const MinRegionSize = 16
+
+// maxRegionSelectorLength bounds a region's CSS selector
+const maxRegionSelectorLength = 512
+
+// Region is a named rectangle of the page a task records on its own. All regions of
+// a task are cropped from the same screenshot, so one browser session produces one
+// recording per region instead of one of the whole page. A region is either a fixed
+// rectangle or a CSS selector, resolved to the element's box when capture starts.
+type Region struct {
+	Name     string `json:"name"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Selector string `json:"selector,omitempty"`
+}
+
+// RecordingOutput is one file (and recording row) written by a session. Region is
+// nil for a recording of the whole page.
+type RecordingOutput struct {
+	RecordingID int64
+	Path        string
+	Region      *Region
+}
+
+// ValidateRegions checks a task's regions against the viewport they are cropped
+// from: at most MaxRegions, each with a unique file-name-safe name and either a
+// selector or a rectangle of at least MinRegionSize inside the viewport
+func ValidateRegions(regions []Region, viewport Viewport) error {
+	if len(regions) > MaxRegions {
+		return fmt.Errorf("a task can have at most %d regions", MaxRegions)
+	}
+	seen := make(map[string]bool, len(regions))
+	for _, r := range regions {
+		if !viewportNamePattern.MatchString(r.Name) {
+			return fmt.Errorf("region name %q is invalid. Allowed: 1-32 of a-z, 0-9, _, - (starting with a letter or digit)", r.Name)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("duplicate region name %q", r.Name)
+		}
+		seen[r.Name] = true
+		if r.Selector != "" {
+			if r.X != 0 || r.Y != 0 || r.Width != 0 || r.Height != 0 {
+				return fmt.Errorf("region %q must set either a selector or x/y/width/height, not both", r.Name)
+			}
+			if len(r.Selector) > maxRegionSelectorLength || strings.TrimSpace(r.Selector) != r.Selector {
+				return fmt.Errorf("region %q selector must be at most %d characters without surrounding spaces", r.Name, maxRegionSelectorLength)
+			}
+			continue
+		}
+		if r.X < 0 || r.Y < 0 || r.Width < MinRegionSize || r.Height < MinRegionSize {
+			return fmt.Errorf("region %q must be at least %dx%d at a non-negative position", r.Name, MinRegionSize, MinRegionSize)
+		}
+		if r.X+r.Width > viewport.Width || r.Y+r.Height > viewport.Height {
+			return fmt.Errorf("region %q must lie within the %dx%d viewport", r.Name, viewport.Width, viewport.Height)
+		}
+	}
+	return nil
+}
+
+// EncodeRegions serializes regions for the tasks.regions column ("" when none)
+func EncodeRegions(regions []Region) (string, error) {
+	if len(regions) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(regions)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// DecodeRegions parses the tasks.regions column; nil when the task records the whole page
+func DecodeRegions(stored string) ([]Region, error) {
+	if stored == "" {
+		return nil, nil
+	}
+	var regions []Region
+	if err := json.Unmarshal([]byte(stored), &regions); err != nil {
+		return nil, fmt.Errorf("invalid stored regions: %w", err)
+	}
+	return regions, nil
+}
+
+// resolveRegions returns the outputs with every selector region replaced by the
+// box of its first matching element, clipped to the viewport. A selector that
+// matches nothing visible fails the recording rather than producing an empty crop.
+func resolveRegions(page playwright.Page, outputs []RecordingOutput, viewport Viewport) ([]RecordingOutput, error) {
+	resolved := make([]RecordingOutput, len(outputs))
+	for i, o := range outputs {
+		resolved[i] = o
+		if o.Region == nil || o.Region.Selector == "" {
+			continue
+		}
+		box, err := page.Locator(o.Region.Selector).First().BoundingBox()
+		if err != nil {
+			return nil, fmt.Errorf("region %q: failed to locate %q: %w", o.Region.Name, o.Region.Selector, err)
+		}
+		if box == nil {
+			return nil, fmt.Errorf("region %q: %q is not visible", o.Region.Name, o.Region.Selector)
+		}
+		r := clipRegion(*o.Region, box, viewport)
+		if r.Width < MinRegionSize || r.Height < MinRegionSize {
+			return nil, fmt.Errorf("region %q: %q is smaller than %dx%d within the viewport", o.Region.Name, o.Region.Selector, MinRegionSize, MinRegionSize)
+		}
+		resolved[i].Region = &r
+	}
+	return resolved, nil
+}
+
+// clipRegion returns r set to the pixel rectangle of box that lies inside the viewport
+func clipRegion(r Region, box *playwright.Rect, viewport Viewport) Region {
+	x0 := math.Max(0, math.Floor(box.X))
+	y0 := math.Max(0, math.Floor(box.Y))
+	x1 := math.Min(float64(viewport.Width), math.Ceil(box.X+box.Width))
+	y1 := math.Min(float64(viewport.Height), math.Ceil(box.Y+box.Height))
+	r.X, r.Y = int(x0), int(y0)
+	r.Width, r.Height = int(math.Max(0, x1-x0)), int(math.Max(0, y1-y0))
+	return r
+}
+
+// cropFilter is the ffmpeg crop of a region. yuv420p needs even dimensions, so an
+// odd width or height loses its last pixel.
+func (r Region) cropFilter() string {
+	return fmt.Sprintf("crop=%d:%d:%d:%d", r.Width&^1, r.Height&^1, r.X, r.Y)
+}
+
+// validateOutputs checks a session writes either one recording of the whole page
+// or one recording per valid region
+func validateOutputs(outputs []RecordingOutput, viewport Viewport) error {
+	if len(outputs) == 0 {
+		return fmt.Errorf("no recording output")
+	}
+	if len(outputs) == 1 && outputs[0].Region == nil {
+		return nil
+	}
+	regions := make([]Region, len(outputs))
+	for i, o := range outputs {
+		if o.Region == nil {
+			return fmt.Errorf("a session recording regions can't also record the whole page")
+		}
+		regions[i] = *o.Region
+	}
+	return ValidateRegions(regions, viewport)
+}
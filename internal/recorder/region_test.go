@@ -0,0 +1,88 @@
+package recorder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestValidateRegions(t *testing.T) {
+	viewport := DefaultViewport()
+	tests := []struct {
+		name      string
+		regions   []Region
+		wantError string
+	}{
+		{"None", nil, ""},
+		{"Rectangles", []Region{{Name: "cpu", Width: 640, Height: 360}, {Name: "mem", X: 640, Width: 640, Height: 360}}, ""},
+		{"Selector", []Region{{Name: "chart", Selector: "#panel-2"}}, ""},
+		{"Bad Name", []Region{{Name: "CPU", Width: 64, Height: 64}}, "invalid"},
+		{"Duplicate", []Region{{Name: "a", Width: 64, Height: 64}, {Name: "a", Width: 64, Height: 64}}, "duplicate"},
+		{"Too Small", []Region{{Name: "a", Width: 8, Height: 64}}, "at least"},
+		{"Negative", []Region{{Name: "a", X: -1, Width: 64, Height: 64}}, "non-negative"},
+		{"Outside Viewport", []Region{{Name: "a", X: viewport.Width - 32, Width: 64, Height: 64}}, "within"},
+		{"Selector And Rectangle", []Region{{Name: "a", Selector: "#x", Width: 64, Height: 64}}, "not both"},
+		{"Too Many", make([]Region, MaxRegions+1), "at most"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRegions(tt.regions, viewport)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("ValidateRegions() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("ValidateRegions() error = %v, want substring %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestRegions_RoundTrip(t *testing.T) {
+	stored, err := EncodeRegions([]Region{{Name: "cpu", X: 10, Y: 20, Width: 300, Height: 200}})
+	if err != nil {
+		t.Fatalf("EncodeRegions() error = %v", err)
+	}
+	regions, err := DecodeRegions(stored)
+	if err != nil || len(regions) != 1 || regions[0].Name != "cpu" || regions[0].Width != 300 {
+		t.Errorf("DecodeRegions() = %v, %v", regions, err)
+	}
+	if stored, _ := EncodeRegions(nil); stored != "" {
+		t.Errorf("EncodeRegions(nil) = %q, want empty", stored)
+	}
+}
+
+func TestClipRegion(t *testing.T) {
+	viewport := Viewport{Width: 800, Height: 600}
+	r := clipRegion(Region{Name: "a"}, &playwright.Rect{X: -10.5, Y: 100.2, Width: 300, Height: 900}, viewport)
+	if r.X != 0 || r.Y != 100 || r.Width != 290 || r.Height != 500 {
+		t.Errorf("clipRegion() = %+v, want 0,100 290x500", r)
+	}
+	if r := clipRegion(Region{}, &playwright.Rect{X: 900, Y: 0, Width: 50, Height: 50}, viewport); r.Width != 0 {
+		t.Errorf("clipRegion() off screen width = %d, want 0", r.Width)
+	}
+}
+
+func TestCropFilter_EvenDimensions(t *testing.T) {
+	if got := (Region{X: 3, Y: 5, Width: 101, Height: 64}).cropFilter(); got != "crop=100:64:3:5" {
+		t.Errorf("cropFilter() = %q, want crop=100:64:3:5", got)
+	}
+}
+
+func TestValidateOutputs(t *testing.T) {
+	viewport := DefaultViewport()
+	region := &Region{Name: "a", Width: 64, Height: 64}
+	if err := validateOutputs([]RecordingOutput{{Path: "a.mkv"}}, viewport); err != nil {
+		t.Errorf("validateOutputs() whole page error = %v", err)
+	}
+	if err := validateOutputs(nil, viewport); err == nil {
+		t.Errorf("validateOutputs(nil) expected error")
+	}
+	if err := validateOutputs([]RecordingOutput{{Path: "a.mkv", Region: region}, {Path: "b.mkv"}}, viewport); err == nil {
+		t.Errorf("validateOutputs() mixing regions and the whole page expected error")
+	}
+}
@@ -0,0 +1,62 @@
+package recorder
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+)
+
+// rotateRecordings enforces the task's max_recordings limit by deleting the
+// oldest recordings (file and row) beyond the newest N runs. A run is one start of
+// the task, so the recordings of its viewports and regions are kept or rotated
+// together. Protected and in-progress recordings are never deleted. A limit of 0
+// means unlimited.
+func (w *Worker) rotateRecordings(ctx context.Context, taskID int64) {
+	if w.queries == nil {
+		return
+	}
+
+	task, err := w.queries.GetTask(ctx, taskID)
+	if err != nil {
+		log.Printf("Rotation: failed to load task %d: %v", taskID, err)
+		return
+	}
+	if task.MaxRecordings <= 0 {
+		return
+	}
+
+	stale, err := w.queries.ListRecordingsToRotate(ctx, database.ListRecordingsToRotateParams{
+		TaskID:   taskID,
+		TaskID_2: taskID,
+		Limit:    task.MaxRecordings,
+	})
+	if err != nil {
+		log.Printf("Rotation: failed to list recordings for task %d: %v", taskID, err)
+		return
+	}
+
+	for _, rec := range stale {
+		if rec.FilePath != "" {
+			if err := os.Remove(rec.FilePath); err != nil && !os.IsNotExist(err) {
+				log.Printf("Rotation: failed to delete file %s: %v", rec.FilePath, err)
+				// Keep the row so the orphaned file stays visible and can be retried
+				continue
+			}
+			if err := os.Remove(PageLogPath(rec.FilePath)); err != nil && !os.IsNotExist(err) {
+				log.Printf("Rotation: failed to delete log for recording %d: %v", rec.ID, err)
+			}
+			for _, still := range MilestonePaths(rec.FilePath) {
+				if err := os.Remove(still); err != nil && !os.IsNotExist(err) {
+					log.Printf("Rotation: failed to delete milestone %s: %v", still, err)
+				}
+			}
+		}
+		if err := w.queries.DeleteRecording(ctx, rec.ID); err != nil {
+			log.Printf("Rotation: failed to delete recording %d: %v", rec.ID, err)
+			continue
+		}
+		log.Printf("Rotation: removed recording %d of task %d (max_recordings=%d)", rec.ID, taskID, task.MaxRecordings)
+	}
+}
@@ -0,0 +1,74 @@
+package recorder
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+)
+
+// newTestQueries returns queries over an in-memory database with the current schema
+func newTestQueries(t *testing.T) *database.Queries {
+	t.Helper()
+	schema, err := os.ReadFile("../../sql/schema/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1) // every connection would get its own in-memory database
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatal(err)
+	}
+	return database.New(db)
+}
+
+func TestRotateRecordings_PerRun(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueries(t)
+	task, err := q.CreateTask(ctx, database.CreateTaskParams{Name: "Test", TargetUrl: "http://example.com", MaxRecordings: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	create := func(runID, name string) database.Recording {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		rec, err := q.CreateRecording(ctx, database.CreateRecordingParams{TaskID: task.ID, Status: string(StatusCompleted), FilePath: path, RunID: runID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	// A legacy row without a run, then two starts of a two-viewport task
+	legacy := create("", "legacy.mkv")
+	old := []database.Recording{create("run1", "old_desktop.mkv"), create("run1", "old_mobile.mkv")}
+	current := []database.Recording{create("run2", "new_desktop.mkv"), create("run2", "new_mobile.mkv")}
+
+	w := &Worker{queries: q}
+	w.rotateRecordings(ctx, task.ID)
+
+	for _, rec := range current {
+		if _, err := q.GetRecording(ctx, rec.ID); err != nil {
+			t.Errorf("recording %d of the newest run was rotated: %v", rec.ID, err)
+		}
+		if _, err := os.Stat(rec.FilePath); err != nil {
+			t.Errorf("file of recording %d of the newest run was deleted", rec.ID)
+		}
+	}
+	for _, rec := range append(old, legacy) {
+		if _, err := q.GetRecording(ctx, rec.ID); err == nil {
+			t.Errorf("recording %d of an older run was kept", rec.ID)
+		}
+	}
+}
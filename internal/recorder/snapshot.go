@@ -0,0 +1,42 @@
+package recorder
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const (
+	// SnapshotJPEGQuality is used for JPEG snapshots (PNG snapshots are lossless)
+	SnapshotJPEGQuality = 95
+	// snapshotTimeout bounds an on-demand snapshot; the recording loop keeps running meanwhile
+	snapshotTimeout = 10 * time.Second
+)
+
+// CaptureSnapshot takes a fresh full-quality screenshot of the session's live recording
+// page, independent of the frame cache used by the live preview.
+// format is "png" or "jpeg".
+func (w *Worker) CaptureSnapshot(key SessionKey, format string) ([]byte, error) {
+	opts := playwright.PageScreenshotOptions{
+		Timeout: playwright.Float(float64(snapshotTimeout.Milliseconds())),
+	}
+	switch format {
+	case "png":
+		opts.Type = playwright.ScreenshotTypePng
+	case "jpeg":
+		opts.Type = playwright.ScreenshotTypeJpeg
+		opts.Quality = playwright.Int(SnapshotJPEGQuality)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot format %q", format)
+	}
+
+	w.mu.Lock()
+	page, ok := w.pages[key]
+	w.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrNoActivePage, key)
+	}
+
+	return page.Screenshot(opts)
+}
@@ -0,0 +1,19 @@
+package recorder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestCaptureSnapshot_Validation(t *testing.T) {
+	w := &Worker{pages: make(map[SessionKey]playwright.Page)}
+
+	if _, err := w.CaptureSnapshot(SessionKey{TaskID: 1}, "gif"); err == nil {
+		t.Errorf("CaptureSnapshot() with unsupported format expected error")
+	}
+	if _, err := w.CaptureSnapshot(SessionKey{TaskID: 1}, "png"); !errors.Is(err, ErrNoActivePage) {
+		t.Errorf("CaptureSnapshot() without page error = %v, want ErrNoActivePage", err)
+	}
+}
@@ -0,0 +1,85 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+)
+
+// RecordingStatus is the lifecycle state of a recording row
+type RecordingStatus string
+
+const (
+	// StatusRecording is the state of every new recording, until its session ends
+	StatusRecording RecordingStatus = "RECORDING"
+	// StatusCompleted is a recording whose encoder finished cleanly
+	StatusCompleted RecordingStatus = "COMPLETED"
+	// StatusFailed is a recording that could not start or ended with an error
+	StatusFailed RecordingStatus = "FAILED"
+)
+
+// RecordingStatuses lists every status, in lifecycle order
+var RecordingStatuses = []RecordingStatus{StatusRecording, StatusCompleted, StatusFailed}
+
+// recordingTransitions lists the statuses each status may move to. Finished
+// recordings are final.
+var recordingTransitions = map[RecordingStatus][]RecordingStatus{
+	StatusRecording: {StatusCompleted, StatusFailed},
+}
+
+// ErrInvalidStatusTransition is returned for a status change the lifecycle doesn't allow
+var ErrInvalidStatusTransition = errors.New("invalid recording status transition")
+
+// ParseRecordingStatus returns the status named by s (case-insensitive)
+func ParseRecordingStatus(s string) (RecordingStatus, error) {
+	status := RecordingStatus(strings.ToUpper(strings.TrimSpace(s)))
+	for _, known := range RecordingStatuses {
+		if status == known {
+			return status, nil
+		}
+	}
+	names := make([]string, len(RecordingStatuses))
+	for i, known := range RecordingStatuses {
+		names[i] = string(known)
+	}
+	return "", fmt.Errorf("unknown recording status %q (one of %s)", s, strings.Join(names, ", "))
+}
+
+// CanTransitionTo reports whether a recording may move from s to next
+func (s RecordingStatus) CanTransitionTo(next RecordingStatus) bool {
+	for _, allowed := range recordingTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports whether a stored status string is s
+func (s RecordingStatus) Is(stored string) bool {
+	return RecordingStatus(stored) == s
+}
+
+// SetRecordingStatus is the one place a recording's status changes. It moves the
+// recording from `from` to `to`, rejecting transitions the lifecycle doesn't allow
+// and recordings that are no longer in `from` (e.g. finished concurrently).
+func SetRecordingStatus(ctx context.Context, q *database.Queries, id int64, from, to RecordingStatus) error {
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, from, to)
+	}
+	n, err := q.UpdateRecordingStatus(ctx, database.UpdateRecordingStatusParams{
+		Status:     string(to),
+		ID:         id,
+		FromStatus: string(from),
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: recording %d is not %s", ErrInvalidStatusTransition, id, from)
+	}
+	return nil
+}
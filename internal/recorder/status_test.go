@@ -0,0 +1,54 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseRecordingStatus(t *testing.T) {
+	for in, want := range map[string]RecordingStatus{
+		"RECORDING": StatusRecording,
+		"completed": StatusCompleted,
+		" Failed ":  StatusFailed,
+	} {
+		got, err := ParseRecordingStatus(in)
+		if err != nil || got != want {
+			t.Errorf("ParseRecordingStatus(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseRecordingStatus("DONE"); err == nil {
+		t.Errorf("ParseRecordingStatus(\"DONE\") expected error")
+	}
+}
+
+func TestRecordingStatus_Transitions(t *testing.T) {
+	tests := []struct {
+		from, to RecordingStatus
+		want     bool
+	}{
+		{StatusRecording, StatusCompleted, true},
+		{StatusRecording, StatusFailed, true},
+		{StatusRecording, StatusRecording, false},
+		{StatusCompleted, StatusFailed, false},
+		{StatusFailed, StatusRecording, false},
+		{StatusCompleted, StatusRecording, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	// Rejected before the database is touched
+	err := SetRecordingStatus(context.Background(), nil, 1, StatusCompleted, StatusRecording)
+	if !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Errorf("SetRecordingStatus() error = %v, want ErrInvalidStatusTransition", err)
+	}
+}
+
+func TestRecordingStatus_Is(t *testing.T) {
+	if !StatusRecording.Is("RECORDING") || StatusRecording.Is("recording") || StatusCompleted.Is("RECORDING") {
+		t.Errorf("Is() must match the stored status exactly")
+	}
+}
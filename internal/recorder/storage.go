@@ -0,0 +1,60 @@
+package recorder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// Storage pre-flight failures, distinguishable by StartTask
+var (
+	ErrStorageFull       = errors.New("disk full")
+	ErrStorageReadOnly   = errors.New("read-only filesystem")
+	ErrStoragePermission = errors.New("permission denied")
+)
+
+// writeProbeSize is written (and synced) by checkWritable so a full disk fails
+// here rather than on ffmpeg's first write; creating an empty file can still succeed.
+const writeProbeSize = 64 * 1024
+
+// checkWritable verifies a recording can actually be written into dir
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return classifyStorageError(fmt.Sprintf("failed to create directory %s", dir), err)
+	}
+
+	f, err := os.CreateTemp(dir, "perm_check")
+	if err != nil {
+		return classifyStorageError(fmt.Sprintf("cannot write to %s", dir), err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(make([]byte, writeProbeSize))
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return classifyStorageError(fmt.Sprintf("cannot write to %s", dir), err)
+	}
+	return nil
+}
+
+// classifyStorageError wraps err with the matching storage sentinel so callers can
+// report an actionable cause instead of a raw errno
+func classifyStorageError(msg string, err error) error {
+	switch {
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT):
+		return fmt.Errorf("%w: %s (free up space or raise the quota): %v", ErrStorageFull, msg, err)
+	case errors.Is(err, syscall.EROFS):
+		return fmt.Errorf("%w: %s (check the volume is mounted read-write): %v", ErrStorageReadOnly, msg, err)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("%w: %s (check ownership and mode of the recordings volume): %v", ErrStoragePermission, msg, err)
+	default:
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+}
@@ -0,0 +1,42 @@
+package recorder
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyStorageError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"Disk Full", &fs.PathError{Op: "write", Path: "/app/recordings/x", Err: syscall.ENOSPC}, ErrStorageFull},
+		{"Read Only", &fs.PathError{Op: "open", Path: "/app/recordings/x", Err: syscall.EROFS}, ErrStorageReadOnly},
+		{"Permission", &fs.PathError{Op: "open", Path: "/app/recordings/x", Err: syscall.EACCES}, ErrStoragePermission},
+		{"Other", &fs.PathError{Op: "open", Path: "/app/recordings/x", Err: syscall.EIO}, nil},
+	}
+
+	sentinels := []error{ErrStorageFull, ErrStorageReadOnly, ErrStoragePermission}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyStorageError("cannot write", tt.err)
+			if !errors.Is(got, tt.err) && tt.want == nil {
+				t.Errorf("classifyStorageError() = %v, should keep the original error", got)
+			}
+			for _, s := range sentinels {
+				if errors.Is(got, s) != (s == tt.want) {
+					t.Errorf("classifyStorageError() = %v, errors.Is(%v) = %v", got, s, !(s == tt.want))
+				}
+			}
+		})
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	if err := checkWritable(t.TempDir()); err != nil {
+		t.Errorf("checkWritable() on a temp dir error = %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package recorder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+)
+
+const (
+	// DefaultTranscodePreset trades encode time for size; transcodes run off the recording path
+	DefaultTranscodePreset = "slow"
+	// DefaultTranscodeCRF matches the default recording quality
+	DefaultTranscodeCRF = 23
+	// transcodeTimeout bounds one re-encode (long archives at slow presets take a while)
+	transcodeTimeout = 6 * time.Hour
+	// transcodeJobRetention is how long finished jobs stay listed
+	transcodeJobRetention = 24 * time.Hour
+	// transcodeNice is the scheduling priority of the encoder, so recordings keep the CPU
+	transcodeNice = 19
+)
+
+// Transcode job states
+const (
+	TranscodeQueued    = "queued"
+	TranscodeRunning   = "running"
+	TranscodeCompleted = "completed"
+	TranscodeFailed    = "failed"
+)
+
+// ErrTranscodePending is returned when the recording already has a queued or running job
+var ErrTranscodePending = errors.New("a transcode of this recording is already pending")
+
+// TranscodeOptions selects the encoder settings of a re-encode
+type TranscodeOptions struct {
+	Preset string
+	CRF    int64
+	// KeepOriginal writes the result next to the original as a new recording (a copy
+	// of the original's row, in the same run); the original and its row are kept.
+	// Otherwise the original is replaced in place.
+	KeepOriginal bool
+}
+
+// TranscodeJob is a queued, running or finished re-encode of a recording
+type TranscodeJob struct {
+	RecordingID  int64  `json:"recording_id"`
+	Status       string `json:"status"`
+	Preset       string `json:"preset"`
+	CRF          int64  `json:"crf"`
+	KeepOriginal bool   `json:"keep_original"`
+	FilePath     string `json:"file_path,omitempty"` // the transcoded file once completed
+	// TranscodedRecordingID is the new recording of a keep_original transcode
+	TranscodedRecordingID int64      `json:"transcoded_recording_id,omitempty"`
+	SizeBefore            int64      `json:"size_before_bytes,omitempty"`
+	SizeAfter             int64      `json:"size_after_bytes,omitempty"`
+	Error                 string     `json:"error,omitempty"`
+	QueuedAt              time.Time  `json:"queued_at"`
+	FinishedAt            *time.Time `json:"finished_at,omitempty"`
+}
+
+// transcodeQueue runs re-encodes one at a time and remembers recent jobs.
+// The zero value is ready to use.
+type transcodeQueue struct {
+	mu   sync.Mutex
+	jobs map[int64]*TranscodeJob // by recording ID
+	run  sync.Mutex              // held by the running job
+}
+
+// EnqueueTranscode schedules a re-encode of a finished recording. Jobs run one at a
+// time in the background at low CPU priority; progress is visible via TranscodeJobs.
+func (w *Worker) EnqueueTranscode(rec database.Recording, opts TranscodeOptions) (TranscodeJob, error) {
+	if opts.Preset == "" {
+		opts.Preset = DefaultTranscodePreset
+	}
+	if err := ValidateEncoderSettings(opts.Preset, ""); err != nil {
+		return TranscodeJob{}, err
+	}
+	if opts.CRF < 0 || opts.CRF > 51 {
+		return TranscodeJob{}, fmt.Errorf("crf must be between 0 and 51")
+	}
+
+	q := &w.transcodes
+	q.mu.Lock()
+	if q.jobs == nil {
+		q.jobs = make(map[int64]*TranscodeJob)
+	}
+	if j, ok := q.jobs[rec.ID]; ok && (j.Status == TranscodeQueued || j.Status == TranscodeRunning) {
+		q.mu.Unlock()
+		return TranscodeJob{}, ErrTranscodePending
+	}
+	for id, j := range q.jobs {
+		if j.FinishedAt != nil && time.Since(*j.FinishedAt) > transcodeJobRetention {
+			delete(q.jobs, id)
+		}
+	}
+	job := &TranscodeJob{
+		RecordingID:  rec.ID,
+		Status:       TranscodeQueued,
+		Preset:       opts.Preset,
+		CRF:          opts.CRF,
+		KeepOriginal: opts.KeepOriginal,
+		QueuedAt:     time.Now().UTC(),
+	}
+	q.jobs[rec.ID] = job
+	snapshot := *job
+	q.mu.Unlock()
+
+	go w.runTranscode(job, rec, opts)
+	return snapshot, nil
+}
+
+// TranscodeJobs lists queued, running and recently finished jobs, newest first
+func (w *Worker) TranscodeJobs() []TranscodeJob {
+	q := &w.transcodes
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]TranscodeJob, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		jobs = append(jobs, *j)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].QueuedAt.After(jobs[j].QueuedAt) })
+	return jobs
+}
+
+func (w *Worker) runTranscode(job *TranscodeJob, rec database.Recording, opts TranscodeOptions) {
+	q := &w.transcodes
+	q.run.Lock()
+	defer q.run.Unlock()
+
+	q.mu.Lock()
+	job.Status = TranscodeRunning
+	q.mu.Unlock()
+	log.Printf("Transcode: re-encoding recording %d (preset %s, crf %d)", rec.ID, opts.Preset, opts.CRF)
+
+	ctx, cancel := context.WithTimeout(context.Background(), transcodeTimeout)
+	defer cancel()
+	path, recordingID, before, after, err := w.transcodeRecording(ctx, rec, opts)
+
+	now := time.Now().UTC()
+	q.mu.Lock()
+	job.FinishedAt = &now
+	job.SizeBefore = before
+	if err != nil {
+		job.Status, job.Error = TranscodeFailed, err.Error()
+	} else {
+		job.Status, job.FilePath, job.SizeAfter = TranscodeCompleted, path, after
+		if recordingID != rec.ID {
+			job.TranscodedRecordingID = recordingID
+		}
+	}
+	q.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Transcode: recording %d failed: %v", rec.ID, err)
+		w.notify(Notification{
+			Event:   "recording.transcode_failed",
+			TaskID:  rec.TaskID,
+			Message: fmt.Sprintf("transcode of recording %d failed: %v", rec.ID, err),
+		})
+		return
+	}
+	w.notify(Notification{
+		Event:   "recording.transcoded",
+		TaskID:  rec.TaskID,
+		Message: fmt.Sprintf("recording %d transcoded: %d -> %d bytes", rec.ID, before, after),
+		Details: map[string]interface{}{"recording_id": rec.ID, "transcoded_recording_id": recordingID, "size_before_bytes": before, "size_after_bytes": after, "file_path": path},
+	})
+}
+
+// transcodeRecording re-encodes the recording into a hidden temporary file next to
+// it and then renames that into place, so readers only ever see a complete file.
+// recordingID is the recording that now holds the result: rec itself, or the new
+// copy of a KeepOriginal transcode.
+func (w *Worker) transcodeRecording(ctx context.Context, rec database.Recording, opts TranscodeOptions) (path string, recordingID, before, after int64, err error) {
+	info, err := os.Stat(rec.FilePath)
+	if err != nil {
+		return "", 0, 0, 0, fmt.Errorf("recording file unavailable: %w", err)
+	}
+	before = info.Size()
+
+	dir, name := filepath.Split(rec.FilePath)
+	ext := filepath.Ext(name)
+	target := rec.FilePath
+	if opts.KeepOriginal {
+		target = filepath.Join(dir, strings.TrimSuffix(name, ext)+".transcoded"+ext)
+		if _, err := os.Stat(target); err == nil {
+			return "", 0, before, 0, fmt.Errorf("%s already exists", target)
+		}
+	}
+	// Dot files are skipped by the orphan scan while the encode runs
+	tmp := filepath.Join(dir, "."+strings.TrimSuffix(name, ext)+".transcode"+ext)
+	defer os.Remove(tmp) // no-op once renamed
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", transcodeArgs(rec.FilePath, tmp, opts.Preset, opts.CRF)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return "", 0, before, 0, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, cmd.Process.Pid, transcodeNice); err != nil {
+		log.Printf("Transcode: could not lower ffmpeg priority: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		return "", 0, before, 0, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// The recording may have been deleted or renamed during the encode
+	if w.queries != nil {
+		current, err := w.queries.GetRecording(ctx, rec.ID)
+		if err != nil {
+			return "", 0, before, 0, fmt.Errorf("recording no longer available: %w", err)
+		}
+		if current.FilePath != rec.FilePath {
+			return "", 0, before, 0, fmt.Errorf("recording was moved during the transcode")
+		}
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		return "", 0, before, 0, err
+	}
+	w.applyFilePermissions(target)
+	if info, err := os.Stat(target); err == nil {
+		after = info.Size()
+	}
+
+	recordingID = rec.ID
+	if w.queries != nil {
+		if opts.KeepOriginal {
+			// The original keeps its row; the result gets its own, so neither file is orphaned
+			copied, err := w.queries.CopyRecording(ctx, database.CopyRecordingParams{FilePath: target, ID: rec.ID})
+			if err != nil {
+				os.Remove(target)
+				return "", 0, before, after, fmt.Errorf("transcoded to %s but failed to record it: %w", target, err)
+			}
+			recordingID = copied.ID
+		}
+		// The old content hash no longer describes the file (and it is no longer a duplicate)
+		hash := ""
+		if rec.ContentHash != "" {
+			if hash, err = contentHash(target); err != nil {
+				log.Printf("Transcode: failed to hash recording %d: %v", recordingID, err)
+			}
+		}
+		if err := w.queries.UpdateRecordingContentHash(ctx, database.UpdateRecordingContentHashParams{
+			ContentHash: hash,
+			DuplicateOf: sql.NullInt64{},
+			ID:          recordingID,
+		}); err != nil {
+			log.Printf("Transcode: failed to update content hash of recording %d: %v", recordingID, err)
+		}
+		// The capture checksum (and signature) stay as they were: the new file is
+		// recorded as a transcode, so verification reports it as such
+		w.storeTranscodeChecksum(ctx, recordingID, target)
+	}
+	return target, recordingID, before, after, nil
+}
+
+// transcodeArgs builds the ffmpeg command line that re-encodes a finished recording
+// with libx264 at the given preset and CRF, copying nothing but the video
+func transcodeArgs(input, output, preset string, crf int64) []string {
+	return []string{
+		"-y", "-hide_banner", "-v", "error",
+		"-i", input,
+		"-map", "0:v",
+		"-c:v", "libx264",
+		"-preset", preset,
+		"-pix_fmt", "yuv420p",
+		"-crf", fmt.Sprintf("%d", crf),
+		output,
+	}
+}
@@ -0,0 +1,84 @@
+package recorder
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nullpo7z/dashboard-recorder/internal/config"
+	"github.com/nullpo7z/dashboard-recorder/internal/database"
+)
+
+func TestTranscodeArgs(t *testing.T) {
+	args := strings.Join(transcodeArgs("/app/recordings/1.mkv", "/app/recordings/.1.transcode.mkv", "slow", 28), " ")
+	for _, want := range []string{"-i /app/recordings/1.mkv", "-c:v libx264", "-preset slow", "-crf 28"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("transcodeArgs() = %q, missing %q", args, want)
+		}
+	}
+	if !strings.HasSuffix(args, "/app/recordings/.1.transcode.mkv") {
+		t.Errorf("transcodeArgs() = %q, output path must be last", args)
+	}
+}
+
+func TestEnqueueTranscode(t *testing.T) {
+	w := &Worker{config: &config.Config{RecordingFileGID: -1}}
+	rec := database.Recording{ID: 7, TaskID: 1, Status: "COMPLETED", FilePath: t.TempDir() + "/missing.mkv"}
+
+	if _, err := w.EnqueueTranscode(rec, TranscodeOptions{Preset: "bogus"}); err == nil {
+		t.Errorf("EnqueueTranscode() with invalid preset = nil, want error")
+	}
+	if _, err := w.EnqueueTranscode(rec, TranscodeOptions{CRF: 60}); err == nil {
+		t.Errorf("EnqueueTranscode() with crf 60 = nil, want error")
+	}
+
+	job, err := w.EnqueueTranscode(rec, TranscodeOptions{CRF: 30})
+	if err != nil {
+		t.Fatalf("EnqueueTranscode() error = %v", err)
+	}
+	if job.Preset != DefaultTranscodePreset || job.CRF != 30 {
+		t.Errorf("EnqueueTranscode() job = %+v, want default preset and crf 30", job)
+	}
+
+	// The file is missing, so the job fails without running ffmpeg
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		jobs := w.TranscodeJobs()
+		if len(jobs) == 1 && jobs[0].Status == TranscodeFailed {
+			if !strings.Contains(jobs[0].Error, "recording file unavailable") {
+				t.Errorf("TranscodeJobs() error = %q", jobs[0].Error)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("TranscodeJobs() = %+v, want one failed job", jobs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCopyRecording_KeepOriginal(t *testing.T) {
+	ctx := context.Background()
+	q := newTestQueries(t)
+	task, err := q.CreateTask(ctx, database.CreateTaskParams{Name: "Test", TargetUrl: "http://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := q.CreateRecording(ctx, database.CreateRecordingParams{TaskID: task.ID, Status: string(StatusCompleted), FilePath: "/rec/a.mkv", Viewport: "mobile", RunID: "run1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A keep_original transcode records its result as a copy in the same run
+	copied, err := q.CopyRecording(ctx, database.CopyRecordingParams{FilePath: "/rec/a.transcoded.mkv", ID: original.ID})
+	if err != nil {
+		t.Fatalf("CopyRecording() error = %v", err)
+	}
+	if copied.ID == original.ID || copied.FilePath != "/rec/a.transcoded.mkv" || copied.RunID != "run1" || copied.Viewport != "mobile" || !copied.StartTime.Equal(original.StartTime) {
+		t.Errorf("CopyRecording() = %+v, want a copy of %+v at the new path", copied, original)
+	}
+	if still, err := q.GetRecording(ctx, original.ID); err != nil || still.FilePath != "/rec/a.mkv" {
+		t.Errorf("original recording = %+v, %v, want it kept at its path", still, err)
+	}
+}
@@ -0,0 +1,296 @@
+package recorder
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lookupIP resolves every A and AAAA record of a host (replaced in tests)
+var lookupIP = net.LookupIP
+
+// blockedNetworks are non-public ranges net.IP has no predicate for
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "this network"
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved, incl. broadcast
+)
+
+// IPv6 prefixes that embed an IPv4 address the traffic ends up at
+var (
+	nat64Prefix = mustParseCIDRs("64:ff9b::/96")[0]
+	sixToFour   = mustParseCIDRs("2002::/16")[0]
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// isBlockedIP reports whether ip is not a public unicast address. IPv4-mapped,
+// NAT64 and 6to4 addresses are judged by the IPv4 address they embed.
+func isBlockedIP(ip net.IP) bool {
+	if ip16 := ip.To16(); ip16 != nil && ip.To4() == nil {
+		switch {
+		case nat64Prefix.Contains(ip16):
+			ip = net.IP(ip16[12:16])
+		case sixToFour.Contains(ip16):
+			ip = net.IP(ip16[2:6])
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return true
+	}
+	for _, n := range blockedNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHostIPs resolves hostname and rejects it when any of its A/AAAA records is
+// non-public. A host with mixed public and private records is rejected too: the
+// browser may connect to whichever address it picks.
+func checkHostIPs(hostname string) error {
+	ips, err := lookupIP(hostname)
+	if err != nil {
+		return fmt.Errorf("failed to resolve hostname: %w", err)
+	}
+	if len(ips) == 0 {
+		return fmt.Errorf("hostname %s has no addresses", hostname)
+	}
+	for _, ip := range ips {
+		if isBlockedIP(ip) {
+			return fmt.Errorf("access to private IP %s is denied", ip.String())
+		}
+	}
+	return nil
+}
+
+// ErrTargetRejected is wrapped by errors caused by the URL policy (SSRF checks)
+var ErrTargetRejected = errors.New("security check failed")
+
+// URLPolicy controls which targets the recorder may navigate to
+type URLPolicy struct {
+	// AllowedPorts restricts the effective port (empty allows any port)
+	AllowedPorts []int
+	// AllowedDomains, when non-empty, is an allowlist of hosts (subdomains match)
+	AllowedDomains []string
+	// DeniedDomains always wins over AllowedDomains
+	DeniedDomains []string
+	// SkipIPCheck waives the private address check, for targets reached through a
+	// proxy that resolves them (see skipTargetIPCheck). Domain and port rules still apply.
+	SkipIPCheck bool
+}
+
+// DefaultURLPolicy only permits the standard web ports
+var DefaultURLPolicy = URLPolicy{AllowedPorts: []int{80, 443}}
+
+// urlPolicy builds the policy from config, falling back to the default
+func (w *Worker) urlPolicy() URLPolicy {
+	if w.config == nil {
+		return DefaultURLPolicy
+	}
+	return URLPolicy{
+		AllowedPorts:   w.config.TargetAllowedPorts,
+		AllowedDomains: w.config.TargetAllowedDomains,
+		DeniedDomains:  w.config.TargetDeniedDomains,
+	}
+}
+
+// validateTargetVia applies the configured policy to a navigation target reached
+// through the proxy ("" = direct). It resolves DNS on every call, so calling it
+// right before navigation catches records that changed since the task was saved
+// (DNS rebinding).
+func (w *Worker) validateTargetVia(targetURL, proxy string) error {
+	policy := w.urlPolicy()
+	policy.SkipIPCheck = w.skipTargetIPCheck(proxy)
+	if err := policy.Validate(targetURL); err != nil {
+		return fmt.Errorf("%w: %w", ErrTargetRejected, err)
+	}
+	return nil
+}
+
+// Validate performs strict validation to prevent SSRF
+func (p URLPolicy) Validate(targetURL string) error {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return fmt.Errorf("invalid url format")
+	}
+
+	// 1. Check Protocol
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid protocol: %s", u.Scheme)
+	}
+
+	// 2. Check Domain Lists
+	hostname := strings.ToLower(u.Hostname())
+	for _, d := range p.DeniedDomains {
+		if matchDomain(hostname, d) {
+			return fmt.Errorf("domain %s is denied by policy", hostname)
+		}
+	}
+	if len(p.AllowedDomains) > 0 {
+		allowed := false
+		for _, d := range p.AllowedDomains {
+			if matchDomain(hostname, d) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("domain %s is not in the allowed list", hostname)
+		}
+	}
+
+	// 3-4. Resolve Hostname and check every address
+	if !p.SkipIPCheck {
+		if err := checkHostIPs(hostname); err != nil {
+			return err
+		}
+	}
+
+	// 5. Check Port (implicit ports follow the scheme)
+	if len(p.AllowedPorts) > 0 {
+		port := 80
+		if u.Scheme == "https" {
+			port = 443
+		}
+		if u.Port() != "" {
+			port, err = strconv.Atoi(u.Port())
+			if err != nil {
+				return fmt.Errorf("invalid port: %s", u.Port())
+			}
+		}
+		allowed := false
+		for _, allowedPort := range p.AllowedPorts {
+			if allowedPort == port {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("port %d is not allowed", port)
+		}
+	}
+
+	return nil
+}
+
+// NormalizeTargetURL checks that a task/preview target is an absolute http(s) URL
+// with a host and returns it in canonical form: scheme and host lowercased and the
+// fragment stripped. Hash-router fragments ("#/dashboards/1", "#!/view") select
+// the page in single-page apps and are kept.
+func NormalizeTargetURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("target_url is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid target_url")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("target_url must use http or https")
+	}
+	if u.Opaque != "" || u.Hostname() == "" {
+		return "", fmt.Errorf("target_url must include a host")
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("target_url has an invalid port")
+		}
+	} else if strings.HasSuffix(u.Host, ":") {
+		return "", fmt.Errorf("target_url has an invalid port")
+	}
+	u.Host = strings.ToLower(u.Host)
+
+	if !strings.HasPrefix(u.Fragment, "/") && !strings.HasPrefix(u.Fragment, "!/") {
+		u.Fragment, u.RawFragment = "", ""
+	}
+	return u.String(), nil
+}
+
+// requestGuardTTL is how long one context reuses the verdict for a host
+const requestGuardTTL = 10 * time.Second
+
+// requestGuard re-applies the private address check to every request of a browser
+// context: redirects, frames and subresources, not just the validated target.
+// Chromium resolves names itself and the browser is shared, so the validated
+// address can't be pinned per context; re-checking when each request is made
+// narrows the DNS rebinding window to requestGuardTTL.
+type requestGuard struct {
+	mu       sync.Mutex
+	verdicts map[string]guardVerdict
+	now      func() time.Time
+}
+
+type guardVerdict struct {
+	err       error
+	checkedAt time.Time
+}
+
+func newRequestGuard() *requestGuard {
+	return &requestGuard{verdicts: make(map[string]guardVerdict), now: time.Now}
+}
+
+// check returns an error when the request would reach a non-public address.
+// Non-network schemes (data:, blob:, about:) are not resolved.
+func (g *requestGuard) check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url format")
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+	default:
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+
+	g.mu.Lock()
+	now := g.now()
+	v, ok := g.verdicts[host]
+	g.mu.Unlock()
+	if ok && now.Sub(v.checkedAt) < requestGuardTTL {
+		return v.err
+	}
+
+	// Resolved unlocked so one slow lookup doesn't stall the page's other requests
+	err = checkHostIPs(host)
+	g.mu.Lock()
+	g.verdicts[host] = guardVerdict{err: err, checkedAt: now}
+	g.mu.Unlock()
+	return err
+}
+
+// requestGuard returns a guard for a new context, or nil when TARGET_RECHECK_REQUESTS is off
+func (w *Worker) requestGuard() *requestGuard {
+	if w.config == nil || !w.config.TargetRecheckRequests {
+		return nil
+	}
+	return newRequestGuard()
+}
+
+// matchDomain reports whether host equals domain or is a subdomain of it
+func matchDomain(host, domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
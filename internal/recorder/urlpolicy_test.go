@@ -0,0 +1,197 @@
+package recorder
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestURLPolicy_Validate(t *testing.T) {
+	policy := URLPolicy{
+		AllowedPorts:   []int{80, 443},
+		AllowedDomains: []string{"example.com", "93.184.216.34"},
+		DeniedDomains:  []string{"blocked.example.com"},
+	}
+
+	tests := []struct {
+		name      string
+		url       string
+		wantError string
+	}{
+		{"Default Port", "http://93.184.216.34", ""},
+		{"Explicit Allowed Port", "https://93.184.216.34:443", ""},
+		{"High Port Blocked", "http://93.184.216.34:8080", "port 8080 is not allowed"},
+		{"Denied Subdomain", "https://blocked.example.com", "denied by policy"},
+		{"Denied Nested Subdomain", "https://a.blocked.example.com", "denied by policy"},
+		{"Not In Allowlist", "https://example.org", "not in the allowed list"},
+		{"Suffix Is Not Subdomain", "https://notexample.com", "not in the allowed list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.url)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("Validate(%q) unexpected error: %v", tt.url, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Validate(%q) error = %v, want substring %q", tt.url, err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestURLPolicy_AnyPort(t *testing.T) {
+	policy := URLPolicy{}
+	if err := policy.Validate("http://93.184.216.34:8080"); err != nil {
+		t.Errorf("Validate() with empty AllowedPorts should allow any port, got %v", err)
+	}
+}
+
+func TestNormalizeTargetURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		want      string
+		wantError string
+	}{
+		{"Unchanged", "https://example.com/dash?id=1", "https://example.com/dash?id=1", ""},
+		{"Lowercase Scheme And Host", " HTTPS://Example.COM:8443/Dash ", "https://example.com:8443/Dash", ""},
+		{"Strip Fragment", "https://example.com/dash#panel-2", "https://example.com/dash", ""},
+		{"Strip Empty Fragment", "https://example.com/#", "https://example.com/", ""},
+		{"Keep Hash Route", "https://example.com/#/dashboards/1", "https://example.com/#/dashboards/1", ""},
+		{"Keep Hashbang Route", "https://example.com/#!/view", "https://example.com/#!/view", ""},
+		{"Empty", "", "", "required"},
+		{"No Host", "http://", "", "must include a host"},
+		{"Port Only", "http://:8080/", "", "must include a host"},
+		{"Opaque", "http:example.com", "", "must include a host"},
+		{"Relative", "/dash", "", "http or https"},
+		{"File Scheme", "file:///etc/passwd", "", "http or https"},
+		{"Bad Port", "http://example.com:99999", "", "invalid port"},
+		{"Empty Port", "http://example.com:", "", "invalid port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeTargetURL(tt.url)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("NormalizeTargetURL(%q) unexpected error: %v", tt.url, err)
+				}
+				if got != tt.want {
+					t.Errorf("NormalizeTargetURL(%q) = %q, want %q", tt.url, got, tt.want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("NormalizeTargetURL(%q) error = %v, want substring %q", tt.url, err, tt.wantError)
+			}
+		})
+	}
+}
+
+// fakeDNS replaces lookupIP with fixed records for the duration of a test
+func fakeDNS(t *testing.T, records map[string][]string) {
+	t.Helper()
+	orig := lookupIP
+	t.Cleanup(func() { lookupIP = orig })
+	lookupIP = func(host string) ([]net.IP, error) {
+		addrs, ok := records[host]
+		if !ok {
+			return nil, fmt.Errorf("no such host %s", host)
+		}
+		ips := make([]net.IP, len(addrs))
+		for i, a := range addrs {
+			ips[i] = net.ParseIP(a)
+		}
+		return ips, nil
+	}
+}
+
+func TestURLPolicy_MixedRecords(t *testing.T) {
+	fakeDNS(t, map[string][]string{
+		"public.example.com":     {"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"},
+		"mixed-a.example.com":    {"93.184.216.34", "10.0.0.5"},
+		"mixed-aaaa.example.com": {"93.184.216.34", "fd00::1"},
+		"mapped.example.com":     {"93.184.216.34", "::ffff:192.168.1.1"},
+		"nat64.example.com":      {"64:ff9b::a9fe:a9fe"}, // 169.254.169.254
+	})
+
+	tests := []struct {
+		url       string
+		wantError string
+	}{
+		{"https://public.example.com", ""},
+		{"https://mixed-a.example.com", "private IP 10.0.0.5"},
+		{"https://mixed-aaaa.example.com", "private IP fd00::1"},
+		{"https://mapped.example.com", "private IP 192.168.1.1"},
+		{"https://nat64.example.com", "private IP 64:ff9b::a9fe:a9fe"},
+	}
+	for _, tt := range tests {
+		err := DefaultURLPolicy.Validate(tt.url)
+		if tt.wantError == "" {
+			if err != nil {
+				t.Errorf("Validate(%q) unexpected error: %v", tt.url, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+			t.Errorf("Validate(%q) error = %v, want substring %q", tt.url, err, tt.wantError)
+		}
+	}
+}
+
+func TestIsBlockedIP(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":    false,
+		"2606:4700::1111":  false,
+		"127.0.0.1":        true,
+		"169.254.169.254":  true, // cloud metadata
+		"100.64.0.1":       true, // carrier-grade NAT
+		"0.0.0.0":          true,
+		"224.0.0.1":        true,
+		"::1":              true,
+		"fe80::1":          true,
+		"fc00::1":          true,
+		"2002:0a00:0001::": true,  // 6to4 of 10.0.0.1
+		"2002:5db8:d822::": false, // 6to4 of 93.184.216.34
+	}
+	for addr, want := range tests {
+		if got := isBlockedIP(net.ParseIP(addr)); got != want {
+			t.Errorf("isBlockedIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestRequestGuard(t *testing.T) {
+	records := map[string][]string{"dash.example.com": {"93.184.216.34"}}
+	fakeDNS(t, records)
+
+	g := newRequestGuard()
+	now := time.Now()
+	g.now = func() time.Time { return now }
+
+	if err := g.check("https://dash.example.com/api/data"); err != nil {
+		t.Errorf("check() public host error = %v", err)
+	}
+	if err := g.check("http://10.0.0.5/admin"); err == nil {
+		t.Errorf("check() private IP literal = nil, want error")
+	}
+	if err := g.check("data:image/png;base64,AAAA"); err != nil {
+		t.Errorf("check() data URL error = %v", err)
+	}
+
+	// Rebinding: the verdict is reused within the TTL, then re-resolved
+	records["dash.example.com"] = []string{"127.0.0.1"}
+	if err := g.check("https://dash.example.com/"); err != nil {
+		t.Errorf("check() within TTL error = %v, want cached verdict", err)
+	}
+	now = now.Add(requestGuardTTL)
+	if err := g.check("https://dash.example.com/"); err == nil {
+		t.Errorf("check() after rebinding = nil, want error")
+	}
+}
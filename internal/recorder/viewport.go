@@ -0,0 +1,94 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// MaxViewports bounds the viewport profiles of one task (each is a parallel recording)
+const MaxViewports = 4
+
+// viewportNamePattern keeps profile names usable in file names
+var viewportNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Viewport is a named browser viewport profile a task records at. A task without
+// profiles records once at the unnamed default viewport.
+type Viewport struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// DefaultViewport is the RecordingWidth x RecordingHeight viewport of tasks without profiles
+func DefaultViewport() Viewport {
+	return Viewport{Width: RecordingWidth, Height: RecordingHeight}
+}
+
+// SessionKey identifies a recording session: one per task and viewport profile
+type SessionKey struct {
+	TaskID   int64
+	Viewport string // profile name, "" for the default viewport
+}
+
+func (k SessionKey) String() string {
+	if k.Viewport == "" {
+		return fmt.Sprintf("task %d", k.TaskID)
+	}
+	return fmt.Sprintf("task %d (%s)", k.TaskID, k.Viewport)
+}
+
+// ValidateViewports checks a task's profiles: at most MaxViewports, each with a
+// unique file-name-safe name and a size within the interactive viewport bounds
+func ValidateViewports(viewports []Viewport) error {
+	if len(viewports) > MaxViewports {
+		return fmt.Errorf("a task can have at most %d viewports", MaxViewports)
+	}
+	seen := make(map[string]bool, len(viewports))
+	for _, v := range viewports {
+		if !viewportNamePattern.MatchString(v.Name) {
+			return fmt.Errorf("viewport name %q is invalid. Allowed: 1-32 of a-z, 0-9, _, - (starting with a letter or digit)", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate viewport name %q", v.Name)
+		}
+		seen[v.Name] = true
+		if v.Width < MinInteractiveWidth || v.Width > MaxInteractiveWidth || v.Height < MinInteractiveHeight || v.Height > MaxInteractiveHeight {
+			return fmt.Errorf("viewport %q must be within %dx%d and %dx%d", v.Name, MinInteractiveWidth, MinInteractiveHeight, MaxInteractiveWidth, MaxInteractiveHeight)
+		}
+	}
+	return nil
+}
+
+// EncodeViewports serializes profiles for the tasks.viewports column ("" when none)
+func EncodeViewports(viewports []Viewport) (string, error) {
+	if len(viewports) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(viewports)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// DecodeViewports parses the tasks.viewports column; nil when the task has no profiles
+func DecodeViewports(stored string) ([]Viewport, error) {
+	if stored == "" {
+		return nil, nil
+	}
+	var viewports []Viewport
+	if err := json.Unmarshal([]byte(stored), &viewports); err != nil {
+		return nil, fmt.Errorf("invalid stored viewports: %w", err)
+	}
+	return viewports, nil
+}
+
+// RecordingViewports returns the viewports StartTask records at: the task's
+// profiles, or the default viewport alone
+func RecordingViewports(profiles []Viewport) []Viewport {
+	if len(profiles) == 0 {
+		return []Viewport{DefaultViewport()}
+	}
+	return profiles
+}
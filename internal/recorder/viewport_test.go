@@ -0,0 +1,72 @@
+package recorder
+
+import (
+	"testing"
+)
+
+func TestValidateViewports(t *testing.T) {
+	tests := []struct {
+		name      string
+		viewports []Viewport
+		wantErr   bool
+	}{
+		{"None", nil, false},
+		{"Valid", []Viewport{{"desktop", 1920, 1080}, {"mobile", 390, 844}}, false},
+		{"Empty Name", []Viewport{{"", 1280, 720}}, true},
+		{"Invalid Name", []Viewport{{"Mobile View", 390, 844}}, true},
+		{"Duplicate", []Viewport{{"a", 1280, 720}, {"a", 800, 600}}, true},
+		{"Too Small", []Viewport{{"tiny", 10, 10}}, true},
+		{"Too Large", []Viewport{{"huge", MaxInteractiveWidth + 1, 1080}}, true},
+		{"Too Many", []Viewport{{"a", 800, 600}, {"b", 800, 600}, {"c", 800, 600}, {"d", 800, 600}, {"e", 800, 600}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateViewports(tt.viewports); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateViewports() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestViewportsRoundTrip(t *testing.T) {
+	if s, err := EncodeViewports(nil); err != nil || s != "" {
+		t.Errorf("EncodeViewports(nil) = %q, %v; want empty", s, err)
+	}
+
+	in := []Viewport{{"desktop", 1920, 1080}, {"mobile", 390, 844}}
+	stored, err := EncodeViewports(in)
+	if err != nil {
+		t.Fatalf("EncodeViewports() error = %v", err)
+	}
+	out, err := DecodeViewports(stored)
+	if err != nil {
+		t.Fatalf("DecodeViewports() error = %v", err)
+	}
+	if len(out) != 2 || out[0] != in[0] || out[1] != in[1] {
+		t.Errorf("DecodeViewports() = %v, want %v", out, in)
+	}
+
+	if _, err := DecodeViewports("{"); err == nil {
+		t.Errorf("DecodeViewports() with invalid JSON expected error")
+	}
+}
+
+func TestRecordingViewports(t *testing.T) {
+	if got := RecordingViewports(nil); len(got) != 1 || got[0] != DefaultViewport() {
+		t.Errorf("RecordingViewports(nil) = %v, want the default viewport", got)
+	}
+	profiles := []Viewport{{"mobile", 390, 844}}
+	if got := RecordingViewports(profiles); len(got) != 1 || got[0] != profiles[0] {
+		t.Errorf("RecordingViewports() = %v, want the profiles", got)
+	}
+}
+
+func TestSessionKeyString(t *testing.T) {
+	if got := (SessionKey{TaskID: 3}).String(); got != "task 3" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (SessionKey{TaskID: 3, Viewport: "mobile"}).String(); got != "task 3 (mobile)" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,183 @@
+package recorder
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const (
+	// MaxWatermarkBytes bounds a data URI watermark image (decoded)
+	MaxWatermarkBytes = 256 * 1024
+	// MinWatermarkWidth bounds the rendered width of a watermark, in CSS pixels
+	MinWatermarkWidth = 16
+	// defaultWatermarkOpacity applies when a watermark sets none
+	defaultWatermarkOpacity = 0.5
+)
+
+// WatermarkPositions are where a watermark can be anchored
+var WatermarkPositions = []string{"top-left", "top-right", "bottom-left", "bottom-right", "center"}
+
+// watermarkImageTypes are the data URI image types a watermark may use. SVG is left
+// out: it is a document that can reference other resources.
+var watermarkImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Watermark is an image (e.g. a logo or a "CONFIDENTIAL" stamp) burned into every
+// frame of a task's recordings. Source is a base64 data URI, or an https URL on
+// WATERMARK_ALLOWED_HOSTS, which the page loads like its own images (so a page
+// whose Content-Security-Policy restricts img-src may block it).
+type Watermark struct {
+	Source   string  `json:"source"`
+	Position string  `json:"position"` // one of WatermarkPositions, "" = bottom-right
+	Width    int     `json:"width"`    // CSS pixels; the height keeps the aspect ratio
+	Opacity  float64 `json:"opacity"`  // 0-1, 0 = defaultWatermarkOpacity
+}
+
+// Validate checks the image source against the allowed types, size and hosts (an
+// empty allowlist permits data URIs only), and the placement within viewport
+func (m Watermark) Validate(allowedHosts []string, viewport Viewport) error {
+	if strings.HasPrefix(m.Source, "data:") {
+		if err := validateWatermarkData(m.Source); err != nil {
+			return err
+		}
+	} else {
+		u, err := url.Parse(m.Source)
+		if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+			return fmt.Errorf("watermark source must be a data URI or an https URL")
+		}
+		if !hostAllowed(u, allowedHosts) {
+			return fmt.Errorf("watermark host %q is not allowed (WATERMARK_ALLOWED_HOSTS)", u.Hostname())
+		}
+	}
+	if m.Position != "" && !containsString(WatermarkPositions, m.Position) {
+		return fmt.Errorf("invalid watermark position %q. Allowed: %s", m.Position, strings.Join(WatermarkPositions, ", "))
+	}
+	if m.Width < MinWatermarkWidth || m.Width > viewport.Width {
+		return fmt.Errorf("watermark width must be between %d and %d", MinWatermarkWidth, viewport.Width)
+	}
+	if m.Opacity < 0 || m.Opacity > 1 {
+		return fmt.Errorf("watermark opacity must be between 0 and 1")
+	}
+	return nil
+}
+
+// validateWatermarkData checks a base64 data URI holds an image of an allowed type
+// (sniffed, not just declared) and at most MaxWatermarkBytes
+func validateWatermarkData(source string) error {
+	header, data, ok := strings.Cut(strings.TrimPrefix(source, "data:"), ",")
+	mediaType, isBase64 := strings.CutSuffix(header, ";base64")
+	if !ok || !isBase64 || !containsString(watermarkImageTypes, mediaType) {
+		return fmt.Errorf("watermark data URI must be base64 %s", strings.Join(watermarkImageTypes, ", "))
+	}
+	if base64.StdEncoding.DecodedLen(len(data)) > MaxWatermarkBytes+2 {
+		return fmt.Errorf("watermark image exceeds %d bytes", MaxWatermarkBytes)
+	}
+	img, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("watermark data URI is not valid base64")
+	}
+	if len(img) > MaxWatermarkBytes {
+		return fmt.Errorf("watermark image exceeds %d bytes", MaxWatermarkBytes)
+	}
+	if sniffed := http.DetectContentType(img); sniffed != mediaType {
+		return fmt.Errorf("watermark data is %s, not the declared %s", sniffed, mediaType)
+	}
+	return nil
+}
+
+// EncodeWatermark serializes a watermark for the tasks.watermark column ("" when nil)
+func EncodeWatermark(m *Watermark) (string, error) {
+	if m == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// DecodeWatermark parses the tasks.watermark column; nil when the task has none
+func DecodeWatermark(stored string) (*Watermark, error) {
+	if stored == "" {
+		return nil, nil
+	}
+	var m Watermark
+	if err := json.Unmarshal([]byte(stored), &m); err != nil {
+		return nil, fmt.Errorf("invalid stored watermark: %w", err)
+	}
+	return &m, nil
+}
+
+// watermarkScript places the watermark image. The source is only ever assigned to
+// img.src; it is re-applied by id, so running it twice doesn't stack images. It
+// resolves once the image is decoded (at most 5s), so the next frame includes it.
+const watermarkScript = `
+	([src, position, width, opacity]) => {
+		const apply = () => {
+			let img = document.getElementById('uniquewatermarkoverlay');
+			if (!img) {
+				img = document.createElement('img');
+				img.id = 'uniquewatermarkoverlay';
+				document.body.appendChild(img);
+			}
+			img.src = src;
+			img.alt = '';
+			img.style.position = 'fixed';
+			img.style.width = width + 'px';
+			img.style.height = 'auto';
+			img.style.opacity = String(opacity);
+			img.style.zIndex = '9998';
+			img.style.pointerEvents = 'none';
+			img.style.top = img.style.bottom = img.style.left = img.style.right = img.style.transform = '';
+			if (position === 'center') {
+				img.style.top = '50%';
+				img.style.left = '50%';
+				img.style.transform = 'translate(-50%, -50%)';
+			} else {
+				img.style[position.startsWith('top') ? 'top' : 'bottom'] = '10px';
+				img.style[position.endsWith('left') ? 'left' : 'right'] = '10px';
+			}
+			return Promise.race([
+				img.decode().catch(() => {}),
+				new Promise((resolve) => setTimeout(resolve, 5000)),
+			]);
+		};
+		if (document.body) {
+			return apply();
+		}
+		document.addEventListener('DOMContentLoaded', apply);
+	}
+`
+
+// InjectWatermark shows the watermark on the current document and, through an init
+// script, on every document the page loads later (e.g. after an outage reload)
+func InjectWatermark(page playwright.Page, m *Watermark) error {
+	position, opacity := m.Position, m.Opacity
+	if position == "" {
+		position = "bottom-right"
+	}
+	if opacity == 0 {
+		opacity = defaultWatermarkOpacity
+	}
+	args := []interface{}{m.Source, position, m.Width, opacity}
+
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	if err := page.AddInitScript(playwright.Script{
+		Content: playwright.String(fmt.Sprintf("(%s)(%s);", watermarkScript, argsJSON)),
+	}); err != nil {
+		return fmt.Errorf("failed to add watermark script: %w", err)
+	}
+	if _, err := page.Evaluate(watermarkScript, args); err != nil {
+		return fmt.Errorf("failed to inject watermark: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestWatermark_Validate(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	logo := base64.StdEncoding.EncodeToString(buf.Bytes())
+	allowed := []string{"cdn.example.com"}
+	viewport := Viewport{Width: 800, Height: 600}
+
+	tests := []struct {
+		name      string
+		watermark Watermark
+		wantError string
+	}{
+		{"Valid Data URI", Watermark{Source: "data:image/png;base64," + logo, Width: 120}, ""},
+		{"Valid URL", Watermark{Source: "https://cdn.example.com/logo.png", Position: "top-left", Width: 120, Opacity: 1}, ""},
+		{"SVG Not Allowed", Watermark{Source: "data:image/svg+xml;base64," + logo, Width: 120}, "must be base64"},
+		{"Not Base64", Watermark{Source: "data:image/png,%89PNG", Width: 120}, "must be base64"},
+		{"Mismatched Type", Watermark{Source: "data:image/jpeg;base64," + logo, Width: 120}, "not the declared"},
+		{"Too Large", Watermark{Source: "data:image/png;base64," + strings.Repeat("A", MaxWatermarkBytes*2), Width: 120}, "exceeds"},
+		{"Host Not Allowed", Watermark{Source: "https://evil.example.com/logo.png", Width: 120}, "not allowed"},
+		{"Plain HTTP", Watermark{Source: "http://cdn.example.com/logo.png", Width: 120}, "https URL"},
+		{"Bad Position", Watermark{Source: "data:image/png;base64," + logo, Position: "middle", Width: 120}, "position"},
+		{"Too Narrow", Watermark{Source: "data:image/png;base64," + logo, Width: 8}, "width"},
+		{"Wider Than Viewport", Watermark{Source: "data:image/png;base64," + logo, Width: 801}, "width"},
+		{"Opacity Above One", Watermark{Source: "data:image/png;base64," + logo, Width: 120, Opacity: 1.5}, "opacity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.watermark.Validate(allowed, viewport)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Validate() error = %v, want substring %q", err, tt.wantError)
+			}
+		})
+	}
+
+	// Without an allowlist only data URIs are accepted
+	if err := (Watermark{Source: "https://cdn.example.com/logo.png", Width: 120}).Validate(nil, viewport); err == nil {
+		t.Error("Validate() accepted a URL with no allowed hosts")
+	}
+}
+
+func TestWatermark_RoundTrip(t *testing.T) {
+	stored, err := EncodeWatermark(&Watermark{Source: "https://cdn.example.com/logo.png", Position: "center", Width: 200, Opacity: 0.25})
+	if err != nil {
+		t.Fatalf("EncodeWatermark() error = %v", err)
+	}
+	m, err := DecodeWatermark(stored)
+	if err != nil || m == nil || m.Position != "center" || m.Width != 200 || m.Opacity != 0.25 {
+		t.Errorf("DecodeWatermark() = %+v, %v", m, err)
+	}
+	if m, err := DecodeWatermark(""); m != nil || err != nil {
+		t.Errorf("DecodeWatermark(\"\") = %+v, %v, want nil", m, err)
+	}
+}
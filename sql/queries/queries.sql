@@ -4,14 +4,17 @@ INSERT INTO users (username, password_hash) VALUES (?, ?) RETURNING *;
 -- name: GetUserByUsername :one
 SELECT * FROM users WHERE username = ? LIMIT 1;
 
+-- name: GetUser :one
+SELECT * FROM users WHERE id = ? LIMIT 1;
+
 -- name: ListTasks :many
-SELECT * FROM tasks WHERE is_deleted = 0 ORDER BY created_at DESC;
+SELECT * FROM tasks WHERE is_deleted = 0 ORDER BY sort_order ASC, created_at DESC;
 
 -- name: GetTask :one
 SELECT * FROM tasks WHERE id = ? LIMIT 1;
 
 -- name: CreateTask :one
-INSERT INTO tasks (name, target_url, is_enabled, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config) VALUES (?, ?, 0, ?, ?, ?, ?, ?, ?) RETURNING *;
+INSERT INTO tasks (name, target_url, is_enabled, filename_template, custom_css, fps, crf, time_overlay, time_overlay_config, max_recordings, capture_console, encoder_preset, encoder_tune, capture_quality, notify_size_bytes, referer, java_script_enabled, offline, viewports, record_on_change, capture_format, blocked_resources, start_delay_ms, capture_milestones, output_dir, proxy_url, regions, keepalive, watermark) VALUES (?, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING *;
 
 -- name: DeleteTask :exec
 UPDATE tasks SET is_deleted = 1, is_enabled = 0 WHERE id = ?;
@@ -26,11 +29,39 @@ UPDATE tasks SET is_enabled = 0 WHERE id = ?;
 SELECT * FROM tasks WHERE is_enabled = 1;
 
 -- name: CreateRecording :one
-INSERT INTO recordings (task_id, status, file_path, start_time) 
-VALUES (?, ?, ?, CURRENT_TIMESTAMP) RETURNING *;
+INSERT INTO recordings (task_id, status, file_path, viewport, region, run_id, start_time) 
+VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP) RETURNING *;
+
+-- name: CopyRecording :one
+INSERT INTO recordings (task_id, status, start_time, end_time, file_path, viewport, region, run_id, note, tags)
+SELECT task_id, status, start_time, end_time, ?, viewport, region, run_id, note, tags FROM recordings WHERE id = ?
+RETURNING *;
+
+-- name: UpdateRecordingStatus :execrows
+UPDATE recordings SET status = ?, end_time = CURRENT_TIMESTAMP WHERE id = ? AND status = sqlc.arg(from_status);
+
+-- name: UpdateRecordingFilePath :exec
+UPDATE recordings SET file_path = ? WHERE id = ?;
+
+-- name: UpdateRecordingFrameStats :exec
+UPDATE recordings SET dropped_frames = ?, is_degraded = ? WHERE id = ?;
+
+-- name: UpdateRecordingMetadata :exec
+UPDATE recordings SET note = ?, tags = ? WHERE id = ?;
+
+-- name: UpdateRecordingContentHash :exec
+UPDATE recordings SET content_hash = ?, duplicate_of = ? WHERE id = ?;
 
--- name: UpdateRecordingStatus :exec
-UPDATE recordings SET status = ?, end_time = CURRENT_TIMESTAMP WHERE id = ?;
+-- name: UpdateRecordingChecksum :exec
+UPDATE recordings SET checksum = ?, checksum_signature = ? WHERE id = ?;
+
+-- name: UpdateRecordingTranscodeChecksum :exec
+UPDATE recordings SET transcode_checksum = ? WHERE id = ?;
+
+-- name: GetLatestRecordingByHash :one
+SELECT * FROM recordings
+WHERE task_id = ? AND content_hash = ? AND id != ? AND status = 'COMPLETED'
+ORDER BY start_time DESC, id DESC LIMIT 1;
 
 -- name: ListRecordings :many
 SELECT r.*, t.name as task_name 
@@ -41,19 +72,81 @@ ORDER BY r.start_time DESC;
 
 
 
+-- name: ListAllRecordings :many
+SELECT * FROM recordings ORDER BY id;
+
 -- name: GetRecording :one
 SELECT * FROM recordings WHERE id = ? LIMIT 1;
 
+-- name: ToggleRecordingProtected :one
+UPDATE recordings SET is_protected = NOT is_protected WHERE id = ? RETURNING *;
+
 -- name: DeleteRecording :exec
 DELETE FROM recordings WHERE id = ?;
 
+-- name: ListRecordingsToRotate :many
+SELECT * FROM recordings
+WHERE task_id = ? AND is_protected = 0 AND status != 'RECORDING'
+  AND COALESCE(NULLIF(run_id, ''), 'id:' || id) NOT IN (
+    SELECT COALESCE(NULLIF(run_id, ''), 'id:' || id) AS run FROM recordings WHERE task_id = ?
+    GROUP BY run ORDER BY MAX(start_time) DESC, MAX(id) DESC LIMIT ?)
+ORDER BY start_time ASC, id ASC;
+
 -- name: UpdateUserPassword :exec
-UPDATE users SET password_hash = ? WHERE username = ?;
+UPDATE users SET password_hash = ?, must_change_password = 0 WHERE username = ?;
+
+-- name: ResetUserPassword :exec
+UPDATE users SET password_hash = ?, must_change_password = ? WHERE id = ?;
 
 -- name: UpdateTask :exec
 UPDATE tasks 
-SET name = ?, target_url = ?, filename_template = ?, custom_css = ?, fps = ?, crf = ?, time_overlay = ?, time_overlay_config = ?
+SET name = ?, target_url = ?, filename_template = ?, custom_css = ?, fps = ?, crf = ?, time_overlay = ?, time_overlay_config = ?, max_recordings = ?, capture_console = ?, encoder_preset = ?, encoder_tune = ?, capture_quality = ?, notify_size_bytes = ?, referer = ?, java_script_enabled = ?, offline = ?, viewports = ?, record_on_change = ?, capture_format = ?, blocked_resources = ?, start_delay_ms = ?, capture_milestones = ?, output_dir = ?, proxy_url = ?, regions = ?, keepalive = ?, watermark = ?
 WHERE id = ?;
 
+-- name: UpdateTaskSortOrder :execrows
+UPDATE tasks SET sort_order = ? WHERE id = ? AND is_deleted = 0;
+
 -- name: CountUsers :one
 SELECT COUNT(*) FROM users;
+
+-- name: CountTasks :one
+SELECT COUNT(*) FROM tasks WHERE is_deleted = 0;
+
+-- name: UpsertOIDCSession :exec
+INSERT INTO oidc_sessions (username, refresh_token, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(username) DO UPDATE SET refresh_token = excluded.refresh_token, updated_at = CURRENT_TIMESTAMP;
+
+-- name: GetOIDCSession :one
+SELECT * FROM oidc_sessions WHERE username = ? LIMIT 1;
+
+-- name: DeleteOIDCSession :exec
+DELETE FROM oidc_sessions WHERE username = ?;
+
+-- name: UpsertUserTOTP :exec
+INSERT INTO user_totp (username, secret, enabled, recovery_codes, updated_at) VALUES (?, ?, 0, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(username) DO UPDATE SET secret = excluded.secret, enabled = 0, recovery_codes = excluded.recovery_codes, updated_at = CURRENT_TIMESTAMP;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp WHERE username = ? LIMIT 1;
+
+-- name: EnableUserTOTP :exec
+UPDATE user_totp SET enabled = 1, updated_at = CURRENT_TIMESTAMP WHERE username = ?;
+
+-- name: UpdateUserTOTPRecoveryCodes :execrows
+UPDATE user_totp SET recovery_codes = ?, updated_at = CURRENT_TIMESTAMP WHERE username = ? AND recovery_codes = sqlc.arg(previous_codes);
+
+-- name: AcceptUserTOTPCounter :execrows
+UPDATE user_totp SET last_counter = ?, updated_at = CURRENT_TIMESTAMP WHERE username = ? AND last_counter < sqlc.arg(counter);
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp WHERE username = ?;
+
+-- name: UpsertTaskCredentials :exec
+INSERT INTO task_credentials (task_id, username, password, username_selector, password_selector, submit_selector, updated_at) VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(task_id) DO UPDATE SET username = excluded.username, password = excluded.password, username_selector = excluded.username_selector, password_selector = excluded.password_selector, submit_selector = excluded.submit_selector, updated_at = CURRENT_TIMESTAMP;
+
+-- name: GetTaskCredentials :one
+SELECT * FROM task_credentials WHERE task_id = ? LIMIT 1;
+
+-- name: DeleteTaskCredentials :exec
+DELETE FROM task_credentials WHERE task_id = ?;
@@ -2,6 +2,7 @@ CREATE TABLE users (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     username TEXT NOT NULL UNIQUE,
     password_hash TEXT NOT NULL,
+    must_change_password BOOLEAN NOT NULL DEFAULT 0, -- set by an admin password reset
     created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -17,6 +18,27 @@ CREATE TABLE tasks (
     crf INTEGER NOT NULL DEFAULT 23,
     time_overlay BOOLEAN NOT NULL DEFAULT 0,
     time_overlay_config TEXT NOT NULL DEFAULT 'bottom-right',
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    max_recordings INTEGER NOT NULL DEFAULT 0,
+    capture_console BOOLEAN NOT NULL DEFAULT 0,
+    encoder_preset TEXT NOT NULL DEFAULT '',
+    encoder_tune TEXT NOT NULL DEFAULT '',
+    capture_quality INTEGER NOT NULL DEFAULT 0,
+    notify_size_bytes INTEGER NOT NULL DEFAULT 0,
+    referer TEXT NOT NULL DEFAULT '',
+    java_script_enabled BOOLEAN NOT NULL DEFAULT 1,
+    offline BOOLEAN NOT NULL DEFAULT 0,
+    viewports TEXT NOT NULL DEFAULT '', -- JSON array of viewport profiles
+    record_on_change BOOLEAN NOT NULL DEFAULT 0,
+    capture_format TEXT NOT NULL DEFAULT 'jpeg', -- jpeg or png
+    blocked_resources TEXT NOT NULL DEFAULT '', -- comma-separated resource types and domains
+    start_delay_ms INTEGER NOT NULL DEFAULT 0, -- wait after the page is ready before capturing
+    capture_milestones BOOLEAN NOT NULL DEFAULT 0, -- stills at load, networkidle and capture start
+    output_dir TEXT NOT NULL DEFAULT '', -- subdirectory of the recordings directory, '' = default layout
+    proxy_url TEXT NOT NULL DEFAULT '', -- egress proxy of the browser context, encrypted at rest; '' = direct
+    regions TEXT NOT NULL DEFAULT '', -- JSON array of capture regions, each cropped into its own recording
+    keepalive TEXT NOT NULL DEFAULT '', -- JSON keep-alive interval and actions run while recording
+    watermark TEXT NOT NULL DEFAULT '', -- JSON watermark image burned into every frame
     created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -27,5 +49,44 @@ CREATE TABLE recordings (
     start_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     end_time DATETIME,
     file_path TEXT NOT NULL,
+    is_protected BOOLEAN NOT NULL DEFAULT 0,
+    dropped_frames INTEGER NOT NULL DEFAULT 0,
+    is_degraded BOOLEAN NOT NULL DEFAULT 0,
+    content_hash TEXT NOT NULL DEFAULT '', -- SHA-256 of the video stream packets
+    duplicate_of INTEGER, -- earlier recording of the same task with identical content
+    note TEXT NOT NULL DEFAULT '',
+    tags TEXT NOT NULL DEFAULT '', -- comma-separated
+    viewport TEXT NOT NULL DEFAULT '', -- viewport profile name, '' for the default
+    region TEXT NOT NULL DEFAULT '', -- capture region name, '' for the full page
+    checksum TEXT NOT NULL DEFAULT '', -- SHA-256 of the finished file, for tamper evidence
+    checksum_signature TEXT NOT NULL DEFAULT '', -- HMAC-SHA256 of the checksum (RECORDING_SIGNING_KEY), '' = unsigned
+    run_id TEXT NOT NULL DEFAULT '', -- shared by the recordings of one start (viewports, regions); '' = its own run
+    transcode_checksum TEXT NOT NULL DEFAULT '', -- SHA-256 of the file written by the last transcode, '' = as captured
+    FOREIGN KEY(task_id) REFERENCES tasks(id) ON DELETE CASCADE
+);
+
+CREATE TABLE oidc_sessions (
+    username TEXT PRIMARY KEY,
+    refresh_token TEXT NOT NULL, -- AES-GCM encrypted
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_totp (
+    username TEXT PRIMARY KEY,
+    secret TEXT NOT NULL, -- AES-GCM encrypted
+    enabled BOOLEAN NOT NULL DEFAULT 0,
+    recovery_codes TEXT NOT NULL DEFAULT '', -- comma-separated SHA-256 hashes
+    last_counter INTEGER NOT NULL DEFAULT 0, -- period of the last accepted TOTP code (replay guard)
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE task_credentials (
+    task_id INTEGER PRIMARY KEY,
+    username TEXT NOT NULL, -- AES-GCM encrypted
+    password TEXT NOT NULL, -- AES-GCM encrypted
+    username_selector TEXT NOT NULL,
+    password_selector TEXT NOT NULL,
+    submit_selector TEXT NOT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY(task_id) REFERENCES tasks(id) ON DELETE CASCADE
 );
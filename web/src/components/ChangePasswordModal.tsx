@@ -5,9 +5,11 @@ import { AlertCircle, Check, Loader2, X } from 'lucide-react'
 interface ChangePasswordModalProps {
     isOpen: boolean
     onClose: () => void
+    // Required after an admin reset: the login token is only good for this change
+    required?: boolean
 }
 
-export function ChangePasswordModal({ isOpen, onClose }: ChangePasswordModalProps) {
+export function ChangePasswordModal({ isOpen, onClose, required = false }: ChangePasswordModalProps) {
     const [oldPassword, setOldPassword] = useState('')
     const [newPassword, setNewPassword] = useState('')
     const [confirmPassword, setConfirmPassword] = useState('')
@@ -64,14 +66,19 @@ export function ChangePasswordModal({ isOpen, onClose }: ChangePasswordModalProp
     return (
         <div className="fixed inset-0 z-50 flex items-center justify-center bg-black/50 backdrop-blur-sm">
             <div className="bg-gray-800 rounded-lg shadow-xl w-full max-w-md border border-gray-700 p-6 relative">
-                <button
-                    onClick={onClose}
-                    className="absolute top-4 right-4 text-gray-400 hover:text-white"
-                >
-                    <X className="w-5 h-5" />
-                </button>
+                {!required && (
+                    <button
+                        onClick={onClose}
+                        className="absolute top-4 right-4 text-gray-400 hover:text-white"
+                    >
+                        <X className="w-5 h-5" />
+                    </button>
+                )}
 
                 <h2 className="text-xl font-bold text-white mb-6">Change Password</h2>
+                {required && (
+                    <p className="text-sm text-gray-400 -mt-4 mb-6">Your password was reset by an administrator. Choose a new password to continue.</p>
+                )}
 
                 {success ? (
                     <div className="flex flex-col items-center justify-center py-8 text-center text-green-400">
@@ -128,7 +135,7 @@ export function ChangePasswordModal({ isOpen, onClose }: ChangePasswordModalProp
                                 onClick={onClose}
                                 className="px-4 py-2 text-gray-300 hover:text-white mr-2"
                             >
-                                Cancel
+                                {required ? 'Sign out' : 'Cancel'}
                             </button>
                             <button
                                 type="submit"
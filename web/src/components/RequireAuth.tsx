@@ -6,7 +6,8 @@ export function RequireAuth({ children }: { children: ReactNode }) {
     const location = useLocation()
 
     if (!token) {
-        return <Navigate to="/login" state={{ from: location }} replace />
+        const next = encodeURIComponent(location.pathname + location.search)
+        return <Navigate to={`/login?next=${next}`} replace />
     }
 
     return children
@@ -21,9 +21,15 @@ axios.interceptors.response.use(
         if (error.response && error.response.status === 401) {
             // Redirect to login if unauthorized
             if (!window.location.pathname.includes('/login')) {
-                window.location.href = '/login'
+                const next = encodeURIComponent(window.location.pathname + window.location.search)
+                window.location.href = `/login?next=${next}`
             }
         }
+        // A token issued for a required password change is refused everywhere else
+        if (error.response && error.response.status === 403 && error.response.data?.must_change_password) {
+            localStorage.removeItem('token')
+            window.location.href = '/login'
+        }
         return Promise.reject(error)
     }
 )
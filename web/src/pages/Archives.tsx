@@ -6,6 +6,7 @@ interface Archive {
     id: number
     task_name: string
     file_path: string
+    download_url?: string
     start_time: string
     size: string
     status: string
@@ -71,7 +72,7 @@ export function Archives() {
                                     </span>
                                     <div className="flex items-center gap-3">
                                         <a
-                                            href={`/recordings/${archive.file_path.split('/').pop()}`}
+                                            href={archive.download_url || `/recordings/${archive.file_path.split('/').pop()}`}
                                             download
                                             className="text-gray-400 hover:text-white transition-colors"
                                             title="Download"
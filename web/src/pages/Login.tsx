@@ -1,11 +1,34 @@
 import React, { useState } from 'react'
 import { useNavigate } from 'react-router-dom'
+import { ChangePasswordModal } from '../components/ChangePasswordModal'
+
+// Page to return to after login (?next=). Same rules as the server's safeNextPath:
+// only a path on this site, never another origin.
+function safeNextPath(next: string | null): string | null {
+    if (!next || !next.startsWith('/') || next.startsWith('//') || /[\\\r\n]/.test(next)) {
+        return null
+    }
+    try {
+        if (new URL(next, window.location.origin).origin !== window.location.origin) {
+            return null
+        }
+    } catch {
+        return null
+    }
+    return next
+}
 
 export function Login() {
     const [username, setUsername] = useState('')
     const [password, setPassword] = useState('')
+    // Second step, shown once the server asks for a TOTP or recovery code
+    const [totpRequired, setTotpRequired] = useState(false)
+    const [totpCode, setTotpCode] = useState('')
+    // Set after an admin reset: the password must be changed before anything else
+    const [mustChangePassword, setMustChangePassword] = useState(false)
     const [error, setError] = useState<string | null>(null)
     const navigate = useNavigate()
+    const next = safeNextPath(new URLSearchParams(window.location.search).get('next'))
 
     // Parse URL params for OIDC handling
     React.useEffect(() => {
@@ -15,7 +38,7 @@ export function Login() {
 
         if (token) {
             localStorage.setItem('token', token)
-            navigate('/', { replace: true })
+            navigate(next ?? '/', { replace: true })
         }
 
         if (errorParam) {
@@ -28,10 +51,11 @@ export function Login() {
                 'invalid_token': 'Authentication failed: Invalid token.',
             }
             setError(errorMap[errorParam] || `Authentication Error: ${errorParam}`)
-            // Clean URL
-            window.history.replaceState({}, document.title, window.location.pathname)
+            // Clean URL, keeping the return target for the next attempt
+            const search = next ? `?next=${encodeURIComponent(next)}` : ''
+            window.history.replaceState({}, document.title, window.location.pathname + search)
         }
-    }, [navigate])
+    }, [navigate, next])
 
     const handleLogin = async (e: React.FormEvent) => {
         e.preventDefault()
@@ -40,14 +64,28 @@ export function Login() {
             const res = await fetch('/api/login', {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ username, password })
+                body: JSON.stringify(totpRequired ? { username, password, totp_code: totpCode } : { username, password })
             })
             if (res.ok) {
                 const data = await res.json()
                 localStorage.setItem('token', data.token)
-                navigate('/')
+                if (data.must_change_password) {
+                    setMustChangePassword(true)
+                    return
+                }
+                navigate(next ?? '/')
+                return
+            }
+            const data = await res.json().catch(() => ({}))
+            if (data.totp_required) {
+                setTotpRequired(true)
+            } else if (totpRequired && data.error === 'invalid totp code') {
+                setError('Login failed: Invalid authentication code')
+                setTotpCode('')
             } else {
                 setError('Login failed: Invalid credentials')
+                setTotpRequired(false)
+                setTotpCode('')
             }
         } catch (err) {
             setError('Error logging in')
@@ -56,6 +94,15 @@ export function Login() {
 
     return (
         <div className="min-h-screen flex items-center justify-center bg-gray-950 text-white p-4">
+            <ChangePasswordModal
+                isOpen={mustChangePassword}
+                required
+                onClose={() => {
+                    localStorage.removeItem('token')
+                    setMustChangePassword(false)
+                    setPassword('')
+                }}
+            />
             <div className="w-full max-w-md space-y-4">
                 {error && (
                     <div className="p-4 rounded-md bg-red-900/30 border border-red-800 text-red-200 text-sm font-medium">
@@ -84,8 +131,23 @@ export function Login() {
                                 className="w-full bg-gray-950 border border-gray-800 rounded px-3 py-2 text-white placeholder-gray-600 focus:border-blue-500 focus:outline-none focus:ring-1 focus:ring-blue-500 transition-colors"
                             />
                         </div>
+                        {totpRequired && (
+                            <div>
+                                <label className="block text-sm text-gray-400 mb-1">Authentication code</label>
+                                <input
+                                    type="text"
+                                    inputMode="numeric"
+                                    autoComplete="one-time-code"
+                                    autoFocus
+                                    value={totpCode}
+                                    onChange={e => setTotpCode(e.target.value)}
+                                    placeholder="123456 or a recovery code"
+                                    className="w-full bg-gray-950 border border-gray-800 rounded px-3 py-2 text-white placeholder-gray-600 focus:border-blue-500 focus:outline-none focus:ring-1 focus:ring-blue-500 transition-colors"
+                                />
+                            </div>
+                        )}
                         <button type="submit" className="w-full bg-blue-600 hover:bg-blue-500 py-2 rounded font-medium transition-colors text-white shadow-lg shadow-blue-900/20">
-                            Sign in with Password
+                            {totpRequired ? 'Verify' : 'Sign in with Password'}
                         </button>
                     </div>
 
@@ -101,7 +163,7 @@ export function Login() {
 
                         <div className="mt-6">
                             <a
-                                href="/auth/login"
+                                href={next ? `/auth/login?next=${encodeURIComponent(next)}` : '/auth/login'}
                                 className="w-full flex items-center justify-center py-2 px-4 border border-gray-700 rounded-md shadow-sm text-sm font-medium text-gray-200 bg-gray-800 hover:bg-gray-750 focus:outline-none focus:ring-2 focus:ring-offset-2 focus:ring-offset-gray-900 focus:ring-gray-500 transition-colors"
                             >
                                 <svg className="h-5 w-5 mr-2" fill="currentColor" viewBox="0 0 24 24">